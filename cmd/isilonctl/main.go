@@ -0,0 +1,523 @@
+package main
+
+/*
+ Copyright (c) 2019 Dell Inc, or its subsidiaries.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/akutz/gournal"
+	"github.com/dell/csi-isilon/common/constants"
+	"github.com/dell/csi-isilon/common/utils"
+	"github.com/dell/csi-isilon/service"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+const defaultEndpoint = "unix:///var/run/csi/csi.sock"
+
+// command is a subcommand of isilonctl
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"migrate", "migrate a volume to another cluster", runMigrate},
+		{"migration-status", "show the status of the volume migrations", runMigrationStatus},
+		{"revert", "revert a volume in place to one of its snapshots", runRevert},
+		{"revert-status", "show the status of the volume reverts", runRevertStatus},
+		{"modify", "change the quota limits, export options, root clients or placement of a volume", runModify},
+		{"import", "adopt an existing directory as a volume and print its PV manifest", runImport},
+		{"decode-id", "decode a volume, snapshot or node ID", runDecodeID},
+		{"show-volume", "show the export, clients, quota and snapshots of a volume", runShowVolume},
+		{"list-artifacts", "list the exports, quotas and snapshots created by the driver under the isiPath of the clusters", runListArtifacts},
+		{"force-unpublish", "remove a node from the clients of the export of a volume", runForceUnpublish},
+		{"check-connectivity", "check the connection to each cluster of the secret", runCheckConnectivity},
+		{"validate-secret", "validate a cluster secret offline and print a report", runValidateSecret},
+		{"cluster-health", "show the health state and circuit breaker of each cluster", runClusterHealth},
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(2)
+	}
+	for _, cmd := range commands {
+		if cmd.name == os.Args[1] {
+			if err := cmd.run(os.Args[2:]); err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "%s: %v\n", cmd.name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	printUsage()
+	os.Exit(2)
+}
+
+func printUsage() {
+	_, _ = fmt.Fprintf(os.Stderr, "Usage: isilonctl <command> [flags]\n\nCommands:\n")
+	for _, cmd := range commands {
+		_, _ = fmt.Fprintf(os.Stderr, "  %-20s %s\n", cmd.name, cmd.usage)
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "\nRun 'isilonctl <command> -h' for the flags of a command.\n")
+}
+
+// dialController connects to the CSI endpoint of the controller, e.g. unix:///var/run/csi/csi.sock
+func dialController(ctx context.Context, endpoint string) (*grpc.ClientConn, error) {
+	network, address := "tcp", endpoint
+	if strings.HasPrefix(endpoint, "unix://") {
+		network, address = "unix", strings.TrimPrefix(endpoint, "unix://")
+	} else if strings.HasPrefix(endpoint, "tcp://") {
+		address = strings.TrimPrefix(endpoint, "tcp://")
+	}
+	return grpc.DialContext(ctx, address, grpc.WithInsecure(), grpc.WithBlock(),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		}))
+}
+
+// initLogger sends the logs of the driver code to stderr, stdout is kept for the output of the commands
+func initLogger(debug bool) {
+	// the logger announces itself on stdout when it is created
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+	utils.GetLogger().SetOutput(os.Stderr)
+	os.Stdout = stdout
+	gournal.DefaultAppender = gournal.NewAppenderWithOptions(os.Stderr)
+	if debug {
+		utils.UpdateLogLevel(logrus.DebugLevel)
+	} else {
+		utils.UpdateLogLevel(logrus.WarnLevel)
+	}
+}
+
+// newClusterAdmin parses the cluster secret of the driver, the commands using it talk to the clusters directly
+// and do not need the controller to run
+func newClusterAdmin(ctx context.Context, configFile string, debug bool) (*service.ClusterAdmin, error) {
+	initLogger(debug)
+	return service.NewClusterAdmin(ctx, configFile)
+}
+
+// addClusterFlags adds the flags of the commands talking to the clusters directly
+func addClusterFlags(flags *flag.FlagSet) (*string, *time.Duration, *bool) {
+	configFile := constants.IsilonConfigFile
+	if file, ok := os.LookupEnv(constants.EnvIsilonConfigFile); ok {
+		configFile = file
+	}
+	config := flags.String("config", configFile, "file of the cluster secret of the driver")
+	timeout := flags.Duration("timeout", 2*time.Minute, "timeout of the command")
+	debug := flags.Bool("debug", false, "log the requests to the clusters")
+	return config, timeout, debug
+}
+
+// printJSON prints the response of a command in a machine-readable form
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func runMigrate(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	endpoint := flags.String("endpoint", defaultEndpoint, "CSI endpoint of the controller")
+	timeout := flags.Duration("timeout", 2*time.Minute, "timeout of the request")
+	req := &service.MigrateVolumeRequest{}
+	flags.StringVar(&req.VolumeID, "volume-id", "", "normalized ID of the volume to migrate (required)")
+	flags.StringVar(&req.TargetCluster, "target-cluster", "", "name of the cluster to migrate the volume to (required)")
+	flags.StringVar(&req.TargetAccessZone, "target-access-zone", "", "access zone of the target volume, the access zone of the driver by default")
+	flags.StringVar(&req.TargetIsiPath, "target-isi-path", "", "isiPath of the target volume, the isiPath of the target cluster by default")
+	flags.StringVar(&req.CopyMethod, "copy-method", service.MigrationCopySyncIQ, "'synciq' or 'rsync'")
+	flags.StringVar(&req.NodeID, "node-id", "", "CSI node ID of the node running the rsync job")
+	flags.BoolVar(&req.DryRun, "dry-run", false, "only validate the migration and print its steps")
+	flags.BoolVar(&req.RetainSource, "retain-source", false, "keep the source volume once migrated")
+	_ = flags.Parse(args)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	conn, err := dialController(ctx, *endpoint)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	resp, err := service.NewAdminClient(conn).MigrateVolume(ctx, req)
+	if err != nil {
+		return err
+	}
+	return printJSON(resp.Migration)
+}
+
+func runMigrationStatus(args []string) error {
+	flags := flag.NewFlagSet("migration-status", flag.ExitOnError)
+	endpoint := flags.String("endpoint", defaultEndpoint, "CSI endpoint of the controller")
+	timeout := flags.Duration("timeout", 30*time.Second, "timeout of the request")
+	req := &service.GetMigrationStatusRequest{}
+	flags.StringVar(&req.VolumeID, "volume-id", "", "normalized ID of the migrated volume, all the migrations if not set")
+	_ = flags.Parse(args)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	conn, err := dialController(ctx, *endpoint)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	resp, err := service.NewAdminClient(conn).GetMigrationStatus(ctx, req)
+	if err != nil {
+		return err
+	}
+	return printJSON(resp.Migrations)
+}
+
+func runRevert(args []string) error {
+	flags := flag.NewFlagSet("revert", flag.ExitOnError)
+	endpoint := flags.String("endpoint", defaultEndpoint, "CSI endpoint of the controller")
+	timeout := flags.Duration("timeout", 2*time.Minute, "timeout of the request, or of the whole revert with -wait")
+	wait := flags.Bool("wait", false, "wait for the end of the revert, reporting its progress on stderr")
+	interval := flags.Duration("interval", 10*time.Second, "interval between two progress reports with -wait")
+	req := &service.RevertVolumeRequest{}
+	flags.StringVar(&req.VolumeID, "volume-id", "", "normalized ID of the volume to revert (required)")
+	flags.StringVar(&req.SnapshotID, "snapshot-id", "", "normalized ID of the snapshot of the volume to revert to (required)")
+	_ = flags.Parse(args)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	conn, err := dialController(ctx, *endpoint)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	client := service.NewAdminClient(conn)
+	resp, err := client.RevertVolume(ctx, req)
+	if err != nil {
+		return err
+	}
+	revert := resp.Revert
+	for *wait && revert.EndTime == nil {
+		_, _ = fmt.Fprintf(os.Stderr, "job %d: %s %s\n", revert.JobID, revert.JobState, revert.Progress)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(*interval):
+		}
+		statusResp, err := client.GetRevertStatus(ctx, &service.GetRevertStatusRequest{VolumeID: req.VolumeID})
+		if err != nil {
+			return err
+		}
+		revert = statusResp.Reverts[0]
+	}
+	if err := printJSON(revert); err != nil {
+		return err
+	}
+	if revert.State == service.RevertStateFailed {
+		return fmt.Errorf("revert of volume '%s' failed: %s", req.VolumeID, revert.Message)
+	}
+	return nil
+}
+
+func runRevertStatus(args []string) error {
+	flags := flag.NewFlagSet("revert-status", flag.ExitOnError)
+	endpoint := flags.String("endpoint", defaultEndpoint, "CSI endpoint of the controller")
+	timeout := flags.Duration("timeout", 30*time.Second, "timeout of the request")
+	req := &service.GetRevertStatusRequest{}
+	flags.StringVar(&req.VolumeID, "volume-id", "", "normalized ID of the reverted volume, all the reverts if not set")
+	_ = flags.Parse(args)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	conn, err := dialController(ctx, *endpoint)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	resp, err := service.NewAdminClient(conn).GetRevertStatus(ctx, req)
+	if err != nil {
+		return err
+	}
+	return printJSON(resp.Reverts)
+}
+
+func runModify(args []string) error {
+	flags := flag.NewFlagSet("modify", flag.ExitOnError)
+	endpoint := flags.String("endpoint", defaultEndpoint, "CSI endpoint of the controller")
+	timeout := flags.Duration("timeout", 2*time.Minute, "timeout of the request")
+	req := &service.ModifyVolumeRequest{MutableParameters: map[string]string{}}
+	flags.StringVar(&req.VolumeID, "volume-id", "", "normalized ID of the volume to modify (required)")
+	flags.Usage = func() {
+		_, _ = fmt.Fprintf(os.Stderr, "Usage: isilonctl modify -volume-id <volume ID> [flags] <parameter>=<value>...\n\n"+
+			"Parameters: %s, %s, %s, %s, %s, %s, %s, %s\n\nFlags:\n",
+			service.RootClientEnabledParam, service.StoragePoolParam, service.ProtectionLevelParam, service.SSDStrategyParam,
+			service.QuotaSoftLimitParam, service.QuotaSoftGracePeriodParam, service.QuotaAdvisoryLimitParam, service.ExportSecurityFlavorsParam)
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if req.VolumeID == "" || flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	for _, arg := range flags.Args() {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid parameter '%s', <parameter>=<value> is expected", arg)
+		}
+		req.MutableParameters[parts[0]] = parts[1]
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	conn, err := dialController(ctx, *endpoint)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := service.NewAdminClient(conn).ModifyVolume(ctx, req); err != nil {
+		return err
+	}
+	_, err = fmt.Printf("volume '%s' modified\n", req.VolumeID)
+	return err
+}
+
+func runImport(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	endpoint := flags.String("endpoint", defaultEndpoint, "CSI endpoint of the controller")
+	timeout := flags.Duration("timeout", 2*time.Minute, "timeout of the request")
+	output := flags.String("output", "yaml", "'yaml' prints the PV manifest, 'json' prints the whole response")
+	accessModes := flags.String("access-modes", "ReadWriteMany", "comma-separated access modes of the PV")
+	req := &service.ImportVolumeRequest{}
+	flags.StringVar(&req.Path, "path", "", "absolute path of the directory to import, e.g. /ifs/data/legacy/app1 (required)")
+	flags.StringVar(&req.ClusterName, "cluster", "", "name of the cluster of the directory, the default cluster if not set")
+	flags.StringVar(&req.AccessZone, "access-zone", "", "access zone of the export, the access zone of the driver by default")
+	flags.StringVar(&req.AzServiceIP, "az-service-ip", "", "IP the nodes mount the export from, the endpoint of the cluster by default")
+	flags.StringVar(&req.Quota, "quota", service.ImportQuotaNone, "'none', 'adopt' the quota of the directory or 'create' one")
+	flags.Int64Var(&req.SizeInBytes, "size", 0, "capacity of the volume in bytes, the hard threshold of an adopted quota by default")
+	flags.BoolVar(&req.RootClientEnabled, "root-client-enabled", false, "add the nodes as root clients of the export")
+	flags.StringVar(&req.PVName, "pv-name", "", "name of the PV, the name of the directory by default")
+	flags.StringVar(&req.StorageClass, "storage-class", "", "storage class of the PV")
+	flags.StringVar(&req.ReclaimPolicy, "reclaim-policy", "Retain", "reclaim policy of the PV")
+	flags.StringVar(&req.ClaimName, "claim-name", "", "name of the PVC to bind the PV to")
+	flags.StringVar(&req.ClaimNamespace, "claim-namespace", "", "namespace of the PVC to bind the PV to")
+	_ = flags.Parse(args)
+	if *accessModes != "" {
+		req.AccessModes = strings.Split(*accessModes, ",")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	conn, err := dialController(ctx, *endpoint)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	resp, err := service.NewAdminClient(conn).ImportVolume(ctx, req)
+	if err != nil {
+		return err
+	}
+	if *output == "json" {
+		return printJSON(resp)
+	}
+	_, err = fmt.Print(resp.PersistentVolume)
+	return err
+}
+
+func runDecodeID(args []string) error {
+	flags := flag.NewFlagSet("decode-id", flag.ExitOnError)
+	debug := flags.Bool("debug", false, "log how the IDs are parsed")
+	flags.Usage = func() {
+		_, _ = fmt.Fprintf(os.Stderr, "Usage: isilonctl decode-id [-debug] <volume, snapshot or node ID>...\n")
+	}
+	_ = flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	initLogger(*debug)
+
+	decodedIDs := make([]*service.DecodedID, 0, flags.NArg())
+	for _, id := range flags.Args() {
+		decodedID, err := service.DecodeID(context.Background(), id)
+		if err != nil {
+			return err
+		}
+		decodedIDs = append(decodedIDs, decodedID)
+	}
+	return printJSON(decodedIDs)
+}
+
+func runShowVolume(args []string) error {
+	flags := flag.NewFlagSet("show-volume", flag.ExitOnError)
+	config, timeout, debug := addClusterFlags(flags)
+	volumeID := flags.String("volume-id", "", "normalized ID of the volume (required)")
+	_ = flags.Parse(args)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	admin, err := newClusterAdmin(ctx, *config, *debug)
+	if err != nil {
+		return err
+	}
+	details, err := admin.ShowVolume(ctx, *volumeID)
+	if err != nil {
+		return err
+	}
+	return printJSON(details)
+}
+
+func runListArtifacts(args []string) error {
+	flags := flag.NewFlagSet("list-artifacts", flag.ExitOnError)
+	config, timeout, debug := addClusterFlags(flags)
+	clusterName := flags.String("cluster", "", "name of the cluster, all the clusters if not set")
+	isiPath := flags.String("isi-path", "", "path to list the artifacts under, the isiPath of each cluster by default")
+	accessZone := flags.String("access-zone", "", "access zone of the exports, the access zone of the driver by default")
+	_ = flags.Parse(args)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	admin, err := newClusterAdmin(ctx, *config, *debug)
+	if err != nil {
+		return err
+	}
+	artifacts, err := admin.ListArtifacts(ctx, *clusterName, *isiPath, *accessZone)
+	if err != nil {
+		return err
+	}
+	return printJSON(artifacts)
+}
+
+func runForceUnpublish(args []string) error {
+	flags := flag.NewFlagSet("force-unpublish", flag.ExitOnError)
+	config, timeout, debug := addClusterFlags(flags)
+	volumeID := flags.String("volume-id", "", "normalized ID of the volume (required)")
+	nodeID := flags.String("node-id", "", "CSI node ID of the node to remove from the export (required)")
+	_ = flags.Parse(args)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	admin, err := newClusterAdmin(ctx, *config, *debug)
+	if err != nil {
+		return err
+	}
+	if err := admin.ForceUnpublish(ctx, *volumeID, *nodeID); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "node '%s' removed from the export of volume '%s'\n", *nodeID, *volumeID)
+	return nil
+}
+
+func runCheckConnectivity(args []string) error {
+	flags := flag.NewFlagSet("check-connectivity", flag.ExitOnError)
+	config, timeout, debug := addClusterFlags(flags)
+	_ = flags.Parse(args)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	admin, err := newClusterAdmin(ctx, *config, *debug)
+	if err != nil {
+		return err
+	}
+	results := admin.CheckConnectivity(ctx)
+	if err := printJSON(results); err != nil {
+		return err
+	}
+	for _, result := range results {
+		if !result.Connected {
+			return fmt.Errorf("cluster '%s' is not reachable", result.ClusterName)
+		}
+	}
+	return nil
+}
+
+func runValidateSecret(args []string) error {
+	flags := flag.NewFlagSet("validate-secret", flag.ExitOnError)
+	config, timeout, debug := addClusterFlags(flags)
+	checkClusters := flags.Bool("check-clusters", false, "connect to each cluster and check its access zones and isiPath")
+	resolveCredentials := flags.Bool("resolve-credentials", false, "resolve the file, env and provider references of the credentials")
+	opts := service.SecretValidationOptions{}
+	customTopology := flags.Bool("custom-topology", false, "validate the secret for a driver with custom topology enabled (default "+constants.EnvCustomTopologyEnabled+")")
+	insecure := flags.Bool("insecure", false, "skip the certificate validation of the clusters without TLS settings (default "+constants.EnvInsecure+")")
+	flags.StringVar(&opts.Port, "port", "", "port of the clusters without isiPort (default "+constants.EnvPort+")")
+	flags.StringVar(&opts.Path, "isi-path", "", "isiPath of the clusters without isiPath (default "+constants.EnvPath+")")
+	flags.StringVar(&opts.AccessZone, "access-zone", "", "access zone checked on the clusters (default "+constants.EnvAccessZone+")")
+	_ = flags.Parse(args)
+	// the options of the driver are only overridden by the flags given on the command line
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "custom-topology":
+			opts.CustomTopologyEnabled = customTopology
+		case "insecure":
+			opts.Insecure = insecure
+		}
+	})
+	opts.ResolveCredentials = *resolveCredentials || *checkClusters
+	opts.CheckClusters = *checkClusters
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	initLogger(*debug)
+	report, err := service.ValidateSecret(ctx, *config, opts)
+	if err != nil {
+		return err
+	}
+	if err := printJSON(report); err != nil {
+		return err
+	}
+	if !report.Valid {
+		return fmt.Errorf("secret '%s' is not valid", *config)
+	}
+	return nil
+}
+
+func runClusterHealth(args []string) error {
+	flags := flag.NewFlagSet("cluster-health", flag.ExitOnError)
+	endpoint := flags.String("endpoint", defaultEndpoint, "CSI endpoint of the controller")
+	timeout := flags.Duration("timeout", 30*time.Second, "timeout of the request")
+	req := &service.GetClusterHealthRequest{}
+	flags.StringVar(&req.ClusterName, "cluster-name", "", "name of the cluster, all the clusters if not set")
+	_ = flags.Parse(args)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	conn, err := dialController(ctx, *endpoint)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	resp, err := service.NewAdminClient(conn).GetClusterHealth(ctx, req)
+	if err != nil {
+		return err
+	}
+	return printJSON(resp.Clusters)
+}
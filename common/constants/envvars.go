@@ -77,4 +77,31 @@ const (
 
 	// EnvMaxVolumesPerNode specifies maximum number of volumes that controller can publish to the node.
 	EnvMaxVolumesPerNode = "X_CSI_MAX_VOLUMES_PER_NODE"
+
+	// EnvNodeCleanupEnabled indicates whether the controller should remove the clients of deleted k8s nodes from the NFS exports
+	EnvNodeCleanupEnabled = "X_CSI_ISI_NODE_CLEANUP_ENABLED"
+
+	// EnvClusterPlacement is the placement mode of the volumes whose storage class has no ClusterName, "default" or "capacity"
+	EnvClusterPlacement = "X_CSI_ISI_CLUSTER_PLACEMENT"
+
+	// EnvMigrationImage is the image, providing rsync, of the jobs copying the data of the volumes migrated with the rsync method
+	EnvMigrationImage = "X_CSI_ISI_MIGRATION_IMAGE"
+
+	// EnvMigrationCopyTimeout is how long the copy of the data of a migrated volume may take before the migration fails, e.g. "12h", "0" sets no limit
+	EnvMigrationCopyTimeout = "X_CSI_ISI_MIGRATION_COPY_TIMEOUT"
+
+	// EnvVolumeIDVersion is the version of the IDs of the volumes created by the controller, "1" (legacy) or "2" (self-describing)
+	EnvVolumeIDVersion = "X_CSI_ISI_VOLUME_ID_VERSION"
+
+	// EnvHealthProbeInterval is the interval the health of the clusters is probed at in the background, e.g. "30s", "0" disables the probes
+	EnvHealthProbeInterval = "X_CSI_ISI_HEALTH_PROBE_INTERVAL"
+
+	// EnvCircuitBreakerThreshold is the number of consecutive failed probes of a cluster, or requests which could not reach it, after which the requests to it fail fast, "0" disables the circuit breaker
+	EnvCircuitBreakerThreshold = "X_CSI_ISI_CIRCUIT_BREAKER_THRESHOLD"
+
+	// EnvCircuitBreakerResetTimeout is how long the requests to an unavailable cluster fail fast before one of them probes it again, e.g. "2m"
+	EnvCircuitBreakerResetTimeout = "X_CSI_ISI_CIRCUIT_BREAKER_RESET_TIMEOUT"
+
+	// EnvMetricsAddress is the address the health metrics of the clusters are served on at /metrics, e.g. ":9090", they are not served if not set
+	EnvMetricsAddress = "X_CSI_ISI_METRICS_ADDRESS"
 )
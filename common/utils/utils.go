@@ -169,7 +169,10 @@ func CombineTwoStrings(s1 string, s2 string, sign string) string {
 var CSIQuotaIDPrefix = "CSI_QUOTA_ID:"
 
 // QuotaIDPattern the regex pattern that identifies the quota id set in the export's description field set by csi driver
-var QuotaIDPattern = regexp.MustCompile(fmt.Sprintf("^%s(.*)", CSIQuotaIDPrefix))
+var QuotaIDPattern = regexp.MustCompile(fmt.Sprintf("^%s(\\S*)", CSIQuotaIDPrefix))
+
+// CSISmartLockTag is the CSI tag stored in the export's description field of the volumes created with SmartLock
+var CSISmartLockTag = "CSI_SMARTLOCK"
 
 // VolumeIDSeparator is the separator that separates volume name and export ID (two components that a normalized volume ID is comprised of)
 var VolumeIDSeparator = "=_=_="
@@ -180,6 +183,25 @@ var SnapshotIDSeparator = "=_=_="
 // VolumeIDPattern is the regex pattern that identifies the quota id set in the export's description field set by csi driver
 var VolumeIDPattern = regexp.MustCompile(fmt.Sprintf("^(.+)%s(\\d+)%s(.+)$", VolumeIDSeparator, VolumeIDSeparator))
 
+// VolumeIDVersion1 is the version of the legacy volume IDs, made of tokens separated by VolumeIDSeparator
+const VolumeIDVersion1 = 1
+
+// VolumeIDVersion2 is the version of the self-describing volume IDs, e.g. v2:cluster1:System:19:nfs:::/ifs/data/csi/k8s-e89c9d089e
+const VolumeIDVersion2 = 2
+
+// VolumeIDV2Prefix is the prefix of the version 2 volume IDs
+var VolumeIDV2Prefix = "v2:"
+
+// VolumeIDV2Pattern is the regex pattern of the version 2 volume IDs, i.e. v2:cluster:zone:exportID:protocol:flags:name:path,
+// the name being omitted when it is the last element of the path
+var VolumeIDV2Pattern = regexp.MustCompile(fmt.Sprintf("^%s([^:]*):([^:]+):(\\d+):([^:]*):([^:]*):([^:]*):(/.*)$", VolumeIDV2Prefix))
+
+// VolumeIDFlagReadOnlyFromSnapshot is the flag of the version 2 volume IDs of read-only volumes created from a snapshot
+var VolumeIDFlagReadOnlyFromSnapshot = "ro"
+
+// MaxVolumeIDLength is the maximum length of a volume ID allowed by the CSI specification
+const MaxVolumeIDLength = 128
+
 // NodeIDSeparator is the separator that separates node name and IP Address
 var NodeIDSeparator = "=#=#="
 
@@ -202,6 +224,21 @@ func GetQuotaIDWithCSITag(quotaID string) string {
 	return fmt.Sprintf("%s%s", CSIQuotaIDPrefix, quotaID)
 }
 
+// GetExportDescription returns the description of the export of a new volume, made of its quota id with the CSI tag
+// followed by the SmartLock tag if the volume is created with SmartLock
+func GetExportDescription(quotaID string, smartLock bool) string {
+	description := GetQuotaIDWithCSITag(quotaID)
+	if smartLock {
+		description = strings.TrimSpace(description + " " + CSISmartLockTag)
+	}
+	return description
+}
+
+// IsSmartLockExport returns true if the description of the export tags its volume as created with SmartLock
+func IsSmartLockExport(export isi.Export) bool {
+	return export != nil && IsStringInSlice(CSISmartLockTag, strings.Fields(export.Description))
+}
+
 // GetQuotaIDFromDescription extracts quota id from the description field of export
 func GetQuotaIDFromDescription(ctx context.Context, export isi.Export) (string, error) {
 	log := GetRunIDLogger(ctx)
@@ -341,17 +378,101 @@ func GetNormalizedVolumeID(ctx context.Context, volName string, exportID int, ac
 // ParseNormalizedVolumeID parses the volume ID(using VolumeIDSeparator) to extract the volume name, export ID, access zone and cluster name(optional) that make up the volume ID
 // e.g. k8s-e89c9d089e=_=_=19=_=_=csi0zone => k8s-e89c9d089e, 19, csi0zone, ""
 // e.g. k8s-e89c9d089e=_=_=19=_=_=csi0zone=_=_=cluster1 => k8s-e89c9d089e, 19, csi0zone, cluster1
+// Versioned volume IDs are parsed as well, see ParseVolumeID
 func ParseNormalizedVolumeID(ctx context.Context, volID string) (string, int, string, string, error) {
+	volumeID, err := ParseVolumeID(ctx, volID)
+	if err != nil {
+		return "", 0, "", "", err
+	}
+	return volumeID.Name, volumeID.ExportID, volumeID.AccessZone, volumeID.ClusterName, nil
+}
+
+// VolumeID holds the components of a volume ID
+type VolumeID struct {
+	// Version is 1 for the legacy volume IDs, made of the name, export ID, access zone and cluster name only
+	Version              int
+	Name                 string
+	ExportID             int
+	AccessZone           string
+	ClusterName          string
+	Protocol             string
+	Path                 string
+	ReadOnlyFromSnapshot bool
+}
+
+// FormatVolumeID returns the volume ID of the given components in the given version of the format. The legacy
+// format is used when the versioned volume ID would exceed MaxVolumeIDLength or cannot hold the components
+// e.g. version 2 of k8s-e89c9d089e + 19 + csi0zone + cluster1 + nfs + /ifs/data/csi/k8s-e89c9d089e
+// => v2:cluster1:csi0zone:19:nfs:::/ifs/data/csi/k8s-e89c9d089e
+func FormatVolumeID(ctx context.Context, volumeID *VolumeID) string {
+	log := GetRunIDLogger(ctx)
+
+	if volumeID.Version < VolumeIDVersion2 {
+		return GetNormalizedVolumeID(ctx, volumeID.Name, volumeID.ExportID, volumeID.AccessZone, volumeID.ClusterName)
+	}
+
+	name := volumeID.Name
+	if name == path.Base(volumeID.Path) {
+		name = ""
+	}
+	var flags string
+	if volumeID.ReadOnlyFromSnapshot {
+		flags = VolumeIDFlagReadOnlyFromSnapshot
+	}
+	volID := fmt.Sprintf("%s%s:%s:%d:%s:%s:%s:%s", VolumeIDV2Prefix, volumeID.ClusterName, volumeID.AccessZone,
+		volumeID.ExportID, volumeID.Protocol, flags, name, volumeID.Path)
+
+	if len(volID) > MaxVolumeIDLength || strings.Contains(volumeID.ClusterName+volumeID.AccessZone+volumeID.Protocol+name, ":") ||
+		!strings.HasPrefix(volumeID.Path, "/") {
+		log.Warnf("volume '%s' cannot be identified with a version %d volume ID, falling back to the legacy format",
+			volumeID.Name, VolumeIDVersion2)
+		return GetNormalizedVolumeID(ctx, volumeID.Name, volumeID.ExportID, volumeID.AccessZone, volumeID.ClusterName)
+	}
+
+	log.Debugf("formatted volume ID '%s' of volume '%s'", volID, volumeID.Name)
+
+	return volID
+}
+
+// ParseVolumeID parses a versioned or a legacy volume ID. The path and the protocol of legacy volume IDs are unknown
+// e.g. v2:cluster1:csi0zone:19:nfs:ro:k8s-e89c9d089e:/ifs/.snapshot/snap1/data/csi/k8s-0a1b2c3d4e
+// => k8s-e89c9d089e, 19, csi0zone, cluster1, nfs, /ifs/.snapshot/snap1/data/csi/k8s-0a1b2c3d4e, read-only from snapshot
+func ParseVolumeID(ctx context.Context, volID string) (*VolumeID, error) {
 	log := GetRunIDLogger(ctx)
+
+	if matches := VolumeIDV2Pattern.FindStringSubmatch(volID); matches != nil {
+		exportID, err := strconv.Atoi(matches[3])
+		if err != nil {
+			return nil, err
+		}
+		volumeID := &VolumeID{
+			Version:              VolumeIDVersion2,
+			Name:                 matches[6],
+			ExportID:             exportID,
+			AccessZone:           matches[2],
+			ClusterName:          matches[1],
+			Protocol:             matches[4],
+			Path:                 matches[7],
+			ReadOnlyFromSnapshot: matches[5] == VolumeIDFlagReadOnlyFromSnapshot,
+		}
+		if volumeID.Name == "" {
+			volumeID.Name = path.Base(volumeID.Path)
+		}
+
+		log.Debugf("volume ID '%s' parsed into '%+v'", volID, *volumeID)
+
+		return volumeID, nil
+	}
+
 	tokens := strings.Split(volID, VolumeIDSeparator)
 	if len(tokens) < 3 {
-		return "", 0, "", "", fmt.Errorf("volume ID '%s' cannot be split into tokens", volID)
+		return nil, fmt.Errorf("volume ID '%s' cannot be split into tokens", volID)
 	}
 
 	var clusterName string
 	exportID, err := strconv.Atoi(tokens[1])
 	if err != nil {
-		return "", 0, "", "", err
+		return nil, err
 	}
 
 	if len(tokens) > 3 {
@@ -361,7 +482,13 @@ func ParseNormalizedVolumeID(ctx context.Context, volID string) (string, int, st
 	log.Debugf("volume ID '%s' parsed into volume name '%s', export ID '%d', access zone '%s' and cluster name '%s'",
 		volID, tokens[0], exportID, tokens[2], clusterName)
 
-	return tokens[0], exportID, tokens[2], clusterName, nil
+	return &VolumeID{
+		Version:     VolumeIDVersion1,
+		Name:        tokens[0],
+		ExportID:    exportID,
+		AccessZone:  tokens[2],
+		ClusterName: clusterName,
+	}, nil
 }
 
 // GetNormalizedSnapshotID combines snapshotID ID and cluster name to form the normalized snapshot ID
@@ -433,6 +560,12 @@ func GetIsiPathFromExportPath(exportPath string) string {
 	return fmt.Sprint(exportPath[0 : strings.LastIndex(exportPath, "/")+1])
 }
 
+// IsPathUnder returns true if dirPath is parentPath or one of its descendants
+func IsPathUnder(dirPath, parentPath string) bool {
+	dirPath, parentPath = path.Clean(dirPath), path.Clean(parentPath)
+	return dirPath == parentPath || strings.HasPrefix(dirPath, strings.TrimSuffix(parentPath, "/")+"/")
+}
+
 // GetExportIDFromConflictMessage returns the export id of the export
 // which is creating or just created when there occurs a conflict
 func GetExportIDFromConflictMessage(message string) int {
@@ -18,8 +18,11 @@ package utils
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 
+	isi "github.com/dell/goisilon"
+	apiv2 "github.com/dell/goisilon/api/v2"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -83,6 +86,54 @@ func TestParseNormalizedVolumeID(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func TestFormatVolumeID(t *testing.T) {
+	ctx := context.Background()
+
+	volumeID := &VolumeID{Version: VolumeIDVersion2, Name: "k8s-e89c9d089e", ExportID: 19, AccessZone: "csi0zone",
+		ClusterName: "cluster1", Protocol: "nfs", Path: "/ifs/data/csi/k8s-e89c9d089e"}
+	assert.Equal(t, "v2:cluster1:csi0zone:19:nfs:::/ifs/data/csi/k8s-e89c9d089e", FormatVolumeID(ctx, volumeID))
+
+	volumeID.Path = "/ifs/.snapshot/snap1/data/csi/k8s-0a1b2c3d4e"
+	volumeID.ReadOnlyFromSnapshot = true
+	assert.Equal(t, "v2:cluster1:csi0zone:19:nfs:ro:k8s-e89c9d089e:/ifs/.snapshot/snap1/data/csi/k8s-0a1b2c3d4e", FormatVolumeID(ctx, volumeID))
+
+	volumeID.Path = "/ifs/data/" + strings.Repeat("csi/", 30) + "k8s-e89c9d089e"
+	assert.Equal(t, "k8s-e89c9d089e=_=_=19=_=_=csi0zone=_=_=cluster1", FormatVolumeID(ctx, volumeID))
+
+	volumeID.Version = VolumeIDVersion1
+	volumeID.Path = "/ifs/data/csi/k8s-e89c9d089e"
+	assert.Equal(t, "k8s-e89c9d089e=_=_=19=_=_=csi0zone=_=_=cluster1", FormatVolumeID(ctx, volumeID))
+}
+
+func TestParseVolumeID(t *testing.T) {
+	ctx := context.Background()
+
+	volumeID, err := ParseVolumeID(ctx, "v2:cluster1:csi0zone:19:nfs:::/ifs/data/csi/k8s-e89c9d089e")
+	assert.Nil(t, err)
+	assert.Equal(t, VolumeID{Version: VolumeIDVersion2, Name: "k8s-e89c9d089e", ExportID: 19, AccessZone: "csi0zone",
+		ClusterName: "cluster1", Protocol: "nfs", Path: "/ifs/data/csi/k8s-e89c9d089e"}, *volumeID)
+
+	volumeID, err = ParseVolumeID(ctx, "v2:cluster1:csi0zone:19:nfs:ro:k8s-e89c9d089e:/ifs/.snapshot/snap1/data/csi/k8s-0a1b2c3d4e")
+	assert.Nil(t, err)
+	assert.Equal(t, "k8s-e89c9d089e", volumeID.Name)
+	assert.Equal(t, "/ifs/.snapshot/snap1/data/csi/k8s-0a1b2c3d4e", volumeID.Path)
+	assert.True(t, volumeID.ReadOnlyFromSnapshot)
+
+	volumeID, err = ParseVolumeID(ctx, "k8s-e89c9d089e=_=_=19=_=_=csi0zone")
+	assert.Nil(t, err)
+	assert.Equal(t, VolumeID{Version: VolumeIDVersion1, Name: "k8s-e89c9d089e", ExportID: 19, AccessZone: "csi0zone"}, *volumeID)
+
+	volName, exportID, accessZone, clusterName, err := ParseNormalizedVolumeID(ctx, "v2::csi0zone:19:nfs:::/ifs/data/csi/k8s-e89c9d089e")
+	assert.Nil(t, err)
+	assert.Equal(t, "k8s-e89c9d089e", volName)
+	assert.Equal(t, 19, exportID)
+	assert.Equal(t, "csi0zone", accessZone)
+	assert.Equal(t, "", clusterName)
+
+	_, err = ParseVolumeID(ctx, "v2:cluster1:csi0zone:not_an_integer:nfs:::/ifs/data/csi/k8s-e89c9d089e")
+	assert.NotNil(t, err)
+}
+
 func TestGetPathForVolume(t *testing.T) {
 	isiPath := "/ifs/data"
 	volName := "k8s-123456"
@@ -102,6 +153,14 @@ func TestGetIsiPathFromExportPath(t *testing.T) {
 	assert.Equal(t, isiPath1, isiPath2)
 }
 
+func TestIsPathUnder(t *testing.T) {
+	assert.True(t, IsPathUnder("/ifs/data/csi-isilon/k8s-123456", "/ifs/data/csi-isilon"))
+	assert.True(t, IsPathUnder("/ifs/data/csi-isilon/", "/ifs/data/csi-isilon"))
+	assert.True(t, IsPathUnder("/ifs/data/csi-isilon/k8s-123456", "/"))
+	assert.False(t, IsPathUnder("/ifs/data/csi-isilon-2/k8s-123456", "/ifs/data/csi-isilon"))
+	assert.False(t, IsPathUnder("/ifs/data", "/ifs/data/csi-isilon"))
+}
+
 func TestGetExportIDFromConflictMessage(t *testing.T) {
 	comparation := 82851
 	message := fmt.Sprintf("Export rules %d and 82859 conflict on '/ifs/data/csi/Daniel/k8s-fd8d12ede9'", comparation)
@@ -125,3 +184,16 @@ func TestGetVolumeNameFromExportPath(t *testing.T) {
 	assert.Equal(t, volName1, "k8s-123456")
 	assert.Equal(t, volName2, "k8s-123456")
 }
+
+func TestGetExportDescription(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, "CSI_QUOTA_ID:AABpAQEAAAAAAAAAAAAAQA0AAAAAAAAA", GetExportDescription("AABpAQEAAAAAAAAAAAAAQA0AAAAAAAAA", false))
+	assert.Equal(t, "CSI_SMARTLOCK", GetExportDescription("", true))
+
+	export := isi.Export(&apiv2.Export{Description: GetExportDescription("AABpAQEAAAAAAAAAAAAAQA0AAAAAAAAA", true)})
+	quotaID, _ := GetQuotaIDFromDescription(ctx, export)
+	assert.Equal(t, "AABpAQEAAAAAAAAAAAAAQA0AAAAAAAAA", quotaID)
+	assert.True(t, IsSmartLockExport(export))
+	assert.False(t, IsSmartLockExport(&apiv2.Export{Description: "CSI_QUOTA_ID:AABpAQEAAAAAAAAAAAAAQA0AAAAAAAAA"}))
+	assert.False(t, IsSmartLockExport(nil))
+}
@@ -3,23 +3,33 @@ module github.com/dell/csi-isilon
 require (
 	github.com/Showmax/go-fqdn v1.0.0
 	github.com/akutz/gournal v0.5.0
-	github.com/container-storage-interface/spec v1.3.0
+	github.com/container-storage-interface/spec v1.9.0
 	github.com/cucumber/godog v0.10.0
 	github.com/dell/gocsi v1.3.0
 	github.com/dell/gofsutil v1.6.0
 	github.com/dell/goisilon v1.4.0
 	github.com/fsnotify/fsnotify v1.4.9
-	github.com/golang/protobuf v1.4.3
-	github.com/google/uuid v1.2.0
+	github.com/golang/protobuf v1.5.3
+	github.com/google/uuid v1.3.0
 	github.com/gorilla/mux v1.8.0
 	github.com/kubernetes-csi/csi-lib-utils v0.9.1
 	github.com/sirupsen/logrus v1.8.1
-	github.com/stretchr/testify v1.6.1
-	golang.org/x/net v0.0.0-20200707034311-ab3426394381
-	google.golang.org/grpc v1.29.0
-	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c
+	github.com/stretchr/testify v1.8.2
+	golang.org/x/net v0.10.0
+	google.golang.org/grpc v1.57.0
+	gopkg.in/yaml.v3 v3.0.1
+	k8s.io/api v0.19.0
 	k8s.io/apimachinery v0.19.0
 	k8s.io/client-go v0.19.0
+	sigs.k8s.io/yaml v1.2.0
 )
 
 go 1.16
+
+// the etcd v3.3 client of gocsi doesn't build with the gRPC versions required by the CSI spec, v1.29.0 is built
+// in place of the v1.57.0 required by the spec v1.9.0, which also raises the other dependencies above
+replace google.golang.org/grpc => google.golang.org/grpc v1.29.0
+
+// the fork of goisilon accepts the transport of the requests, to verify the certificates of the clusters with
+// their own CAs and fingerprints and to authenticate with sessions
+replace github.com/dell/goisilon => ./third_party/goisilon
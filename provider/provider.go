@@ -48,6 +48,9 @@ func New() gocsi.StoragePluginProvider {
 		BeforeServe: svc.BeforeServe,
 		ServerOpts:  serverOptions,
 
+		// serve the admin service next to the CSI services
+		RegisterAdditionalServers: svc.RegisterAdditionalServers,
+
 		EnvVars: []string{
 			// Enable request validation
 			gocsi.EnvVarSpecReqValidation + "=true",
@@ -0,0 +1,207 @@
+package service
+
+/*
+ Copyright (c) 2019 Dell Inc, or its subsidiaries.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+import (
+	"context"
+	"encoding/json"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+)
+
+// The admin service is served on the CSI endpoint of the controller, next to the CSI services. Its messages are
+// plain Go structs encoded in JSON, so its clients must call it with the AdminCodecName content subtype, as
+// AdminClient does.
+const (
+	// AdminServiceName is the full name of the admin gRPC service
+	AdminServiceName = "isilon.admin.v1.Admin"
+	// AdminCodecName is the content subtype of the admin gRPC service
+	AdminCodecName = "json"
+)
+
+// AdminServer is the administrative gRPC service of the controller
+type AdminServer interface {
+	MigrateVolume(context.Context, *MigrateVolumeRequest) (*MigrateVolumeResponse, error)
+	GetMigrationStatus(context.Context, *GetMigrationStatusRequest) (*GetMigrationStatusResponse, error)
+	ImportVolume(context.Context, *ImportVolumeRequest) (*ImportVolumeResponse, error)
+	RevertVolume(context.Context, *RevertVolumeRequest) (*RevertVolumeResponse, error)
+	GetRevertStatus(context.Context, *GetRevertStatusRequest) (*GetRevertStatusResponse, error)
+	ModifyVolume(context.Context, *ModifyVolumeRequest) (*ModifyVolumeResponse, error)
+	GetClusterHealth(context.Context, *GetClusterHealthRequest) (*GetClusterHealthResponse, error)
+}
+
+func init() {
+	encoding.RegisterCodec(adminCodec{})
+}
+
+// adminCodec encodes the admin messages in JSON
+type adminCodec struct{}
+
+func (adminCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (adminCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (adminCodec) Name() string {
+	return AdminCodecName
+}
+
+// adminMethod returns the handler of an admin RPC, newRequest returns the request to decode the call into
+func adminMethod(name string, newRequest func() interface{},
+	call func(AdminServer, context.Context, interface{}) (interface{}, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			req := newRequest()
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+			if interceptor == nil {
+				return call(srv.(AdminServer), ctx, req)
+			}
+			info := &grpc.UnaryServerInfo{
+				Server:     srv,
+				FullMethod: "/" + AdminServiceName + "/" + name,
+			}
+			return interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return call(srv.(AdminServer), ctx, req)
+			})
+		},
+	}
+}
+
+var adminServiceDesc = grpc.ServiceDesc{
+	ServiceName: AdminServiceName,
+	HandlerType: (*AdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		adminMethod("MigrateVolume", func() interface{} { return new(MigrateVolumeRequest) },
+			func(srv AdminServer, ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.MigrateVolume(ctx, req.(*MigrateVolumeRequest))
+			}),
+		adminMethod("GetMigrationStatus", func() interface{} { return new(GetMigrationStatusRequest) },
+			func(srv AdminServer, ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.GetMigrationStatus(ctx, req.(*GetMigrationStatusRequest))
+			}),
+		adminMethod("ImportVolume", func() interface{} { return new(ImportVolumeRequest) },
+			func(srv AdminServer, ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.ImportVolume(ctx, req.(*ImportVolumeRequest))
+			}),
+		adminMethod("RevertVolume", func() interface{} { return new(RevertVolumeRequest) },
+			func(srv AdminServer, ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.RevertVolume(ctx, req.(*RevertVolumeRequest))
+			}),
+		adminMethod("GetRevertStatus", func() interface{} { return new(GetRevertStatusRequest) },
+			func(srv AdminServer, ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.GetRevertStatus(ctx, req.(*GetRevertStatusRequest))
+			}),
+		adminMethod("ModifyVolume", func() interface{} { return new(ModifyVolumeRequest) },
+			func(srv AdminServer, ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.ModifyVolume(ctx, req.(*ModifyVolumeRequest))
+			}),
+		adminMethod("GetClusterHealth", func() interface{} { return new(GetClusterHealthRequest) },
+			func(srv AdminServer, ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.GetClusterHealth(ctx, req.(*GetClusterHealthRequest))
+			}),
+	},
+	Streams: []grpc.StreamDesc{},
+}
+
+// RegisterAdditionalServers registers the admin service on the gRPC server of the plugin
+func (s *service) RegisterAdditionalServers(server *grpc.Server) {
+	server.RegisterService(&adminServiceDesc, s)
+}
+
+// AdminClient calls the admin service of a controller
+type AdminClient struct {
+	conn *grpc.ClientConn
+}
+
+// NewAdminClient returns a client of the admin service served on the given connection
+func NewAdminClient(conn *grpc.ClientConn) *AdminClient {
+	return &AdminClient{conn: conn}
+}
+
+func (c *AdminClient) invoke(ctx context.Context, method string, req, resp interface{}) error {
+	return c.conn.Invoke(ctx, "/"+AdminServiceName+"/"+method, req, resp, grpc.CallContentSubtype(AdminCodecName))
+}
+
+// MigrateVolume starts the migration of a volume to another cluster, or only plans it if DryRun is set
+func (c *AdminClient) MigrateVolume(ctx context.Context, req *MigrateVolumeRequest) (*MigrateVolumeResponse, error) {
+	resp := new(MigrateVolumeResponse)
+	if err := c.invoke(ctx, "MigrateVolume", req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetMigrationStatus returns the status of the migration of a volume, or of all the migrations
+func (c *AdminClient) GetMigrationStatus(ctx context.Context, req *GetMigrationStatusRequest) (*GetMigrationStatusResponse, error) {
+	resp := new(GetMigrationStatusResponse)
+	if err := c.invoke(ctx, "GetMigrationStatus", req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ImportVolume adopts an existing directory as a volume and returns its volume ID and a PV manifest
+func (c *AdminClient) ImportVolume(ctx context.Context, req *ImportVolumeRequest) (*ImportVolumeResponse, error) {
+	resp := new(ImportVolumeResponse)
+	if err := c.invoke(ctx, "ImportVolume", req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// RevertVolume starts the revert of a volume to one of its snapshots
+func (c *AdminClient) RevertVolume(ctx context.Context, req *RevertVolumeRequest) (*RevertVolumeResponse, error) {
+	resp := new(RevertVolumeResponse)
+	if err := c.invoke(ctx, "RevertVolume", req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetRevertStatus returns the status of the revert of a volume, or of all the reverts
+func (c *AdminClient) GetRevertStatus(ctx context.Context, req *GetRevertStatusRequest) (*GetRevertStatusResponse, error) {
+	resp := new(GetRevertStatusResponse)
+	if err := c.invoke(ctx, "GetRevertStatus", req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ModifyVolume applies mutable parameters to an existing volume
+func (c *AdminClient) ModifyVolume(ctx context.Context, req *ModifyVolumeRequest) (*ModifyVolumeResponse, error) {
+	resp := new(ModifyVolumeResponse)
+	if err := c.invoke(ctx, "ModifyVolume", req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetClusterHealth returns the health state of a cluster, or of all the clusters
+func (c *AdminClient) GetClusterHealth(ctx context.Context, req *GetClusterHealthRequest) (*GetClusterHealthResponse, error) {
+	resp := new(GetClusterHealthResponse)
+	if err := c.invoke(ctx, "GetClusterHealth", req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
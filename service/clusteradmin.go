@@ -0,0 +1,423 @@
+package service
+
+/*
+ Copyright (c) 2019 Dell Inc, or its subsidiaries.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dell/csi-isilon/common/constants"
+	"github.com/dell/csi-isilon/common/utils"
+	isi "github.com/dell/goisilon"
+	apiv1 "github.com/dell/goisilon/api/v1"
+)
+
+// Types of the IDs decoded by DecodeID
+const (
+	IDTypeVolume   = "volume"
+	IDTypeSnapshot = "snapshot"
+	IDTypeNode     = "node"
+)
+
+// csiSnapshotNamePrefix is the prefix of the names the CSI external-snapshotter gives to the snapshots it creates,
+// 'snapshot-<UID of the VolumeSnapshot>'
+const csiSnapshotNamePrefix = "snapshot-"
+
+// DecodedID holds the components of a volume, snapshot or node ID of the driver
+type DecodedID struct {
+	Type                 string `json:"type"`
+	Version              int    `json:"version,omitempty"`
+	VolumeName           string `json:"volumeName,omitempty"`
+	ExportID             int    `json:"exportId,omitempty"`
+	AccessZone           string `json:"accessZone,omitempty"`
+	Protocol             string `json:"protocol,omitempty"`
+	Path                 string `json:"path,omitempty"`
+	ReadOnlyFromSnapshot bool   `json:"readOnlyFromSnapshot,omitempty"`
+	SnapshotID           string `json:"snapshotId,omitempty"`
+	ClusterName          string `json:"clusterName,omitempty"`
+	NodeName             string `json:"nodeName,omitempty"`
+	NodeFQDN             string `json:"nodeFQDN,omitempty"`
+	NodeIP               string `json:"nodeIP,omitempty"`
+}
+
+// ExportDetails describes an NFS export and its clients
+type ExportDetails struct {
+	ID               int      `json:"id"`
+	Zone             string   `json:"zone"`
+	Paths            []string `json:"paths"`
+	Description      string   `json:"description,omitempty"`
+	QuotaID          string   `json:"quotaId,omitempty"`
+	Clients          []string `json:"clients,omitempty"`
+	ReadOnlyClients  []string `json:"readOnlyClients,omitempty"`
+	ReadWriteClients []string `json:"readWriteClients,omitempty"`
+	RootClients      []string `json:"rootClients,omitempty"`
+}
+
+// QuotaDetails describes a directory quota and its usage
+type QuotaDetails struct {
+	ID            string `json:"id"`
+	Path          string `json:"path"`
+	Enforced      bool   `json:"enforced"`
+	HardThreshold int64  `json:"hardThreshold,omitempty"`
+	LogicalUsage  int64  `json:"logicalUsage"`
+	PhysicalUsage int64  `json:"physicalUsage"`
+}
+
+// SnapshotDetails describes a snapshot
+type SnapshotDetails struct {
+	ID      int64     `json:"id"`
+	Name    string    `json:"name"`
+	Path    string    `json:"path"`
+	State   string    `json:"state"`
+	Size    int64     `json:"size"`
+	Created time.Time `json:"created"`
+}
+
+// VolumeDetails describes a volume as found on its cluster
+type VolumeDetails struct {
+	VolumeID    string            `json:"volumeId"`
+	ClusterName string            `json:"clusterName"`
+	Path        string            `json:"path,omitempty"`
+	Export      *ExportDetails    `json:"export,omitempty"`
+	Quota       *QuotaDetails     `json:"quota,omitempty"`
+	Snapshots   []SnapshotDetails `json:"snapshots"`
+	// Errors lists what could not be retrieved
+	Errors []string `json:"errors,omitempty"`
+}
+
+// ClusterArtifacts lists the exports, quotas and snapshots created by the driver on a cluster under its isiPath
+type ClusterArtifacts struct {
+	ClusterName string            `json:"clusterName"`
+	IsiPath     string            `json:"isiPath"`
+	AccessZone  string            `json:"accessZone"`
+	Exports     []ExportDetails   `json:"exports"`
+	Quotas      []QuotaDetails    `json:"quotas"`
+	Snapshots   []SnapshotDetails `json:"snapshots"`
+	Errors      []string          `json:"errors,omitempty"`
+}
+
+// ClusterConnectivity is the result of the connectivity check of a cluster
+type ClusterConnectivity struct {
+	ClusterName  string `json:"clusterName"`
+	Endpoint     string `json:"endpoint"`
+	IsDefault    bool   `json:"isDefault"`
+	Connected    bool   `json:"connected"`
+	ResponseTime string `json:"responseTime,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// ClusterAdmin runs administrative commands directly against the clusters of the driver secret, with the options
+// the driver reads from its environment
+type ClusterAdmin struct {
+	s *service
+}
+
+// NewClusterAdmin parses the cluster secret the same way as the driver does
+func NewClusterAdmin(ctx context.Context, configFile string) (*ClusterAdmin, error) {
+	s := &service{}
+	if err := s.initializeServiceOpts(ctx); err != nil {
+		return nil, err
+	}
+	// the commands only use the controller side of the driver
+	s.mode = constants.ModeController
+
+	configBytes, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("file ('%s') error: %v", configFile, err)
+	}
+	newIsilonConfigs, defaultClusterName, _, err := s.getNewIsilonConfigs(ctx, configBytes)
+	if err != nil {
+		return nil, err
+	}
+	s.isiClusters = new(sync.Map)
+	for k, v := range newIsilonConfigs {
+		s.isiClusters.Store(k, v)
+	}
+	s.defaultIsiClusterName = defaultClusterName
+	return &ClusterAdmin{s: s}, nil
+}
+
+// DecodeID returns the components of a normalized volume ID, a normalized snapshot ID or a node ID
+func DecodeID(ctx context.Context, id string) (*DecodedID, error) {
+	if utils.NodeIDPattern.MatchString(id) {
+		nodeName, nodeFQDN, nodeIP, err := utils.ParseNodeID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return &DecodedID{Type: IDTypeNode, NodeName: nodeName, NodeFQDN: nodeFQDN, NodeIP: nodeIP}, nil
+	}
+	if utils.VolumeIDV2Pattern.MatchString(id) || utils.VolumeIDPattern.MatchString(id) {
+		volumeID, err := utils.ParseVolumeID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return &DecodedID{
+			Type:                 IDTypeVolume,
+			Version:              volumeID.Version,
+			VolumeName:           volumeID.Name,
+			ExportID:             volumeID.ExportID,
+			AccessZone:           volumeID.AccessZone,
+			Protocol:             volumeID.Protocol,
+			Path:                 volumeID.Path,
+			ReadOnlyFromSnapshot: volumeID.ReadOnlyFromSnapshot,
+			ClusterName:          volumeID.ClusterName,
+		}, nil
+	}
+	if tokens := strings.Split(id, utils.SnapshotIDSeparator); len(tokens) <= 2 {
+		if _, err := strconv.ParseInt(tokens[0], 10, 64); err == nil {
+			snapshotID, clusterName, err := utils.ParseNormalizedSnapshotID(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			return &DecodedID{Type: IDTypeSnapshot, SnapshotID: snapshotID, ClusterName: clusterName}, nil
+		}
+	}
+	return nil, fmt.Errorf("'%s' is neither a volume, a snapshot nor a node ID", id)
+}
+
+// ShowVolume returns the export, clients, quota and snapshots of a volume
+func (a *ClusterAdmin) ShowVolume(ctx context.Context, volumeID string) (*VolumeDetails, error) {
+	volName, exportID, accessZone, clusterName, err := utils.ParseNormalizedVolumeID(ctx, volumeID)
+	if err != nil {
+		return nil, err
+	}
+	isiConfig, err := a.getClusterConfig(ctx, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	details := &VolumeDetails{VolumeID: volumeID, ClusterName: isiConfig.ClusterName, Snapshots: []SnapshotDetails{}}
+	export, err := isiConfig.isiSvc.GetExportByIDWithZone(ctx, exportID, accessZone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get export '%d' of volume '%s' : '%v'", exportID, volName, err)
+	}
+	details.Export = newExportDetails(ctx, export)
+	if len(details.Export.Paths) == 0 {
+		return details, nil
+	}
+	details.Path = details.Export.Paths[0]
+
+	if quota, err := isiConfig.isiSvc.GetDirectoryQuota(ctx, details.Path); err != nil {
+		details.Errors = append(details.Errors, err.Error())
+	} else if quota != nil {
+		details.Quota = newQuotaDetails(quota)
+	}
+	if snapshots, err := isiConfig.isiSvc.GetSnapshotsUnderPath(ctx, details.Path); err != nil {
+		details.Errors = append(details.Errors, err.Error())
+	} else {
+		for _, snapshot := range snapshots {
+			// snapshots of the directories below the volume are not snapshots of the volume
+			if snapshot.Path == details.Path {
+				details.Snapshots = append(details.Snapshots, newSnapshotDetails(snapshot))
+			}
+		}
+	}
+	return details, nil
+}
+
+// ListArtifacts lists the exports, quotas and snapshots created by the driver under the isiPath of the given cluster,
+// or of every cluster. isiPath and accessZone default to the ones of the driver.
+// The exports of the driver are the ones with the dummy localhost client, its quotas the ones recorded in the
+// description of these exports and its snapshots the ones named by the CSI snapshotter or taken by a CSI schedule.
+func (a *ClusterAdmin) ListArtifacts(ctx context.Context, clusterName, isiPath, accessZone string) ([]*ClusterArtifacts, error) {
+	var clusterNames []string
+	if clusterName != "" {
+		clusterNames = []string{clusterName}
+	} else {
+		for _, isiConfig := range a.s.getIsilonClusters() {
+			clusterNames = append(clusterNames, isiConfig.ClusterName)
+		}
+		sort.Strings(clusterNames)
+	}
+	if accessZone == "" {
+		accessZone = a.s.opts.AccessZone
+	}
+
+	allArtifacts := make([]*ClusterArtifacts, 0, len(clusterNames))
+	for _, name := range clusterNames {
+		isiConfig, err := a.getClusterConfig(ctx, name)
+		if err != nil {
+			allArtifacts = append(allArtifacts, &ClusterArtifacts{ClusterName: name, Errors: []string{err.Error()}})
+			continue
+		}
+		artifacts := &ClusterArtifacts{
+			ClusterName: isiConfig.ClusterName,
+			IsiPath:     isiPath,
+			AccessZone:  accessZone,
+			Exports:     []ExportDetails{},
+			Quotas:      []QuotaDetails{},
+			Snapshots:   []SnapshotDetails{},
+		}
+		if artifacts.IsiPath == "" {
+			artifacts.IsiPath = isiConfig.IsiPath
+		}
+
+		// the quotas of the driver are only known through the exports of the driver
+		csiQuotaIDs := make(map[string]bool)
+		if exports, err := isiConfig.isiSvc.GetExportsWithZone(ctx, accessZone); err != nil {
+			artifacts.Errors = append(artifacts.Errors, err.Error())
+		} else {
+			for _, export := range exports {
+				if !isCSIExport(getExportClients(export)) {
+					continue
+				}
+				if quotaID, _ := utils.GetQuotaIDFromDescription(ctx, export); quotaID != "" {
+					csiQuotaIDs[quotaID] = true
+				}
+				if export.Paths != nil && len(*export.Paths) > 0 && utils.IsPathUnder((*export.Paths)[0], artifacts.IsiPath) {
+					artifacts.Exports = append(artifacts.Exports, *newExportDetails(ctx, export))
+				}
+			}
+		}
+		if quotas, err := isiConfig.isiSvc.GetDirectoryQuotasUnderPath(ctx, artifacts.IsiPath); err != nil {
+			artifacts.Errors = append(artifacts.Errors, err.Error())
+		} else {
+			for i := range quotas {
+				if csiQuotaIDs[quotas[i].Id] {
+					artifacts.Quotas = append(artifacts.Quotas, *newQuotaDetails(&quotas[i]))
+				}
+			}
+		}
+		if snapshots, err := isiConfig.isiSvc.GetSnapshotsUnderPath(ctx, artifacts.IsiPath); err != nil {
+			artifacts.Errors = append(artifacts.Errors, err.Error())
+		} else {
+			for _, snapshot := range snapshots {
+				if isCSISnapshot(snapshot) {
+					artifacts.Snapshots = append(artifacts.Snapshots, newSnapshotDetails(snapshot))
+				}
+			}
+		}
+		allArtifacts = append(allArtifacts, artifacts)
+	}
+	return allArtifacts, nil
+}
+
+func isCSISnapshot(snapshot *apiv1.IsiSnapshot) bool {
+	return strings.HasPrefix(snapshot.Name, csiSnapshotNamePrefix) || strings.HasPrefix(snapshot.Schedule, snapshotSchedulePrefix)
+}
+
+// ForceUnpublish removes a node from the clients of the export of a volume, whatever the state of its
+// VolumeAttachment, e.g. once the node is gone for good
+func (a *ClusterAdmin) ForceUnpublish(ctx context.Context, volumeID, nodeID string) error {
+	_, exportID, accessZone, clusterName, err := utils.ParseNormalizedVolumeID(ctx, volumeID)
+	if err != nil {
+		return err
+	}
+	if _, _, _, err := utils.ParseNodeID(ctx, nodeID); err != nil {
+		return err
+	}
+	isiConfig, err := a.getClusterConfig(ctx, clusterName)
+	if err != nil {
+		return err
+	}
+	return isiConfig.isiSvc.RemoveExportClientByIDWithZone(ctx, exportID, accessZone, nodeID)
+}
+
+// CheckConnectivity checks the connection to every cluster of the secret
+func (a *ClusterAdmin) CheckConnectivity(ctx context.Context) []*ClusterConnectivity {
+	isilonClusters := a.s.getIsilonClusters()
+	sort.Slice(isilonClusters, func(i, j int) bool {
+		return isilonClusters[i].ClusterName < isilonClusters[j].ClusterName
+	})
+
+	results := make([]*ClusterConnectivity, 0, len(isilonClusters))
+	for _, isiConfig := range isilonClusters {
+		result := &ClusterConnectivity{
+			ClusterName: isiConfig.ClusterName,
+			Endpoint:    isiConfig.EndpointURL,
+			IsDefault:   isiConfig.ClusterName == a.s.defaultIsiClusterName,
+		}
+		start := time.Now()
+		_, err := a.getClusterConfig(ctx, isiConfig.ClusterName)
+		if err == nil {
+			err = a.s.controllerProbe(ctx, isiConfig)
+		}
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Connected = true
+			result.ResponseTime = time.Since(start).String()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// getClusterConfig returns the config of a cluster, connecting to it if the driver secret was parsed without
+// reaching it
+func (a *ClusterAdmin) getClusterConfig(ctx context.Context, clusterName string) (*IsilonClusterConfig, error) {
+	isiConfig, err := a.s.getIsilonConfig(ctx, &clusterName)
+	if err != nil {
+		return nil, err
+	}
+	if isiConfig.isiSvc == nil {
+		if isiConfig.isiSvc, err = a.s.GetIsiService(ctx, isiConfig, utils.GetLogger().GetLevel()); err != nil {
+			return nil, fmt.Errorf("failed to connect to cluster '%s' : '%v'", clusterName, err)
+		}
+	}
+	return isiConfig, nil
+}
+
+func newExportDetails(ctx context.Context, export isi.Export) *ExportDetails {
+	details := &ExportDetails{
+		ID:               export.ID,
+		Zone:             export.Zone,
+		Paths:            stringsOf(export.Paths),
+		Description:      export.Description,
+		Clients:          stringsOf(export.Clients),
+		ReadOnlyClients:  stringsOf(export.ReadOnlyClients),
+		ReadWriteClients: stringsOf(export.ReadWriteClients),
+		RootClients:      stringsOf(export.RootClients),
+	}
+	details.QuotaID, _ = utils.GetQuotaIDFromDescription(ctx, export)
+	return details
+}
+
+func stringsOf(values *[]string) []string {
+	if values == nil {
+		return nil
+	}
+	return *values
+}
+
+func newQuotaDetails(quota *apiv1.IsiQuota) *QuotaDetails {
+	return &QuotaDetails{
+		ID:            quota.Id,
+		Path:          quota.Path,
+		Enforced:      quota.Enforced,
+		HardThreshold: quota.Thresholds.Hard,
+		LogicalUsage:  quota.Usage.Logical,
+		PhysicalUsage: quota.Usage.Physical,
+	}
+}
+
+func newSnapshotDetails(snapshot *apiv1.IsiSnapshot) SnapshotDetails {
+	return SnapshotDetails{
+		ID:      snapshot.Id,
+		Name:    snapshot.Name,
+		Path:    snapshot.Path,
+		State:   snapshot.State,
+		Size:    snapshot.Size,
+		Created: time.Unix(snapshot.Created, 0).UTC(),
+	}
+}
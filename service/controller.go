@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"path"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -32,16 +33,17 @@ import (
 	isi "github.com/dell/goisilon"
 	isiApi "github.com/dell/goisilon/api"
 	timestamp "github.com/golang/protobuf/ptypes/timestamp"
+	wrappers "github.com/golang/protobuf/ptypes/wrappers"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	v1 "k8s.io/api/core/v1"
 )
 
 // constants
 const (
 	errUnknownAccessType          = "unknown access type is not Mount"
 	errUnknownAccessMode          = "unknown or unsupported access mode"
-	errNoSingleNodeReader         = "Single node only reader access mode is not supported"
 	errNoMultiNodeSingleWriter    = "Multi node single writer access mode is not supported"
 	MaxRetries                    = 10
 	RetrySleepTime                = 1000 * time.Millisecond
@@ -54,6 +56,44 @@ const (
 	DeleteSnapshotMarker          = "DELETE_SNAPSHOT"
 	IgnoreDotAndDotDotSubDirs     = 2
 	ClusterNameParam              = "ClusterName"
+	VolumeOwnerParam              = "VolumeOwner"
+	VolumeGroupParam              = "VolumeGroup"
+	VolumeModeParam               = "VolumeMode"
+	VolumeACLParam                = "VolumeACL"
+	ProtocolNFS                   = "nfs"
+
+	// SmartLock (WORM) storage class parameters, the retention periods are durations such as '7Y' or '30D'
+	SmartLockEnabledParam          = "SmartLockEnabled"
+	SmartLockTypeParam             = "SmartLockType"
+	SmartLockDefaultRetentionParam = "SmartLockDefaultRetention"
+	SmartLockMinRetentionParam     = "SmartLockMinRetention"
+	SmartLockMaxRetentionParam     = "SmartLockMaxRetention"
+	SmartLockAutocommitOffsetParam = "SmartLockAutocommitOffset"
+
+	// OverprovisioningRatio scales the capacity reported by GetCapacity, e.g. "1.5"
+	OverprovisioningRatioParam = "OverprovisioningRatio"
+
+	// VolumeSnapshotClass parameters, the expiration is a duration such as '30D' and the alias the name of an
+	// alias snapshot OneFS points to the latest snapshot of the class
+	SnapshotExpirationParam = "SnapshotExpiration"
+	SnapshotAliasParam      = "SnapshotAlias"
+
+	// Snapshot schedule storage class parameters, the schedule is a OneFS schedule such as 'every 1 hours', the
+	// pattern names the snapshots and the retention is a duration such as '7D'
+	SnapshotScheduleParam          = "SnapshotSchedule"
+	SnapshotSchedulePatternParam   = "SnapshotSchedulePattern"
+	SnapshotScheduleRetentionParam = "SnapshotScheduleRetention"
+
+	// SnapshotRestoreModeParam selects how volumes are created from snapshots: 'copy', 'writable' or 'auto'
+	SnapshotRestoreModeParam = "SnapshotRestoreMode"
+
+	// SnapRevertEnabledParam puts the volumes in a SnapRevert domain, so that they can be reverted to their snapshots
+	SnapRevertEnabledParam = "SnapRevertEnabled"
+
+	// SmartPools storage class parameters
+	StoragePoolParam     = "StoragePool"
+	ProtectionLevelParam = "ProtectionLevel"
+	SSDStrategyParam     = "SSDStrategy"
 
 	// These are available when enabling --extra-create-metadata for the external-provisioner.
 	csiPersistentVolumeName           = "csi.storage.k8s.io/pv/name"
@@ -120,6 +160,16 @@ func (s *service) CreateVolume(
 
 	// Fetch log handler
 	ctx, _, runID := GetRunIDLog(ctx)
+
+	// let the placement mode choose among the clusters if the storage class does not name one
+	if params[ClusterNameParam] == "" && s.opts.ClusterPlacement == ClusterPlacementCapacity {
+		selectedClusterName, err := s.selectCluster(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		clusterName = selectedClusterName
+	}
+
 	ctx, log := setClusterContext(ctx, clusterName)
 	log.Debugf("Cluster Name: %v", clusterName)
 
@@ -129,6 +179,12 @@ func (s *service) CreateVolume(
 		return nil, err
 	}
 
+	// the volume must be accessible from one of the requisite topologies, which the capacity placement already ensures
+	if !s.opts.CustomTopologyEnabled && !isAccessibleFrom(isiConfig, req.GetAccessibilityRequirements()) {
+		return nil, status.Error(codes.ResourceExhausted, utils.GetMessageWithRunID(runID,
+			"cluster '%s' is not accessible from the requisite topologies of volume '%s'", clusterName, req.GetName()))
+	}
+
 	// auto probe
 	if err := s.autoProbe(ctx, isiConfig); err != nil {
 		return nil, status.Error(codes.FailedPrecondition, err.Error())
@@ -176,6 +232,11 @@ func (s *service) CreateVolume(
 		azServiceIP = isiConfig.IsiIP
 	}
 
+	// restrict the volume to the access zone, isiPath and clusters of the tenant of the PVC namespace
+	if err := s.applyTenancy(ctx, params, clusterName, sizeInBytes, &accessZone, &isiPath, &azServiceIP); err != nil {
+		return nil, err
+	}
+
 	if val, ok := params[RootClientEnabledParam]; ok {
 		_, err := strconv.ParseBool(val)
 		// use the default if the boolean literal from the storage class is malformed
@@ -191,6 +252,53 @@ func (s *service) CreateVolume(
 		rootClientEnabled = RootClientEnabledParamDefault
 	}
 
+	permissions, err := getVolumePermissions(params)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID, err.Error()))
+	}
+
+	smartLock, err := getSmartLockSettings(params)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID, err.Error()))
+	}
+
+	smartPools, err := getSmartPoolsSettings(params)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID, err.Error()))
+	}
+
+	snapshotSchedule, err := getSnapshotScheduleSettings(params)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID, err.Error()))
+	}
+
+	restoreMode, err := getSnapshotRestoreMode(params)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID, err.Error()))
+	}
+	// the SmartLock domain is created on the empty directory of the volume, files copied into it from a content source
+	// could be committed before the creation completes, and could then not be deleted by a rollback
+	if smartLock != nil && req.GetVolumeContentSource() != nil {
+		return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID, "SmartLock cannot be used with a volume content source"))
+	}
+
+	snapRevertEnabled, err := getSnapRevertEnabled(params)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID, err.Error()))
+	}
+	// the data under retention cannot be reverted
+	if snapRevertEnabled && smartLock != nil {
+		return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID, "'%s' cannot be used with SmartLock", SnapRevertEnabledParam))
+	}
+	// the SnapRevert domain cannot be created on a writable snapshot
+	if snapRevertEnabled && restoreMode != snapshotRestoreModeCopy {
+		if restoreMode == snapshotRestoreModeWritable {
+			return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID, "'%s' cannot be used with '%s' '%s'",
+				SnapRevertEnabledParam, SnapshotRestoreModeParam, restoreMode))
+		}
+		restoreMode = snapshotRestoreModeCopy
+	}
+
 	//CSI specific metada for authorization
 	var headerMetadata = addMetaData(params)
 
@@ -236,7 +344,8 @@ func (s *service) CreateVolume(
 					return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID, "access mode is required"))
 				}
 
-				if am.Mode == csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY {
+				if am.Mode == csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY ||
+					am.Mode == csi.VolumeCapability_AccessMode_SINGLE_NODE_READER_ONLY {
 					isROVolumeFromSnapshot = true
 					break
 				}
@@ -321,9 +430,31 @@ func (s *service) CreateVolume(
 		}
 	}
 
-	// create volume (directory) with ACL 0777
-	if !isROVolumeFromSnapshot {
-		if len(headerMetadata) == 0 {
+	// a writable snapshot of the source snapshot creates the volume directory along with its content
+	restoredAsWritable := false
+	if !isROVolumeFromSnapshot && sourceSnapshotID != "" && restoreMode != snapshotRestoreModeCopy {
+		// a snapshot deleted by Kubernetes is only kept until the volumes depending on it are deleted
+		if !foundVol {
+			pending, err := s.isSnapshotDeletionPending(ctx, isiConfig, sourceSnapshotID)
+			if err != nil {
+				return nil, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, "failed to check whether snapshot id '%s' is being deleted: '%v'", sourceSnapshotID, err))
+			}
+			if pending {
+				return nil, status.Error(codes.FailedPrecondition, utils.GetMessageWithRunID(runID, "snapshot id '%s' is being deleted, no volume can be restored from it", sourceSnapshotID))
+			}
+		}
+		if restoredAsWritable, err = s.createVolumeFromWritableSnapshot(ctx, isiConfig, isiPath, sourceSnapshotID, req.GetName(), sizeInBytes, restoreMode, foundVol); err != nil {
+			return nil, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, err.Error()))
+		}
+	}
+
+	// create volume (directory) with ACL 0777, or with the mode given in the storage class
+	if !isROVolumeFromSnapshot && !restoredAsWritable {
+		if permissions != nil && permissions.mode != "" {
+			if err = isiConfig.isiSvc.CreateVolumeWithAccessControl(ctx, isiPath, req.GetName(), permissions.mode, headerMetadata); err != nil {
+				return nil, err
+			}
+		} else if len(headerMetadata) == 0 {
 			if err = isiConfig.isiSvc.CreateVolume(ctx, isiPath, req.GetName()); err != nil {
 				return nil, err
 			}
@@ -335,27 +466,77 @@ func (s *service) CreateVolume(
 		}
 	}
 
+	// SmartLock is refused with a volume content source, so the driver copies no data into the domain
+	if smartLock != nil && !isROVolumeFromSnapshot {
+		if err = isiConfig.isiSvc.applySmartLock(ctx, utils.GetPathForVolume(isiPath, req.GetName()), smartLock); err != nil {
+			log.Errorf("failed to apply SmartLock to volume '%s', roll back by deleting it: '%v'", req.GetName(), err)
+			if err := s.rollBackCreatedVolume(ctx, isiConfig, isiPath, req.GetName(), foundVol); err != nil {
+				log.Infof("Delete volume in CreateVolume returned error '%s'", err)
+			}
+			return nil, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, "failed to apply SmartLock to volume '%s': '%v'", req.GetName(), err))
+		}
+	}
+
+	if snapRevertEnabled && !foundVol && !isROVolumeFromSnapshot {
+		if err = isiConfig.isiSvc.applySnapRevertDomain(ctx, utils.GetPathForVolume(isiPath, req.GetName())); err != nil {
+			log.Errorf("failed to create the SnapRevert domain of volume '%s', roll back by deleting it: '%v'", req.GetName(), err)
+			if err := s.rollBackCreatedVolume(ctx, isiConfig, isiPath, req.GetName(), foundVol); err != nil {
+				log.Infof("Delete volume in CreateVolume returned error '%s'", err)
+			}
+			return nil, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, "failed to create the SnapRevert domain of volume '%s': '%v'", req.GetName(), err))
+		}
+	}
+
 	// if volume content source is not null and new volume request is not for RO volume from snapshot,
 	// copy content from the datasource
-	if contentSource != nil && !isROVolumeFromSnapshot {
+	if contentSource != nil && !isROVolumeFromSnapshot && !restoredAsWritable {
 		err = s.createVolumeFromSource(ctx, isiConfig, isiPath, contentSource, req, sizeInBytes)
 		if err != nil {
 			// Clear volume since the volume creation is not successful
-			if err := isiConfig.isiSvc.DeleteVolume(ctx, isiPath, req.GetName()); err != nil {
+			if err := s.rollBackCreatedVolume(ctx, isiConfig, isiPath, req.GetName(), foundVol); err != nil {
 				log.Infof("Delete volume in CreateVolume returned error '%s'", err)
 			}
 			return nil, err
 		}
 	}
 
+	if !isROVolumeFromSnapshot {
+		if err = s.applyVolumePermissions(ctx, isiConfig, isiPath, req.GetName(), accessZone, contentSource, snapshotIsiPath, permissions); err != nil {
+			log.Errorf("failed to set the permissions of volume '%s', roll back by deleting it: '%v'", req.GetName(), err)
+			if err := s.rollBackCreatedVolume(ctx, isiConfig, isiPath, req.GetName(), foundVol); err != nil {
+				log.Infof("Delete volume in CreateVolume returned error '%s'", err)
+			}
+			return nil, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, "failed to set the permissions of volume '%s': '%v'", req.GetName(), err))
+		}
+	}
+
+	if !isROVolumeFromSnapshot {
+		if err = s.applySmartPoolsSettings(ctx, isiConfig, isiPath, req.GetName(), contentSource, smartPools); err != nil {
+			log.Errorf("failed to apply the SmartPools settings of volume '%s', roll back by deleting it: '%v'", req.GetName(), err)
+			if err := s.rollBackCreatedVolume(ctx, isiConfig, isiPath, req.GetName(), foundVol); err != nil {
+				log.Infof("Delete volume in CreateVolume returned error '%s'", err)
+			}
+			return nil, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, "failed to apply the SmartPools settings of volume '%s': '%v'", req.GetName(), err))
+		}
+		if err = s.applySnapshotSchedule(ctx, isiConfig, isiPath, req.GetName(), snapshotSchedule); err != nil {
+			log.Errorf("failed to apply the snapshot schedule of volume '%s', roll back by deleting it: '%v'", req.GetName(), err)
+			if err := s.rollBackCreatedVolume(ctx, isiConfig, isiPath, req.GetName(), foundVol); err != nil {
+				log.Infof("Delete volume in CreateVolume returned error '%s'", err)
+			}
+			return nil, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, "failed to apply the snapshot schedule of volume '%s': '%v'", req.GetName(), err))
+		}
+	}
+
 	if !foundVol && !isROVolumeFromSnapshot {
 		// create quota
 		if quotaID, err = isiConfig.isiSvc.CreateQuota(ctx, path, req.GetName(), sizeInBytes, s.opts.QuotaEnabled); err != nil {
 			log.Errorf("error creating quota ('%s', '%d' bytes), abort, also roll back by deleting the newly created volume: '%v'", req.GetName(), sizeInBytes, err)
 			//roll back, delete the newly created volume
-			if err = isiConfig.isiSvc.DeleteVolume(ctx, isiPath, req.GetName()); err != nil {
+			if err = s.rollBackCreatedVolume(ctx, isiConfig, isiPath, req.GetName(), foundVol); err != nil {
 				return nil, fmt.Errorf("rollback (deleting volume '%s') failed with error : '%v'", req.GetName(), err)
 			}
+			s.recordClaimEvent(ctx, params, v1.EventTypeWarning, EventReasonQuotaCreationFailed,
+				"failed to create quota of '%d' bytes for volume '%s' on cluster '%s'", sizeInBytes, req.GetName(), clusterName)
 			return nil, fmt.Errorf("error creating quota ('%s', '%d' bytes), abort, also succesfully rolled back by deleting the newly created volume", req.GetName(), sizeInBytes)
 		}
 	}
@@ -375,17 +556,22 @@ func (s *service) CreateVolume(
 						}
 					}
 					// return the response
+					s.recordClaimEvent(ctx, params, v1.EventTypeNormal, EventReasonVolumeCreated,
+						"volume '%s' created with export id '%d' in access zone '%s' on cluster '%s'", req.GetName(), exportID, accessZone, clusterName)
 					return s.getCreateVolumeResponse(ctx, exportID, req.GetName(), path, accessZone, sizeInBytes, azServiceIP, rootClientEnabled, sourceSnapshotID, sourceVolumeID, clusterName), nil
 				}
 				time.Sleep(RetrySleepTime)
 				log.Printf("Begin to retry '%d' time(s), for export id '%d' and path '%s'\n", i+1, exportID, path)
 			}
 		} else {
+			s.recordClaimEvent(ctx, params, v1.EventTypeWarning, EventReasonExportCreationFailed,
+				"failed to export path '%s' in access zone '%s' on cluster '%s': '%v'", path, accessZone, clusterName, err)
 			return nil, err
 		}
 	} else {
 
-		if exportID, err = isiConfig.isiSvc.ExportVolumeWithZone(ctx, isiPath, req.GetName(), accessZone, utils.GetQuotaIDWithCSITag(quotaID)); err == nil && exportID != 0 {
+		// the description also tags the volumes created with SmartLock, only their retention is checked in DeleteVolume
+		if exportID, err = isiConfig.isiSvc.ExportVolumeWithZone(ctx, isiPath, req.GetName(), accessZone, utils.GetExportDescription(quotaID, smartLock != nil)); err == nil && exportID != 0 {
 			// get the export and retry if not found to ensure the export has been created
 			for i := 0; i < MaxRetries; i++ {
 				if export, _ := isiConfig.isiSvc.GetExportByIDWithZone(ctx, exportID, accessZone); export != nil {
@@ -397,25 +583,45 @@ func (s *service) CreateVolume(
 						}
 					}
 					// return the response
+					s.recordClaimEvent(ctx, params, v1.EventTypeNormal, EventReasonVolumeCreated,
+						"volume '%s' created with export id '%d' in access zone '%s' on cluster '%s'", req.GetName(), exportID, accessZone, clusterName)
 					return s.getCreateVolumeResponse(ctx, exportID, req.GetName(), path, accessZone, sizeInBytes, azServiceIP, rootClientEnabled, sourceSnapshotID, sourceVolumeID, clusterName), nil
 				}
 				time.Sleep(RetrySleepTime)
 				log.Printf("Begin to retry '%d' time(s), for export id '%d' and path '%s'\n", i+1, exportID, path)
 			}
 		} else {
-			// clear quota and delete volume since the export cannot be created
-			if error := isiConfig.isiSvc.ClearQuotaByID(ctx, quotaID); error != nil {
-				log.Infof("Clear Quota returned error '%s'", error)
+			// clear quota and delete volume since the export cannot be created, unless the volume was found by a
+			// retried request
+			if !foundVol {
+				if error := isiConfig.isiSvc.ClearQuotaByID(ctx, quotaID); error != nil {
+					log.Infof("Clear Quota returned error '%s'", error)
+				}
 			}
-			if error := isiConfig.isiSvc.DeleteVolume(ctx, isiPath, req.GetName()); error != nil {
+			if error := s.rollBackCreatedVolume(ctx, isiConfig, isiPath, req.GetName(), foundVol); error != nil {
 				log.Infof("Delete volume in CreateVolume returned error '%s'", error)
 			}
+			s.recordClaimEvent(ctx, params, v1.EventTypeWarning, EventReasonExportCreationFailed,
+				"failed to export volume '%s' in access zone '%s' on cluster '%s': '%v'", req.GetName(), accessZone, clusterName, err)
 			return nil, err
 		}
 	}
 	return nil, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, "the export id '"+strconv.Itoa(exportID)+"' and path '"+path+"' may not be ready yet after retrying"))
 }
 
+// rollBackCreatedVolume deletes the snapshot schedule and the directory of a volume whose creation failed. A volume
+// found by a retried request is not deleted along with its data.
+func (s *service) rollBackCreatedVolume(ctx context.Context, isiConfig *IsilonClusterConfig, isiPath, volName string, foundVol bool) error {
+	if foundVol {
+		return nil
+	}
+	if err := s.deleteSnapshotSchedule(ctx, isiConfig, volName); err != nil {
+		log := utils.GetRunIDLogger(ctx)
+		log.Infof("Delete snapshot schedule in CreateVolume returned error '%s'", err)
+	}
+	return isiConfig.isiSvc.DeleteVolume(ctx, isiPath, volName)
+}
+
 func (s *service) createVolumeFromSnapshot(ctx context.Context, isiConfig *IsilonClusterConfig,
 	isiPath, normalizedSnapshotID, dstVolumeName string, sizeInBytes int64) error {
 	var snapshotSrc isi.Snapshot
@@ -535,22 +741,41 @@ func (s *service) getCSIVolume(ctx context.Context, exportID int, volName, path,
 		}
 	}
 
+	volumeID := &utils.VolumeID{
+		Version:              s.opts.VolumeIDVersion,
+		Name:                 volName,
+		ExportID:             exportID,
+		AccessZone:           accessZone,
+		ClusterName:          clusterName,
+		Protocol:             ProtocolNFS,
+		Path:                 path,
+		ReadOnlyFromSnapshot: strings.Index(path, constants.VolumeSnapshotsPath) == 0,
+	}
 	vi := &csi.Volume{
-		VolumeId:      utils.GetNormalizedVolumeID(ctx, volName, exportID, accessZone, clusterName),
-		CapacityBytes: sizeInBytes,
-		VolumeContext: attributes,
-		ContentSource: contentSource,
+		VolumeId:           utils.FormatVolumeID(ctx, volumeID),
+		CapacityBytes:      sizeInBytes,
+		VolumeContext:      attributes,
+		ContentSource:      contentSource,
+		AccessibleTopology: s.getAccessibleTopology(clusterName),
 	}
 	return vi
 }
 
+// getVolumeIDPath returns the path recorded in a versioned volume ID, or defaultPath for the legacy volume IDs
+func getVolumeIDPath(ctx context.Context, volID, defaultPath string) string {
+	if volumeID, err := utils.ParseVolumeID(ctx, volID); err == nil && volumeID.Path != "" {
+		return volumeID.Path
+	}
+	return defaultPath
+}
+
 func (s *service) DeleteVolume(
 	ctx context.Context,
 	req *csi.DeleteVolumeRequest) (
 	*csi.DeleteVolumeResponse, error) {
 	// TODO more checks need to be done, e.g. if access mode is VolumeCapability_AccessMode_MULTI_NODE_XXX, then other nodes might still be using this volume, thus the delete should be skipped
 	// Fetch log handler
-	ctx, _, _ = GetRunIDLog(ctx)
+	ctx, _, runID := GetRunIDLog(ctx)
 
 	// validate request
 	if err := s.ValidateDeleteVolumeRequest(ctx, req); err != nil {
@@ -584,7 +809,10 @@ func (s *service) DeleteVolume(
 	if err != nil {
 		if jsonError, ok := err.(*isiApi.JSONError); ok {
 			if jsonError.StatusCode == 404 {
-				// export not found means the volume doesn't exist
+				// export not found means the volume doesn't exist, the schedule may be left by a previous call
+				if err := s.deleteSnapshotSchedule(ctx, isiConfig, volName); err != nil {
+					return nil, err
+				}
 				return &csi.DeleteVolumeResponse{}, nil
 			}
 			return nil, err
@@ -592,6 +820,9 @@ func (s *service) DeleteVolume(
 		return nil, err
 	} else if export == nil {
 		// in case it occurs the case that export is nil and error is also nil
+		if err := s.deleteSnapshotSchedule(ctx, isiConfig, volName); err != nil {
+			return nil, err
+		}
 		return &csi.DeleteVolumeResponse{}, nil
 	}
 
@@ -607,6 +838,29 @@ func (s *service) DeleteVolume(
 	}
 
 	isiPath := utils.GetIsiPathFromExportPath(exportPath)
+
+	// Files under SmartLock retention cannot be deleted. This is checked first so that
+	// a refused deletion leaves the quota and the export of the volume untouched.
+	// Only the volumes created with SmartLock are checked, the export description tags them.
+	if utils.IsSmartLockExport(export) {
+		retainedFile, retentionDate, err := isiConfig.isiSvc.getRetainedFile(ctx, utils.GetPathForVolume(isiPath, volName))
+		if err == errRetentionCheckLimitReached {
+			s.recordVolumeEvent(ctx, volName, v1.EventTypeWarning, EventReasonVolumeDeletionBlocked,
+				"volume '%s' is in a SmartLock domain and %v, it must be deleted by the storage administrator", volName, err)
+			return nil, status.Error(codes.FailedPrecondition, utils.GetMessageWithRunID(runID,
+				"volume '%s' is in a SmartLock domain and %v, it must be deleted by the storage administrator", volName, err))
+		}
+		if err != nil {
+			return nil, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, "failed to check the SmartLock retention of volume '%s': '%v'", volName, err))
+		}
+		if retainedFile != "" {
+			s.recordVolumeEvent(ctx, volName, v1.EventTypeWarning, EventReasonVolumeDeletionBlocked,
+				"file '%s' of volume '%s' is under SmartLock retention until '%s', it is not possible to delete the volume", retainedFile, volName, retentionDate.Format(time.RFC3339))
+			return nil, status.Error(codes.FailedPrecondition, utils.GetMessageWithRunID(runID,
+				"file '%s' of volume '%s' is under SmartLock retention until '%s', it is not possible to delete the volume", retainedFile, volName, retentionDate.Format(time.RFC3339)))
+		}
+	}
+
 	// to ensure idempotency, check if the volume and export still exists.
 	// k8s might have made the same DeleteVolume call in quick succession and the volume was already deleted in the first run
 	log.Debugf("controller begins to delete volume, name '%s', quotaEnabled '%t'", volName, quotaEnabled)
@@ -623,6 +877,10 @@ func (s *service) DeleteVolume(
 
 	if !isiConfig.isiSvc.IsVolumeExistent(ctx, isiPath, "", volName) {
 		log.Debugf("volume '%s' not found, skip calling delete directory.", volName)
+		// the schedule may be left by a previous call which deleted the directory
+		if err := s.deleteSnapshotSchedule(ctx, isiConfig, volName); err != nil {
+			return nil, err
+		}
 	} else {
 		// Before deleting the Volume, we would like to check if there are any
 		// NFS exports which still exist on the Volume. These exports could
@@ -649,12 +907,26 @@ func (s *service) DeleteVolume(
 				return nil, err
 			}
 		} else if exports != nil && exports.Total > 1 {
+			s.recordVolumeEvent(ctx, volName, v1.EventTypeWarning, EventReasonVolumeDeletionBlocked,
+				"'%d' exports found for volume '%s' in access zone '%s' on cluster '%s', it is not safe to delete the volume", exports.Total, volName, accessZone, clusterName)
 			return nil, fmt.Errorf("exports found for volume %s in AccessZone %s. It is not safe to delete the volume", volName, accessZone)
 		}
 
+		// the schedule must not outlive the directory, OneFS would fail to take its snapshots
+		if err := s.deleteSnapshotSchedule(ctx, isiConfig, volName); err != nil {
+			return nil, err
+		}
+		// nil if the volume isn't a writable snapshot
+		writable, _ := isiConfig.isiSvc.GetWritableSnapshot(ctx, path)
 		if err := isiConfig.isiSvc.DeleteVolume(ctx, isiPath, volName); err != nil {
 			return nil, err
 		}
+		// the source snapshot of a volume restored as a writable snapshot may wait for the volume to be deleted
+		if writable != nil {
+			if err := s.releaseSnapshotOfWritableVolume(ctx, isiConfig, writable); err != nil {
+				return nil, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, "failed to release the source snapshot of volume '%s': '%v'", volName, err))
+			}
+		}
 	}
 	return &csi.DeleteVolumeResponse{}, nil
 }
@@ -710,12 +982,23 @@ func (s *service) processSnapshotTrackingDirectoryDuringDeleteVolume(
 				log.Errorf("failed to delete snapshot directory export with id '%v'", export.ID)
 				return nil
 			}
+			// volumes restored as writable snapshots still depend on the snapshot, the last one deletes it
+			if snapshot, err := isiConfig.isiSvc.GetSnapshot(ctx, snapshotName); err == nil {
+				if dependents, err := s.getWritableSnapshotDependents(ctx, isiConfig, snapshot, ""); err != nil || len(dependents) > 0 {
+					log.Debugf("snapshot '%s' is kept for the volumes '%v' depending on it: '%v'", snapshotName, dependents, err)
+					return nil
+				}
+			}
 			// Delete snapshot tracking directory
 			if err := isiConfig.isiSvc.DeleteVolume(ctx, isiPath, snapshotTrackingDir); err != nil {
 				log.Errorf("error while deleting snapshot tracking directory '%s'", path.Join(isiPath, snapshotName))
 				return nil
 			}
-			// Delete snapshot
+			// Delete snapshot, unless it expired and OneFS deletes it
+			if snapshot, err := isiConfig.isiSvc.GetSnapshot(ctx, snapshotName); (err != nil && isNotFoundError(err)) || isSnapshotExpired(snapshot) {
+				log.Debugf("snapshot '%s' expired, it is deleted by OneFS", snapshotName)
+				return nil
+			}
 			err = isiConfig.isiSvc.client.RemoveSnapshot(context.Background(), -1, snapshotName)
 			if err != nil {
 				log.Errorf("error deleting snapshot: '%s'", err.Error())
@@ -761,6 +1044,9 @@ func (s *service) ControllerExpandVolume(
 	}
 
 	requiredBytes := req.GetCapacityRange().GetRequiredBytes()
+	if err := s.checkTenantMaxCapacity(ctx, req.GetVolumeId(), requiredBytes); err != nil {
+		return nil, err
+	}
 
 	// when Quota is disabled, always return success
 	// Otherwise, update the quota size as requested
@@ -784,6 +1070,104 @@ func (s *service) ControllerExpandVolume(
 	return &csi.ControllerExpandVolumeResponse{CapacityBytes: requiredBytes, NodeExpansionRequired: false}, nil
 }
 
+// ControllerModifyVolume applies the mutable parameters of a VolumeAttributesClass to an existing volume: the limits
+// of its quota, the security flavors and root clients of its export and the SmartPools settings of its directory
+func (s *service) ControllerModifyVolume(
+	ctx context.Context,
+	req *csi.ControllerModifyVolumeRequest) (*csi.ControllerModifyVolumeResponse, error) {
+	ctx, log, runID := GetRunIDLog(ctx)
+
+	volID := req.GetVolumeId()
+	if volID == "" {
+		return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID, "a volume ID is required"))
+	}
+	if len(req.GetMutableParameters()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID, "no parameter to modify"))
+	}
+	volName, exportID, accessZone, clusterName, err := utils.ParseNormalizedVolumeID(ctx, volID)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID, "failed to parse volume ID '%s': '%v'", volID, err))
+	}
+	modification, err := getVolumeModification(req.GetMutableParameters())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID, err.Error()))
+	}
+	if modification.modifiesQuota() && !s.opts.QuotaEnabled {
+		return nil, status.Error(codes.FailedPrecondition, utils.GetMessageWithRunID(runID, "quotas are disabled, the quota limits cannot be modified"))
+	}
+
+	isiConfig, err := s.getIsilonConfig(ctx, &clusterName)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, utils.GetMessageWithRunID(runID, err.Error()))
+	}
+	if err := s.autoProbe(ctx, isiConfig); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, utils.GetMessageWithRunID(runID, "failed to probe cluster '%s': '%v'", clusterName, err))
+	}
+
+	export, err := isiConfig.isiSvc.GetExportByIDWithZone(ctx, exportID, accessZone)
+	if err != nil || export == nil {
+		return nil, status.Error(codes.NotFound, utils.GetMessageWithRunID(runID, "failed to get the export of volume '%s': '%v'", volID, err))
+	}
+	if export.Paths == nil || len(*export.Paths) == 0 {
+		return nil, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, "export '%d' of volume '%s' has no path", exportID, volID))
+	}
+	volPath := (*export.Paths)[0]
+
+	if modification.modifiesQuota() {
+		quota, err := isiConfig.isiSvc.GetVolumeQuota(ctx, volName, exportID, accessZone)
+		if err != nil {
+			return nil, status.Error(codes.NotFound, utils.GetMessageWithRunID(runID, err.Error()))
+		}
+		thresholds, err := getModifiedQuotaThresholds(quota.Thresholds.Hard, quota.Thresholds.Soft, quota.Thresholds.Advisory, modification)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID, err.Error()))
+		}
+		if err := isiConfig.isiSvc.UpdateQuotaThresholds(ctx, quota.Id, thresholds); err != nil {
+			return nil, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, err.Error()))
+		}
+	}
+
+	if modification.securityFlavors != nil {
+		if err := isiConfig.isiSvc.SetExportSecurityFlavors(ctx, exportID, accessZone, modification.securityFlavors); err != nil {
+			return nil, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, err.Error()))
+		}
+	}
+
+	// the nodes the volume is published to are given root access or lose it, the nodes it is published to later
+	// still get the access set by the RootClientEnabled attribute of the PV
+	if modification.rootClientEnabled != nil {
+		rootClients := make([]string, 0)
+		if *modification.rootClientEnabled {
+			dummyName, dummyFQDN, dummyIP, _ := utils.ParseNodeID(ctx, utils.DummyHostNodeID)
+			if export.RootClients != nil {
+				rootClients = append(rootClients, *export.RootClients...)
+			}
+			var clients []string
+			if export.Clients != nil {
+				clients = *export.Clients
+			}
+			for _, client := range clients {
+				if client != dummyName && client != dummyFQDN && client != dummyIP && !utils.IsStringInSlice(client, rootClients) {
+					rootClients = append(rootClients, client)
+				}
+			}
+		}
+		if err := isiConfig.isiSvc.SetExportRootClients(ctx, exportID, accessZone, rootClients); err != nil {
+			return nil, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, err.Error()))
+		}
+	}
+
+	if modification.smartPools != nil {
+		if err := s.modifySmartPoolsSettings(ctx, isiConfig, volName, volPath, modification.smartPools); err != nil {
+			return nil, status.Error(codes.Internal, utils.GetMessageWithRunID(runID,
+				"failed to modify the SmartPools settings of volume '%s': '%v'", volID, err))
+		}
+	}
+
+	log.Infof("volume '%s' modified with parameters '%v'", volID, req.GetMutableParameters())
+	return &csi.ControllerModifyVolumeResponse{}, nil
+}
+
 func (s *service) getAddClientFunc(rootClientEnabled bool, isiConfig *IsilonClusterConfig) (addClientFunc func(ctx context.Context, exportID int, accessZone, clientIP string) error) {
 	if rootClientEnabled {
 		return isiConfig.isiSvc.AddExportRootClientByIDWithZone
@@ -837,6 +1221,17 @@ func (s *service) ControllerPublishVolume(
 		return nil, err
 	}
 
+	// the nodes are not given access to a volume whose data is being copied to another cluster
+	if migration := s.getInFlightMigration(ctx, volName, exportID, accessZone, clusterName); migration != nil {
+		return nil, status.Error(codes.FailedPrecondition, utils.GetMessageWithRunID(runID,
+			"volume '%s' is being migrated to cluster '%s'", volID, migration.TargetCluster))
+	}
+	// nor to a volume whose data is being reverted to a snapshot
+	if revert := s.getInFlightRevert(ctx, volName, exportID, accessZone, isiConfig.ClusterName); revert != nil {
+		return nil, status.Error(codes.FailedPrecondition, utils.GetMessageWithRunID(runID,
+			"volume '%s' is being reverted to snapshot '%s'", volID, revert.SnapshotID))
+	}
+
 	if err := s.autoProbe(ctx, isiConfig); err != nil {
 		log.Error("Failed to probe with error: " + err.Error())
 		return nil, err
@@ -847,7 +1242,7 @@ func (s *service) ControllerPublishVolume(
 	}
 
 	if exportPath = volumeContext[ExportPathParam]; exportPath == "" {
-		exportPath = utils.GetPathForVolume(isiConfig.IsiPath, volName)
+		exportPath = getVolumeIDPath(ctx, volID, utils.GetPathForVolume(isiConfig.IsiPath, volName))
 	}
 
 	isROVolumeFromSnapshot = isiConfig.isiSvc.isROVolumeFromSnapshot(exportPath)
@@ -905,9 +1300,24 @@ func (s *service) ControllerPublishVolume(
 
 	addClientFunc := s.getAddClientFunc(rootClientEnabled, isiConfig)
 
+	// a read-only publish adds the node to the read-only clients of the export, whatever the access mode
+	readOnly := req.GetReadonly() || am.Mode == csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY ||
+		am.Mode == csi.VolumeCapability_AccessMode_SINGLE_NODE_READER_ONLY
+	if readOnly {
+		addClientFunc = isiConfig.isiSvc.AddExportReadOnlyClientByIDWithZone
+	}
+
+	// OneFS gives a node in both the read-only clients and the clients of an export read-only access, and
+	// ControllerUnpublishVolume removes all the entries of the node, so a node cannot have both publications
+	isReadOnlyClient, isReadWriteClient := isiConfig.isiSvc.GetNodeExportAccess(ctx, exportID, accessZone, nodeID)
+	if readOnly && isReadWriteClient || !readOnly && isReadOnlyClient {
+		return nil, status.Errorf(codes.AlreadyExists, utils.GetMessageWithRunID(runID,
+			"volume '%s' is already published to node '%s' with read-only '%v', cannot publish it with read-only '%v'", volID, nodeID, !readOnly, readOnly))
+	}
+
 	switch am.Mode {
 	case csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER:
-		if isROVolumeFromSnapshot {
+		if isROVolumeFromSnapshot && !readOnly {
 			err = fmt.Errorf("unsupported access mode: '%s'", am.String())
 			break
 		}
@@ -917,27 +1327,32 @@ func (s *service) ControllerPublishVolume(
 		}
 
 		err = isiConfig.isiSvc.AddExportClientNetworkIdentifierByIDWithZone(ctx, exportID, accessZone, nodeID, addClientFunc)
-		if err == nil && rootClientEnabled {
+		if err == nil && rootClientEnabled && !readOnly {
 			err = isiConfig.isiSvc.AddExportClientNetworkIdentifierByIDWithZone(ctx, exportID, accessZone, nodeID, isiConfig.isiSvc.AddExportClientByIDWithZone)
 		}
 	case csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY:
-		err = isiConfig.isiSvc.AddExportClientNetworkIdentifierByIDWithZone(ctx, exportID, accessZone, nodeID, isiConfig.isiSvc.AddExportReadOnlyClientByIDWithZone)
-	case csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER:
-		if isROVolumeFromSnapshot {
+		err = isiConfig.isiSvc.AddExportClientNetworkIdentifierByIDWithZone(ctx, exportID, accessZone, nodeID, addClientFunc)
+	case csi.VolumeCapability_AccessMode_SINGLE_NODE_READER_ONLY:
+		if isiConfig.isiSvc.OtherClientsAlreadyAdded(ctx, exportID, accessZone, nodeID) {
+			return nil, s.otherClientsAlreadyAddedError(ctx, runID, volName, exportID, accessZone, clusterName, nodeID, am.Mode)
+		}
+		err = isiConfig.isiSvc.AddExportClientNetworkIdentifierByIDWithZone(ctx, exportID, accessZone, nodeID, addClientFunc)
+	case csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER,
+		csi.VolumeCapability_AccessMode_SINGLE_NODE_SINGLE_WRITER,
+		csi.VolumeCapability_AccessMode_SINGLE_NODE_MULTI_WRITER:
+		if isROVolumeFromSnapshot && !readOnly {
 			err = fmt.Errorf("unsupported access mode: '%s'", am.String())
 			break
 		}
 		if isiConfig.isiSvc.OtherClientsAlreadyAdded(ctx, exportID, accessZone, nodeID) {
-			return nil, status.Errorf(codes.FailedPrecondition, utils.GetMessageWithRunID(runID,
-				"export '%d' in access zone '%s' already has other clients added to it, and the access mode is "+
-					"SINGLE_NODE_WRITER, thus the request fails", exportID, accessZone))
+			return nil, s.otherClientsAlreadyAddedError(ctx, runID, volName, exportID, accessZone, clusterName, nodeID, am.Mode)
 		}
 
 		if !isiConfig.isiSvc.IsHostAlreadyAdded(ctx, exportID, accessZone, utils.DummyHostNodeID) {
 			err = isiConfig.isiSvc.AddExportClientNetworkIdentifierByIDWithZone(ctx, exportID, accessZone, utils.DummyHostNodeID, isiConfig.isiSvc.AddExportClientByIDWithZone)
 		}
 		err = isiConfig.isiSvc.AddExportClientNetworkIdentifierByIDWithZone(ctx, exportID, accessZone, nodeID, addClientFunc)
-		if err == nil && rootClientEnabled {
+		if err == nil && rootClientEnabled && !readOnly {
 			err = isiConfig.isiSvc.AddExportClientNetworkIdentifierByIDWithZone(ctx, exportID, accessZone, nodeID, isiConfig.isiSvc.AddExportClientByIDWithZone)
 		}
 	default:
@@ -948,7 +1363,22 @@ func (s *service) ControllerPublishVolume(
 		return nil, status.Errorf(codes.Internal, utils.GetMessageWithRunID(runID,
 			"internal error occured when attempting to add client ip '%s' to export '%d', error : '%v'", nodeID, exportID, err))
 	}
-	return &csi.ControllerPublishVolumeResponse{}, nil
+	// the node ID is kept in the attachment metadata of the VolumeAttachment, so that the node can be removed from
+	// the export even after both the node and its CSINode are gone
+	return &csi.ControllerPublishVolumeResponse{
+		PublishContext: map[string]string{publishContextNodeID: nodeID},
+	}, nil
+}
+
+// otherClientsAlreadyAddedError records an event and returns the error of a single node publish to an export
+// which already has clients other than the node
+func (s *service) otherClientsAlreadyAddedError(ctx context.Context, runID, volName string, exportID int, accessZone, clusterName, nodeID string,
+	mode csi.VolumeCapability_AccessMode_Mode) error {
+	s.recordVolumeEvent(ctx, volName, v1.EventTypeWarning, EventReasonExportHasOtherClients,
+		"export '%d' in access zone '%s' on cluster '%s' already has other clients, cannot publish to node '%s' with access mode %s", exportID, accessZone, clusterName, nodeID, mode)
+	return status.Errorf(codes.FailedPrecondition, utils.GetMessageWithRunID(runID,
+		"export '%d' in access zone '%s' already has other clients added to it, and the access mode is "+
+			"%s, thus the request fails", exportID, accessZone, mode))
 }
 
 func (s *service) ValidateVolumeCapabilities(
@@ -986,7 +1416,7 @@ func (s *service) ValidateVolumeCapabilities(
 
 	volumeContext := req.GetVolumeContext()
 	if exportPath = volumeContext[ExportPathParam]; exportPath == "" {
-		exportPath = utils.GetPathForVolume(s.opts.Path, volName)
+		exportPath = getVolumeIDPath(ctx, volID, utils.GetPathForVolume(s.opts.Path, volName))
 	}
 	isiPath = utils.GetIsiPathFromExportPath(exportPath)
 
@@ -1084,9 +1514,47 @@ func (s *service) ListVolumes(ctx context.Context,
 	return nil, status.Error(codes.Unimplemented, "")
 }
 
-func (s *service) ListSnapshots(context.Context,
-	*csi.ListSnapshotsRequest) (*csi.ListSnapshotsResponse, error) {
-	return nil, status.Error(codes.Unimplemented, "")
+func (s *service) ListSnapshots(
+	ctx context.Context,
+	req *csi.ListSnapshotsRequest) (
+	*csi.ListSnapshotsResponse, error) {
+	// Fetch log handler
+	ctx, log, runID := GetRunIDLog(ctx)
+
+	var entries []*csi.ListSnapshotsResponse_Entry
+	var err error
+	if req.GetSnapshotId() != "" {
+		entries, err = s.listSnapshotsByID(ctx, req.GetSnapshotId())
+	} else if req.GetSourceVolumeId() != "" {
+		entries, err = s.listSnapshotsOfVolume(ctx, req.GetSourceVolumeId())
+	} else {
+		entries, err = s.listAllSnapshots(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// the snapshots are listed in a stable order for the starting tokens to be consistent between calls
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Snapshot.CreationTime.Seconds < entries[j].Snapshot.CreationTime.Seconds
+	})
+
+	start := 0
+	if req.GetStartingToken() != "" {
+		if start, err = strconv.Atoi(req.GetStartingToken()); err != nil || start < 0 || start > len(entries) {
+			return nil, status.Error(codes.Aborted, utils.GetMessageWithRunID(runID, "the starting token '%s' is not valid", req.GetStartingToken()))
+		}
+	}
+	end := len(entries)
+	if req.GetMaxEntries() > 0 && start+int(req.GetMaxEntries()) < end {
+		end = start + int(req.GetMaxEntries())
+	}
+
+	resp := &csi.ListSnapshotsResponse{Entries: entries[start:end]}
+	if end < len(entries) {
+		resp.NextToken = strconv.Itoa(end)
+	}
+	log.Debugf("listed '%d' of '%d' snapshots", end-start, len(entries))
+	return resp, nil
 }
 
 func (s *service) ControllerUnpublishVolume(
@@ -1126,6 +1594,8 @@ func (s *service) ControllerUnpublishVolume(
 			utils.GetMessageWithRunID(runID, "node ID is required"))
 	}
 
+	// the node is either a read-only or a read-write client of the export, as ControllerPublishVolume doesn't
+	// publish a volume to a node with both flags, so removing all the entries of the node only removes its publication
 	if err := isiConfig.isiSvc.RemoveExportClientByIDWithZone(ctx, exportID, accessZone, nodeID); err != nil {
 		return nil, status.Errorf(codes.Internal, utils.GetMessageWithRunID(runID, "error encountered when"+
 			" trying to remove client '%s' from export '%d' with access zone '%s' on cluster '%s'", nodeID, exportID, accessZone, clusterName))
@@ -1156,15 +1626,30 @@ func (s *service) GetCapacity(
 	ctx, log = setClusterContext(ctx, clusterName)
 	log.Debugf("Cluster Name: %v", clusterName)
 
-	isiConfig, err := s.getIsilonConfig(ctx, &clusterName)
-	if err != nil {
-		log.Error("Failed to get Isilon config with error ", err.Error())
-		return nil, err
+	// without a ClusterName, the capacity placement may put the volume on any of the clusters
+	placement := params[ClusterNameParam] == "" && s.opts.ClusterPlacement == ClusterPlacementCapacity
+
+	var isiConfigs []*IsilonClusterConfig
+	if placement {
+		isiConfigs = s.getPlacementClusters()
+	} else {
+		isiConfig, err := s.getIsilonConfig(ctx, &clusterName)
+		if err != nil {
+			log.Error("Failed to get Isilon config with error ", err.Error())
+			return nil, err
+		}
+		isiConfigs = append(isiConfigs, isiConfig)
 	}
 
-	if err := s.autoProbe(ctx, isiConfig); err != nil {
-		log.Error("Failed to probe with error: " + err.Error())
-		return nil, err
+	// only the clusters accessible from the topology segment can host the volumes
+	if topology := req.GetAccessibleTopology(); topology != nil {
+		var accessible []*IsilonClusterConfig
+		for _, isiConfig := range isiConfigs {
+			if isInTopology(isiConfig, topology) {
+				accessible = append(accessible, isiConfig)
+			}
+		}
+		isiConfigs = accessible
 	}
 
 	// Optionally validate the volume capability
@@ -1177,21 +1662,100 @@ func (s *service) GetCapacity(
 		}
 	}
 
+	overprovisioningRatio, err := getOverprovisioningRatio(params)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID, err.Error()))
+	}
+
+	var availableCapacity, maximumVolumeSize int64
+	for _, isiConfig := range isiConfigs {
+		ctx, log := setClusterContext(ctx, isiConfig.ClusterName)
+
+		isiPath := isiConfig.IsiPath
+		if params[IsiPathParam] != "" {
+			isiPath = params[IsiPathParam]
+		}
+
+		capacity, err := s.getClusterCapacity(ctx, runID, isiConfig, isiPath, overprovisioningRatio)
+		if err != nil {
+			if !placement {
+				return nil, err
+			}
+			// an unreachable cluster is not chosen by the capacity placement
+			log.Warnf("failed to get the capacity of cluster '%s': '%v'", isiConfig.ClusterName, err)
+			continue
+		}
+
+		log.Debugf("capacity of isiPath '%s' on cluster '%s' is '%d' bytes", isiPath, isiConfig.ClusterName, capacity)
+		availableCapacity += capacity
+		if capacity > maximumVolumeSize {
+			maximumVolumeSize = capacity
+		}
+	}
+
+	return &csi.GetCapacityResponse{
+		AvailableCapacity: availableCapacity,
+		MaximumVolumeSize: &wrappers.Int64Value{Value: maximumVolumeSize},
+	}, nil
+}
+
+// getClusterCapacity returns the free space of a cluster scaled by the overprovisioning ratio, limited by the directory
+// quota of the isiPath if there is one. The ratio does not apply to the quota, which is enforced on the logical size.
+func (s *service) getClusterCapacity(ctx context.Context, runID string, isiConfig *IsilonClusterConfig, isiPath string, overprovisioningRatio float64) (int64, error) {
+	log := utils.GetRunIDLogger(ctx)
+
+	if err := s.autoProbe(ctx, isiConfig); err != nil {
+		log.Error("Failed to probe with error: " + err.Error())
+		return 0, err
+	}
+
 	//pass the key(s) to rest api
 	keyArray := []string{"ifs.bytes.avail"}
 
 	stat, err := isiConfig.isiSvc.GetStatistics(ctx, keyArray)
-	if err != nil || len(stat.StatsList) < 1 {
-		return nil, status.Errorf(codes.Internal, utils.GetMessageWithRunID(runID, "Could not retrieve capacity. Error '%s'", err.Error()))
+	if err != nil {
+		return 0, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, "Could not retrieve capacity. Error '%s'", err.Error()))
+	}
+	if len(stat.StatsList) < 1 {
+		return 0, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, "Could not retrieve capacity. No statistics returned"))
 	}
 	if stat.StatsList[0].Error != "" {
-		return nil, status.Errorf(codes.Internal, utils.GetMessageWithRunID("Could not retrieve capacity. Data returned error '%s'", stat.StatsList[0].Error))
+		return 0, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, "Could not retrieve capacity. Data returned error '%s'", stat.StatsList[0].Error))
 	}
-	remainingCapInBytes := stat.StatsList[0].Value
+	remainingCapInBytes := int64(float64(stat.StatsList[0].Value) * overprovisioningRatio)
 
-	return &csi.GetCapacityResponse{
-		AvailableCapacity: int64(remainingCapInBytes),
-	}, nil
+	quota, err := isiConfig.isiSvc.GetDirectoryQuota(ctx, isiPath)
+	if err != nil {
+		return 0, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, "failed to get the quota of isiPath '%s': '%v'", isiPath, err))
+	}
+	if quota != nil && quota.Thresholds.Hard > 0 {
+		used := quota.Usage.Logical
+		if quota.ThresholdsIncludeOverhead {
+			used = quota.Usage.Physical
+		}
+		quotaRemaining := quota.Thresholds.Hard - used
+		if quotaRemaining < 0 {
+			quotaRemaining = 0
+		}
+		if quotaRemaining < remainingCapInBytes {
+			log.Debugf("capacity of isiPath '%s' is limited to '%d' bytes by its quota", isiPath, quotaRemaining)
+			remainingCapInBytes = quotaRemaining
+		}
+	}
+	return remainingCapInBytes, nil
+}
+
+// getOverprovisioningRatio parses the OverprovisioningRatio storage class parameter, 1 if it is not set
+func getOverprovisioningRatio(params map[string]string) (float64, error) {
+	value, ok := params[OverprovisioningRatioParam]
+	if !ok || value == "" {
+		return 1, nil
+	}
+	ratio, err := strconv.ParseFloat(value, 64)
+	if err != nil || ratio < 1 {
+		return 0, fmt.Errorf("invalid value '%s' for '%s', a number not less than 1 is expected", value, OverprovisioningRatioParam)
+	}
+	return ratio, nil
 }
 
 func (s *service) ControllerGetCapabilities(
@@ -1236,13 +1800,13 @@ func (s *service) ControllerGetCapabilities(
 					},
 				},
 			},
-			/*{
+			{
 				Type: &csi.ControllerServiceCapability_Rpc{
 					Rpc: &csi.ControllerServiceCapability_RPC{
 						Type: csi.ControllerServiceCapability_RPC_LIST_SNAPSHOTS,
 					},
 				},
-			},*/
+			},
 			{
 				Type: &csi.ControllerServiceCapability_Rpc{
 					Rpc: &csi.ControllerServiceCapability_RPC{
@@ -1257,6 +1821,20 @@ func (s *service) ControllerGetCapabilities(
 					},
 				},
 			},
+			{
+				Type: &csi.ControllerServiceCapability_Rpc{
+					Rpc: &csi.ControllerServiceCapability_RPC{
+						Type: csi.ControllerServiceCapability_RPC_SINGLE_NODE_MULTI_WRITER,
+					},
+				},
+			},
+			{
+				Type: &csi.ControllerServiceCapability_Rpc{
+					Rpc: &csi.ControllerServiceCapability_RPC{
+						Type: csi.ControllerServiceCapability_RPC_MODIFY_VOLUME,
+					},
+				},
+			},
 		},
 	}, nil
 }
@@ -1337,6 +1915,10 @@ func (s *service) CreateSnapshot(
 	if err != nil {
 		return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID, err.Error()))
 	}
+	settings, err := getSnapshotSettings(params)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID, err.Error()))
+	}
 
 	log.Infof("snapshot name is '%s' and source volume ID is '%s' ", snapshotName, srcVolumeID)
 	// check if snapshot already exists
@@ -1344,6 +1926,12 @@ func (s *service) CreateSnapshot(
 	log.Infof("check for existence of snapshot '%s'", snapshotName)
 	if snapshotByName, err = isiConfig.isiSvc.GetSnapshot(ctx, snapshotName); snapshotByName != nil {
 		if path.Base(snapshotByName.Path) == srcVolumeID {
+			// the alias may not point to the snapshot yet if a previous call failed to set it
+			if settings != nil && settings.alias != "" {
+				if err := isiConfig.isiSvc.SetSnapshotAlias(ctx, settings.alias, snapshotName); err != nil {
+					return nil, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, err.Error()))
+				}
+			}
 			// return the existent snapshot
 			return s.getCreateSnapshotResponse(ctx, strconv.FormatInt(snapshotByName.Id, 10), req.GetSourceVolumeId(), snapshotByName.Created, isiConfig.isiSvc.GetSnapshotSize(ctx, isiPath, snapshotName), clusterName), nil
 		}
@@ -1355,7 +1943,7 @@ func (s *service) CreateSnapshot(
 
 	// create new snapshot for source direcory
 	path := utils.GetPathForVolume(isiPath, srcVolumeID)
-	if snapshotNew, err = isiConfig.isiSvc.CreateSnapshot(ctx, path, snapshotName); err != nil {
+	if snapshotNew, err = isiConfig.isiSvc.CreateSnapshot(ctx, path, snapshotName, settings); err != nil {
 		return nil, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, err.Error()))
 	}
 	_, _ = isiConfig.isiSvc.GetSnapshot(ctx, snapshotName)
@@ -1477,9 +2065,18 @@ func (s *service) DeleteSnapshot(
 		}
 	}
 
+	// An expired snapshot is deleted by OneFS, only the export and tracking directory of its RO volumes are cleaned up
+	snapshotExpired := isSnapshotExpired(snapshot)
+	if snapshotExpired {
+		log.Infof("snapshot with id '%s' expired, it is deleted by OneFS", snapshotID)
+	}
+
 	// Get snapshot path
 	snapshotIsiPath, err := isiConfig.isiSvc.GetSnapshotIsiPath(ctx, isiConfig.IsiPath, snapshotID)
 	if err != nil {
+		if snapshotExpired {
+			return &csi.DeleteSnapshotResponse{}, nil
+		}
 		return nil, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, err.Error()))
 	}
 	log.Debugf("The Isilon directory path of snapshot is= %v", snapshotIsiPath)
@@ -1500,7 +2097,22 @@ func (s *service) DeleteSnapshot(
 		}
 	}
 
-	if deleteSnapshot {
+	// the volumes restored as writable snapshots of this snapshot depend on it as well
+	if deleteSnapshot && !snapshotExpired {
+		dependents, err := s.getWritableSnapshotDependents(ctx, isiConfig, snapshot, "")
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, utils.GetMessageWithRunID(runID, "cannot get the writable snapshots of the snapshot: '%s'", err.Error()))
+		}
+		if len(dependents) > 0 {
+			log.Infof("volumes '%v' depend on snapshot with id '%s', defer its deletion", dependents, snapshotID)
+			if err := s.deferSnapshotDeletion(ctx, snapshotIsiPath, isiConfig); err != nil {
+				return nil, status.Errorf(codes.Internal, utils.GetMessageWithRunID(runID, "cannot defer the deletion of the snapshot: '%s'", err.Error()))
+			}
+			deleteSnapshot = false
+		}
+	}
+
+	if deleteSnapshot && !snapshotExpired {
 		err = isiConfig.isiSvc.DeleteSnapshot(ctx, id, "")
 		if err != nil {
 			return nil, status.Errorf(codes.Internal, utils.GetMessageWithRunID(runID, "error deleting snapshot: '%s'", err.Error()))
@@ -1555,6 +2167,17 @@ func (s *service) processSnapshotTrackingDirectoryDuringDeleteSnapshot(
 	return nil
 }
 
+// deferSnapshotDeletion sets the delete marker in the tracking directory of a snapshot, the snapshot is then deleted
+// along with the last volume depending on it
+func (s *service) deferSnapshotDeletion(ctx context.Context, snapshotIsiPath string, isiConfig *IsilonClusterConfig) error {
+	isiPath, snapshotName, _ := isiConfig.isiSvc.GetSnapshotIsiPathComponents(snapshotIsiPath)
+	snapshotTrackingDir := isiConfig.isiSvc.GetSnapshotTrackingDirName(snapshotName)
+	if err := isiConfig.isiSvc.CreateVolume(ctx, isiPath, snapshotTrackingDir); err != nil {
+		return err
+	}
+	return isiConfig.isiSvc.CreateVolume(ctx, isiPath, path.Join(snapshotTrackingDir, DeleteSnapshotMarker))
+}
+
 //Validate volume capabilities
 func validateVolumeCaps(
 	vcs []*csi.VolumeCapability,
@@ -1581,8 +2204,11 @@ func validateVolumeCaps(
 		case csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER:
 			break
 		case csi.VolumeCapability_AccessMode_SINGLE_NODE_READER_ONLY:
-			supported = false
-			reason = errNoSingleNodeReader
+			break
+		case csi.VolumeCapability_AccessMode_SINGLE_NODE_SINGLE_WRITER:
+			break
+		case csi.VolumeCapability_AccessMode_SINGLE_NODE_MULTI_WRITER:
+			break
 		case csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY:
 			break
 		case csi.VolumeCapability_AccessMode_MULTI_NODE_SINGLE_WRITER:
@@ -0,0 +1,263 @@
+package service
+
+/*
+ Copyright (c) 2019 Dell Inc, or its subsidiaries.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dell/csi-isilon/common/utils"
+)
+
+const (
+	// prefixes of the references to credentials stored outside of the secret
+	credentialFilePrefix     = "file://"
+	credentialEnvPrefix      = "env:"
+	credentialProviderPrefix = "provider:"
+
+	defaultCredentialRefreshInterval = 5 * time.Minute
+	secretProviderTimeout            = 30 * time.Second
+)
+
+// SecretProviderConfig is the HTTP secret provider the "provider:<key>" references of the credentials are resolved
+// with, the secrets are fetched from <endpoint>/<key> with the token, or the token its reference resolves to, as
+// bearer token
+type SecretProviderConfig struct {
+	Endpoint                  string `json:"endpoint" yaml:"endpoint"`
+	Token                     string `json:"token,omitempty" yaml:"token,omitempty"`
+	TokenRef                  string `json:"tokenRef,omitempty" yaml:"tokenRef,omitempty"`
+	SkipCertificateValidation bool   `json:"skipCertificateValidation,omitempty" yaml:"skipCertificateValidation,omitempty"`
+}
+
+// secretProviderResponse is the response of the secret provider, its body is used as the secret if it is not JSON
+type secretProviderResponse struct {
+	Value *string `json:"value"`
+}
+
+// credentialResolver resolves the references to the credentials of the clusters
+type credentialResolver struct {
+	provider *SecretProviderConfig
+	client   *http.Client
+}
+
+func newCredentialResolver(provider *SecretProviderConfig) *credentialResolver {
+	r := &credentialResolver{provider: provider}
+	if provider != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: provider.SkipCertificateValidation}
+		r.client = &http.Client{
+			Timeout:   secretProviderTimeout,
+			Transport: transport,
+		}
+	}
+	return r
+}
+
+// getCredentialRefreshInterval returns the interval the credential references are resolved again at, 0 if they are
+// not refreshed
+func getCredentialRefreshInterval(inputConfigs *IsilonClusters) (time.Duration, error) {
+	if inputConfigs.CredentialRefreshInterval == "" {
+		return defaultCredentialRefreshInterval, nil
+	}
+	interval, err := time.ParseDuration(inputConfigs.CredentialRefreshInterval)
+	if err != nil || interval < 0 {
+		return 0, fmt.Errorf("not a valid credentialRefreshInterval: %q", inputConfigs.CredentialRefreshInterval)
+	}
+	return interval, nil
+}
+
+// resolveClusterCredentials sets the username and password of a cluster config from their usernameRef and
+// passwordRef, which are kept in the config to be resolved again when the credentials are refreshed. The username
+// and password themselves are always used as they are.
+func (r *credentialResolver) resolveClusterCredentials(ctx context.Context, config *IsilonClusterConfig) error {
+	var err error
+	if config.UserRef != "" {
+		if config.User, err = r.resolve(ctx, config.UserRef); err != nil {
+			return fmt.Errorf("failed to resolve the username: %v", err)
+		}
+	}
+	if config.PasswordRef != "" {
+		if config.Password, err = r.resolve(ctx, config.PasswordRef); err != nil {
+			return fmt.Errorf("failed to resolve the password: %v", err)
+		}
+	}
+	return nil
+}
+
+// resolve returns the value of a credential reference
+func (r *credentialResolver) resolve(ctx context.Context, ref string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, credentialFilePrefix):
+		path := strings.TrimPrefix(ref, credentialFilePrefix)
+		value, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read '%s': %v", path, err)
+		}
+		return strings.TrimRight(string(value), "\r\n"), nil
+
+	case strings.HasPrefix(ref, credentialEnvPrefix):
+		name := strings.TrimPrefix(ref, credentialEnvPrefix)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable '%s' is not set", name)
+		}
+		return value, nil
+
+	case strings.HasPrefix(ref, credentialProviderPrefix):
+		return r.getProviderSecret(ctx, strings.TrimPrefix(ref, credentialProviderPrefix))
+	}
+	return "", fmt.Errorf("invalid reference '%s', a \"%s\", \"%s\" or \"%s\" reference is expected", ref,
+		credentialFilePrefix, credentialEnvPrefix, credentialProviderPrefix)
+}
+
+// getProviderSecret fetches a secret from the secret provider
+func (r *credentialResolver) getProviderSecret(ctx context.Context, key string) (string, error) {
+	log := utils.GetRunIDLogger(ctx)
+	if r.provider == nil || r.provider.Endpoint == "" {
+		return "", fmt.Errorf("secret '%s' cannot be fetched, no secretProvider is configured", key)
+	}
+	log.Debugf("begin to fetch secret '%s' from the secret provider", key)
+
+	token := r.provider.Token
+	if r.provider.TokenRef != "" {
+		if strings.HasPrefix(r.provider.TokenRef, credentialProviderPrefix) {
+			return "", errors.New("the token of the secret provider cannot be a secret of the secret provider")
+		}
+		var err error
+		if token, err = r.resolve(ctx, r.provider.TokenRef); err != nil {
+			return "", fmt.Errorf("failed to resolve the token of the secret provider: %v", err)
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(r.provider.Endpoint, "/")+"/"+url.PathEscape(key), nil)
+	if err != nil {
+		return "", err
+	}
+	req = req.WithContext(ctx)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch secret '%s': %v", key, err)
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch secret '%s': %v", key, err)
+	}
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", fmt.Errorf("secret '%s' not found in the secret provider", key)
+	default:
+		return "", fmt.Errorf("failed to fetch secret '%s': '%s'", key, res.Status)
+	}
+
+	resp := &secretProviderResponse{}
+	if err := json.Unmarshal(body, resp); err == nil && resp.Value != nil {
+		return *resp.Value, nil
+	}
+	return strings.TrimRight(string(body), "\r\n"), nil
+}
+
+// startCredentialRefresh periodically resolves the credential references of the clusters again
+func (s *service) startCredentialRefresh(ctx context.Context) {
+	ctx, log := GetLogger(ctx)
+	go func() {
+		for {
+			interval := s.getCredentialRefresh()
+			wait := interval
+			if wait == 0 {
+				// the refresh is disabled, check again later if it is enabled by a reload of the secret
+				wait = defaultCredentialRefreshInterval
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+			if interval == 0 {
+				continue
+			}
+			if err := s.refreshCredentials(ctx); err != nil {
+				log.Errorf("failed to refresh the credentials of the clusters: '%v'", err)
+			}
+		}
+	}()
+}
+
+func (s *service) getCredentialRefresh() time.Duration {
+	s.credentialsLock.RLock()
+	defer s.credentialsLock.RUnlock()
+	return s.credentialRefreshInterval
+}
+
+// refreshCredentials resolves the credential references of the clusters again, and updates the clients of the
+// clusters whose credentials have changed
+func (s *service) refreshCredentials(ctx context.Context) error {
+	ctx, log := GetLogger(ctx)
+	syncMutex.Lock()
+	defer syncMutex.Unlock()
+
+	s.credentialsLock.RLock()
+	resolver := s.credentialResolver
+	s.credentialsLock.RUnlock()
+	if resolver == nil {
+		return nil
+	}
+
+	var errs []string
+	for _, isiConfig := range s.getIsilonClusters() {
+		if isiConfig.UserRef == "" && isiConfig.PasswordRef == "" {
+			continue
+		}
+
+		s.isiSvcLock.Lock()
+		newConfig := *isiConfig
+		s.isiSvcLock.Unlock()
+		if err := resolver.resolveClusterCredentials(ctx, &newConfig); err != nil {
+			errs = append(errs, fmt.Sprintf("cluster '%s': %v", isiConfig.ClusterName, err))
+			continue
+		}
+		if newConfig.User == isiConfig.User && newConfig.Password == isiConfig.Password {
+			continue
+		}
+
+		log.Infof("credentials of cluster '%s' changed, updating its client", isiConfig.ClusterName)
+		var err error
+		if newConfig.isiSvc, err = s.GetIsiService(ctx, &newConfig, utils.GetLogger().GetLevel()); err != nil {
+			log.Warnf("failed to create the client of cluster '%s' with its new credentials: '%v'", isiConfig.ClusterName, err)
+		}
+		s.isiClusters.Store(newConfig.ClusterName, &newConfig)
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package service
+
+/*
+ Copyright (c) 2019 Dell Inc, or its subsidiaries.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+import (
+	"context"
+	"strings"
+
+	"github.com/dell/csi-isilon/common/constants"
+	"github.com/dell/csi-isilon/common/k8sutils"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Reasons of the Kubernetes events recorded by the controller
+const (
+	EventReasonVolumeCreated         = "VolumeCreated"
+	EventReasonQuotaCreationFailed   = "QuotaCreationFailed"
+	EventReasonExportCreationFailed  = "ExportCreationFailed"
+	EventReasonExportHasOtherClients = "ExportHasOtherClients"
+	EventReasonVolumeDeletionBlocked = "VolumeDeletionBlocked"
+)
+
+// initEventRecorder creates the recorder used to surface driver-side failures as Kubernetes events.
+// Events are only recorded by the controller, and only if a kubernetes client can be created.
+func (s *service) initEventRecorder(ctx context.Context) {
+	ctx, log := GetLogger(ctx)
+	if !strings.EqualFold(s.mode, constants.ModeController) {
+		return
+	}
+
+	clientset, err := k8sutils.CreateKubeClientSet(s.opts.KubeConfigPath)
+	if err != nil {
+		log.Warnf("failed to create kubernetes client, events will not be recorded: '%v'", err)
+		return
+	}
+
+	s.k8sclient = clientset
+	s.eventRecorder = k8sutils.CreateEventRecorder(clientset, constants.PluginName)
+	log.Debug("kubernetes event recorder initialized")
+}
+
+// recordClaimEvent records an event on the PVC identified by the 'csi.storage.k8s.io/pvc/name' and
+// 'csi.storage.k8s.io/pvc/namespace' parameters of a CreateVolume request
+func (s *service) recordClaimEvent(ctx context.Context, params map[string]string, eventType, reason, messageFmt string, args ...interface{}) {
+	if s.eventRecorder == nil {
+		return
+	}
+	ctx, log := GetLogger(ctx)
+
+	claimName := params[csiPersistentVolumeClaimName]
+	claimNamespace := params[csiPersistentVolumeClaimNamespace]
+	if claimName == "" || claimNamespace == "" {
+		log.Debugf("no PVC name or namespace available in the request parameters, skip recording event '%s'", reason)
+		return
+	}
+
+	ref := &v1.ObjectReference{
+		Kind:       "PersistentVolumeClaim",
+		APIVersion: "v1",
+		Name:       claimName,
+		Namespace:  claimNamespace,
+	}
+	// the UID is required by 'kubectl describe' to match the event with the PVC
+	if pvc, err := s.k8sclient.CoreV1().PersistentVolumeClaims(claimNamespace).Get(ctx, claimName, metav1.GetOptions{}); err == nil {
+		ref.UID = pvc.UID
+		ref.ResourceVersion = pvc.ResourceVersion
+	} else {
+		log.Debugf("failed to get PVC '%s/%s': '%v'", claimNamespace, claimName, err)
+	}
+
+	s.eventRecorder.Eventf(ref, eventType, reason, messageFmt, args...)
+}
+
+// recordVolumeEvent records an event on the PV named volName and on the PVC bound to it, if any
+func (s *service) recordVolumeEvent(ctx context.Context, volName, eventType, reason, messageFmt string, args ...interface{}) {
+	if s.eventRecorder == nil {
+		return
+	}
+	ctx, log := GetLogger(ctx)
+
+	pv, err := s.k8sclient.CoreV1().PersistentVolumes().Get(ctx, volName, metav1.GetOptions{})
+	if err != nil {
+		log.Debugf("failed to get PV '%s', skip recording event '%s': '%v'", volName, reason, err)
+		return
+	}
+
+	s.eventRecorder.Eventf(pv, eventType, reason, messageFmt, args...)
+	if pv.Spec.ClaimRef != nil {
+		s.eventRecorder.Eventf(pv.Spec.ClaimRef, eventType, reason, messageFmt, args...)
+	}
+}
@@ -0,0 +1,85 @@
+Feature: Isilon CSI interface
+    As a consumer of the CSI interface
+    I want to test the administrative commands run against the clusters
+    So that they are known to work
+
+    Scenario Outline: Decode IDs
+      Given a Isilon service
+      When I decode ID <id>
+      Then the decoded ID is a <type> of cluster <cluster>
+
+      Examples:
+      | id                                         | type       | cluster    |
+      | "volume1=_=_=557=_=_=System=_=_=cluster1"  | "volume"   | "cluster1" |
+      | "volume1=_=_=557=_=_=System"               | "volume"   | ""         |
+      | "2=_=_=cluster1"                           | "snapshot" | "cluster1" |
+      | "node1=#=#=node1.example.com=#=#=10.0.0.1" | "node"     | ""         |
+
+    Scenario Outline: Decode versioned volume IDs
+      Given a Isilon service
+      When I decode ID <id>
+      Then the decoded ID is a "volume" of cluster "cluster1"
+      And the decoded volume ID has version <version>, path <path> and read-only flag <readonly>
+
+      Examples:
+      | id                                                                                   | version | path                                           | readonly |
+      | "v2:cluster1:System:557:nfs:::/ifs/data/csi-isilon/volume1"                          | 2       | "/ifs/data/csi-isilon/volume1"                 | "false"  |
+      | "v2:cluster1:System:558:nfs:ro:volume2:/ifs/.snapshot/snap1/data/csi-isilon/volume1" | 2       | "/ifs/.snapshot/snap1/data/csi-isilon/volume1" | "true"   |
+      | "volume1=_=_=557=_=_=System=_=_=cluster1"                                            | 1       | ""                                             | "false"  |
+
+    Scenario: Decode an invalid ID
+      Given a Isilon service
+      When I decode ID "volume1"
+      Then the error contains "'volume1' is neither a volume, a snapshot nor a node ID"
+
+    Scenario: Show a volume
+      Given a Isilon service
+      When I call Probe
+      And I call ShowVolume "volume1=_=_=557=_=_=System=_=_=cluster1" with the cluster admin
+      Then the volume has export 557 with 0 clients and 1 snapshots
+
+    Scenario: Show a volume whose export cannot be found
+      Given a Isilon service
+      When I call Probe
+      And I induce error "GetExportByIDNotFoundError"
+      And I call ShowVolume "volume1=_=_=557=_=_=System=_=_=cluster1" with the cluster admin
+      Then the error contains "failed to get export '557' of volume 'volume1'"
+
+    Scenario: List the artifacts of the clusters
+      Given a Isilon service
+      When I call Probe
+      And I induce error "IsiPathQuotaExists"
+      And I induce error "CSISnapshotsExist"
+      And I call ListArtifacts of cluster "" with the cluster admin
+      Then cluster "cluster1" has 1 exports, 1 quotas and 1 snapshots under its isiPath
+
+    Scenario: List the artifacts of the clusters without the ones not created by the driver
+      Given a Isilon service
+      When I call Probe
+      And I induce error "ScheduledSnapshotsExist"
+      And I call ListArtifacts of cluster "" with the cluster admin
+      Then cluster "cluster1" has 1 exports, 0 quotas and 2 snapshots under its isiPath
+
+    Scenario: Force unpublish a volume from a node
+      Given a Isilon service
+      When I call Probe
+      And I call ForceUnpublish "volume1=_=_=557=_=_=System=_=_=cluster1" from node "node1=#=#=node1.example.com=#=#=10.0.0.1" with the cluster admin
+      Then the error contains "none"
+
+    Scenario: Force unpublish a volume from an invalid node
+      Given a Isilon service
+      When I call Probe
+      And I call ForceUnpublish "volume1=_=_=557=_=_=System=_=_=cluster1" from node "node1" with the cluster admin
+      Then the error contains "node ID 'node1' cannot match the expected"
+
+    Scenario Outline: Check the connectivity of the clusters
+      Given a Isilon service
+      When I call Probe
+      And I induce error <induced>
+      And I call CheckConnectivity with the cluster admin
+      Then the connectivity of cluster "cluster1" is <connectivity>
+
+      Examples:
+      | induced                          | connectivity    |
+      | "none"                           | "connected"     |
+      | "ControllerHasNoConnectionError" | "not connected" |
@@ -0,0 +1,81 @@
+Feature: Isilon CSI interface
+    As a consumer of the CSI interface
+    I want to test the references to the credentials of the clusters
+    So that they are known to work
+
+    Scenario: Resolve the credentials of a cluster from an environment variable and a file
+      Given a Isilon service
+      And environment variable "TEST_ISILON_USERNAME" is set to "envuser"
+      And file "/tmp/csi-isilon-test-password" contains "filepassword"
+      When I call getNewIsilonConfigs with credentials '"usernameRef":"env:TEST_ISILON_USERNAME","passwordRef":"file:///tmp/csi-isilon-test-password"'
+      Then the error contains "none"
+      And the credentials of cluster "tlscluster" are "envuser" and "filepassword"
+
+    Scenario: Use the username and password as they are even when they look like references
+      Given a Isilon service
+      When I call getNewIsilonConfigs with credentials '"username":"env:USER","password":"file://secret"'
+      Then the error contains "none"
+      And the credentials of cluster "tlscluster" are "env:USER" and "file://secret"
+
+    Scenario Outline: Resolve the credentials of a cluster from the secret provider
+      Given a Isilon service
+      And a secret provider with secret <key> set to <value>
+      When I call getNewIsilonConfigs with credentials <settings>
+      Then the error contains "none"
+      And the credentials of cluster "tlscluster" are "blah" and <value>
+
+      Examples:
+      | key                   | value         | settings                                       |
+      | "isilon-password"     | "vaultsecret" | '"passwordRef":"provider:isilon-password"'     |
+      | "isilon-password-raw" | "rawsecret"   | '"passwordRef":"provider:isilon-password-raw"' |
+
+    Scenario: Resolve the token of the secret provider from an environment variable
+      Given a Isilon service
+      And environment variable "TEST_ISILON_PROVIDER_TOKEN" is set to "provider-token"
+      And a secret provider with secret "isilon-password" set to "vaultsecret"
+      And the secret provider token is a reference to "env:TEST_ISILON_PROVIDER_TOKEN"
+      When I call getNewIsilonConfigs with credentials '"passwordRef":"provider:isilon-password"'
+      Then the error contains "none"
+      And the credentials of cluster "tlscluster" are "blah" and "vaultsecret"
+
+    Scenario Outline: Fail to resolve the credentials of a cluster
+      Given a Isilon service
+      And a secret provider with secret "isilon-password" set to "vaultsecret"
+      And the secret provider token is <token>
+      When I call getNewIsilonConfigs with credentials <settings>
+      Then the error contains <errormsg>
+
+      Examples:
+      | token            | settings                                                        | errormsg                                                                                                                         |
+      | "provider-token" | '"usernameRef":"env:TEST_ISILON_UNSET_VARIABLE"'                | "invalid credentials at index [0]: failed to resolve the username: environment variable 'TEST_ISILON_UNSET_VARIABLE' is not set" |
+      | "provider-token" | '"passwordRef":"file:///no/such/password"'                      | "failed to resolve the password: failed to read '/no/such/password'"                                                             |
+      | "provider-token" | '"passwordRef":"provider:unknown"'                              | "secret 'unknown' not found in the secret provider"                                                                              |
+      | "provider-token" | '"passwordRef":"isilon-password"'                               | "failed to resolve the password: invalid reference 'isilon-password'"                                                            |
+      | "provider-token" | '"password":"blah","passwordRef":"provider:isilon-password"'    | "specify either of password or passwordRef attribute at index [0]"                                                               |
+      | "provider-token" | '"username":"blah","usernameRef":"env:TEST_ISILON_USERNAME"'    | "specify either of username or usernameRef attribute at index [0]"                                                               |
+      | "wrong-token"    | '"passwordRef":"provider:isilon-password"'                      | "failed to fetch secret 'isilon-password': '401 Unauthorized'"                                                                   |
+
+    Scenario: Fail to resolve the token of the secret provider
+      Given a Isilon service
+      And a secret provider with secret "isilon-password" set to "vaultsecret"
+      And the secret provider token is a reference to "env:TEST_ISILON_UNSET_VARIABLE"
+      When I call getNewIsilonConfigs with credentials '"passwordRef":"provider:isilon-password"'
+      Then the error contains "failed to resolve the token of the secret provider"
+
+    Scenario: Fail to resolve a secret without secret provider
+      Given a Isilon service
+      When I call getNewIsilonConfigs with credentials '"passwordRef":"provider:isilon-password"'
+      Then the error contains "secret 'isilon-password' cannot be fetched, no secretProvider is configured"
+
+    Scenario: Refresh the credentials of a cluster from the secret provider
+      Given a Isilon service
+      And a secret provider with secret "isilon-password" set to "blah"
+      When the password of the cluster is a reference to "provider:isilon-password"
+      And I call CreateVolume "volume1"
+      And a secret provider with secret "isilon-password" set to "rotated"
+      And the cluster accepts the password "rotated"
+      And I refresh the credentials
+      And I call CreateVolume "volume1"
+      Then the error contains "none"
+      And the credentials of cluster "cluster1" are "blah" and "rotated"
+      And the requests are authenticated with a session created with password "rotated"
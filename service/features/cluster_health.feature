@@ -0,0 +1,109 @@
+Feature: Isilon CSI interface
+    As a consumer of the CSI interface
+    I want to test the health state and circuit breaker of the clusters
+    So that they are known to work
+
+    Scenario: Track the health of a reachable cluster
+      Given a Isilon service
+      When I call Probe
+      Then a valid ProbeResponse is returned
+      And the health of cluster "cluster1" is "healthy" with circuit "closed"
+      And the health of the clusters returned by Probe is "cluster1=healthy,circuit=closed"
+
+    Scenario: Open the circuit breaker after repeated failed probes and fail the requests fast
+      Given a Isilon service
+      And the circuit breaker opens after 2 failed probes and is reset after "1h"
+      When I render Isilon service unreachable
+      And I run the health probes of the clusters
+      Then the health of cluster "cluster1" is "degraded" with circuit "closed"
+      When I run the health probes of the clusters
+      Then the health of cluster "cluster1" is "unavailable" with circuit "open"
+      When I call CreateVolume "volume1"
+      Then the error contains "code = Unavailable desc = cluster 'cluster1' is unavailable after '2' consecutive failures"
+
+    Scenario: Open the circuit breaker after a request which could not reach the cluster
+      Given a Isilon service
+      And the circuit breaker opens after 1 failed probes and is reset after "1h"
+      When I call Probe
+      And I render Isilon service unreachable
+      And I call CreateVolume "volume1"
+      Then the health of cluster "cluster1" is "unavailable" with circuit "open"
+      When I call CreateVolume "volume1"
+      Then the error contains "code = Unavailable desc = cluster 'cluster1' is unavailable after"
+
+    Scenario: Keep the circuit breaker closed when it is disabled
+      Given a Isilon service
+      And the circuit breaker opens after 0 failed probes and is reset after "1h"
+      When I render Isilon service unreachable
+      And I run the health probes of the clusters
+      And I run the health probes of the clusters
+      Then the health of cluster "cluster1" is "degraded" with circuit "closed"
+
+    Scenario: Close the circuit breaker when the cluster is reachable again
+      Given a Isilon service
+      And the circuit breaker opens after 1 failed probes and is reset after "0s"
+      When I render Isilon service unreachable
+      And I run the health probes of the clusters
+      Then the health of cluster "cluster1" is "unavailable" with circuit "open"
+      When I render Isilon service reachable
+      And I call CreateVolume "volume1"
+      Then the error contains "none"
+      And the health of cluster "cluster1" is "healthy" with circuit "closed"
+
+    Scenario: Keep the circuit breaker open when the cluster is still unreachable
+      Given a Isilon service
+      And the circuit breaker opens after 1 failed probes and is reset after "0s"
+      When I render Isilon service unreachable
+      And I run the health probes of the clusters
+      And I call CreateVolume "volume1"
+      Then the error contains "controller probe failed"
+      And the health of cluster "cluster1" is "unavailable" with circuit "open"
+
+    Scenario: Create the client of a cluster when its circuit breaker is half-opened
+      Given I induce error "noIsiService"
+      And a Isilon service with params "blah" "controller"
+      And the circuit breaker opens after 1 failed probes and is reset after "0s"
+      When I render Isilon service unreachable
+      And I run the health probes of the clusters
+      Then the health of cluster "cluster1" is "unavailable" with circuit "open"
+      When I render Isilon service reachable
+      And I call autoProbe
+      Then the error contains "none"
+      And the health of cluster "cluster1" is "healthy" with circuit "closed"
+
+    Scenario: Close the circuit breaker with the background probes
+      Given a Isilon service
+      And the circuit breaker opens after 1 failed probes and is reset after "1h"
+      When I render Isilon service unreachable
+      And I run the health probes of the clusters
+      And I render Isilon service reachable
+      And I run the health probes of the clusters
+      Then the health of cluster "cluster1" is "healthy" with circuit "closed"
+
+    Scenario: Report the health of the clusters in the error of Probe
+      Given a Isilon service
+      And the circuit breaker opens after 1 failed probes and is reset after "1h"
+      When I render Isilon service unreachable
+      And I run the health probes of the clusters
+      And I call Probe
+      Then an invalid ProbeResponse is returned
+      And the error contains "probe of all isilon clusters failed: cluster 'cluster1' (unavailable)"
+      And the error contains "health of the isilon clusters: 'cluster1': unavailable (circuit open)"
+      And the health of the clusters returned by Probe is "cluster1=unavailable,circuit=open"
+
+    Scenario: Get the health of the clusters through the admin service
+      Given a Isilon service
+      When I call Probe
+      And I call GetClusterHealth "" through the admin service
+      Then the health of clusters "cluster1" is returned
+      When I call GetClusterHealth "cluster2" through the admin service
+      Then the error contains "cluster 'cluster2' not found"
+
+    Scenario: Expose the health of the clusters as metrics
+      Given a Isilon service
+      And the circuit breaker opens after 1 failed probes and is reset after "1h"
+      When I render Isilon service unreachable
+      And I run the health probes of the clusters
+      Then the health metrics contain 'csi_isilon_cluster_health_state{cluster="cluster1",state="unavailable"} 1'
+      And the health metrics contain 'csi_isilon_cluster_circuit_breaker_state{cluster="cluster1",state="open"} 1'
+      And the health metrics contain 'csi_isilon_cluster_probe_failures_total{cluster="cluster1"} 1'
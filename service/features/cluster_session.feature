@@ -0,0 +1,42 @@
+Feature: Isilon CSI interface
+    As a consumer of the CSI interface
+    I want to test the session authentication to the clusters
+    So that they are known to work
+
+    Scenario: Authenticate the requests with a session
+      Given a Isilon service
+      When I call CreateVolume "volume1"
+      Then the error contains "none"
+      And 1 session is created
+      And the requests are authenticated with a session created with password "blah"
+
+    Scenario: Create a new session when the session has expired
+      Given a Isilon service
+      When I call CreateVolume "volume1"
+      And I induce error "SessionExpired"
+      And I call CreateVolume "volume1"
+      Then the error contains "none"
+      And 2 sessions are created
+
+    Scenario: Use the rotated password in the requests of the clients created before the rotation
+      Given a Isilon service
+      When I call CreateVolume "volume1"
+      And the password of the cluster is rotated to "rotated"
+      And I call CreateVolume "volume1"
+      Then the error contains "none"
+      And 2 sessions are created
+      And the requests are authenticated with a session created with password "rotated"
+
+    Scenario: Fail the requests when the password is rejected
+      Given a Isilon service
+      When I call CreateVolume "volume1"
+      And the cluster rejects the password
+      And I call CreateVolume "volume1"
+      Then the error contains "failed to create a OneFS session for user 'blah'"
+
+    Scenario: Authenticate the requests with basic authentication
+      Given a Isilon service
+      When the cluster is configured with basic authentication
+      And I call CreateVolume "volume1"
+      Then the error contains "none"
+      And the requests are authenticated with basic authentication
@@ -0,0 +1,43 @@
+Feature: Isilon CSI interface
+    As a consumer of the CSI interface
+    I want to test the TLS trust settings of the clusters
+    So that they are known to work
+
+    Scenario Outline: Connect to a cluster with a CA bundle or a certificate fingerprint
+      Given a Isilon service
+      When I call GetIsiService on a TLS server trusted with <trust>
+      Then the error contains <errormsg>
+
+      Examples:
+      | trust                      | errormsg                                    |
+      | "ca"                       | "none"                                      |
+      | "ca path"                  | "none"                                      |
+      | "fingerprint"              | "none"                                      |
+      | "ca and fingerprint"       | "none"                                      |
+      | "system ca"                | "certificate"                               |
+      | "other ca"                 | "certificate signed by unknown authority"   |
+      | "ca and other fingerprint" | "does not match the configured fingerprint" |
+
+    Scenario Outline: Parse the TLS settings of a cluster config
+      Given a Isilon service
+      When I call getNewIsilonConfigs with TLS settings <settings>
+      Then the error contains <errormsg>
+
+      Examples:
+      | settings                                                         | errormsg                                                                                                     |
+      | '"certificateFingerprint":"ab"'                                  | "invalid TLS settings at index [0]: invalid certificate fingerprint 'ab', a SHA-256 fingerprint is expected" |
+      | '"certificateAuthority":"not a PEM"'                             | "no PEM certificate found in the CA bundle"                                                                  |
+      | '"certificateAuthorityPath":"/no/such/ca.pem"'                   | "failed to read the CA bundle '/no/such/ca.pem'"                                                             |
+      | '"certificateAuthority":"x","certificateAuthorityPath":"y"'      | "specify either of certificateAuthority or certificateAuthorityPath attribute at index [0]"                  |
+      | '"skipCertificateValidation":true,"certificateFingerprint":"ab"' | "cannot be used with skipCertificateValidation at index [0]"                                                 |
+      | ''                                                               | "none"                                                                                                       |
+
+    Scenario: A cluster with a certificate fingerprint is not connected to insecurely
+      Given a Isilon service
+      When I call getNewIsilonConfigs with TLS settings '"certificateFingerprint":"AB:CD:EF:01:23:45:67:89:AB:CD:EF:01:23:45:67:89:AB:CD:EF:01:23:45:67:89:AB:CD:EF:01:23:45:67:89"'
+      Then the certificate of cluster "tlscluster" is verified
+
+    Scenario: Share the transport of a cluster between its clients
+      Given a Isilon service
+      When I call Probe
+      Then the clients of the cluster share its transport until its TLS settings change
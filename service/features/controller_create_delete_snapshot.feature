@@ -76,3 +76,50 @@ Feature: Isilon CSI interface
     | ""           | "snapshot id to be deleted is required"  |
     | "404"        | "none"                                   |
     | "str"        | "cannot convert snapshot to integer"     |
+
+  Scenario: Create snapshot with an expiration and an alias
+    Given a Isilon service
+    When I call Probe
+    And I call CreateSnapshot "volume2=_=_=19=_=_=System" "create_snapshot_name" with expiration "7D" and alias "volume2-latest"
+    Then a valid CreateSnapshotResponse is returned
+    And the snapshot is created with an expiration in 604800 seconds and alias "volume2-latest"
+
+  Scenario: Create snapshot with an alias which already points to another snapshot
+    Given a Isilon service
+    When I call Probe
+    And I induce error "SnapshotAliasExists"
+    And I call CreateSnapshot "volume2=_=_=19=_=_=System" "create_snapshot_name" with expiration "7D" and alias "volume2-latest"
+    Then a valid CreateSnapshotResponse is returned
+    And snapshot alias "retargeted:volume2-latest" points to snapshot "create_snapshot_name"
+
+  Scenario: Create an existing snapshot with an alias
+    Given a Isilon service
+    When I call Probe
+    And I call CreateSnapshot "volume2=_=_=19=_=_=System" "existent_comp_snapshot_name" with expiration "" and alias "volume2-latest"
+    Then a valid CreateSnapshotResponse is returned
+    And snapshot alias "volume2-latest" points to snapshot "existent_comp_snapshot_name"
+
+  Scenario Outline: Create snapshot with an invalid expiration
+    Given a Isilon service
+    When I call Probe
+    And I call CreateSnapshot "volume2=_=_=19=_=_=System" "create_snapshot_name" with expiration <expiration> and alias ""
+    Then the error contains <errormsg>
+
+    Examples:
+    | expiration | errormsg                                                                       |
+    | "7 days"   | "invalid value '7 days' for 'SnapshotExpiration'"                              |
+    | "0D"       | "invalid value '0D' for 'SnapshotExpiration': a positive duration is expected" |
+    | "1M"       | "invalid value '1M' for 'SnapshotExpiration'"                                  |
+
+  Scenario Outline: Delete a snapshot which is expired or not
+    Given a Isilon service
+    When I call Probe
+    And I induce error <induced>
+    And I call DeleteSnapshot "34"
+    Then the error contains <errormsg>
+
+    Examples:
+    | induced            | errormsg                                     |
+    | "none"             | "none"                                       |
+    | "SnapshotExpired"  | "none"                                       |
+    | "GetSnapshotError" | "cannot check the existence of the snapshot" |
@@ -17,6 +17,24 @@ Feature: Isilon CSI interface
       And I call CreateVolume with persistent metadata "volume1"
       Then a valid CreateVolumeResponse is returned
 
+    Scenario: Create volume good scenario with event recording
+      Given a Isilon service
+      And I enable event recording
+      When I call Probe
+      And I call CreateVolume with persistent metadata "volume1"
+      Then a valid CreateVolumeResponse is returned
+      And an event with reason "VolumeCreated" is recorded
+
+    Scenario: Create volume with quota error and event recording
+      Given a Isilon service
+      And I enable quota
+      And I enable event recording
+      When I call Probe
+      And I induce error "CreateQuotaError"
+      And I call CreateVolume with persistent metadata "volume1"
+      Then the error contains "error creating quota"
+      And an event with reason "QuotaCreationFailed" is recorded
+
     Scenario: Create volume good scenario with quota enabled
       Given a Isilon service
       And I enable quota
@@ -103,6 +121,161 @@ Feature: Isilon CSI interface
       | "volume1=_=_=10=_=_=System"   | "volume1"       | "failed to get volume"       |
       | "volume2=_=_=20=_=_=System"   | "volume2"       | "none"                       |
 
+    Scenario Outline: Create volume with ownership, mode and ACL parameters
+      Given a Isilon service
+      When I call Probe
+      And I call CreateVolume "volume1" with parameter <param> set to <value>
+      Then the error contains <errormsg>
+
+      Examples:
+      | param         | value                                                                                                  | errormsg                                  |
+      | "VolumeMode"  | '0750'                                                                                                 | "none"                                    |
+      | "VolumeMode"  | '750'                                                                                                  | "none"                                    |
+      | "VolumeMode"  | '0999'                                                                                                 | "invalid value '0999' for 'VolumeMode'"   |
+      | "VolumeMode"  | '17777'                                                                                                | "invalid value '17777' for 'VolumeMode'"  |
+      | "VolumeOwner" | '1000'                                                                                                 | "none"                                    |
+      | "VolumeOwner" | 'user1'                                                                                                | "none"                                    |
+      | "VolumeOwner" | 'unknown'                                                                                              | "failed to look up user 'unknown'"        |
+      | "VolumeGroup" | 'group1'                                                                                               | "none"                                    |
+      | "VolumeACL"   | '[{"trustee":{"id":"UID:1000"},"accesstype":"allow","accessrights":["dir_gen_all"],"inherit_flags":[]}]' | "none"                                    |
+      | "VolumeACL"   | 'dir_gen_all'                                                                                          | "invalid value for 'VolumeACL'"           |
+
+    Scenario: Create volume with mode parameter and induced error
+      Given a Isilon service
+      When I call Probe
+      And I induce error "SetACLError"
+      And I call CreateVolume "volume1" with parameter "VolumeOwner" set to '1000'
+      Then the error contains "failed to set the permissions of volume 'volume1'"
+
+    Scenario Outline: Create volume with SmartLock parameters
+      Given a Isilon service
+      When I call Probe
+      And I induce error <induced>
+      And I call CreateVolume "volume1" with SmartLock parameters <params>
+      Then the error contains <errormsg>
+
+     Examples:
+     | induced                 | params                                                                                | errormsg                                               |
+     | "none"                  | "SmartLockMinRetention=30D,SmartLockMaxRetention=7Y,SmartLockDefaultRetention=1Y"     | "none"                                                 |
+     | "none"                  | "SmartLockType=compliance,SmartLockMaxRetention=forever,SmartLockAutocommitOffset=1H" | "none"                                                 |
+     | "none"                  | "SmartLockDefaultRetention=use_min,SmartLockMinRetention=1Y6M"                        | "none"                                                 |
+     | "none"                  | "SmartLockEnabled=false,SmartLockType=unknown"                                        | "none"                                                 |
+     | "WormDomainExists"      | "SmartLockMinRetention=30D"                                                           | "none"                                                 |
+     | "CreateWormDomainError" | "SmartLockMinRetention=30D"                                                           | "failed to apply SmartLock to volume 'volume1'"        |
+     | "none"                  | "SmartLockEnabled=maybe"                                                              | "invalid boolean value 'maybe' for 'SmartLockEnabled'" |
+     | "none"                  | "SmartLockType=unknown"                                                               | "invalid value 'unknown' for 'SmartLockType'"          |
+     | "none"                  | "SmartLockMinRetention=30 days"                                                       | "invalid value '30 days' for 'SmartLockMinRetention'"  |
+     | "none"                  | "SmartLockAutocommitOffset=1h"                                                        | "invalid value '1h' for 'SmartLockAutocommitOffset'"   |
+     | "none"                  | "SmartLockMinRetention=2Y,SmartLockMaxRetention=1Y"                                   | "'SmartLockMinRetention' must not be longer than"      |
+     | "none"                  | "SmartLockMinRetention=1Y,SmartLockDefaultRetention=6M"                               | "'SmartLockDefaultRetention' must be between"          |
+     | "none"                  | "SmartLockMinRetention=use_max"                                                       | "invalid value 'use_max' for 'SmartLockMinRetention'"  |
+
+    Scenario Outline: Create volume with SmartPools parameters
+      Given a Isilon service
+      When I call Probe
+      And I call CreateVolume "volume1" with parameter <param> set to <value>
+      Then the error contains "none"
+      And directory "/ifs/data/csi-isilon/volume1" has attribute <attribute> set to <attributeValue>
+
+     Examples:
+     | param             | value       | attribute              | attributeValue |
+     | "ProtectionLevel" | '+2d:1n'    | "requested_protection" | '+2d:1n'       |
+     | "StoragePool"     | 'nvme_pool' | "storage_pool"         | 'nvme_pool'    |
+     | "SSDStrategy"     | 'Avoid'     | "ssd_strategy"         | 'avoid'        |
+
+    Scenario Outline: Create volume with invalid SmartPools parameters or induced errors
+      Given a Isilon service
+      When I call Probe
+      And I induce error <induced>
+      And I call CreateVolume "volume1" with parameter <param> set to <value>
+      Then the error contains <errormsg>
+      And no directory attribute is set
+
+     Examples:
+     | induced                       | param             | value       | errormsg                                                         |
+     | "none"                        | "ProtectionLevel" | '+5n'       | "invalid value '+5n' for 'ProtectionLevel'"                      |
+     | "none"                        | "SSDStrategy"     | 'fast'      | "invalid value 'fast' for 'SSDStrategy'"                         |
+     | "SetDirectoryAttributesError" | "StoragePool"     | 'nvme_pool' | "failed to apply the SmartPools settings of volume 'volume1'"    |
+
+    Scenario: Create volume from volume inherits the SmartPools settings of the source volume
+      Given a Isilon service
+      When I call Probe
+      And I call CreateVolumeFromVolume "volume2=_=_=19=_=_=System" "volume1"
+      Then the error contains "none"
+      And directory "/ifs/data/csi-isilon/volume1" has attribute "storage_pool" set to 'nvme_pool'
+      And directory "/ifs/data/csi-isilon/volume1" has attribute "ssd_strategy" set to 'data'
+
+    Scenario: Create volume from volume whose export cannot be found
+      Given a Isilon service
+      When I call Probe
+      And I induce error "GetExportByIDNotFoundError"
+      And I call CreateVolumeFromVolume "volume2=_=_=19=_=_=System" "volume1"
+      Then the error contains "failed to get the export of source volume 'volume2'"
+
+    Scenario Outline: Create volume with capacity placement
+      Given a Isilon service
+      And I enable capacity placement
+      And I add cluster "cluster2" with IP "127.0.0.2" and placement weight <weight>
+      And I induce error "VolumeDirectoryNotFound"
+      When I call CreateVolume "volume1" with requisite topology <topology>
+      Then the error contains "none"
+      And the volume is created on cluster <cluster>
+
+     Examples:
+     | weight | topology                                                  | cluster    |
+     | 2      | ""                                                        | "cluster2" |
+     | 1      | ""                                                        | "cluster1" |
+     | 0      | ""                                                        | "cluster1" |
+     | 2      | "csi-isilon.dellemc.com/127.0.0.1=csi-isilon.dellemc.com" | "cluster1" |
+
+    Scenario Outline: Create volume with capacity placement and preferred topologies
+      Given a Isilon service
+      And I enable capacity placement
+      And I add cluster "cluster2" with IP "127.0.0.2" and placement weight 2
+      And I induce error "VolumeDirectoryNotFound"
+      When I call CreateVolume "volume1" with preferred topologies <topologies>
+      Then the error contains "none"
+      And the volume is created on cluster <cluster>
+      And the volume is accessible from topology <accessible>
+
+     Examples:
+     | topologies                                                                                                          | cluster    | accessible                                                |
+     | "csi-isilon.dellemc.com/127.0.0.1=csi-isilon.dellemc.com;csi-isilon.dellemc.com/127.0.0.2=csi-isilon.dellemc.com" | "cluster1" | "csi-isilon.dellemc.com/127.0.0.1=csi-isilon.dellemc.com" |
+     | "csi-isilon.dellemc.com/127.0.0.2=csi-isilon.dellemc.com;csi-isilon.dellemc.com/127.0.0.1=csi-isilon.dellemc.com" | "cluster2" | "csi-isilon.dellemc.com/127.0.0.2=csi-isilon.dellemc.com" |
+     | "csi-isilon.dellemc.com/10.0.0.1=csi-isilon.dellemc.com"                                                          | "cluster2" | "csi-isilon.dellemc.com/127.0.0.2=csi-isilon.dellemc.com" |
+
+    Scenario: Create volume with capacity placement reuses the cluster the volume already exists on
+      Given a Isilon service
+      And I enable capacity placement
+      And I add cluster "cluster2" with IP "127.0.0.2" and placement weight 2
+      When I call CreateVolume "volume1" with requisite topology ""
+      Then the error contains "none"
+      And the volume is created on cluster "cluster1"
+
+    Scenario Outline: Create volume on a cluster with requisite topologies
+      Given a Isilon service
+      When I call CreateVolume "volume1" with requisite topology <topology>
+      Then the error contains <errormsg>
+
+     Examples:
+     | topology                                                  | errormsg                                                                       |
+     | "csi-isilon.dellemc.com/127.0.0.1=csi-isilon.dellemc.com" | "none"                                                                         |
+     | "csi-isilon.dellemc.com/10.0.0.1=csi-isilon.dellemc.com"  | "cluster 'cluster1' is not accessible from the requisite topologies of volume" |
+
+    Scenario Outline: Create volume with capacity placement and no eligible cluster
+      Given a Isilon service
+      And I enable capacity placement
+      And I add cluster "cluster2" with IP "127.0.0.2" and placement weight 1
+      And I induce error "VolumeDirectoryNotFound"
+      And I induce error <induced>
+      When I call CreateVolume "volume1" with requisite topology <topology>
+      Then the error contains <errormsg>
+
+     Examples:
+     | induced      | topology                                                 | errormsg                                                        |
+     | "none"       | "csi-isilon.dellemc.com/10.0.0.1=csi-isilon.dellemc.com" | "no cluster is eligible for the placement of volume 'volume1'" |
+     | "StatsError" | ""                                                       | "no cluster can host volume 'volume1'"                          |
+
 @deleteVolume
 @v1.0.0
     Scenario: Delete volume good scenario with quota enabled
@@ -111,6 +284,48 @@ Feature: Isilon CSI interface
       When I call DeleteVolume "volume1=_=_=43=_=_=System"
       Then a valid DeleteVolumeResponse is returned
 
+    Scenario Outline: Delete volume in a SmartLock domain
+      Given a Isilon service
+      And I enable quota
+      And I induce error "ExportSmartLock"
+      And I induce error <induced>
+      When I call DeleteVolume "volume1=_=_=557=_=_=System"
+      Then the error contains <errormsg>
+
+     Examples:
+     | induced                   | errormsg                                                                                                                       |
+     | "WormDomainExists"        | "none"                                                                                                                         |
+     | "FileUnderRetention"      | "file '/ifs/data/csi-isilon/volume1/dir1/file2' of volume 'volume1' is under SmartLock retention until '2100-01-01T00:00:00Z'" |
+     | "GetWormDomainsError"     | "failed to check the SmartLock retention of volume 'volume1'"                                                                  |
+     | "GetWormDomainsForbidden" | "none"                                                                                                                         |
+
+    Scenario Outline: Delete volume created without SmartLock
+      Given a Isilon service
+      And I enable quota
+      And I induce error <induced>
+      When I call DeleteVolume "volume1=_=_=557=_=_=System"
+      Then the error contains "none"
+
+     Examples:
+     | induced               |
+     | "GetWormDomainsError" |
+     | "FileUnderRetention"  |
+
+    Scenario: Delete volume in a SmartLock domain with too many files to check their retention
+      Given a Isilon service
+      And I enable quota
+      And I induce error "ExportSmartLock"
+      And I induce error "WormDomainExists"
+      And the SmartLock retention check is limited to 2 entries
+      When I call DeleteVolume "volume1=_=_=557=_=_=System"
+      Then the error contains "it must be deleted by the storage administrator"
+
+    Scenario: Create volume from snapshot with SmartLock parameters
+      Given a Isilon service
+      When I call Probe
+      And I call CreateVolumeFromSnapshot "2" "volume1" with parameter "SmartLockEnabled" set to 'true'
+      Then the error contains "SmartLock cannot be used with a volume content source"
+
     Scenario Outline: Delete volume with invalid volume id
       Given a Isilon service
       And I enable quota
@@ -139,4 +354,81 @@ Feature: Isilon CSI interface
      | "VolumeNotExistError"            | "none"                                                              |
      | "DeleteQuotaError"               | "EOF"                                                               |
      | "GetExportInternalError"         | "EOF"                                                               |
-     | "QuotaNotFoundError"             | "Failed to fetch quota domain record: No such file or directory"    |
\ No newline at end of file
+     | "QuotaNotFoundError"             | "Failed to fetch quota domain record: No such file or directory"    |
+   Scenario Outline: Create volume with tenancy mapping
+      Given a Isilon service
+      And I configure tenant "tenant1" for namespace <namespace> with params <accessZone> <isiPathPrefix> <allowedClusters> <maxCapacity>
+      When I call Probe
+      And I call CreateVolume with persistent metadata "volume1"
+      Then the error contains <errormsg>
+
+     Examples:
+     | namespace         | accessZone | isiPathPrefix          | allowedClusters | maxCapacity | errormsg                                            |
+     | "pv-namespace"    | "System"   | "/ifs/data/csi-isilon" | "cluster1"      | "10Gi"      | "none"                                              |
+     | "pv-namespace"    | ""         | "/ifs/data"            | ""              | ""          | "none"                                              |
+     | "other-namespace" | "System"   | "/ifs/other"           | "cluster2"      | "1Gi"       | "none"                                              |
+     | "pv-namespace"    | ""         | ""                     | "cluster2"      | ""          | "cluster 'cluster1' is not allowed for tenant"      |
+     | "pv-namespace"    | ""         | ""                     | ""              | "1Gi"       | "exceeds the max capacity '1Gi' of tenant 'tenant1'" |
+
+   Scenario: Create volume with tenancy mapping and no PVC namespace
+      Given a Isilon service
+      And I configure tenant "tenant1" for namespace "pv-namespace" with params "System" "" "cluster1" ""
+      When I call Probe
+      And I call CreateVolume without persistent metadata "volume1"
+      Then the error contains "the PVC namespace is not available"
+
+   Scenario Outline: Configure tenancy mapping with invalid values
+      Given a Isilon service
+      When I configure tenant <name> for namespace <namespace> with params "System" <isiPathPrefix> <allowedClusters> <maxCapacity>
+      Then the error contains <errormsg>
+
+     Examples:
+     | name      | namespace      | isiPathPrefix | allowedClusters     | maxCapacity | errormsg                                                                             |
+     | ""        | "pv-namespace" | ""            | ""                  | ""          | "invalid value for tenant name"                                                      |
+     | "tenant1" | ""             | ""            | ""                  | ""          | "specify namespaces or namespaceLabels"                                              |
+     | "tenant1" | "pv-namespace" | "ifs/data"    | ""                  | ""          | "is not an absolute path"                                                            |
+     | "tenant1" | "pv-namespace" | ""            | "cluster3"          | ""          | "allowed cluster 'cluster3' of tenant"                                               |
+     | "tenant1" | "pv-namespace" | ""            | ""                  | "lots"      | "invalid value for maxCapacity 'lots'"                                               |
+     | "tenant1" | "pv-namespace" | ""            | ""                  | ""          | "tenant 'tenant1' sets azServiceIP, allowedClusters must be set to a single cluster" |
+     | "tenant1" | "pv-namespace" | ""            | "cluster1,cluster2" | ""          | "tenant 'tenant1' sets azServiceIP, allowedClusters must be set to a single cluster" |
+
+   Scenario: Roll back the volume created by the request when the export cannot be created
+      Given a Isilon service
+      When I call Probe
+      And I induce error "CreateExportError"
+      And I call CreateVolume "volume1"
+      Then the error contains "EOF"
+      And 1 volume directories are deleted
+
+   Scenario Outline: Expand volume with tenancy mapping
+      Given a Isilon service
+      And a PV "pv1" exists for volume "volume1=_=_=557=_=_=System=_=_=cluster1"
+      And I configure tenant "tenant1" for namespace <namespace> with params "" "" "" "1Gi"
+      When I call Probe
+      And I call ControllerExpandVolume "volume1=_=_=557=_=_=System=_=_=cluster1" <size>
+      Then the error contains <errormsg>
+
+     Examples:
+     | namespace         | size         | errormsg                                             |
+     | "default"         | "1073741824" | "none"                                               |
+     | "default"         | "2147483648" | "exceeds the max capacity '1Gi' of tenant 'tenant1'" |
+     | "other-namespace" | "2147483648" | "none"                                               |
+
+   Scenario: Configure tenancy mapping with an access zone and no azServiceIP
+      Given a Isilon service
+      When I configure tenant "tenant1" for namespace "pv-namespace" with access zone "tenant-zone" and no azServiceIP
+      Then the error contains "azServiceIP must be set for tenant 'tenant1' as it sets accessZone 'tenant-zone'"
+
+    Scenario: Create volume with a version 2 volume ID
+      Given a Isilon service
+      And I set the volume ID version to 2
+      When I call Probe
+      And I call CreateVolume "volume1"
+      Then a valid CreateVolumeResponse is returned
+      And the created volume ID is "v2:cluster1:System:557:nfs:::/ifs/data/csi-isilon/volume1"
+
+    Scenario: Delete volume with a version 2 volume ID
+      Given a Isilon service
+      When I call Probe
+      And I call DeleteVolume "v2:cluster1:System:557:nfs:::/ifs/data/csi-isilon/volume1"
+      Then a valid DeleteVolumeResponse is returned
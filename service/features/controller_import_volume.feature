@@ -0,0 +1,46 @@
+Feature: Isilon CSI interface
+    As a consumer of the CSI interface
+    I want to test the import of existing directories as volumes
+    So that they are known to work
+
+    Scenario: Import an exported directory and adopt its quota
+      Given a Isilon service
+      When I call Probe
+      And I induce error "VolumeExists"
+      And I induce error "ExportExists"
+      And I induce error "IsiPathQuotaExists"
+      And I call ImportVolume "/ifs/data/csi-isilon/volume1" with quota "adopt" and size 0 through the admin service
+      Then the imported volume ID is "volume1=_=_=557=_=_=System=_=_=cluster1"
+      And the PV manifest contains "storage: 100Gi"
+      And the PV manifest contains "volumeHandle: volume1=_=_=557=_=_=System=_=_=cluster1"
+      And the PV manifest contains "name: pvc1"
+      And the PV manifest contains "key: csi-isilon.dellemc.com/127.0.0.1"
+
+    Scenario: Import a directory without export and create its quota
+      Given a Isilon service
+      When I call Probe
+      And I induce error "VolumeExists"
+      And I call ImportVolume "/ifs/data/csi-isilon/volume1" with quota "create" and size 1073741824 through the admin service
+      Then the imported volume ID is "volume1=_=_=557=_=_=System=_=_=cluster1"
+      And the PV manifest contains "storage: 1Gi"
+      And the PV manifest contains "persistentVolumeReclaimPolicy: Retain"
+
+    Scenario Outline: Import a directory with invalid requests or induced errors
+      Given a Isilon service
+      When I call Probe
+      And I induce error "VolumeExists"
+      And I induce error <induced>
+      And I call ImportVolume <path> with quota <quota> and size <size> through the admin service
+      Then the error contains <errormsg>
+
+      Examples:
+      | induced               | path                           | quota    | size | errormsg                                                                   |
+      | "none"                | ""                             | "none"   | 1024 | "invalid path '', an absolute path under /ifs is expected"                 |
+      | "none"                | "/data/volume1"                | "none"   | 1024 | "invalid path '/data/volume1', an absolute path under /ifs is expected"    |
+      | "none"                | "/ifs/data/csi-isilon/volume1" | "shared" | 1024 | "invalid quota 'shared'"                                                   |
+      | "none"                | "/ifs/data/csi-isilon/volume1" | "none"   | 0    | "a positive size is required unless a quota is adopted"                    |
+      | "VolumeNotExistError" | "/ifs/data/csi-isilon/volume1" | "none"   | 1024 | "directory '/ifs/data/csi-isilon/volume1' not found on cluster 'cluster1'" |
+      | "none"                | "/ifs/data/csi-isilon/volume1" | "adopt"  | 0    | "no directory quota found on '/ifs/data/csi-isilon/volume1' to adopt"      |
+      | "IsiPathQuotaExists"  | "/ifs/data/csi-isilon/volume1" | "create" | 1024 | "already has quota 'AABpAQEAAAAAAAAAAAAAQA0AAAAAAAAA', adopt it instead"   |
+      | "CreateQuotaError"    | "/ifs/data/csi-isilon/volume1" | "create" | 1024 | "creating quota failed"                                                    |
+      | "CreateExportError"   | "/ifs/data/csi-isilon/volume1" | "none"   | 1024 | "failed to export '/ifs/data/csi-isilon/volume1' in access zone 'System'"  |
@@ -0,0 +1,161 @@
+Feature: Isilon CSI interface
+    As a consumer of the CSI interface
+    I want to test volume migration between clusters
+    So that they are known to work
+
+    Scenario: Migrate volume with SyncIQ
+      Given a Isilon service
+      When I call Probe
+      And I add cluster "cluster2" with IP "10.0.0.2" and placement weight 1
+      And a PV "pv1" exists for volume "volume1=_=_=557=_=_=System=_=_=cluster1"
+      And I induce error "VolumeNotExistError"
+      And I call MigrateVolume "volume1=_=_=557=_=_=System=_=_=cluster1" to cluster "cluster2" with copy method "synciq" and node ""
+      Then the migration of volume "volume1=_=_=557=_=_=System=_=_=cluster1" ends in phase "Completed"
+      And PV "pv1" refers to a volume on cluster "cluster2"
+      And SyncIQ policy copies "/ifs/data/csi-isilon/volume1" to "/ifs/data/csi-isilon/volume1" on "10.0.0.2"
+
+    Scenario: Migrate volume with rsync
+      Given a Isilon service
+      When I call Probe
+      And I add cluster "cluster2" with IP "10.0.0.2" and placement weight 1
+      And a PV "pv1" exists for volume "volume1=_=_=557=_=_=System=_=_=cluster1"
+      And rsync jobs succeed with image "rsync:latest"
+      And I induce error "VolumeNotExistError"
+      And I call MigrateVolume "volume1=_=_=557=_=_=System=_=_=cluster1" to cluster "cluster2" with copy method "rsync" and node "node1=#=#=node1.example.com=#=#=10.0.0.10"
+      Then the migration of volume "volume1=_=_=557=_=_=System=_=_=cluster1" ends in phase "Completed"
+      And PV "pv1" refers to a volume on cluster "cluster2"
+
+    Scenario: Migrate volume in dry run mode
+      Given a Isilon service
+      When I call Probe
+      And I add cluster "cluster2" with IP "10.0.0.2" and placement weight 1
+      And a PV "pv1" exists for volume "volume1=_=_=557=_=_=System=_=_=cluster1"
+      And I induce error "VolumeNotExistError"
+      And I call MigrateVolume "volume1=_=_=557=_=_=System=_=_=cluster1" to cluster "cluster2" in dry run mode
+      Then a migration plan of 4 steps is returned
+
+    Scenario Outline: Migrate volume with a failing copy
+      Given a Isilon service
+      When I call Probe
+      And I add cluster "cluster2" with IP "10.0.0.2" and placement weight 1
+      And a PV "pv1" exists for volume "volume1=_=_=557=_=_=System=_=_=cluster1"
+      And I induce error "VolumeNotExistError"
+      And I induce error <induced>
+      And I call MigrateVolume "volume1=_=_=557=_=_=System=_=_=cluster1" to cluster "cluster2" with copy method "synciq" and node ""
+      Then the migration of volume "volume1=_=_=557=_=_=System=_=_=cluster1" ends in phase "Failed"
+      And PV "pv1" refers to a volume on cluster "cluster1"
+
+      Examples:
+      | induced           |
+      | "SyncPolicyError" |
+      | "SyncJobFailed"   |
+
+    Scenario Outline: Migrate volume with invalid requests
+      Given a Isilon service
+      When I call Probe
+      And I add cluster "cluster2" with IP "10.0.0.2" and placement weight 1
+      And a PV "pv1" exists for volume <pvVolume>
+      And I induce error <induced>
+      And I call MigrateVolume <volume> to cluster <cluster> with copy method <method> and node <node>
+      Then the error contains <errormsg>
+
+      Examples:
+      | pvVolume                                  | induced               | volume                                    | cluster    | method   | node                                        | errormsg                                                                        |
+      | "volume1=_=_=557=_=_=System=_=_=cluster1" | "VolumeNotExistError" | "volume1=_=_=557=_=_=System=_=_=cluster1" | "cluster3" | "synciq" | ""                                          | "failed to get cluster config details for clusterName: 'cluster3'"              |
+      | "volume1=_=_=557=_=_=System=_=_=cluster1" | "VolumeNotExistError" | "volume1=_=_=557=_=_=System=_=_=cluster1" | "cluster1" | "synciq" | ""                                          | "is already on cluster 'cluster1'"                                              |
+      | "volume1=_=_=557=_=_=System=_=_=cluster1" | "VolumeNotExistError" | "volume1=_=_=557=_=_=System=_=_=cluster1" | "cluster2" | "scp"    | ""                                          | "invalid copy method 'scp'"                                                     |
+      | "volume1=_=_=557=_=_=System=_=_=cluster1" | "VolumeNotExistError" | "volume1=_=_=557=_=_=System=_=_=cluster1" | "cluster2" | "rsync"  | ""                                          | "a node ID is required by the 'rsync' copy method"                              |
+      | "volume1=_=_=557=_=_=System=_=_=cluster1" | "VolumeNotExistError" | "volume1=_=_=557=_=_=System=_=_=cluster1" | "cluster2" | "rsync"  | "node1=#=#=node1.example.com=#=#=10.0.0.10" | "the 'rsync' copy method requires an image"                                     |
+      | "volume2=_=_=557=_=_=System=_=_=cluster1" | "VolumeNotExistError" | "volume1=_=_=557=_=_=System=_=_=cluster1" | "cluster2" | "synciq" | ""                                          | "no PV found for volume"                                                        |
+      | "volume1=_=_=557=_=_=System=_=_=cluster1" | "VolumeExists"        | "volume1=_=_=557=_=_=System=_=_=cluster1" | "cluster2" | "synciq" | ""                                          | "directory '/ifs/data/csi-isilon/volume1' already exists on cluster 'cluster2'" |
+      | "volume1=_=_=557=_=_=System=_=_=cluster1" | "none"                | ""                                        | "cluster2" | "synciq" | ""                                          | "a volume ID and a target cluster are required"                                 |
+
+    Scenario: Get the status of a migration through the admin service
+      Given a Isilon service
+      When I call Probe
+      And I add cluster "cluster2" with IP "10.0.0.2" and placement weight 1
+      And a PV "pv1" exists for volume "volume1=_=_=557=_=_=System=_=_=cluster1"
+      And I induce error "VolumeNotExistError"
+      And I call MigrateVolume "volume1=_=_=557=_=_=System=_=_=cluster1" to cluster "cluster2" with copy method "synciq" and node ""
+      And the migration of volume "volume1=_=_=557=_=_=System=_=_=cluster1" ends in phase "Completed"
+      And I call GetMigrationStatus "volume1=_=_=557=_=_=System=_=_=cluster1" through the admin service
+      Then the error contains "none"
+
+    Scenario: Get the status of an unknown migration through the admin service
+      Given a Isilon service
+      When I call GetMigrationStatus "volume9=_=_=557=_=_=System=_=_=cluster1" through the admin service
+      Then the error contains "no migration of volume 'volume9=_=_=557=_=_=System=_=_=cluster1' found"
+
+    Scenario: Migrate volume whose PV cannot be switched restores the original PV
+      Given a Isilon service
+      When I call Probe
+      And I add cluster "cluster2" with IP "10.0.0.2" and placement weight 1
+      And a PV "pv1" exists for volume "volume1=_=_=557=_=_=System=_=_=cluster1"
+      And creating PVs of cluster "cluster2" fails
+      And I induce error "VolumeNotExistError"
+      And I call MigrateVolume "volume1=_=_=557=_=_=System=_=_=cluster1" to cluster "cluster2" with copy method "synciq" and node ""
+      Then the migration of volume "volume1=_=_=557=_=_=System=_=_=cluster1" ends in phase "Failed"
+      And PV "pv1" refers to a volume on cluster "cluster1"
+      And PV "pv1" has no migration state
+
+    Scenario Outline: Migrate volume whose PV cannot be deleted restores the PVC and the reclaim policy
+      Given a Isilon service
+      When I call Probe
+      And I add cluster "cluster2" with IP "10.0.0.2" and placement weight 1
+      And a PV "pv1" exists for volume "volume1=_=_=557=_=_=System=_=_=cluster1"
+      And deleting PV "pv1" <outcome>
+      And I induce error "VolumeNotExistError"
+      And I call MigrateVolume "volume1=_=_=557=_=_=System=_=_=cluster1" to cluster "cluster2" with copy method "synciq" and node ""
+      Then the migration of volume "volume1=_=_=557=_=_=System=_=_=cluster1" ends in phase "Failed"
+      And PV "pv1" refers to a volume on cluster "cluster1"
+      And PV "pv1" has reclaim policy "Delete"
+
+      Examples:
+      | outcome |
+      | fails   |
+      | hangs   |
+
+    Scenario: Migrate volume whose copy does not finish in time
+      Given a Isilon service
+      When I call Probe
+      And I add cluster "cluster2" with IP "10.0.0.2" and placement weight 1
+      And a PV "pv1" exists for volume "volume1=_=_=557=_=_=System=_=_=cluster1"
+      And rsync jobs never finish with image "rsync:latest"
+      And the copy of the migrations times out after 50 milliseconds
+      And I induce error "VolumeNotExistError"
+      And I call MigrateVolume "volume1=_=_=557=_=_=System=_=_=cluster1" to cluster "cluster2" with copy method "rsync" and node "node1=#=#=node1.example.com=#=#=10.0.0.10"
+      Then the migration of volume "volume1=_=_=557=_=_=System=_=_=cluster1" ends in phase "Failed"
+      And PV "pv1" refers to a volume on cluster "cluster1"
+
+    Scenario: Migrate volume used by a pod
+      Given a Isilon service
+      When I call Probe
+      And I add cluster "cluster2" with IP "10.0.0.2" and placement weight 1
+      And a PV "pv1" exists for volume "volume1=_=_=557=_=_=System=_=_=cluster1"
+      And a pod "pod1" uses PVC "pvc1"
+      And I induce error "VolumeNotExistError"
+      And I call MigrateVolume "volume1=_=_=557=_=_=System=_=_=cluster1" to cluster "cluster2" with copy method "synciq" and node ""
+      Then the error contains "PV 'pv1' is used by pod 'pod1'"
+
+    Scenario: Publish a volume being migrated
+      Given a Isilon service
+      When I call Probe
+      And a migration of volume "volume2=_=_=43=_=_=System=_=_=cluster1" to cluster "cluster2" is in progress
+      And I call ControllerPublishVolume with name "volume2=_=_=43=_=_=System=_=_=cluster1" and access type "multiple-writer" to "vpi7125=#=#=vpi7125.a.b.com=#=#=1.1.1.1"
+      Then the error contains "volume 'volume2=_=_=43=_=_=System=_=_=cluster1' is being migrated to cluster 'cluster2'"
+
+    Scenario Outline: Clean up a migration interrupted by a restart of the controller
+      Given a Isilon service
+      When I call Probe
+      And I add cluster "cluster2" with IP "10.0.0.2" and placement weight 1
+      And a PV "pv1" exists for volume <pvVolume>
+      And PV "pv1" has an interrupted migration of volume "volume1=_=_=557=_=_=System=_=_=cluster1" to volume "volume1=_=_=557=_=_=System=_=_=cluster2" in phase <phase>
+      And I reconcile the migrations
+      Then the migration of volume "volume1=_=_=557=_=_=System=_=_=cluster1" ends in phase <endPhase>
+      And PV "pv1" has no migration state
+
+      Examples:
+      | pvVolume                                  | phase              | endPhase    |
+      | "volume1=_=_=557=_=_=System=_=_=cluster1" | "CopyingData"      | "Failed"    |
+      | "volume1=_=_=557=_=_=System=_=_=cluster1" | "SwitchingVolume"  | "Failed"    |
+      | "volume1=_=_=557=_=_=System=_=_=cluster2" | "CleaningUpSource" | "Completed" |
@@ -0,0 +1,90 @@
+Feature: Isilon CSI interface
+    As a consumer of the CSI interface
+    I want to test the modification of existing volumes
+    So that they are known to work
+
+    Scenario: Modify the quota limits of a volume
+      Given a Isilon service
+      When I call Probe
+      And I enable quota
+      And I call ModifyVolume "volume2=_=_=43=_=_=System" with parameters "QuotaSoftLimit=90;QuotaSoftGracePeriod=168h;QuotaAdvisoryLimit=80" through the admin service
+      Then the error contains "none"
+      And the thresholds of the quota are set to '{"hard":8589934592,"soft":7730941132,"soft_grace":604800,"advisory":6871947673}'
+
+    Scenario: Remove the advisory limit of a volume
+      Given a Isilon service
+      When I call Probe
+      And I enable quota
+      And I call ControllerModifyVolume "volume2=_=_=43=_=_=System" with parameters "QuotaAdvisoryLimit=0"
+      Then the error contains "none"
+      And the thresholds of the quota are set to '{"hard":8589934592,"soft":null,"advisory":null}'
+
+    Scenario: Modify the security flavors of the export of a volume
+      Given a Isilon service
+      When I call Probe
+      And I call ControllerModifyVolume "volume2=_=_=43=_=_=System" with parameters "ExportSecurityFlavors=krb5, KRB5P"
+      Then the error contains "none"
+      And the security flavors of the export are set to "krb5,krb5p"
+
+    Scenario: Give root access to the nodes a volume is published to
+      Given a Isilon service
+      When I call Probe
+      And I induce error "ExportReadWriteNode"
+      And I call ModifyVolume "volume2=_=_=43=_=_=System" with parameters "RootClientEnabled=true" through the admin service
+      Then the node "vpi7125.a.b.com" is added to the "root_clients" of the export
+      And the node "localhost" is not added to the "root_clients" of the export
+
+    Scenario: Remove the root access of the nodes a volume is published to
+      Given a Isilon service
+      When I call Probe
+      And I call ControllerModifyVolume "volume2=_=_=43=_=_=System" with parameters "RootClientEnabled=false"
+      Then the error contains "none"
+      And the root clients of the export are removed
+
+    Scenario: Modify the placement of a volume, keeping the SmartPools settings which are not modified
+      Given a Isilon service
+      When I call Probe
+      And I call ControllerModifyVolume "volume2=_=_=43=_=_=System" with parameters "StoragePool=ssd_pool"
+      Then the error contains "none"
+      And directory "/ifs/data/csi-isilon/volume1" has attribute "storage_pool" set to 'ssd_pool'
+      And attribute "requested_protection" of directory "/ifs/data/csi-isilon/volume1" is not set
+
+    Scenario Outline: Modify volume with invalid requests or induced errors
+      Given a Isilon service
+      When I call Probe
+      And I enable quota
+      And I induce error <induced>
+      And I call ControllerModifyVolume <volumeID> with parameters <params>
+      Then the error contains <errormsg>
+
+      Examples:
+      | induced                       | volumeID                    | params                       | errormsg                                                                         |
+      | "none"                        | ""                          | "RootClientEnabled=true"     | "a volume ID is required"                                                        |
+      | "none"                        | "volume2=_=_=43=_=_=System" | ""                           | "no parameter to modify"                                                         |
+      | "none"                        | "volume2"                   | "RootClientEnabled=true"     | "failed to parse volume ID 'volume2'"                                            |
+      | "none"                        | "volume2=_=_=43=_=_=System" | "Foo=bar"                    | "unknown parameter 'Foo', the parameters which can be modified are"              |
+      | "none"                        | "volume2=_=_=43=_=_=System" | "IsiPath=/ifs/data/other"    | "parameter 'IsiPath' cannot be modified once the volume is created"              |
+      | "none"                        | "volume2=_=_=43=_=_=System" | "SmartLockEnabled=true"      | "parameter 'SmartLockEnabled' cannot be modified once the volume is created"     |
+      | "none"                        | "volume2=_=_=43=_=_=System" | "RootClientEnabled=maybe"    | "invalid value 'maybe' for 'RootClientEnabled'"                                  |
+      | "none"                        | "volume2=_=_=43=_=_=System" | "QuotaAdvisoryLimit=120"     | "a percentage between 0 and 100 is expected"                                     |
+      | "none"                        | "volume2=_=_=43=_=_=System" | "QuotaSoftGracePeriod=week"  | "invalid value 'week' for 'QuotaSoftGracePeriod'"                                |
+      | "none"                        | "volume2=_=_=43=_=_=System" | "QuotaSoftGracePeriod=7D"    | "invalid value '7D' for 'QuotaSoftGracePeriod'"                                  |
+      | "none"                        | "volume2=_=_=43=_=_=System" | "QuotaSoftLimit=90"          | "'QuotaSoftGracePeriod' is required to set 'QuotaSoftLimit'"                     |
+      | "none"                        | "volume2=_=_=43=_=_=System" | "ExportSecurityFlavors=krb6" | "invalid value 'krb6' for 'ExportSecurityFlavors'"                               |
+      | "none"                        | "volume2=_=_=43=_=_=System" | "ProtectionLevel=+9n"        | "invalid value '+9n' for 'ProtectionLevel'"                                      |
+      | "UpdateQuotaError"            | "volume2=_=_=43=_=_=System" | "QuotaAdvisoryLimit=80"      | "failed to update the thresholds of quota"                                       |
+      | "QuotaNotFoundError"          | "volume2=_=_=43=_=_=System" | "QuotaAdvisoryLimit=80"      | "Failed to fetch quota domain record"                                            |
+      | "SetDirectoryAttributesError" | "volume2=_=_=43=_=_=System" | "SSDStrategy=avoid"          | "failed to modify the SmartPools settings of volume 'volume2=_=_=43=_=_=System'" |
+
+    Scenario: Give root access to the nodes of an export without clients
+      Given a Isilon service
+      When I call Probe
+      And I induce error "ExportWithoutClients"
+      And I call ControllerModifyVolume "volume2=_=_=43=_=_=System" with parameters "RootClientEnabled=true"
+      Then the error contains "none"
+
+    Scenario: Modify volume with invalid requests through the admin service
+      Given a Isilon service
+      When I call Probe
+      And I call ModifyVolume "volume2=_=_=43=_=_=System" with parameters "IsiPath=/ifs/data/other" through the admin service
+      Then the error contains "parameter 'IsiPath' cannot be modified once the volume is created"
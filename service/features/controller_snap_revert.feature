@@ -0,0 +1,124 @@
+Feature: Isilon CSI interface
+    As a consumer of the CSI interface
+    I want to test the in place revert of the volumes to their snapshots
+    So that they are known to work
+
+    Scenario: Create volume in a SnapRevert domain
+      Given a Isilon service
+      When I call Probe
+      And I call CreateVolume "volume1" with parameter "SnapRevertEnabled" set to 'true'
+      Then a valid CreateVolumeResponse is returned
+      And a SnapRevert domain is created on "/ifs/data/csi-isilon/volume1"
+
+    Scenario Outline: Create volume in a SnapRevert domain with invalid parameters or induced errors
+      Given a Isilon service
+      When I call Probe
+      And I induce error <induced>
+      And I call CreateVolume "volume1" with SmartLock parameters <params>
+      Then the error contains <errormsg>
+
+      Examples:
+      | induced    | params                                        | errormsg                                                     |
+      | "none"     | "SmartLockEnabled=false,SnapRevertEnabled=no" | "invalid boolean value 'no' for 'SnapRevertEnabled'"         |
+      | "none"     | "SnapRevertEnabled=true"                      | "'SnapRevertEnabled' cannot be used with SmartLock"          |
+      | "JobError" | "SmartLockEnabled=false,SnapRevertEnabled=1"  | "failed to create the SnapRevert domain of volume 'volume1'" |
+
+    Scenario Outline: Revert volume to a snapshot
+      Given a Isilon service
+      When I call Probe
+      And I induce error "SnapRevertDomainMarked"
+      And I induce error <induced>
+      And I call RevertVolume "volume1=_=_=557=_=_=System=_=_=cluster1" to snapshot "5=_=_=cluster1" through the admin service
+      Then a SnapRevert job of snapshot 5 is started
+      And the SnapRevert domain of "/ifs/data/csi-isilon/volume1" is recorded as created at "1567000000"
+      And the revert of volume "volume1=_=_=557=_=_=System=_=_=cluster1" is in state <state> with job state <jobState>
+
+      Examples:
+      | induced      | state       | jobState    |
+      | "none"       | "Completed" | "succeeded" |
+      | "JobRunning" | "Running"   | "running"   |
+      | "JobFailed"  | "Failed"    | "failed"    |
+
+    Scenario Outline: Revert volume with invalid requests or induced errors
+      Given a Isilon service
+      When I call Probe
+      And I induce error "SnapRevertDomainMarked"
+      And I induce error <induced>
+      And I call RevertVolume <volume> to snapshot <snapshot> through the admin service
+      Then the error contains <errormsg>
+
+      Examples:
+      | induced           | volume                                    | snapshot         | errormsg                                              |
+      | "none"            | "volume1=_=_=557=_=_=System=_=_=cluster1" | ""               | "a volume ID and a snapshot ID are required"          |
+      | "none"            | "volume1=_=_=557=_=_=System=_=_=cluster1" | "5=_=_=cluster2" | "snapshot '5=_=_=cluster2' is on cluster 'cluster2'"  |
+      | "none"            | "volume1=_=_=557=_=_=System=_=_=cluster1" | "2=_=_=cluster1" | "is not a snapshot of volume 'volume1'"               |
+      | "ExportPublished" | "volume1=_=_=557=_=_=System=_=_=cluster1" | "5=_=_=cluster1" | "is published to nodes, stop the pods using it first" |
+      | "JobError"        | "volume1=_=_=557=_=_=System=_=_=cluster1" | "5=_=_=cluster1" | "failed to revert volume"                             |
+
+    Scenario: Revert volume already being reverted
+      Given a Isilon service
+      When I call Probe
+      And I induce error "SnapRevertDomainMarked"
+      And I induce error "JobRunning"
+      And I call RevertVolume "volume1=_=_=557=_=_=System=_=_=cluster1" to snapshot "5=_=_=cluster1" through the admin service
+      And I call RevertVolume "volume1=_=_=557=_=_=System=_=_=cluster1" to snapshot "5=_=_=cluster1" through the admin service
+      Then the error contains "is already being reverted to snapshot '5=_=_=cluster1'"
+
+    Scenario Outline: Revert volume whose SnapRevert domain is missing or doesn't predate the snapshot
+      Given a Isilon service
+      When I call Probe
+      And I induce error <marked>
+      And I induce error <induced>
+      And I call RevertVolume "volume1=_=_=557=_=_=System=_=_=cluster1" to snapshot "5=_=_=cluster1" through the admin service
+      Then the error contains <errormsg>
+
+      Examples:
+      | marked                   | induced                   | errormsg                                                                                           |
+      | "none"                   | "none"                    | "'/ifs/data/csi-isilon/volume1' is not in a SnapRevert domain"                                     |
+      | "SnapRevertDomainMarked" | "DomainMarkRunning"       | "is still being created by DomainMark job '41', retry later"                                       |
+      | "SnapRevertDomainMarked" | "DomainMarkFailed"        | "DomainMark job '41' creating the SnapRevert domain of '/ifs/data/csi-isilon/volume1' is 'failed'" |
+      | "SnapRevertDomainMarked" | "DomainMarkAfterSnapshot" | "snapshot 'volume1_2019-08-29_08-00' was taken before the SnapRevert domain"                       |
+
+    Scenario Outline: Publish a volume being reverted
+      Given a Isilon service
+      When I call Probe
+      And I induce error <induced>
+      And a revert of volume "volume2=_=_=43=_=_=System=_=_=cluster1" to snapshot "5=_=_=cluster1" is in progress
+      And I call ControllerPublishVolume with name "volume2=_=_=43=_=_=System=_=_=cluster1" and access type "multiple-writer" to "vpi7125=#=#=vpi7125.a.b.com=#=#=1.1.1.1"
+      Then the error contains <errormsg>
+
+      Examples:
+      | induced      | errormsg                                                                                         |
+      | "JobRunning" | "volume 'volume2=_=_=43=_=_=System=_=_=cluster1' is being reverted to snapshot '5=_=_=cluster1'" |
+      | "none"       | "none"                                                                                           |
+
+    Scenario: Revert volume with a PV
+      Given a Isilon service
+      When I call Probe
+      And a PV "pv1" exists for volume "volume1=_=_=557=_=_=System=_=_=cluster1"
+      And I induce error "SnapRevertDomainMarked"
+      And I induce error "JobRunning"
+      And I call RevertVolume "volume1=_=_=557=_=_=System=_=_=cluster1" to snapshot "5=_=_=cluster1" through the admin service
+      Then PV "pv1" has the state of the revert
+
+    Scenario Outline: Restore a revert running when the controller restarted
+      Given a Isilon service
+      When I call Probe
+      And a PV "pv1" exists for volume "volume1=_=_=557=_=_=System=_=_=cluster1"
+      And PV "pv1" has a running revert of volume "volume1=_=_=557=_=_=System=_=_=cluster1" to snapshot "5=_=_=cluster1"
+      And I induce error <induced>
+      And I reconcile the reverts
+      Then the revert of volume "volume1=_=_=557=_=_=System=_=_=cluster1" is in state <state> with job state <jobState>
+
+      Examples:
+      | induced      | state       | jobState    |
+      | "JobRunning" | "Running"   | "running"   |
+      | "none"       | "Completed" | "succeeded" |
+
+    Scenario: Clear the state of a revert which is over
+      Given a Isilon service
+      When I call Probe
+      And a PV "pv1" exists for volume "volume1=_=_=557=_=_=System=_=_=cluster1"
+      And PV "pv1" has a running revert of volume "volume1=_=_=557=_=_=System=_=_=cluster1" to snapshot "5=_=_=cluster1"
+      And I reconcile the reverts
+      Then PV "pv1" has no revert state
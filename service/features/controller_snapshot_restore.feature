@@ -0,0 +1,103 @@
+Feature: Isilon CSI interface
+    As a consumer of the CSI interface
+    I want to test the restore modes of the volumes created from snapshots
+    So that they are known to work
+
+    Scenario: Create volume from snapshot as a writable snapshot
+      Given a Isilon service
+      When I call Probe
+      And I call CreateVolumeFromSnapshot "2" "volume1" with restore mode "writable"
+      Then a valid CreateVolumeResponse is returned
+      And the volume is restored from snapshot "2" as writable snapshot "/ifs/data/csi-isilon/volume1"
+
+    Scenario Outline: Create volume from snapshot by copying the snapshot
+      Given a Isilon service
+      When I call Probe
+      And I induce error <induced>
+      And I call CreateVolumeFromSnapshot "2" "volume1" with restore mode <mode>
+      Then a valid CreateVolumeResponse is returned
+      And the snapshot is copied
+
+      Examples:
+      | induced                       | mode   |
+      | "none"                        | ""     |
+      | "none"                        | "copy" |
+      | "WritableSnapshotUnsupported" | "auto" |
+
+    Scenario Outline: Create volume from snapshot with an invalid restore mode or induced errors
+      Given a Isilon service
+      When I call Probe
+      And I induce error <induced>
+      And I call CreateVolumeFromSnapshot "2" "volume1" with restore mode <mode>
+      Then the error contains <errormsg>
+
+      Examples:
+      | induced                       | mode       | errormsg                                                |
+      | "none"                        | "clone"    | "invalid value 'clone' for 'SnapshotRestoreMode'"       |
+      | "WritableSnapshotUnsupported" | "writable" | "failed to create writable snapshot of snapshot id '2'" |
+
+    Scenario: Delete volume restored as a writable snapshot
+      Given a Isilon service
+      And I enable quota
+      And I induce error "VolumeExists"
+      And I induce error "WritableSnapshotExists"
+      When I call DeleteVolume "volume1=_=_=557=_=_=System"
+      Then the error contains "none"
+      And writable snapshot "/ifs/data/csi-isilon/volume1" is deleted
+
+    Scenario: Create volume from snapshot as a writable snapshot locks the snapshot
+      Given a Isilon service
+      When I call Probe
+      And I call CreateVolumeFromSnapshot "2" "volume1" with restore mode "writable"
+      Then a valid CreateVolumeResponse is returned
+      And snapshot "2" is locked by "/ifs/data/csi-isilon/volume1"
+
+    Scenario Outline: Create volume from a snapshot whose deletion is pending
+      Given a Isilon service
+      When I call Probe
+      And I induce error "SnapshotDeletionPending"
+      And I call CreateVolumeFromSnapshot "2" "volume1" with restore mode <mode>
+      Then the error contains "snapshot id '2' is being deleted, no volume can be restored from it"
+
+      Examples:
+      | mode       |
+      | "writable" |
+      | "auto"     |
+
+    Scenario Outline: Create volume from snapshot in a SnapRevert domain
+      Given a Isilon service
+      When I call Probe
+      And I call CreateVolumeFromSnapshot "2" "volume1" with restore mode <mode> and parameter "SnapRevertEnabled" set to 'true'
+      Then the error contains <errormsg>
+
+      Examples:
+      | mode       | errormsg                                                                   |
+      | "writable" | "'SnapRevertEnabled' cannot be used with 'SnapshotRestoreMode' 'writable'" |
+      | "auto"     | "none"                                                                     |
+
+    Scenario: Delete snapshot with volumes restored as writable snapshots
+      Given a Isilon service
+      When I call Probe
+      And I induce error "WritableSnapshotDependents"
+      And I call DeleteSnapshot "2"
+      Then the error contains "none"
+      And the deletion of snapshot "2" is deferred
+
+    Scenario: Delete snapshot without volumes restored as writable snapshots
+      Given a Isilon service
+      When I call Probe
+      And I call DeleteSnapshot "2"
+      Then the error contains "none"
+      And snapshot "2" is deleted
+
+    Scenario: Delete the last volume restored as a writable snapshot of a deleted snapshot
+      Given a Isilon service
+      And I enable quota
+      And I induce error "VolumeExists"
+      And I induce error "WritableSnapshotExists"
+      And I induce error "SnapshotDeletionPending"
+      When I call DeleteVolume "volume1=_=_=557=_=_=System"
+      Then the error contains "none"
+      And writable snapshot "/ifs/data/csi-isilon/volume1" is deleted
+      And snapshot "2" is unlocked
+      And snapshot "2" is deleted
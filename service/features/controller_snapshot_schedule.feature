@@ -0,0 +1,131 @@
+Feature: Isilon CSI interface
+    As a consumer of the CSI interface
+    I want to test the scheduled snapshots of the volumes
+    So that they are known to work
+
+    Scenario Outline: Create volume with a snapshot schedule
+      Given a Isilon service
+      When I call Probe
+      And I induce error <induced>
+      And I call CreateVolume "volume1" with snapshot schedule <schedule> pattern <pattern> and retention <retention>
+      Then a valid CreateVolumeResponse is returned
+      And snapshot schedule "csi-volume1" takes snapshots of <path> <schedule> named <named> kept <duration> seconds
+
+      # the path of an existing schedule is not updated
+      Examples:
+      | induced                  | schedule                  | pattern           | retention | path                           | named                    | duration |
+      | "none"                   | "every 1 hours"           | ""                | "7D"      | "/ifs/data/csi-isilon/volume1" | "volume1_%Y-%m-%d_%H-%M" | 604800   |
+      | "none"                   | "every day at 00:00"      | "daily-%Y-%m-%d"  | ""        | "/ifs/data/csi-isilon/volume1" | "daily-%Y-%m-%d"         | 0        |
+      | "SnapshotScheduleExists" | "every 1 weeks on Sunday" | "weekly-%Y-%m-%d" | "4W"      | ""                             | "weekly-%Y-%m-%d"        | 2419200  |
+
+    Scenario Outline: Create volume with an invalid snapshot schedule or induced errors
+      Given a Isilon service
+      When I call Probe
+      And I induce error <induced>
+      And I call CreateVolume "volume1" with snapshot schedule <schedule> pattern <pattern> and retention <retention>
+      Then the error contains <errormsg>
+
+      Examples:
+      | induced                 | schedule        | pattern  | retention | errormsg                                                    |
+      | "none"                  | ""              | ""       | "7D"      | "'SnapshotSchedule' is required"                            |
+      | "none"                  | "every 1 hours" | "hourly" | ""        | "invalid value 'hourly' for 'SnapshotSchedulePattern'"      |
+      | "none"                  | "every 1 hours" | ""       | "1 week"  | "invalid value '1 week' for 'SnapshotScheduleRetention'"    |
+      | "SnapshotScheduleError" | "every 1 hours" | ""       | ""        | "failed to apply the snapshot schedule of volume 'volume1'" |
+
+    Scenario Outline: Delete volume with a snapshot schedule
+      Given a Isilon service
+      And I enable quota
+      And I induce error "VolumeExists"
+      And I induce error <induced>
+      When I call DeleteVolume "volume1=_=_=557=_=_=System"
+      Then the error contains <errormsg>
+
+      Examples:
+      | induced                  | errormsg                                                     |
+      | "SnapshotScheduleExists" | "none"                                                       |
+      | "SnapshotScheduleError"  | "failed to delete the snapshot schedule of volume 'volume1'" |
+
+    Scenario: Delete volume deletes its snapshot schedule
+      Given a Isilon service
+      And I enable quota
+      And I induce error "VolumeExists"
+      And I induce error "SnapshotScheduleExists"
+      When I call DeleteVolume "volume1=_=_=557=_=_=System"
+      Then the error contains "none"
+      And snapshot schedule "csi-volume1" is deleted
+
+    Scenario Outline: Delete volume which is already gone deletes its snapshot schedule
+      Given a Isilon service
+      And I enable quota
+      And I induce error <induced>
+      And I induce error "SnapshotScheduleExists"
+      When I call DeleteVolume "volume1=_=_=557=_=_=System"
+      Then the error contains "none"
+      And snapshot schedule "csi-volume1" is deleted
+
+      Examples:
+      | induced                      |
+      | "GetExportByIDNotFoundError" |
+      | "VolumeNotExistError"        |
+
+    Scenario Outline: Roll back a volume with a snapshot schedule
+      Given a Isilon service
+      And I enable quota
+      When I call Probe
+      And I induce error "SnapshotScheduleExists"
+      And I induce error <induced>
+      And I call CreateVolume "volume1" with snapshot schedule "every 1 hours" pattern "" and retention ""
+      Then the error contains <errormsg>
+      And snapshot schedule "csi-volume1" is deleted
+
+      Examples:
+      | induced             | errormsg               |
+      | "CreateQuotaError"  | "error creating quota" |
+      | "CreateExportError" | "EOF"                  |
+
+    Scenario Outline: List snapshots
+      Given a Isilon service
+      When I call Probe
+      And I induce error "ScheduledSnapshotsExist"
+      And a PV "pv1" exists for volume "volume1=_=_=557=_=_=System=_=_=cluster1"
+      And I call ListSnapshots with snapshot ID <snapshotID> source volume ID <volumeID> max entries <max> and starting token <token>
+      Then the snapshots <snapshots> are listed with next token <next>
+
+      Examples:
+      | snapshotID       | volumeID                                  | max | token | snapshots                                                                                                       | next |
+      | ""               | "volume1=_=_=557=_=_=System=_=_=cluster1" | 0   | ""    | "2=_=_=cluster1@volume1=_=_=557=_=_=System=_=_=cluster1,5=_=_=cluster1@volume1=_=_=557=_=_=System=_=_=cluster1" | ""   |
+      | ""               | "volume1=_=_=557=_=_=System=_=_=cluster1" | 1   | ""    | "2=_=_=cluster1@volume1=_=_=557=_=_=System=_=_=cluster1"                                                        | "1"  |
+      | ""               | "volume1=_=_=557=_=_=System=_=_=cluster1" | 1   | "1"   | "5=_=_=cluster1@volume1=_=_=557=_=_=System=_=_=cluster1"                                                        | ""   |
+      | ""               | ""                                        | 0   | ""    | "2=_=_=cluster1@volume1=_=_=557=_=_=System=_=_=cluster1,5=_=_=cluster1@volume1=_=_=557=_=_=System=_=_=cluster1" | ""   |
+      | "2=_=_=cluster1" | ""                                        | 0   | ""    | "2=_=_=cluster1@"                                                                                               | ""   |
+      | "snap"           | ""                                        | 0   | ""    | ""                                                                                                              | ""   |
+      | ""               | "volume1"                                 | 0   | ""    | ""                                                                                                              | ""   |
+
+    # the source volume of a snapshot is the handle of its PV, in whichever format it was created
+    Scenario Outline: List the snapshots of the volumes with or without a PV
+      Given a Isilon service
+      When I call Probe
+      And I induce error "ScheduledSnapshotsExist"
+      And a PV "pv1" exists for volume <volumeID>
+      And I call ListSnapshots with snapshot ID "" source volume ID "" max entries 0 and starting token ""
+      Then the snapshots <snapshots> are listed with next token ""
+
+      Examples:
+      | volumeID                                 | snapshots                                                                             |
+      | "volume1=_=_=557=_=_=System"             | "2=_=_=cluster1@volume1=_=_=557=_=_=System,5=_=_=cluster1@volume1=_=_=557=_=_=System" |
+      | "volume2=_=_=19=_=_=System=_=_=cluster1" | "2=_=_=cluster1@,5=_=_=cluster1@"                                                     |
+
+    Scenario: List snapshots of an unavailable cluster
+      Given a Isilon service
+      And the circuit breaker opens after 1 failed probes and is reset after "1h"
+      When I render Isilon service unreachable
+      And I run the health probes of the clusters
+      And I call ListSnapshots with snapshot ID "" source volume ID "" max entries 0 and starting token ""
+      Then the snapshots "" are listed with next token ""
+
+    Scenario: List snapshots with an invalid starting token
+      Given a Isilon service
+      When I call Probe
+      And I induce error "ScheduledSnapshotsExist"
+      And I call ListSnapshots with snapshot ID "" source volume ID "" max entries 1 and starting token "10"
+      Then the error contains "the starting token '10' is not valid"
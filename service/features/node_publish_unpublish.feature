@@ -60,6 +60,8 @@ Feature: Isilon CSI interface
     | "mount"      | "multiple-reader"              | "none"                                               |
     | "mount"      | "single-writer"                | "Mount point already in use for same device"         |
     | "mount"      | "multiple-writer"              | "none"                                               |
+    | "mount"      | "single-node-single-writer"    | "Mount point already in use for same device"         |
+    | "mount"      | "single-node-multi-writer"     | "none"                                               |
     | "block"      | "single-writer"                | "Invalid access type"                                |
     | "block"      | "multiple-writer"              | "Invalid access type"                                |
     | "block"      | "multiple-reader"              | "Invalid access type"                                |
@@ -143,6 +145,8 @@ Feature: Isilon CSI interface
     | "mount"      | "multiple-reader"              | "none"                                       |
     | "mount"      | "single-writer"                | "none" |
     | "mount"      | "multiple-writer"              | "none"                                       |
+    | "mount"      | "single-node-single-writer"    | "none"                                       |
+    | "mount"      | "single-node-multi-writer"     | "none"                                       |
     | "block"      | "multiple-reader"              | "Invalid access type"                        |
 
 @nodeUnpublish
@@ -0,0 +1,64 @@
+Feature: Isilon CSI interface
+    As a consumer of the CSI interface
+    I want to test the validation of the cluster secret
+    So that they are known to work
+
+    Scenario: Validate a valid secret
+      Given a Isilon service
+      When I validate the secret '{"isilonClusters":[{"clusterName":"c2","username":"u","password":"p","endpoint":"1.2.3.5"},{"clusterName":"c1","username":"u","password":"p","endpoint":"1.2.3.4","isDefault":true}],"logLevel":"info"}'
+      Then the secret is valid with clusters "c1,c2" and default cluster "c1"
+
+    Scenario Outline: Validate an invalid secret
+      Given a Isilon service
+      When I validate the secret <config>
+      Then the secret is not valid with error <errormsg>
+
+      Examples:
+      | config                                                                                                                                                                                                   | errormsg                                                               |
+      | '{"isilonClusters":[{"clusterName":"c1","username":"u","password":"p","endpoint":"1.2.3.4","isiIP":"1.2.3.4"}]}'                                                                                         | "specify either of isiIP or endpoint attribute at index [0]"           |
+      | '{"isilonClusters":[{"clusterName":"c1","username":"u","password":"p","endpoint":"1.2.3.4","isDefault":true},{"clusterName":"c2","username":"u","password":"p","endpoint":"1.2.3.5","isDefault":true}]}' | "'isDefaultCluster' attribute set for multiple isilon cluster configs" |
+      | '{"isilonClusters":[{"clusterName":"c1","username":"u","password":"p","endpoint":"1.2.3.4"}],"logLevel":"verbose"}'                                                                                      | "not a valid log level"                                                |
+      | '{"isilonClusters":[{"clusterName":"c1","username":"u","password":"p","endpoint":"1.2.3.4"}],"tenants":[{"name":"t1","namespaces":["a"],"allowedClusters":["c9"]}]}'                                     | "allowed cluster 'c9' of tenant 't1' is not defined in isilonClusters" |
+      | 'not a secret'                                                                                                                                                                                           | "failed to parse isilon clusters' config details"                      |
+
+    Scenario: The credential references are not resolved by default
+      Given a Isilon service
+      When I validate the secret '{"isilonClusters":[{"clusterName":"c1","username":"u","passwordRef":"env:TEST_ISILON_UNSET_VARIABLE","endpoint":"1.2.3.4"}]}'
+      Then the secret is valid with clusters "c1" and default cluster ""
+
+    Scenario: The CA bundle files are not read by default
+      Given a Isilon service
+      When I validate the secret '{"isilonClusters":[{"clusterName":"c1","username":"u","password":"p","endpoint":"1.2.3.4","certificateAuthorityPath":"/no/such/ca.pem"}]}'
+      Then the secret is valid with clusters "c1" and default cluster ""
+
+    Scenario: The CA bundle files are read when the clusters are checked
+      Given a Isilon service
+      When I validate the secret '{"isilonClusters":[{"clusterName":"c1","username":"u","password":"p","endpoint":"1.2.3.4","certificateAuthorityPath":"/no/such/ca.pem"}]}' with options "check-clusters"
+      Then the secret is not valid with error "failed to read the CA bundle '/no/such/ca.pem'"
+
+    Scenario: Validate a secret for a driver with custom topology
+      Given a Isilon service
+      When I validate the secret '{"isilonClusters":[{"clusterName":"c2","username":"u","password":"p","endpoint":"1.2.3.5"},{"clusterName":"c1","username":"u","password":"p","endpoint":"1.2.3.4"}]}' with options "custom-topology"
+      Then the secret is not valid with error "custom topology is enabled and it expects single cluster config in secret"
+
+    Scenario: Check a cluster
+      Given a Isilon service
+      When I check cluster "cluster1" with access zones "System,zone1"
+      Then the check "connectivity" of "" passed with error ""
+      And the check "accessZone" of "System" passed with error ""
+      And the check "accessZone" of "zone1" passed with error ""
+      And the check "isiPath" of "/ifs/data/csi-isilon" passed with error ""
+
+    Scenario: Check a cluster without the access zone and isiPath of the secret
+      Given a Isilon service
+      And I induce error "IsiPathNotFound"
+      When I check cluster "cluster1" with access zones "System,unknownzone"
+      Then the check "accessZone" of "System" passed with error ""
+      And the check "accessZone" of "unknownzone" failed with error "access zone 'unknownzone' not found"
+      And the check "isiPath" of "/ifs/data/csi-isilon" failed with error "directory '/ifs/data/csi-isilon' not found"
+
+    Scenario: Check an unreachable cluster
+      Given a Isilon service
+      And I render Isilon service unreachable
+      When I check cluster "cluster1" with access zones "System"
+      Then the check "connectivity" of "" failed with error ""
@@ -12,6 +12,7 @@ Feature: Isilon CSI interface
       Given a Isilon service
       When I call GetPluginCapabilities
       Then a valid GetPluginCapabilitiesResponse is returned
+      And the plugin capability "VOLUME_ACCESSIBILITY_CONSTRAINTS" is advertised
 
     Scenario: Identity Probe good call
       Given a Isilon service
@@ -44,15 +45,17 @@ Feature: Isilon CSI interface
       Then the error contains <errormsg>
 
       Examples:
-      | voltype    | access                     | errormsg                                                   |
-      | "mount"    | "single-writer"            | "none"                                                     |
-      | "mount"    | "single-reader"            | "Single node only reader access mode is not supported"     |
-      | "mount"    | "multi-reader"             | "none"                                                     |
-      | "mount"    | "multi-writer"             | "none"                                                     |
-      | "mount"    | "multi-node-single-writer" | "Multi node single writer access mode is not supported"    |
-      | "mount"    | "unknown"                  | "unknown or unsupported access mode"                       |
-      | "mount"    | ""                         | "unknown or unsupported access mode"                       |
-      | "block"    | "single-writer"            | "unknown access type is not Mount"                         |
+      | voltype | access                      | errormsg                                                |
+      | "mount" | "single-writer"             | "none"                                                  |
+      | "mount" | "single-reader"             | "none"                                                  |
+      | "mount" | "single-node-single-writer" | "none"                                                  |
+      | "mount" | "single-node-multi-writer"  | "none"                                                  |
+      | "mount" | "multi-reader"              | "none"                                                  |
+      | "mount" | "multi-writer"              | "none"                                                  |
+      | "mount" | "multi-node-single-writer"  | "Multi node single writer access mode is not supported" |
+      | "mount" | "unknown"                   | "unknown or unsupported access mode"                    |
+      | "mount" | ""                          | "unknown or unsupported access mode"                    |
+      | "block" | "single-writer"             | "unknown access type is not Mount"                      |
 
     Scenario: Call GetCapacity
       Given a Isilon service
@@ -84,10 +87,48 @@ Feature: Isilon CSI interface
       Then the error contains <errormsg>
 
      Examples:
-     | induced               | errormsg                                                           |
-     | "StatsError"          | "runid=Could not retrieve capacity. Data returned error"         |
+     | induced               | errormsg                                                                   |
+     | "StatsError"          | "runid=1 Could not retrieve capacity. Data returned error"                 |
+     | "EmptyStatsError"     | "runid=1 Could not retrieve capacity. No statistics returned"              |
      | "InstancesError"      | "runid=1 Could not retrieve capacity. Error 'Error retrieving Statistics'" |
-     | "none"                | "none"                                                             |
+     | "none"                | "none"                                                                     |
+
+    Scenario Outline: Call GetCapacity with isiPath quota, overprovisioning ratio and topology
+      Given a Isilon service
+      When I call Probe
+      And I induce error <induced>
+      And I call GetCapacity with parameters <params> and topology <topology>
+      Then the available capacity is <available> and the maximum volume size is <maximum>
+
+     Examples:
+     | induced              | params                                           | topology                                                  | available       | maximum         |
+     | "none"               | "ClusterName=cluster1"                           | ""                                                        | 81224996814848  | 81224996814848  |
+     | "IsiPathQuotaExists" | "ClusterName=cluster1"                           | ""                                                        | 64424509440     | 64424509440     |
+     | "none"               | "ClusterName=cluster1,OverprovisioningRatio=1.5" | ""                                                        | 121837495222272 | 121837495222272 |
+     | "IsiPathQuotaExists" | "ClusterName=cluster1,OverprovisioningRatio=1.5" | ""                                                        | 64424509440     | 64424509440     |
+     | "IsiPathQuotaExists" | "ClusterName=cluster1"                           | "csi-isilon.dellemc.com/127.0.0.1=csi-isilon.dellemc.com" | 64424509440     | 64424509440     |
+     | "none"               | "ClusterName=cluster1"                           | "csi-isilon.dellemc.com/10.0.0.2=csi-isilon.dellemc.com"  | 0               | 0               |
+
+    Scenario: Call GetCapacity with invalid overprovisioning ratio
+      Given a Isilon service
+      When I call Probe
+      And I call GetCapacity with parameters "ClusterName=cluster1,OverprovisioningRatio=0.5" and topology ""
+      Then the error contains "invalid value '0.5' for 'OverprovisioningRatio'"
+
+    Scenario Outline: Call GetCapacity with capacity placement
+      Given a Isilon service
+      When I call Probe
+      And I enable capacity placement
+      And I add cluster "cluster2" with IP "10.0.0.2" and placement weight <weight>
+      And I induce error "IsiPathQuotaExists"
+      And I call GetCapacity with parameters "" and topology <topology>
+      Then the available capacity is <available> and the maximum volume size is <maximum>
+
+     Examples:
+     | weight | topology                                                 | available    | maximum     |
+     | 1      | ""                                                       | 128849018880 | 64424509440 |
+     | 0      | ""                                                       | 64424509440  | 64424509440 |
+     | 1      | "csi-isilon.dellemc.com/10.0.0.2=csi-isilon.dellemc.com" | 64424509440  | 64424509440 |
 
     Scenario: Call NodeGetInfo
       Given a Isilon service
@@ -145,16 +186,56 @@ Feature: Isilon CSI interface
       Then the error contains <errormsg>
 
       Examples:
-      | volumeID                       | accessType                | errormsg                            |
-      | "volume2=_=_=43=_=_=System"    | "single-writer"           | "none"                              |
-      | "volume2=_=_=43=_=_=System=_=_=cluster1" | "single-writer" | "none"                              |
-      | "volume2=_=_=43=_=_=System=_=_=cluster2" | "single-writer" | "failed to get cluster config details for clusterName: 'cluster2'" |
-      | "volume2=_=_=43"               | "single-writer"           | "failed to parse volume ID"         |
-      | "volume2=_=_=0=_=_=System"     | "single-writer"           | "invalid export ID"                 |
-      | "volume2=_=_=43=_=_=System"    | "multiple-reader"         | "none"                              |
-      | "volume2=_=_=43=_=_=System"    | "multiple-writer"         | "none"                              |
-      | "volume2=_=_=43=_=_=System"    | "single-reader"           | "unsupported access mode"           |
-      | "volume2=_=_=43=_=_=System"    | "unknown"                 | "unknown or unsupported access mode"|
+      | volumeID                                 | accessType                  | errormsg                                                           |
+      | "volume2=_=_=43=_=_=System"              | "single-writer"             | "none"                                                             |
+      | "volume2=_=_=43=_=_=System=_=_=cluster1" | "single-writer"             | "none"                                                             |
+      | "volume2=_=_=43=_=_=System=_=_=cluster2" | "single-writer"             | "failed to get cluster config details for clusterName: 'cluster2'" |
+      | "volume2=_=_=43"                         | "single-writer"             | "failed to parse volume ID"                                        |
+      | "volume2=_=_=0=_=_=System"               | "single-writer"             | "invalid export ID"                                                |
+      | "volume2=_=_=43=_=_=System"              | "multiple-reader"           | "none"                                                             |
+      | "volume2=_=_=43=_=_=System"              | "multiple-writer"           | "none"                                                             |
+      | "volume2=_=_=43=_=_=System"              | "single-reader"             | "none"                                                             |
+      | "volume2=_=_=43=_=_=System"              | "single-node-single-writer" | "none"                                                             |
+      | "volume2=_=_=43=_=_=System"              | "single-node-multi-writer"  | "none"                                                             |
+      | "volume2=_=_=43=_=_=System"              | "unknown"                   | "unknown or unsupported access mode"                               |
+
+    Scenario Outline: ControllerPublishVolume read only adds the node to the read-only clients
+      Given a Isilon service
+      When I call Probe
+      And I call ControllerPublishVolume with name "volume2=_=_=43=_=_=System" and access type <accessType> and read only "true" to "vpi7125=#=#=vpi7125.a.b.com=#=#=1.1.1.1"
+      Then the error contains "none"
+      And the node "vpi7125.a.b.com" is added to the "read_only_clients" of the export
+      And the node "vpi7125.a.b.com" is not added to the "clients" of the export
+
+      Examples:
+      | accessType                  |
+      | "single-writer"             |
+      | "single-node-single-writer" |
+      | "multiple-writer"           |
+      | "multiple-reader"           |
+
+    Scenario: ControllerPublishVolume read write adds the node to the clients
+      Given a Isilon service
+      When I call Probe
+      And I call ControllerPublishVolume with name "volume2=_=_=43=_=_=System" and access type "multiple-writer" to "vpi7125=#=#=vpi7125.a.b.com=#=#=1.1.1.1"
+      Then the error contains "none"
+      And the node "vpi7125.a.b.com" is added to the "clients" of the export
+      And the node "vpi7125.a.b.com" is not added to the "read_only_clients" of the export
+
+    Scenario Outline: ControllerPublishVolume to a node which already has another read-only flag
+      Given a Isilon service
+      And I induce error <induced>
+      When I call Probe
+      And I call ControllerPublishVolume with name "volume2=_=_=43=_=_=System" and access type <accessType> and read only <readOnly> to "vpi7125=#=#=vpi7125.a.b.com=#=#=1.1.1.1"
+      Then the error contains <errormsg>
+
+      Examples:
+      | induced               | readOnly | accessType        | errormsg                                                                                     |
+      | "ExportReadWriteNode" | "true"   | "multiple-writer" | "already published to node 'vpi7125=#=#=vpi7125.a.b.com=#=#=1.1.1.1' with read-only 'false'" |
+      | "ExportReadWriteNode" | "false"  | "multiple-reader" | "already published to node 'vpi7125=#=#=vpi7125.a.b.com=#=#=1.1.1.1' with read-only 'false'" |
+      | "ExportReadOnlyNode"  | "false"  | "multiple-writer" | "already published to node 'vpi7125=#=#=vpi7125.a.b.com=#=#=1.1.1.1' with read-only 'true'"  |
+      | "ExportReadOnlyNode"  | "true"   | "multiple-writer" | "none"                                                                                       |
+      | "ExportReadWriteNode" | "false"  | "multiple-writer" | "none"                                                                                       |
 
     Scenario: ControllerUnpublishVolume good scenario
       Given a Isilon service
@@ -193,6 +274,13 @@ Feature: Isilon CSI interface
       And I call CreateVolumeFromSnapshot "2" "volume1"
       Then a valid CreateVolumeResponse is returned
 
+    Scenario: Create volume from snapshot with induced error while copying the permissions
+      Given a Isilon service
+      When I call Probe
+      And I induce error "SetACLError"
+      And I call CreateVolumeFromSnapshot "2" "volume1"
+      Then the error contains "failed to set the permissions of volume 'volume1'"
+
     Scenario Outline: Create volume from snapshot with negative or idempotent arguments
       Given a Isilon service
       When I call CreateVolumeFromSnapshot <snapshotID> <volumeName>
@@ -271,7 +359,7 @@ Feature: Isilon CSI interface
       Given I induce error "noIsiService"
       And a Isilon service with params "blah" "controller"
       When I call autoProbe
-      Then the error contains "clusterConfig.isiSvc (type isiService) is nil, probe failed"
+      Then the error contains "none"
     
     Scenario: Calling functions with autoProbe failed
       Given a Isilon service
@@ -307,3 +395,51 @@ Feature: Isilon CSI interface
       When I call set allowed networks with multiple networks "1.2.3.4/33" "127.0.0.0/8"
       And I call NodeGetInfo
       Then a valid NodeGetInfoResponse is returned
+
+    Scenario: Remove a deleted node from the exports
+      Given a Isilon service
+      When I call Probe
+      And I call removeNodeFromExports "node2=#=#=node2.example.com=#=#=10.0.0.2"
+      Then the error contains "none"
+
+    Scenario: Remove a deleted node from the exports with induced errors
+      Given a Isilon service
+      When I call Probe
+      And I induce error "GetExportInternalError"
+      And I call removeNodeFromExports "node2=#=#=node2.example.com=#=#=10.0.0.2"
+      Then the error contains "failed to get exports in access zone 'System'"
+
+    Scenario: Remove a deleted node with invalid node ID from the exports
+      Given a Isilon service
+      When I call removeNodeFromExports "node2"
+      Then the error contains "cannot match the expected"
+
+    Scenario: Resolve the node ID of a deleted node from its CSINode
+      Given a Isilon service
+      And a CSINode "node2" with node ID "node2=#=#=node2.example.com=#=#=10.0.0.2"
+      When I call resolveNodeID "node2"
+      Then the error contains "none"
+      And the resolved node ID is "node2=#=#=node2.example.com=#=#=10.0.0.2"
+
+    Scenario: Resolve the node ID of a deleted node from the attachment metadata of its VolumeAttachment
+      Given a Isilon service
+      And a VolumeAttachment of PV "pv1" to node "node2" with node ID "node2=#=#=node2.example.com=#=#=10.0.0.2"
+      When I call resolveNodeID "node2"
+      Then the error contains "none"
+      And the resolved node ID is "node2=#=#=node2.example.com=#=#=10.0.0.2"
+
+    Scenario: Resolve the node ID of a deleted node from the export of the volume of its VolumeAttachment
+      Given a Isilon service
+      And a PV "pv1" exists for volume "k8s-ac7b91962d=_=_=557=_=_=System=_=_=cluster1"
+      And a VolumeAttachment of PV "pv1" to node "vpi7125" with node ID ""
+      And I induce error "ExportReadWriteNode"
+      When I call Probe
+      And I call resolveNodeID "vpi7125"
+      Then the error contains "none"
+      And the resolved node ID is "vpi7125=#=#=vpi7125.a.b.com=#=#=vpi7125.a.b.com"
+
+    Scenario: The node ID of a deleted node without CSINode nor VolumeAttachment cannot be resolved
+      Given a Isilon service
+      When I call resolveNodeID "node2"
+      Then the error contains "none"
+      And the resolved node ID is ""
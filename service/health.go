@@ -0,0 +1,438 @@
+package service
+
+/*
+ Copyright (c) 2019 Dell Inc, or its subsidiaries.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dell/csi-isilon/common/utils"
+	csictx "github.com/dell/gocsi/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ProbeHealthHeader is the header of the response of Probe with the health state of the clusters, one
+// "<cluster>=<state>,circuit=<circuit>" value per cluster
+const ProbeHealthHeader = "csi-isilon-cluster-health"
+
+// Health states of the clusters
+const (
+	// ClusterHealthUnknown is the state of a cluster which has not been probed yet
+	ClusterHealthUnknown = "unknown"
+	// ClusterHealthHealthy is the state of a cluster whose last probe succeeded
+	ClusterHealthHealthy = "healthy"
+	// ClusterHealthDegraded is the state of a cluster whose last probes failed, but not enough of them to open its
+	// circuit breaker
+	ClusterHealthDegraded = "degraded"
+	// ClusterHealthUnavailable is the state of a cluster whose circuit breaker is open, the requests to it fail fast
+	ClusterHealthUnavailable = "unavailable"
+)
+
+// States of the circuit breakers of the clusters
+const (
+	// CircuitClosed lets the requests to the cluster through
+	CircuitClosed = "closed"
+	// CircuitOpen fails the requests to the cluster with Unavailable
+	CircuitOpen = "open"
+	// CircuitHalfOpen lets a single request probe the cluster, the others fail with Unavailable until it is done
+	CircuitHalfOpen = "half-open"
+)
+
+const (
+	defaultHealthProbeInterval        = 30 * time.Second
+	defaultCircuitBreakerThreshold    = 3
+	defaultCircuitBreakerResetTimeout = 2 * time.Minute
+
+	metricsPath = "/metrics"
+)
+
+// clusterProbeKey marks the context of the probes of a cluster, whose requests are recorded as probes rather than
+// as requests
+type clusterProbeKey struct{}
+
+// ClusterHealth is the health state of a cluster, refreshed by the probes of the cluster
+type ClusterHealth struct {
+	ClusterName         string     `json:"clusterName"`
+	State               string     `json:"state"`
+	Circuit             string     `json:"circuit"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	Probes              int64      `json:"probes"`
+	Failures            int64      `json:"failures"`
+	LastError           string     `json:"lastError,omitempty"`
+	LastProbeTime       *time.Time `json:"lastProbeTime,omitempty"`
+	LastSuccessTime     *time.Time `json:"lastSuccessTime,omitempty"`
+	CircuitChangeTime   *time.Time `json:"circuitChangeTime,omitempty"`
+}
+
+// GetClusterHealthRequest is the request of the GetClusterHealth admin RPC, all the clusters are returned if no
+// ClusterName is given
+type GetClusterHealthRequest struct {
+	ClusterName string `json:"clusterName,omitempty"`
+}
+
+// GetClusterHealthResponse is the response of the GetClusterHealth admin RPC
+type GetClusterHealthResponse struct {
+	Clusters []*ClusterHealth `json:"clusters"`
+}
+
+// parseDurationFromContext returns the duration set in an env variable, or the default value if it is not set or
+// not valid
+func parseDurationFromContext(ctx context.Context, key string, defaultValue time.Duration) time.Duration {
+	log := utils.GetRunIDLogger(ctx)
+	value, ok := csictx.LookupEnv(ctx, key)
+	if !ok || value == "" {
+		return defaultValue
+	}
+	if value == "0" {
+		return 0
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		log.Warnf("invalid value '%s' for env variable '%s', defaulting to '%s'", value, key, defaultValue)
+		return defaultValue
+	}
+	return duration
+}
+
+// getClusterHealthLocked returns the health state of a cluster, creating it if the cluster was never probed.
+// healthLock must be held.
+func (s *service) getClusterHealthLocked(clusterName string) *ClusterHealth {
+	if s.health == nil {
+		s.health = make(map[string]*ClusterHealth)
+	}
+	health, ok := s.health[clusterName]
+	if !ok {
+		health = &ClusterHealth{
+			ClusterName: clusterName,
+			State:       ClusterHealthUnknown,
+			Circuit:     CircuitClosed,
+		}
+		s.health[clusterName] = health
+	}
+	return health
+}
+
+// getClusterHealth returns a copy of the health state of a cluster
+func (s *service) getClusterHealth(clusterName string) ClusterHealth {
+	s.healthLock.Lock()
+	defer s.healthLock.Unlock()
+	return *s.getClusterHealthLocked(clusterName)
+}
+
+// recordClusterProbe updates the health state of a cluster with the result of a probe, opening its circuit breaker
+// when the probe failed too many times in a row, and closing it when the probe succeeds
+func (s *service) recordClusterProbe(ctx context.Context, clusterName string, err error) {
+	s.recordClusterResult(ctx, clusterName, err, true)
+}
+
+// recordClusterRequest updates the health state of a cluster with the result of a request, which counts toward its
+// circuit breaker like the probes
+func (s *service) recordClusterRequest(ctx context.Context, clusterName string, err error) {
+	s.recordClusterResult(ctx, clusterName, err, false)
+}
+
+func (s *service) recordClusterResult(ctx context.Context, clusterName string, err error, probe bool) {
+	log := utils.GetRunIDLogger(ctx)
+	s.healthLock.Lock()
+	defer s.healthLock.Unlock()
+
+	health := s.getClusterHealthLocked(clusterName)
+	previousState, previousCircuit := health.State, health.Circuit
+	now := time.Now()
+	if probe {
+		health.Probes++
+		health.LastProbeTime = &now
+	}
+
+	if err == nil {
+		health.State = ClusterHealthHealthy
+		health.ConsecutiveFailures = 0
+		health.LastError = ""
+		health.LastSuccessTime = &now
+		if health.Circuit != CircuitClosed {
+			health.Circuit = CircuitClosed
+			health.CircuitChangeTime = &now
+		}
+	} else {
+		health.Failures++
+		health.ConsecutiveFailures++
+		health.LastError = err.Error()
+		threshold := s.opts.CircuitBreakerThreshold
+		if health.Circuit == CircuitHalfOpen || (health.Circuit == CircuitClosed && threshold > 0 && health.ConsecutiveFailures >= threshold) {
+			health.Circuit = CircuitOpen
+			health.CircuitChangeTime = &now
+		}
+		health.State = ClusterHealthDegraded
+		if health.Circuit == CircuitOpen {
+			health.State = ClusterHealthUnavailable
+		}
+	}
+
+	switch {
+	case previousCircuit != CircuitOpen && health.Circuit == CircuitOpen:
+		log.Warnf("circuit breaker of cluster '%s' opened after '%d' consecutive failures, the requests to it fail fast for '%s': '%s'",
+			clusterName, health.ConsecutiveFailures, s.opts.CircuitBreakerResetTimeout, health.LastError)
+	case previousCircuit != CircuitClosed && health.Circuit == CircuitClosed:
+		log.Infof("cluster '%s' is reachable again, circuit breaker closed", clusterName)
+	case previousState != health.State && health.State == ClusterHealthDegraded:
+		log.Warnf("health of cluster '%s' changed from '%s' to '%s': '%s'", clusterName, previousState, health.State, health.LastError)
+	case previousState != health.State:
+		log.Infof("health of cluster '%s' changed from '%s' to '%s'", clusterName, previousState, health.State)
+	}
+}
+
+// checkClusterCircuit returns an Unavailable error if the circuit breaker of a cluster is open. Once the reset
+// timeout has elapsed, the breaker is half-opened and true is returned to the single caller which must probe the
+// cluster, the others keep failing until the probe is recorded or the reset timeout elapses again.
+func (s *service) checkClusterCircuit(ctx context.Context, clusterName string) (bool, error) {
+	log := utils.GetRunIDLogger(ctx)
+	s.healthLock.Lock()
+	defer s.healthLock.Unlock()
+
+	health, ok := s.health[clusterName]
+	if !ok || health.Circuit == CircuitClosed {
+		return false, nil
+	}
+
+	now := time.Now()
+	if health.CircuitChangeTime == nil || now.Sub(*health.CircuitChangeTime) >= s.opts.CircuitBreakerResetTimeout {
+		log.Debugf("circuit breaker of cluster '%s' half-opened", clusterName)
+		health.Circuit = CircuitHalfOpen
+		health.CircuitChangeTime = &now
+		return true, nil
+	}
+
+	return false, status.Error(codes.Unavailable, fmt.Sprintf(
+		"cluster '%s' is unavailable after '%d' consecutive failures, last error: '%s'",
+		clusterName, health.ConsecutiveFailures, health.LastError))
+}
+
+// pruneClusterHealth drops the health state of the clusters removed from the secret
+func (s *service) pruneClusterHealth(isiClusters map[interface{}]interface{}) {
+	s.healthLock.Lock()
+	defer s.healthLock.Unlock()
+	for clusterName := range s.health {
+		if _, ok := isiClusters[clusterName]; !ok {
+			delete(s.health, clusterName)
+		}
+	}
+}
+
+// listClusterHealth returns the health state of the given clusters, sorted by cluster name
+func (s *service) listClusterHealth(isiClusters []*IsilonClusterConfig) []*ClusterHealth {
+	clusters := make([]*ClusterHealth, 0, len(isiClusters))
+	for _, isiConfig := range isiClusters {
+		health := s.getClusterHealth(isiConfig.ClusterName)
+		clusters = append(clusters, &health)
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i].ClusterName < clusters[j].ClusterName
+	})
+	return clusters
+}
+
+// formatClusterHealth formats the health state of the given clusters for the logs
+func (s *service) formatClusterHealth(isiClusters []*IsilonClusterConfig) string {
+	var states []string
+	for _, health := range s.listClusterHealth(isiClusters) {
+		states = append(states, fmt.Sprintf("'%s': %s (circuit %s)", health.ClusterName, health.State, health.Circuit))
+	}
+	return strings.Join(states, ", ")
+}
+
+// setProbeHealthHeader returns the health state of the given clusters in the header of the response of Probe, as
+// the CSI ProbeResponse has no field for it
+func (s *service) setProbeHealthHeader(ctx context.Context, isiClusters []*IsilonClusterConfig) {
+	log := utils.GetRunIDLogger(ctx)
+	var states []string
+	for _, health := range s.listClusterHealth(isiClusters) {
+		states = append(states, fmt.Sprintf("%s=%s,circuit=%s", health.ClusterName, health.State, health.Circuit))
+	}
+	// there is no response to set the header of when the clusters are probed on start
+	if err := grpc.SetHeader(ctx, metadata.MD{ProbeHealthHeader: states}); err != nil {
+		log.Debugf("health of the isilon clusters not set in the header of the response: '%v'", err)
+	}
+}
+
+// startClusterHealthProbes periodically probes all the clusters to refresh their health state
+func (s *service) startClusterHealthProbes(ctx context.Context) {
+	ctx, log := GetLogger(ctx)
+	if s.opts.HealthProbeInterval == 0 {
+		log.Info("background probes of the clusters are disabled")
+		return
+	}
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(s.opts.HealthProbeInterval):
+			}
+			s.probeClusterHealth(ctx)
+		}
+	}()
+}
+
+// probeClusterHealth probes all the clusters concurrently, so that an unreachable cluster does not delay the
+// others. The clients of the clusters which could not be created when the secret was loaded are created again.
+func (s *service) probeClusterHealth(ctx context.Context) {
+	ctx, log := GetLogger(ctx)
+	isiClusters := s.getIsilonClusters()
+
+	var wg sync.WaitGroup
+	for _, isiConfig := range isiClusters {
+		wg.Add(1)
+		go func(isiConfig *IsilonClusterConfig) {
+			defer wg.Done()
+			_ = s.probeCluster(ctx, isiConfig)
+		}(isiConfig)
+	}
+	wg.Wait()
+	log.Debugf("health of the isilon clusters: %s", s.formatClusterHealth(isiClusters))
+}
+
+// healthTransport records the requests to a cluster which could not reach it toward its circuit breaker: the
+// transport errors and the timeout and gateway statuses
+type healthTransport struct {
+	s           *service
+	clusterName string
+	transport   http.RoundTripper
+}
+
+// RoundTrip sends a request and records its result in the health state of the cluster, unless it is a request of
+// a probe, which records its own result, or it was canceled by the caller
+func (t *healthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.transport.RoundTrip(req)
+	ctx := req.Context()
+	if ctx.Value(clusterProbeKey{}) != nil || ctx.Err() != nil {
+		return res, err
+	}
+	failure := err
+	if err == nil {
+		switch res.StatusCode {
+		case http.StatusRequestTimeout, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			failure = fmt.Errorf("request '%s %s' failed with status '%s'", req.Method, req.URL.Path, res.Status)
+		}
+	}
+	t.s.recordClusterRequest(ctx, t.clusterName, failure)
+	return res, err
+}
+
+// GetClusterHealth returns the health state of a cluster, or of all the clusters
+func (s *service) GetClusterHealth(ctx context.Context, req *GetClusterHealthRequest) (*GetClusterHealthResponse, error) {
+	_, _, runID := GetRunIDLog(ctx)
+
+	isiClusters := s.getIsilonClusters()
+	if req.ClusterName != "" {
+		isiConfig := s.getIsilonClusterConfig(req.ClusterName)
+		if isiConfig == nil {
+			return nil, status.Error(codes.NotFound, utils.GetMessageWithRunID(runID, "cluster '%s' not found", req.ClusterName))
+		}
+		isiClusters = []*IsilonClusterConfig{isiConfig}
+	}
+	return &GetClusterHealthResponse{Clusters: s.listClusterHealth(isiClusters)}, nil
+}
+
+// startMetricsServer serves the health metrics of the clusters in the Prometheus text format, if an address is
+// configured
+func (s *service) startMetricsServer(ctx context.Context) {
+	ctx, log := GetLogger(ctx)
+	if s.opts.MetricsAddress == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(metricsPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writeClusterHealthMetrics(w, s.listClusterHealth(s.getIsilonClusters()))
+	})
+	server := &http.Server{Addr: s.opts.MetricsAddress, Handler: mux}
+
+	go func() {
+		log.Infof("serving the health metrics of the clusters on '%s%s'", s.opts.MetricsAddress, metricsPath)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Errorf("failed to serve the health metrics of the clusters: '%v'", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+}
+
+var metricsLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writeClusterHealthMetrics writes the health state of the clusters in the Prometheus text format
+func writeClusterHealthMetrics(w io.Writer, clusters []*ClusterHealth) {
+	metric := func(name, kind, help string, value func(health *ClusterHealth, label string) string) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		for _, health := range clusters {
+			label := fmt.Sprintf(`cluster="%s"`, metricsLabelEscaper.Replace(health.ClusterName))
+			fmt.Fprint(w, value(health, label))
+		}
+	}
+	enum := func(name, current string, values []string, label string) string {
+		var lines string
+		for _, value := range values {
+			set := 0
+			if value == current {
+				set = 1
+			}
+			lines += fmt.Sprintf("%s{%s,state=\"%s\"} %d\n", name, label, value, set)
+		}
+		return lines
+	}
+
+	metric("csi_isilon_cluster_health_state", "gauge", "Health state of the cluster.",
+		func(health *ClusterHealth, label string) string {
+			return enum("csi_isilon_cluster_health_state", health.State,
+				[]string{ClusterHealthUnknown, ClusterHealthHealthy, ClusterHealthDegraded, ClusterHealthUnavailable}, label)
+		})
+	metric("csi_isilon_cluster_circuit_breaker_state", "gauge", "State of the circuit breaker of the cluster.",
+		func(health *ClusterHealth, label string) string {
+			return enum("csi_isilon_cluster_circuit_breaker_state", health.Circuit,
+				[]string{CircuitClosed, CircuitOpen, CircuitHalfOpen}, label)
+		})
+	metric("csi_isilon_cluster_consecutive_probe_failures", "gauge", "Number of consecutive failed probes of the cluster.",
+		func(health *ClusterHealth, label string) string {
+			return fmt.Sprintf("csi_isilon_cluster_consecutive_probe_failures{%s} %d\n", label, health.ConsecutiveFailures)
+		})
+	metric("csi_isilon_cluster_probes_total", "counter", "Number of probes of the cluster.",
+		func(health *ClusterHealth, label string) string {
+			return fmt.Sprintf("csi_isilon_cluster_probes_total{%s} %d\n", label, health.Probes)
+		})
+	metric("csi_isilon_cluster_probe_failures_total", "counter", "Number of failed probes of the cluster.",
+		func(health *ClusterHealth, label string) string {
+			return fmt.Sprintf("csi_isilon_cluster_probe_failures_total{%s} %d\n", label, health.Failures)
+		})
+	metric("csi_isilon_cluster_last_probe_timestamp_seconds", "gauge", "Time of the last probe of the cluster.",
+		func(health *ClusterHealth, label string) string {
+			if health.LastProbeTime == nil {
+				return ""
+			}
+			return fmt.Sprintf("csi_isilon_cluster_last_probe_timestamp_seconds{%s} %d\n", label, health.LastProbeTime.Unix())
+		})
+}
@@ -0,0 +1,310 @@
+package service
+
+/*
+ Copyright (c) 2019 Dell Inc, or its subsidiaries.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/dell/csi-isilon/common/constants"
+	"github.com/dell/csi-isilon/common/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// Quota handling of an imported volume
+const (
+	// ImportQuotaNone leaves the directory without quota, or with the quota already recorded in its export
+	ImportQuotaNone = "none"
+	// ImportQuotaAdopt records the directory quota already set on the path in the export of the volume
+	ImportQuotaAdopt = "adopt"
+	// ImportQuotaCreate creates a directory quota of the requested size on the path
+	ImportQuotaCreate = "create"
+)
+
+// readWriteOncePod is the access mode of a PV used by a single pod, it isn't defined by the vendored core API
+const readWriteOncePod v1.PersistentVolumeAccessMode = "ReadWriteOncePod"
+
+// ImportVolumeRequest is the request of the ImportVolume admin RPC
+type ImportVolumeRequest struct {
+	// ClusterName is the name of the cluster of the directory, the default cluster if not set
+	ClusterName string `json:"clusterName,omitempty"`
+	// Path is the absolute path of the directory to import, e.g. /ifs/data/legacy/app1
+	Path string `json:"path"`
+	// AccessZone defaults to the access zone of the driver
+	AccessZone string `json:"accessZone,omitempty"`
+	// AzServiceIP defaults to the endpoint of the cluster
+	AzServiceIP string `json:"azServiceIP,omitempty"`
+	// Quota is either "none" (default), "adopt" or "create"
+	Quota string `json:"quota,omitempty"`
+	// SizeInBytes is the capacity of the volume, the hard threshold of the adopted quota by default
+	SizeInBytes       int64 `json:"sizeInBytes,omitempty"`
+	RootClientEnabled bool  `json:"rootClientEnabled,omitempty"`
+	// PVName, StorageClass, AccessModes, ReclaimPolicy and the claim are only used in the PV manifest
+	PVName         string   `json:"pvName,omitempty"`
+	StorageClass   string   `json:"storageClass,omitempty"`
+	AccessModes    []string `json:"accessModes,omitempty"`
+	ReclaimPolicy  string   `json:"reclaimPolicy,omitempty"`
+	ClaimName      string   `json:"claimName,omitempty"`
+	ClaimNamespace string   `json:"claimNamespace,omitempty"`
+}
+
+// ImportVolumeResponse is the response of the ImportVolume admin RPC
+type ImportVolumeResponse struct {
+	VolumeID      string            `json:"volumeId"`
+	ExportID      int               `json:"exportId"`
+	QuotaID       string            `json:"quotaId,omitempty"`
+	SizeInBytes   int64             `json:"sizeInBytes"`
+	VolumeContext map[string]string `json:"volumeContext"`
+	// PersistentVolume is the YAML manifest of a PV of the imported volume
+	PersistentVolume string `json:"persistentVolume"`
+}
+
+// ImportVolume brings an existing directory under the management of the driver: the directory gets an NFS export
+// with the localhost client, the same way as a volume created by CreateVolume, and optionally a quota recorded in
+// the description of the export. The export and quota already set on the directory are reused.
+func (s *service) ImportVolume(ctx context.Context, req *ImportVolumeRequest) (*ImportVolumeResponse, error) {
+	ctx, log, runID := GetRunIDLog(ctx)
+
+	dirPath := path.Clean(req.Path)
+	if req.Path == "" || !strings.HasPrefix(dirPath, "/ifs/") {
+		return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID, "invalid path '%s', an absolute path under /ifs is expected", req.Path))
+	}
+	quotaMode := strings.ToLower(req.Quota)
+	if quotaMode == "" {
+		quotaMode = ImportQuotaNone
+	}
+	if !utils.IsStringInSlice(quotaMode, []string{ImportQuotaNone, ImportQuotaAdopt, ImportQuotaCreate}) {
+		return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID,
+			"invalid quota '%s', '%s', '%s' or '%s' is expected", req.Quota, ImportQuotaNone, ImportQuotaAdopt, ImportQuotaCreate))
+	}
+	if req.SizeInBytes < 0 || (req.SizeInBytes == 0 && quotaMode != ImportQuotaAdopt) {
+		return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID, "a positive size is required unless a quota is adopted"))
+	}
+	accessModes, err := getPersistentVolumeAccessModes(req.AccessModes)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID, err.Error()))
+	}
+	reclaimPolicy := v1.PersistentVolumeReclaimRetain
+	if req.ReclaimPolicy != "" {
+		reclaimPolicy = v1.PersistentVolumeReclaimPolicy(req.ReclaimPolicy)
+		if reclaimPolicy != v1.PersistentVolumeReclaimRetain && reclaimPolicy != v1.PersistentVolumeReclaimDelete {
+			return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID,
+				"invalid reclaim policy '%s', '%s' or '%s' is expected", req.ReclaimPolicy, v1.PersistentVolumeReclaimRetain, v1.PersistentVolumeReclaimDelete))
+		}
+	}
+
+	clusterName := req.ClusterName
+	isiConfig, err := s.getIsilonConfig(ctx, &clusterName)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, utils.GetMessageWithRunID(runID, err.Error()))
+	}
+	if err := s.autoProbe(ctx, isiConfig); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, utils.GetMessageWithRunID(runID, "failed to probe cluster '%s': '%v'", clusterName, err))
+	}
+	accessZone := req.AccessZone
+	if accessZone == "" {
+		accessZone = s.opts.AccessZone
+	}
+	azServiceIP := req.AzServiceIP
+	if azServiceIP == "" || s.opts.CustomTopologyEnabled {
+		azServiceIP = isiConfig.IsiIP
+	}
+
+	isiPath, volName := path.Dir(dirPath), path.Base(dirPath)
+	if !isiConfig.isiSvc.IsVolumeExistent(ctx, isiPath, "", volName) {
+		return nil, status.Error(codes.NotFound, utils.GetMessageWithRunID(runID, "directory '%s' not found on cluster '%s'", dirPath, clusterName))
+	}
+
+	export, err := isiConfig.isiSvc.GetExportWithPathAndZone(ctx, dirPath, accessZone)
+	if err != nil {
+		return nil, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, err.Error()))
+	}
+	exportQuotaID := ""
+	if export != nil {
+		if export.Paths != nil && len(*export.Paths) > 1 {
+			return nil, status.Error(codes.FailedPrecondition, utils.GetMessageWithRunID(runID,
+				"export '%d' of directory '%s' has several paths, it cannot be imported", export.ID, dirPath))
+		}
+		if exportQuotaID, err = utils.GetQuotaIDFromDescription(ctx, export); err != nil {
+			return nil, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, err.Error()))
+		}
+	}
+
+	quota, err := isiConfig.isiSvc.GetDirectoryQuota(ctx, dirPath)
+	if err != nil {
+		return nil, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, err.Error()))
+	}
+	sizeInBytes := req.SizeInBytes
+	quotaID := exportQuotaID
+	createdQuota := false
+	switch quotaMode {
+	case ImportQuotaAdopt:
+		if quota == nil {
+			return nil, status.Error(codes.FailedPrecondition, utils.GetMessageWithRunID(runID, "no directory quota found on '%s' to adopt", dirPath))
+		}
+		quotaID = quota.Id
+		if sizeInBytes == 0 {
+			sizeInBytes = quota.Thresholds.Hard
+		}
+		if sizeInBytes <= 0 {
+			return nil, status.Error(codes.FailedPrecondition, utils.GetMessageWithRunID(runID,
+				"quota '%s' of '%s' has no hard threshold, a size is required", quota.Id, dirPath))
+		}
+	case ImportQuotaCreate:
+		if quota != nil {
+			return nil, status.Error(codes.AlreadyExists, utils.GetMessageWithRunID(runID,
+				"directory '%s' already has quota '%s', adopt it instead", dirPath, quota.Id))
+		}
+		if quotaID, err = isiConfig.isiSvc.CreateQuota(ctx, dirPath, volName, sizeInBytes, true); err != nil {
+			return nil, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, err.Error()))
+		}
+		createdQuota = quotaID != ""
+	}
+
+	var exportID int
+	if export == nil {
+		if exportID, err = isiConfig.isiSvc.ExportVolumeWithZone(ctx, isiPath, volName, accessZone, utils.GetQuotaIDWithCSITag(quotaID)); err != nil {
+			if createdQuota {
+				if err := isiConfig.isiSvc.ClearQuotaByID(ctx, quotaID); err != nil {
+					log.Infof("Clear Quota returned error '%s'", err)
+				}
+			}
+			return nil, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, "failed to export '%s' in access zone '%s': '%v'", dirPath, accessZone, err))
+		}
+	} else {
+		exportID = export.ID
+		log.Debugf("directory '%s' is already exported with id '%d'", dirPath, exportID)
+		if quotaID != exportQuotaID {
+			if err := isiConfig.isiSvc.SetExportDescription(ctx, exportID, accessZone, utils.GetQuotaIDWithCSITag(quotaID)); err != nil {
+				return nil, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, err.Error()))
+			}
+		}
+	}
+
+	// Add dummy localhost entry for pvc security
+	if !isiConfig.isiSvc.IsHostAlreadyAdded(ctx, exportID, accessZone, utils.DummyHostNodeID) {
+		if err := isiConfig.isiSvc.AddExportClientNetworkIdentifierByIDWithZone(ctx, exportID, accessZone, utils.DummyHostNodeID, isiConfig.isiSvc.AddExportClientByIDWithZone); err != nil {
+			return nil, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, "failed to add the localhost client to export '%d': '%v'", exportID, err))
+		}
+	}
+
+	volume := s.getCSIVolume(ctx, exportID, volName, dirPath, accessZone, sizeInBytes, azServiceIP,
+		strconv.FormatBool(req.RootClientEnabled), "", "", clusterName)
+	pvName := req.PVName
+	if pvName == "" {
+		pvName = volName
+	}
+	manifest, err := yaml.Marshal(newImportedPersistentVolume(pvName, volume, accessModes, reclaimPolicy, req))
+	if err != nil {
+		return nil, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, "failed to build the PV manifest: '%v'", err))
+	}
+
+	log.Infof("directory '%s' of cluster '%s' imported as volume '%s'", dirPath, clusterName, volume.GetVolumeId())
+	return &ImportVolumeResponse{
+		VolumeID:         volume.GetVolumeId(),
+		ExportID:         exportID,
+		QuotaID:          quotaID,
+		SizeInBytes:      sizeInBytes,
+		VolumeContext:    volume.GetVolumeContext(),
+		PersistentVolume: string(manifest),
+	}, nil
+}
+
+// getPersistentVolumeAccessModes parses the access modes of a PV, ReadWriteMany by default
+func getPersistentVolumeAccessModes(modes []string) ([]v1.PersistentVolumeAccessMode, error) {
+	if len(modes) == 0 {
+		return []v1.PersistentVolumeAccessMode{v1.ReadWriteMany}, nil
+	}
+	accessModes := make([]v1.PersistentVolumeAccessMode, 0, len(modes))
+	for _, mode := range modes {
+		switch accessMode := v1.PersistentVolumeAccessMode(mode); accessMode {
+		case v1.ReadWriteOnce, v1.ReadOnlyMany, v1.ReadWriteMany, readWriteOncePod:
+			accessModes = append(accessModes, accessMode)
+		default:
+			return nil, fmt.Errorf("invalid access mode '%s', '%s', '%s', '%s' or '%s' is expected", mode, v1.ReadWriteOnce, v1.ReadOnlyMany, v1.ReadWriteMany, readWriteOncePod)
+		}
+	}
+	return accessModes, nil
+}
+
+// newImportedPersistentVolume returns a PV of an imported volume, which binds to the given claim if any
+func newImportedPersistentVolume(pvName string, volume *csi.Volume, accessModes []v1.PersistentVolumeAccessMode,
+	reclaimPolicy v1.PersistentVolumeReclaimPolicy, req *ImportVolumeRequest) *v1.PersistentVolume {
+	pv := &v1.PersistentVolume{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "PersistentVolume",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: pvName,
+		},
+		Spec: v1.PersistentVolumeSpec{
+			Capacity: v1.ResourceList{
+				v1.ResourceStorage: *resource.NewQuantity(volume.GetCapacityBytes(), resource.BinarySI),
+			},
+			AccessModes:                   accessModes,
+			PersistentVolumeReclaimPolicy: reclaimPolicy,
+			StorageClassName:              req.StorageClass,
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				CSI: &v1.CSIPersistentVolumeSource{
+					Driver:           constants.PluginName,
+					VolumeHandle:     volume.GetVolumeId(),
+					VolumeAttributes: volume.GetVolumeContext(),
+				},
+			},
+		},
+	}
+	// restrict the PV to the nodes having access to its cluster, as the external-provisioner does for new volumes
+	if topologies := volume.GetAccessibleTopology(); len(topologies) > 0 {
+		var terms []v1.NodeSelectorTerm
+		for _, topology := range topologies {
+			var term v1.NodeSelectorTerm
+			for key, value := range topology.GetSegments() {
+				term.MatchExpressions = append(term.MatchExpressions, v1.NodeSelectorRequirement{
+					Key:      key,
+					Operator: v1.NodeSelectorOpIn,
+					Values:   []string{value},
+				})
+			}
+			terms = append(terms, term)
+		}
+		pv.Spec.NodeAffinity = &v1.VolumeNodeAffinity{
+			Required: &v1.NodeSelector{NodeSelectorTerms: terms},
+		}
+	}
+	if req.ClaimName != "" {
+		namespace := req.ClaimNamespace
+		if namespace == "" {
+			namespace = metav1.NamespaceDefault
+		}
+		pv.Spec.ClaimRef = &v1.ObjectReference{
+			Kind:      "PersistentVolumeClaim",
+			Name:      req.ClaimName,
+			Namespace: namespace,
+		}
+	}
+	return pv
+}
@@ -19,6 +19,7 @@ import (
 	"context"
 	"fmt"
 	"path"
+	"strconv"
 	"strings"
 
 	"github.com/dell/csi-isilon/common/constants"
@@ -26,8 +27,14 @@ import (
 	utils "github.com/dell/csi-isilon/common/utils"
 	isi "github.com/dell/goisilon"
 	"github.com/dell/goisilon/api"
+	apiv1 "github.com/dell/goisilon/api/v1"
+	apiv2 "github.com/dell/goisilon/api/v2"
 )
 
+const quotasPath = "platform/1/quota/quotas"
+
+const exportsPath = "platform/2/protocols/nfs/exports"
+
 type isiService struct {
 	endpoint string
 	client   *isi.Client
@@ -65,7 +72,7 @@ func (svc *isiService) CopyVolume(ctx context.Context, isiPath, srcVolumeName, d
 	return volumeNew, nil
 }
 
-func (svc *isiService) CreateSnapshot(ctx context.Context, path, snapshotName string) (isi.Snapshot, error) {
+func (svc *isiService) CreateSnapshot(ctx context.Context, path, snapshotName string, settings *snapshotSettings) (isi.Snapshot, error) {
 	// Fetch log handler
 	log := utils.GetRunIDLogger(ctx)
 
@@ -73,7 +80,12 @@ func (svc *isiService) CreateSnapshot(ctx context.Context, path, snapshotName st
 
 	var snapshot isi.Snapshot
 	var err error
-	if snapshot, err = svc.client.CreateSnapshotWithPath(ctx, path, snapshotName); err != nil {
+	if settings != nil {
+		snapshot, err = svc.createSnapshotWithSettings(ctx, path, snapshotName, settings)
+	} else {
+		snapshot, err = svc.client.CreateSnapshotWithPath(ctx, path, snapshotName)
+	}
+	if err != nil {
 		log.Errorf("create snapshot failed, '%s'", err.Error())
 		return nil, err
 	}
@@ -275,6 +287,69 @@ func (svc *isiService) GetVolumeQuota(ctx context.Context, volName string, expor
 	return svc.client.GetQuotaByID(ctx, quotaID)
 }
 
+// GetDirectoryQuota returns the directory quota set on the given path, nil if there is none
+func (svc *isiService) GetDirectoryQuota(ctx context.Context, dirPath string) (isi.Quota, error) {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("begin to get directory quota of path '%s'", dirPath)
+	params := api.OrderedValues{
+		{[]byte("path"), []byte(dirPath)},
+		{[]byte("type"), []byte("directory")},
+	}
+	var resp struct {
+		Quotas []apiv1.IsiQuota `json:"quotas"`
+	}
+	if err := svc.client.API.Get(ctx, quotasPath, "", params, nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get directory quota of path '%s' : '%v'", dirPath, err)
+	}
+	for i := range resp.Quotas {
+		if resp.Quotas[i].Path == dirPath {
+			return &resp.Quotas[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// GetDirectoryQuotasUnderPath returns the directory quotas set on the given path and on the directories below it
+func (svc *isiService) GetDirectoryQuotasUnderPath(ctx context.Context, dirPath string) ([]apiv1.IsiQuota, error) {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("begin to get the directory quotas under path '%s'", dirPath)
+	params := api.OrderedValues{
+		{[]byte("path"), []byte(dirPath)},
+		{[]byte("recurse_path_children"), []byte("true")},
+		{[]byte("type"), []byte("directory")},
+	}
+	var resp struct {
+		Quotas []apiv1.IsiQuota `json:"quotas"`
+	}
+	if err := svc.client.API.Get(ctx, quotasPath, "", params, nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get the directory quotas under path '%s' : '%v'", dirPath, err)
+	}
+	return resp.Quotas, nil
+}
+
+// GetSnapshotsUnderPath returns the snapshots of the given path and of the directories below it
+func (svc *isiService) GetSnapshotsUnderPath(ctx context.Context, dirPath string) (isi.SnapshotList, error) {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("begin to get the snapshots under path '%s'", dirPath)
+	snapshots, err := svc.client.GetSnapshots(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snapshots : '%v'", err)
+	}
+	snapshotsUnderPath := make(isi.SnapshotList, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		if utils.IsPathUnder(snapshot.Path, dirPath) {
+			snapshotsUnderPath = append(snapshotsUnderPath, snapshot)
+		}
+	}
+	return snapshotsUnderPath, nil
+}
+
 func (svc *isiService) UpdateQuotaSize(ctx context.Context, quotaID string, updatedSize int64) error {
 	// Fetch log handler
 	log := utils.GetRunIDLogger(ctx)
@@ -288,6 +363,21 @@ func (svc *isiService) UpdateQuotaSize(ctx context.Context, quotaID string, upda
 	return nil
 }
 
+// UpdateQuotaThresholds replaces the thresholds of a quota
+func (svc *isiService) UpdateQuotaThresholds(ctx context.Context, quotaID string, thresholds *quotaThresholds) error {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("begin to update the thresholds of quota '%s' to '%+v'", quotaID, *thresholds)
+	body := struct {
+		Thresholds *quotaThresholds `json:"thresholds"`
+	}{thresholds}
+	if err := svc.client.API.Put(ctx, quotasPath, quotaID, nil, nil, body, nil); err != nil {
+		return fmt.Errorf("failed to update the thresholds of quota '%s' : '%v'", quotaID, err)
+	}
+	return nil
+}
+
 func (svc *isiService) UnexportByIDWithZone(ctx context.Context, exportID int, accessZone string) error {
 	// Fetch log handler
 	log := utils.GetRunIDLogger(ctx)
@@ -315,12 +405,465 @@ func (svc *isiService) GetExportsWithParams(ctx context.Context, params api.Orde
 	return exports, nil
 }
 
+func (svc *isiService) GetExportsWithZone(ctx context.Context, accessZone string) (isi.ExportList, error) {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("begin to get exports in access zone '%s'", accessZone)
+	var exportList isi.ExportList
+	params := api.OrderedValues{
+		{[]byte("zone"), []byte(accessZone)},
+	}
+	for {
+		exports, err := svc.client.GetExportsWithParams(ctx, params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get exports in access zone '%s' : '%s'", accessZone, err.Error())
+		}
+		exportList = append(exportList, exports.Exports...)
+		if exports.Resume == "" {
+			break
+		}
+		params = api.OrderedValues{
+			{[]byte("resume"), []byte(exports.Resume)},
+		}
+	}
+	return exportList, nil
+}
+
+func (svc *isiService) CreateVolumeWithAccessControl(ctx context.Context, isiPath, volName, accessControl string, metadata map[string]string) error {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("begin to create volume '%s' with access control '%s'", volName, accessControl)
+	log.Debugf("header metadata '%v'", metadata)
+
+	if _, err := apiv1.CreateIsiVolumeWithACLAndIsiPathMetaData(ctx, svc.client.API, isiPath, volName, accessControl, metadata); err != nil {
+		log.Errorf("create volume failed, '%s'", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (svc *isiService) GetVolumeACL(ctx context.Context, dirPath string) (*volumeACL, error) {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("begin to get ACL of directory '%s'", dirPath)
+
+	var acl volumeACL
+	if err := svc.client.API.Get(ctx, path.Join(namespacePath, path.Dir(dirPath)), path.Base(dirPath), aclQueryParams, nil, &acl); err != nil {
+		return nil, fmt.Errorf("failed to get ACL of directory '%s' : '%v'", dirPath, err)
+	}
+
+	return &acl, nil
+}
+
+func (svc *isiService) SetVolumeACL(ctx context.Context, dirPath string, acl *volumeACL) error {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("begin to set ACL of directory '%s' to '%+v'", dirPath, *acl)
+
+	if err := svc.client.API.Put(ctx, path.Join(namespacePath, path.Dir(dirPath)), path.Base(dirPath), aclQueryParams, nil, acl, nil); err != nil {
+		return fmt.Errorf("failed to set ACL of directory '%s' : '%v'", dirPath, err)
+	}
+
+	return nil
+}
+
+// GetPersonaID resolves a user or group name to its UID or GID in the given access zone
+func (svc *isiService) GetPersonaID(ctx context.Context, personaType, name, accessZone string) (string, error) {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("begin to look up %s '%s' in access zone '%s'", personaType, name, accessZone)
+
+	params := api.OrderedValues{
+		{[]byte("zone"), []byte(accessZone)},
+	}
+	var resp struct {
+		Users []struct {
+			UID volumePersona `json:"uid"`
+		} `json:"users,omitempty"`
+		Groups []struct {
+			GID volumePersona `json:"gid"`
+		} `json:"groups,omitempty"`
+	}
+	if err := svc.client.API.Get(ctx, authPath+personaType+"s", name, params, nil, &resp); err != nil {
+		return "", fmt.Errorf("failed to look up %s '%s' in access zone '%s' : '%v'", personaType, name, accessZone, err)
+	}
+
+	if personaType == personaTypeUser && len(resp.Users) > 0 && resp.Users[0].UID.ID != "" {
+		return resp.Users[0].UID.ID, nil
+	}
+	if personaType == personaTypeGroup && len(resp.Groups) > 0 && resp.Groups[0].GID.ID != "" {
+		return resp.Groups[0].GID.ID, nil
+	}
+	return "", fmt.Errorf("%s '%s' not found in access zone '%s'", personaType, name, accessZone)
+}
+
+// GetWormDomains returns all the SmartLock domains of the cluster
+func (svc *isiService) GetWormDomains(ctx context.Context) ([]wormDomain, error) {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debug("begin to get SmartLock domains")
+	var domains []wormDomain
+	var params api.OrderedValues
+	for {
+		var resp wormDomainList
+		if err := svc.client.API.Get(ctx, wormDomainsPath, "", params, nil, &resp); err != nil {
+			return nil, fmt.Errorf("failed to get SmartLock domains : '%w'", err)
+		}
+		domains = append(domains, resp.Domains...)
+		if resp.Resume == "" {
+			break
+		}
+		params = api.OrderedValues{
+			{[]byte("resume"), []byte(resp.Resume)},
+		}
+	}
+	return domains, nil
+}
+
+// CreateWormDomain creates a SmartLock domain on an empty directory
+func (svc *isiService) CreateWormDomain(ctx context.Context, domain *wormDomain) error {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("begin to create SmartLock domain '%+v'", *domain)
+	var resp struct {
+		ID int64 `json:"id"`
+	}
+	if err := svc.client.API.Post(ctx, wormDomainsPath, "", nil, nil, domain, &resp); err != nil {
+		return fmt.Errorf("failed to create SmartLock domain on directory '%s' : '%v'", domain.Path, err)
+	}
+	log.Infof("SmartLock domain '%d' created on directory '%s'", resp.ID, domain.Path)
+	return nil
+}
+
+// GetDirectoryChildren returns the files and directories directly under the given directory
+func (svc *isiService) GetDirectoryChildren(ctx context.Context, dirPath string) ([]directoryChild, error) {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("begin to list directory '%s'", dirPath)
+	var children []directoryChild
+	params := api.OrderedValues{
+		{[]byte("detail"), []byte("type")},
+	}
+	for {
+		var resp directoryChildren
+		if err := svc.client.API.Get(ctx, path.Join(namespacePath, path.Dir(dirPath)), path.Base(dirPath), params, nil, &resp); err != nil {
+			return nil, err
+		}
+		children = append(children, resp.Children...)
+		if resp.Resume == "" {
+			break
+		}
+		params = api.OrderedValues{
+			{[]byte("detail"), []byte("type")},
+			{[]byte("resume"), []byte(resp.Resume)},
+		}
+	}
+	return children, nil
+}
+
+// GetFileWormStatus returns the SmartLock commit and retention state of a file
+func (svc *isiService) GetFileWormStatus(ctx context.Context, filePath string) (*fileWormStatus, error) {
+	var status fileWormStatus
+	if err := svc.client.API.Get(ctx, path.Join(namespacePath, path.Dir(filePath)), path.Base(filePath), wormQueryParams, nil, &status); err != nil {
+		return nil, fmt.Errorf("failed to get SmartLock status of file '%s' : '%v'", filePath, err)
+	}
+	return &status, nil
+}
+
+// GetDirectoryAttributes returns the attributes of the given directory
+func (svc *isiService) GetDirectoryAttributes(ctx context.Context, dirPath string) (*directoryAttributes, error) {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("begin to get the attributes of directory '%s'", dirPath)
+	var attrs directoryAttributes
+	if err := svc.client.API.Get(ctx, path.Join(namespacePath, path.Dir(dirPath)), path.Base(dirPath), metadataQueryParams, nil, &attrs); err != nil {
+		return nil, fmt.Errorf("failed to get the attributes of directory '%s' : '%v'", dirPath, err)
+	}
+	return &attrs, nil
+}
+
+// SetDirectoryAttributes updates the given attributes of a directory, its other attributes are left unchanged
+func (svc *isiService) SetDirectoryAttributes(ctx context.Context, dirPath string, attrs []directoryAttribute) error {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("begin to set the attributes of directory '%s' to '%+v'", dirPath, attrs)
+	req := &directoryAttributes{Action: attributesActionUpdate, Attrs: attrs}
+	if err := svc.client.API.Put(ctx, path.Join(namespacePath, path.Dir(dirPath)), path.Base(dirPath), metadataQueryParams, nil, req, nil); err != nil {
+		return fmt.Errorf("failed to set the attributes of directory '%s' : '%v'", dirPath, err)
+	}
+	return nil
+}
+
+func (svc *isiService) GetSnapshotSchedule(ctx context.Context, name string) (*snapshotSchedule, error) {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("begin to get snapshot schedule '%s'", name)
+	var resp snapshotScheduleList
+	if err := svc.client.API.Get(ctx, snapshotSchedulesPath, name, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Schedules) == 0 {
+		return nil, fmt.Errorf("snapshot schedule '%s' not found", name)
+	}
+	return &resp.Schedules[0], nil
+}
+
+func (svc *isiService) CreateSnapshotSchedule(ctx context.Context, schedule *snapshotSchedule) error {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("begin to create snapshot schedule '%+v'", *schedule)
+	if err := svc.client.API.Post(ctx, snapshotSchedulesPath, "", nil, nil, schedule, nil); err != nil {
+		return fmt.Errorf("failed to create snapshot schedule '%s' : '%v'", schedule.Name, err)
+	}
+	return nil
+}
+
+func (svc *isiService) UpdateSnapshotSchedule(ctx context.Context, schedule *snapshotSchedule) error {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("begin to update snapshot schedule '%+v'", *schedule)
+	update := *schedule
+	// the name identifies the schedule and the path of a schedule cannot be changed
+	update.Name, update.Path = "", ""
+	if err := svc.client.API.Put(ctx, snapshotSchedulesPath, schedule.Name, nil, nil, &update, nil); err != nil {
+		return fmt.Errorf("failed to update snapshot schedule '%s' : '%v'", schedule.Name, err)
+	}
+	return nil
+}
+
+func (svc *isiService) DeleteSnapshotSchedule(ctx context.Context, name string) error {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("begin to delete snapshot schedule '%s'", name)
+	return svc.client.API.Delete(ctx, snapshotSchedulesPath, name, nil, nil, nil)
+}
+
+func (svc *isiService) CreateWritableSnapshot(ctx context.Context, srcSnapshot, dstPath string) error {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("begin to create writable snapshot '%s' of snapshot '%s'", dstPath, srcSnapshot)
+	snapshot := &writableSnapshot{SrcSnap: srcSnapshot, DstPath: dstPath}
+	return svc.client.API.Post(ctx, writableSnapshotsPath, "", nil, nil, snapshot, nil)
+}
+
+// GetWritableSnapshot returns the writable snapshot created at dstPath, a not found error if dstPath isn't
+// a writable snapshot or if the cluster doesn't support them
+func (svc *isiService) GetWritableSnapshot(ctx context.Context, dstPath string) (*writableSnapshot, error) {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("begin to get writable snapshot '%s'", dstPath)
+	var resp writableSnapshotList
+	if err := svc.client.API.Get(ctx, writableSnapshotsPath, strings.TrimPrefix(dstPath, "/"), nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Writable) == 0 {
+		return nil, fmt.Errorf("writable snapshot '%s' not found", dstPath)
+	}
+	return &resp.Writable[0], nil
+}
+
+func (svc *isiService) DeleteWritableSnapshot(ctx context.Context, dstPath string) error {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("begin to delete writable snapshot '%s'", dstPath)
+	if err := svc.client.API.Delete(ctx, writableSnapshotsPath, strings.TrimPrefix(dstPath, "/"), nil, nil, nil); err != nil {
+		return fmt.Errorf("failed to delete writable snapshot '%s' : '%v'", dstPath, err)
+	}
+	return nil
+}
+
+// GetWritableSnapshots returns the writable snapshots of the cluster, a not found error if the cluster doesn't
+// support them
+func (svc *isiService) GetWritableSnapshots(ctx context.Context) ([]writableSnapshot, error) {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("begin to get writable snapshots")
+	var resp writableSnapshotList
+	if err := svc.client.API.Get(ctx, writableSnapshotsPath, "", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Writable, nil
+}
+
+// LockSnapshot locks a snapshot with the given comment, unless it already holds a lock with this comment
+func (svc *isiService) LockSnapshot(ctx context.Context, snapshotID, comment string) error {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	locks, err := svc.getSnapshotLocks(ctx, snapshotID)
+	if err != nil {
+		return err
+	}
+	for _, lock := range locks {
+		if lock.Comment == comment {
+			return nil
+		}
+	}
+	log.Debugf("begin to lock snapshot '%s' with comment '%s'", snapshotID, comment)
+	return svc.client.API.Post(ctx, path.Join(snapshotsPath, snapshotID, "locks"), "", nil, nil, &snapshotLock{Comment: comment}, nil)
+}
+
+// UnlockSnapshot deletes the locks of a snapshot with the given comment
+func (svc *isiService) UnlockSnapshot(ctx context.Context, snapshotID, comment string) error {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	locks, err := svc.getSnapshotLocks(ctx, snapshotID)
+	if err != nil {
+		return err
+	}
+	for _, lock := range locks {
+		if lock.Comment != comment {
+			continue
+		}
+		log.Debugf("begin to delete lock '%d' of snapshot '%s'", lock.ID, snapshotID)
+		if err := svc.client.API.Delete(ctx, path.Join(snapshotsPath, snapshotID, "locks"), strconv.FormatInt(lock.ID, 10), nil, nil, nil); err != nil && !isNotFoundError(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (svc *isiService) getSnapshotLocks(ctx context.Context, snapshotID string) ([]snapshotLock, error) {
+	var resp snapshotLockList
+	if err := svc.client.API.Get(ctx, path.Join(snapshotsPath, snapshotID, "locks"), "", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Locks, nil
+}
+
+// StartJob starts a OneFS job and returns its ID
+func (svc *isiService) StartJob(ctx context.Context, job *jobCreation) (int64, error) {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("begin to start job '%+v'", *job)
+	var resp struct {
+		ID int64 `json:"id"`
+	}
+	if err := svc.client.API.Post(ctx, jobsPath, "", nil, nil, job, &resp); err != nil {
+		return 0, fmt.Errorf("failed to start '%s' job : '%v'", job.Type, err)
+	}
+	return resp.ID, nil
+}
+
+func (svc *isiService) GetJob(ctx context.Context, jobID int64) (*job, error) {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("begin to get job '%d'", jobID)
+	var resp jobList
+	if err := svc.client.API.Get(ctx, jobsPath, strconv.FormatInt(jobID, 10), nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Jobs) == 0 {
+		return nil, fmt.Errorf("job '%d' not found", jobID)
+	}
+	return &resp.Jobs[0], nil
+}
+
+func (svc *isiService) CreateSyncPolicy(ctx context.Context, policy *syncPolicy) error {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("begin to create SyncIQ policy '%+v'", *policy)
+	if err := svc.client.API.Post(ctx, syncPoliciesPath, "", nil, nil, policy, nil); err != nil {
+		return fmt.Errorf("failed to create SyncIQ policy '%s' : '%v'", policy.Name, err)
+	}
+	return nil
+}
+
+// DeleteSyncPolicy deletes the SyncIQ policy of the given name along with its association on the target cluster,
+// the error is returned as is so that a 404 can be told apart
+func (svc *isiService) DeleteSyncPolicy(ctx context.Context, name string) error {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("begin to delete SyncIQ policy '%s'", name)
+	return svc.client.API.Delete(ctx, syncPoliciesPath, name, nil, nil, nil)
+}
+
+func (svc *isiService) StartSyncJob(ctx context.Context, policyName string) error {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("begin to start SyncIQ job of policy '%s'", policyName)
+	if err := svc.client.API.Post(ctx, syncJobsPath, "", nil, nil, &syncJob{ID: policyName}, nil); err != nil {
+		return fmt.Errorf("failed to start SyncIQ job of policy '%s' : '%v'", policyName, err)
+	}
+	return nil
+}
+
+// GetSyncJob returns the running SyncIQ job of the given policy, the error is returned as is so that a 404,
+// returned once the job is over, can be told apart
+func (svc *isiService) GetSyncJob(ctx context.Context, policyName string) (*syncJob, error) {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("begin to get SyncIQ job of policy '%s'", policyName)
+	var resp syncJobList
+	if err := svc.client.API.Get(ctx, syncJobsPath, policyName, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Jobs) == 0 {
+		return nil, fmt.Errorf("SyncIQ job of policy '%s' not found", policyName)
+	}
+	return &resp.Jobs[0], nil
+}
+
+// GetLatestSyncReport returns the report of the last SyncIQ job of the given policy, nil if there is none
+func (svc *isiService) GetLatestSyncReport(ctx context.Context, policyName string) (*syncJob, error) {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("begin to get the latest SyncIQ report of policy '%s'", policyName)
+	params := api.OrderedValues{
+		{[]byte("policy_name"), []byte(policyName)},
+		{[]byte("reports_per_policy"), []byte("1")},
+	}
+	var resp syncReportList
+	if err := svc.client.API.Get(ctx, syncReportsPath, "", params, nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to get SyncIQ reports of policy '%s' : '%v'", policyName, err)
+	}
+	if len(resp.Reports) == 0 {
+		return nil, nil
+	}
+	return &resp.Reports[0], nil
+}
+
 func (svc *isiService) DeleteVolume(ctx context.Context, isiPath, volName string) error {
 	// Fetch log handler
 	log := utils.GetRunIDLogger(ctx)
 
 	log.Debugf("begin to delete volume directory '%s'", volName)
 
+	// the directory of a volume restored as a writable snapshot is removed along with the writable snapshot
+	volPath := utils.GetPathForVolume(isiPath, volName)
+	if _, err := svc.GetWritableSnapshot(ctx, volPath); err == nil {
+		return svc.DeleteWritableSnapshot(ctx, volPath)
+	} else if !isNotFoundError(err) {
+		log.Debugf("failed to check whether '%s' is a writable snapshot, delete it as a directory: '%v'", volPath, err)
+	}
+
 	if err := svc.client.DeleteVolumeWithIsiPath(ctx, isiPath, volName); err != nil {
 		return fmt.Errorf("failed to delete volume directory '%v' : '%v'", volName, err)
 	}
@@ -352,6 +895,41 @@ func (svc *isiService) TestConnection(ctx context.Context) error {
 	return nil
 }
 
+// GetZoneByName returns the access zone with the given name, nil if it does not exist
+func (svc *isiService) GetZoneByName(ctx context.Context, accessZone string) (*apiv1.IsiZone, error) {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("begin to get access zone '%s'", accessZone)
+
+	zone, err := svc.client.GetZoneByName(ctx, accessZone)
+	if err != nil {
+		if isNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get access zone '%s' : '%v'", accessZone, err)
+	}
+
+	return zone, nil
+}
+
+// IsDirectoryExistent checks whether a directory exists
+func (svc *isiService) IsDirectoryExistent(ctx context.Context, dirPath string) (bool, error) {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("begin to check the existence of directory '%s'", dirPath)
+
+	if err := svc.client.API.Get(ctx, path.Join(namespacePath, path.Dir(dirPath)), path.Base(dirPath), metadataQueryParams, nil, nil); err != nil {
+		if isNotFoundError(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get directory '%s' : '%v'", dirPath, err)
+	}
+
+	return true, nil
+}
+
 func (svc *isiService) GetNFSExportURLForPath(ip string, dirPath string) string {
 	return fmt.Sprintf("%s:%s", ip, dirPath)
 }
@@ -397,7 +975,9 @@ func (svc *isiService) GetStatistics(ctx context.Context, keys []string) (isi.St
 		log.Errorf("failed to get array statistics '%s'", err)
 		return nil, err
 	}
-	fmt.Printf("Available capacity: %+v\n", stat.StatsList[0])
+	if len(stat.StatsList) > 0 {
+		fmt.Printf("Available capacity: %+v\n", stat.StatsList[0])
+	}
 	return stat, nil
 }
 
@@ -464,6 +1044,35 @@ func (svc *isiService) OtherClientsAlreadyAdded(ctx context.Context, exportID in
 	return clientFieldsNotEmpty && !isNodeInClientFields && !isNodeFQDNInClientFields
 }
 
+// GetNodeExportAccess returns whether a node is a read-only client of an export, and whether it is
+// a read-write client, i.e. in the clients, read-write clients or root clients of the export
+func (svc *isiService) GetNodeExportAccess(ctx context.Context, exportID int, accessZone, nodeID string) (readOnly, readWrite bool) {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	export, _ := svc.GetExportByIDWithZone(ctx, exportID, accessZone)
+	if export == nil {
+		log.Debugf("failed to get export by id '%d' with access zone '%s', the node is considered as not added", exportID, accessZone)
+		return false, false
+	}
+
+	clientName, clientFQDN, clientIP, err := utils.ParseNodeID(ctx, nodeID)
+	if err != nil {
+		log.Debugf("failed to parse node ID '%s', the node is considered as not added", nodeID)
+		return false, false
+	}
+
+	isNodeInClients := func(clients ...[]string) bool {
+		return utils.IsStringInSlices(clientName, clients...) || utils.IsStringInSlices(clientFQDN, clients...) ||
+			clientIP != "" && utils.IsStringInSlices(clientIP, clients...)
+	}
+
+	readOnly = isNodeInClients(*export.ReadOnlyClients)
+	readWrite = isNodeInClients(*export.Clients, *export.ReadWriteClients, *export.RootClients)
+	log.Debugf("node '%s' is a read-only client of export '%d' : '%v', a read-write client : '%v'", nodeID, exportID, readOnly, readWrite)
+	return readOnly, readWrite
+}
+
 func (svc *isiService) AddExportClientNetworkIdentifierByIDWithZone(ctx context.Context, exportID int, accessZone, nodeID string, addClientFunc func(ctx context.Context, exportID int, accessZone, clientIP string) error) error {
 	// Fetch log handler
 	log := utils.GetRunIDLogger(ctx)
@@ -641,6 +1250,49 @@ func (svc *isiService) GetExportWithPathAndZone(ctx context.Context, path, acces
 	return export, nil
 }
 
+// SetExportDescription replaces the description of an export, e.g. to record the quota ID of the exported directory
+func (svc *isiService) SetExportDescription(ctx context.Context, exportID int, accessZone, description string) error {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("begin to set the description of export '%d' with access zone '%s' to '%s'", exportID, accessZone, description)
+	if err := apiv2.ExportUpdateWithZone(ctx, svc.client.API, &apiv2.Export{ID: exportID, Description: description}, accessZone); err != nil {
+		return fmt.Errorf("failed to set the description of export '%d' : '%v'", exportID, err)
+	}
+
+	return nil
+}
+
+// SetExportSecurityFlavors replaces the security flavors of an export, e.g. 'unix' or 'krb5p'
+func (svc *isiService) SetExportSecurityFlavors(ctx context.Context, exportID int, accessZone string, flavors []string) error {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("begin to set the security flavors of export '%d' with access zone '%s' to '%v'", exportID, accessZone, flavors)
+	params := api.OrderedValues{
+		{[]byte("zone"), []byte(accessZone)},
+	}
+	body := struct {
+		SecurityFlavors []string `json:"security_flavors"`
+	}{flavors}
+	if err := svc.client.API.Put(ctx, exportsPath, strconv.Itoa(exportID), params, nil, body, nil); err != nil {
+		return fmt.Errorf("failed to set the security flavors of export '%d' : '%v'", exportID, err)
+	}
+	return nil
+}
+
+// SetExportRootClients replaces the root clients of an export
+func (svc *isiService) SetExportRootClients(ctx context.Context, exportID int, accessZone string, rootClients []string) error {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("begin to set the root clients of export '%d' with access zone '%s' to '%v'", exportID, accessZone, rootClients)
+	if err := apiv2.ExportUpdateWithZone(ctx, svc.client.API, &apiv2.Export{ID: exportID, RootClients: &rootClients}, accessZone); err != nil {
+		return fmt.Errorf("failed to set the root clients of export '%d' : '%v'", exportID, err)
+	}
+	return nil
+}
+
 func (svc *isiService) GetSnapshotIsiPath(ctx context.Context, isiPath string, sourceSnapshotID string) (string, error) {
 	return svc.client.GetSnapshotIsiPath(ctx, isiPath, sourceSnapshotID)
 }
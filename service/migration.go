@@ -0,0 +1,1244 @@
+package service
+
+/*
+ Copyright (c) 2019 Dell Inc, or its subsidiaries.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/dell/csi-isilon/common/constants"
+	"github.com/dell/csi-isilon/common/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// Copy methods of a volume migration
+const (
+	// MigrationCopySyncIQ copies the data with a SyncIQ policy run from the source cluster
+	MigrationCopySyncIQ = "synciq"
+	// MigrationCopyRsync copies the data with a Kubernetes job running rsync on a node mounting both exports
+	MigrationCopyRsync = "rsync"
+)
+
+// Phases of a volume migration
+const (
+	MigrationPhasePlanned          = "Planned"
+	MigrationPhasePreparingTarget  = "PreparingTarget"
+	MigrationPhaseCopyingData      = "CopyingData"
+	MigrationPhaseSwitchingVolume  = "SwitchingVolume"
+	MigrationPhaseCleaningUpSource = "CleaningUpSource"
+	MigrationPhaseCompleted        = "Completed"
+	MigrationPhaseFailed           = "Failed"
+)
+
+const (
+	syncPoliciesPath       = "platform/1/sync/policies"
+	syncJobsPath           = "platform/1/sync/jobs"
+	syncReportsPath        = "platform/1/sync/reports"
+	syncPolicyActionCopy   = "copy"
+	syncJobStateFinished   = "finished"
+	migrationPolicyPrefix  = "csi-migrate-"
+	migrationJobPrefix     = "csi-isilon-migrate-"
+	migratedFromAnnotation = constants.PluginName + "/migrated-from"
+	migrationAnnotation    = constants.PluginName + "/migration"
+	migrationSourceMount   = "/source"
+	migrationTargetMount   = "/target"
+
+	// annotations of a bound PVC which are set by the PV controller
+	bindCompletedAnnotation     = "pv.kubernetes.io/bind-completed"
+	boundByControllerAnnotation = "pv.kubernetes.io/bound-by-controller"
+)
+
+var (
+	// interval between two polls of the SyncIQ or rsync job copying the data of a volume, or of the PV and PVC
+	// being switched to the target volume
+	migrationPollInterval = 10 * time.Second
+
+	// time given to the PV and PVC of a migrated volume to be deleted, and to their replacements to be created
+	migrationSwitchTimeout = 5 * time.Minute
+
+	// time given to the copy of the data of a migrated volume unless set by X_CSI_ISI_MIGRATION_COPY_TIMEOUT
+	defaultMigrationCopyTimeout = 24 * time.Hour
+
+	// states of a SyncIQ job which will not finish without an intervention
+	syncJobFailedStates = []string{"failed", "canceled", "needs_attention", "unknown"}
+)
+
+// MigrateVolumeRequest is the request of the MigrateVolume admin RPC
+type MigrateVolumeRequest struct {
+	// VolumeID is the normalized ID of the volume to migrate
+	VolumeID string `json:"volumeId"`
+	// TargetCluster is the name of the cluster to migrate the volume to
+	TargetCluster string `json:"targetCluster"`
+	// TargetAccessZone and TargetIsiPath default to the access zone of the driver and the isiPath of the target cluster
+	TargetAccessZone string `json:"targetAccessZone,omitempty"`
+	TargetIsiPath    string `json:"targetIsiPath,omitempty"`
+	// CopyMethod is either "synciq" (default) or "rsync"
+	CopyMethod string `json:"copyMethod,omitempty"`
+	// NodeID is the CSI node ID of the node running the rsync job
+	NodeID string `json:"nodeId,omitempty"`
+	// DryRun only validates the request and returns the steps of the migration
+	DryRun bool `json:"dryRun,omitempty"`
+	// RetainSource keeps the source directory, quota and export once the volume is migrated
+	RetainSource bool `json:"retainSource,omitempty"`
+}
+
+// MigrationStatus is the state of the migration of a volume
+type MigrationStatus struct {
+	VolumeID       string     `json:"volumeId"`
+	TargetVolumeID string     `json:"targetVolumeId,omitempty"`
+	PVName         string     `json:"pvName"`
+	SourceCluster  string     `json:"sourceCluster"`
+	TargetCluster  string     `json:"targetCluster"`
+	SourcePath     string     `json:"sourcePath"`
+	TargetPath     string     `json:"targetPath"`
+	CopyMethod     string     `json:"copyMethod"`
+	SizeInBytes    int64      `json:"sizeInBytes"`
+	CopiedBytes    int64      `json:"copiedBytes,omitempty"`
+	DryRun         bool       `json:"dryRun,omitempty"`
+	Phase          string     `json:"phase"`
+	Message        string     `json:"message,omitempty"`
+	Steps          []string   `json:"steps,omitempty"`
+	StartTime      time.Time  `json:"startTime"`
+	EndTime        *time.Time `json:"endTime,omitempty"`
+}
+
+// MigrateVolumeResponse is the response of the MigrateVolume admin RPC
+type MigrateVolumeResponse struct {
+	Migration *MigrationStatus `json:"migration"`
+}
+
+// GetMigrationStatusRequest is the request of the GetMigrationStatus admin RPC, all the migrations are returned
+// if no VolumeID is given
+type GetMigrationStatusRequest struct {
+	VolumeID string `json:"volumeId,omitempty"`
+}
+
+// GetMigrationStatusResponse is the response of the GetMigrationStatus admin RPC
+type GetMigrationStatusResponse struct {
+	Migrations []*MigrationStatus `json:"migrations"`
+}
+
+// syncPolicy is a SyncIQ policy as accepted by 'platform/1/sync/policies'
+type syncPolicy struct {
+	Name           string `json:"name"`
+	Description    string `json:"description,omitempty"`
+	Action         string `json:"action"`
+	SourceRootPath string `json:"source_root_path"`
+	TargetHost     string `json:"target_host"`
+	TargetPath     string `json:"target_path"`
+	Enabled        bool   `json:"enabled"`
+}
+
+// syncJob is a SyncIQ job as returned by 'platform/1/sync/jobs', or the report of a job as returned by 'platform/1/sync/reports'
+type syncJob struct {
+	ID               string   `json:"id"`
+	State            string   `json:"state,omitempty"`
+	TotalBytes       int64    `json:"total_bytes,omitempty"`
+	BytesTransferred int64    `json:"bytes_transferred,omitempty"`
+	Errors           []string `json:"errors,omitempty"`
+}
+
+type syncJobList struct {
+	Jobs []syncJob `json:"jobs"`
+}
+
+type syncReportList struct {
+	Reports []syncJob `json:"reports"`
+}
+
+// persistedMigration is the state of a migration kept in an annotation of the PV of the volume, so that a migration
+// interrupted by a restart of the controller is cleaned up instead of leaving its SyncIQ policy, rsync job or
+// target volume behind
+type persistedMigration struct {
+	Request          MigrateVolumeRequest `json:"request"`
+	Phase            string               `json:"phase"`
+	TargetVolumeID   string               `json:"targetVolumeId,omitempty"`
+	TargetAccessZone string               `json:"targetAccessZone"`
+	TargetIsiPath    string               `json:"targetIsiPath"`
+	StartTime        time.Time            `json:"startTime"`
+}
+
+// migration holds what a running migration needs besides its status
+type migration struct {
+	status           *MigrationStatus
+	req              *MigrateVolumeRequest
+	pv               *v1.PersistentVolume
+	sourceConfig     *IsilonClusterConfig
+	targetConfig     *IsilonClusterConfig
+	sourceExportID   int
+	sourceAccessZone string
+	targetAccessZone string
+	targetIsiPath    string
+}
+
+// MigrateVolume moves a volume to another cluster: the target directory, quota and export are created, the data is
+// copied, the PV is recreated with the new volume ID and the source is deleted. The volume must not be published.
+// The migration runs in the background once validated, GetMigrationStatus reports its progress.
+func (s *service) MigrateVolume(ctx context.Context, req *MigrateVolumeRequest) (*MigrateVolumeResponse, error) {
+	ctx, log, runID := GetRunIDLog(ctx)
+
+	m, err := s.planMigration(ctx, runID, req)
+	if err != nil {
+		return nil, err
+	}
+	if req.DryRun {
+		return &MigrateVolumeResponse{Migration: m.status}, nil
+	}
+
+	s.migrationsLock.Lock()
+	if s.migrations == nil {
+		s.migrations = make(map[string]*MigrationStatus)
+	}
+	if previous, ok := s.migrations[req.VolumeID]; ok && previous.EndTime == nil {
+		s.migrationsLock.Unlock()
+		return nil, status.Error(codes.AlreadyExists, utils.GetMessageWithRunID(runID,
+			"volume '%s' is already being migrated to cluster '%s'", req.VolumeID, previous.TargetCluster))
+	}
+	m.status.Phase = MigrationPhasePreparingTarget
+	s.migrations[req.VolumeID] = m.status
+	response := &MigrateVolumeResponse{Migration: copyMigrationStatus(m.status)}
+	s.migrationsLock.Unlock()
+
+	if err := s.persistMigration(ctx, m); err != nil {
+		s.failMigration(ctx, m, err.Error())
+		return nil, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, err.Error()))
+	}
+
+	log.Infof("starting the migration of volume '%s' from cluster '%s' to cluster '%s'", req.VolumeID, m.status.SourceCluster, m.status.TargetCluster)
+	// the migration outlives the request
+	migrationCtx, _ := setRunIDContext(context.Background(), runID)
+	go s.runMigration(migrationCtx, m)
+
+	return response, nil
+}
+
+// GetMigrationStatus returns the status of the migration of a volume, or of all the migrations since the controller started
+func (s *service) GetMigrationStatus(ctx context.Context, req *GetMigrationStatusRequest) (*GetMigrationStatusResponse, error) {
+	_, _, runID := GetRunIDLog(ctx)
+
+	s.migrationsLock.Lock()
+	defer s.migrationsLock.Unlock()
+
+	response := &GetMigrationStatusResponse{Migrations: []*MigrationStatus{}}
+	if req.VolumeID != "" {
+		migrationStatus, ok := s.migrations[req.VolumeID]
+		if !ok {
+			return nil, status.Error(codes.NotFound, utils.GetMessageWithRunID(runID, "no migration of volume '%s' found", req.VolumeID))
+		}
+		response.Migrations = append(response.Migrations, copyMigrationStatus(migrationStatus))
+		return response, nil
+	}
+
+	for _, migrationStatus := range s.migrations {
+		response.Migrations = append(response.Migrations, copyMigrationStatus(migrationStatus))
+	}
+	sort.Slice(response.Migrations, func(i, j int) bool {
+		return response.Migrations[i].StartTime.Before(response.Migrations[j].StartTime)
+	})
+	return response, nil
+}
+
+// planMigration validates a migration request against the source volume, its PV and the target cluster
+func (s *service) planMigration(ctx context.Context, runID string, req *MigrateVolumeRequest) (*migration, error) {
+	if req.VolumeID == "" || req.TargetCluster == "" {
+		return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID, "a volume ID and a target cluster are required"))
+	}
+	copyMethod := strings.ToLower(req.CopyMethod)
+	if copyMethod == "" {
+		copyMethod = MigrationCopySyncIQ
+	}
+	if copyMethod != MigrationCopySyncIQ && copyMethod != MigrationCopyRsync {
+		return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID,
+			"invalid copy method '%s', '%s' or '%s' is expected", req.CopyMethod, MigrationCopySyncIQ, MigrationCopyRsync))
+	}
+	if copyMethod == MigrationCopyRsync {
+		if req.NodeID == "" {
+			return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID, "a node ID is required by the '%s' copy method", MigrationCopyRsync))
+		}
+		if _, _, _, err := utils.ParseNodeID(ctx, req.NodeID); err != nil {
+			return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID, "failed to parse node ID '%s': '%v'", req.NodeID, err))
+		}
+		if s.opts.MigrationImage == "" {
+			return nil, status.Error(codes.FailedPrecondition, utils.GetMessageWithRunID(runID,
+				"the '%s' copy method requires an image, set '%s'", MigrationCopyRsync, constants.EnvMigrationImage))
+		}
+	}
+	if s.k8sclient == nil {
+		return nil, status.Error(codes.FailedPrecondition, utils.GetMessageWithRunID(runID, "no kubernetes client available to switch the PV of the volume"))
+	}
+
+	volName, exportID, accessZone, sourceCluster, err := utils.ParseNormalizedVolumeID(ctx, req.VolumeID)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID, "failed to parse volume ID '%s': '%v'", req.VolumeID, err))
+	}
+	sourceConfig, err := s.getIsilonConfig(ctx, &sourceCluster)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, utils.GetMessageWithRunID(runID, err.Error()))
+	}
+	targetCluster := req.TargetCluster
+	targetConfig, err := s.getIsilonConfig(ctx, &targetCluster)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, utils.GetMessageWithRunID(runID, err.Error()))
+	}
+	if sourceConfig.ClusterName == targetConfig.ClusterName {
+		return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID, "volume '%s' is already on cluster '%s'", req.VolumeID, targetCluster))
+	}
+	for _, isiConfig := range []*IsilonClusterConfig{sourceConfig, targetConfig} {
+		if err := s.autoProbe(ctx, isiConfig); err != nil {
+			return nil, status.Error(codes.FailedPrecondition, utils.GetMessageWithRunID(runID, "failed to probe cluster '%s': '%v'", isiConfig.ClusterName, err))
+		}
+	}
+
+	pv, err := s.getPersistentVolumeByHandle(ctx, req.VolumeID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, err.Error()))
+	}
+	if pv == nil {
+		return nil, status.Error(codes.NotFound, utils.GetMessageWithRunID(runID, "no PV found for volume '%s'", req.VolumeID))
+	}
+
+	if pv.Annotations[migrationAnnotation] != "" {
+		return nil, status.Error(codes.FailedPrecondition, utils.GetMessageWithRunID(runID,
+			"PV '%s' has the state of an unfinished migration, wait until it is cleaned up", pv.Name))
+	}
+	if pod, err := s.getPodUsingClaim(ctx, pv.Spec.ClaimRef); err != nil {
+		return nil, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, err.Error()))
+	} else if pod != "" {
+		return nil, status.Error(codes.FailedPrecondition, utils.GetMessageWithRunID(runID,
+			"PV '%s' is used by pod '%s', stop the pods using it first", pv.Name, pod))
+	}
+
+	export, err := sourceConfig.isiSvc.GetExportByIDWithZone(ctx, exportID, accessZone)
+	if err != nil || export == nil {
+		return nil, status.Error(codes.NotFound, utils.GetMessageWithRunID(runID, "failed to get the export of volume '%s': '%v'", req.VolumeID, err))
+	}
+	if sourceConfig.isiSvc.OtherClientsAlreadyAdded(ctx, exportID, accessZone, utils.DummyHostNodeID) {
+		return nil, status.Error(codes.FailedPrecondition, utils.GetMessageWithRunID(runID,
+			"volume '%s' is published to nodes, stop the pods using PV '%s' first", req.VolumeID, pv.Name))
+	}
+
+	if export.Paths == nil || len(*export.Paths) == 0 {
+		return nil, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, "export '%d' of volume '%s' has no path", exportID, req.VolumeID))
+	}
+
+	targetAccessZone := req.TargetAccessZone
+	if targetAccessZone == "" {
+		targetAccessZone = s.opts.AccessZone
+	}
+	targetIsiPath := req.TargetIsiPath
+	if targetIsiPath == "" {
+		targetIsiPath = targetConfig.IsiPath
+	}
+	if targetConfig.isiSvc.IsVolumeExistent(ctx, targetIsiPath, "", volName) {
+		return nil, status.Error(codes.AlreadyExists, utils.GetMessageWithRunID(runID,
+			"directory '%s' already exists on cluster '%s'", utils.GetPathForVolume(targetIsiPath, volName), targetCluster))
+	}
+
+	capacity := pv.Spec.Capacity[v1.ResourceStorage]
+	sizeInBytes := capacity.Value()
+	available, err := s.getClusterCapacity(ctx, runID, targetConfig, targetIsiPath, 1)
+	if err != nil {
+		return nil, err
+	}
+	if available < sizeInBytes {
+		return nil, status.Error(codes.ResourceExhausted, utils.GetMessageWithRunID(runID,
+			"cluster '%s' has '%d' bytes available for volume '%s' of '%d' bytes", targetCluster, available, req.VolumeID, sizeInBytes))
+	}
+
+	m := &migration{
+		req:              req,
+		pv:               pv,
+		sourceConfig:     sourceConfig,
+		targetConfig:     targetConfig,
+		sourceExportID:   exportID,
+		sourceAccessZone: accessZone,
+		targetAccessZone: targetAccessZone,
+		targetIsiPath:    targetIsiPath,
+		status: &MigrationStatus{
+			VolumeID:      req.VolumeID,
+			PVName:        pv.Name,
+			SourceCluster: sourceConfig.ClusterName,
+			TargetCluster: targetConfig.ClusterName,
+			SourcePath:    (*export.Paths)[0],
+			TargetPath:    utils.GetPathForVolume(targetIsiPath, volName),
+			CopyMethod:    copyMethod,
+			SizeInBytes:   sizeInBytes,
+			DryRun:        req.DryRun,
+			Phase:         MigrationPhasePlanned,
+			StartTime:     time.Now(),
+		},
+	}
+	m.status.Steps = []string{
+		fmt.Sprintf("create directory '%s', a quota of '%d' bytes and an export in access zone '%s' on cluster '%s'",
+			m.status.TargetPath, sizeInBytes, targetAccessZone, targetCluster),
+		fmt.Sprintf("copy the data of '%s' with %s", m.status.SourcePath, copyMethod),
+		fmt.Sprintf("recreate PV '%s' with the volume ID of the target, and its claim", pv.Name),
+	}
+	if req.RetainSource {
+		m.status.Steps = append(m.status.Steps, fmt.Sprintf("keep the source directory '%s' on cluster '%s'", m.status.SourcePath, sourceConfig.ClusterName))
+	} else {
+		m.status.Steps = append(m.status.Steps, fmt.Sprintf("delete the source directory '%s', its quota and export on cluster '%s'", m.status.SourcePath, sourceConfig.ClusterName))
+	}
+	return m, nil
+}
+
+// runMigration carries out a planned migration, the target volume is deleted if the data cannot be copied
+func (s *service) runMigration(ctx context.Context, m *migration) {
+	s.setMigrationPhase(m, MigrationPhasePreparingTarget, "")
+	target, err := s.createMigrationTarget(ctx, m)
+	if err != nil {
+		s.rollBackMigration(ctx, m, "", fmt.Sprintf("failed to create the target volume: %v", err))
+		return
+	}
+	s.migrationsLock.Lock()
+	m.status.TargetVolumeID = target.GetVolumeId()
+	s.migrationsLock.Unlock()
+
+	s.setMigrationPhase(m, MigrationPhaseCopyingData, "")
+	if err := s.persistMigration(ctx, m); err != nil {
+		s.rollBackMigration(ctx, m, target.GetVolumeId(), err.Error())
+		return
+	}
+	copyCtx, cancel := ctx, context.CancelFunc(func() {})
+	if s.opts.MigrationCopyTimeout > 0 {
+		copyCtx, cancel = context.WithTimeout(ctx, s.opts.MigrationCopyTimeout)
+	}
+	if m.status.CopyMethod == MigrationCopyRsync {
+		err = s.copyWithRsync(ctx, copyCtx, m, target)
+	} else {
+		err = s.copyWithSyncIQ(ctx, copyCtx, m)
+	}
+	cancel()
+	if err != nil {
+		s.rollBackMigration(ctx, m, target.GetVolumeId(), fmt.Sprintf("failed to copy the data: %v", err))
+		return
+	}
+
+	// ControllerPublishVolume refuses the volume while it is migrated, but it may have been published by a
+	// request which was already past that check, in which case the copied data may be outdated
+	if m.sourceConfig.isiSvc.OtherClientsAlreadyAdded(ctx, m.sourceExportID, m.sourceAccessZone, utils.DummyHostNodeID) {
+		s.rollBackMigration(ctx, m, target.GetVolumeId(), fmt.Sprintf("volume '%s' was published while its data was copied", m.req.VolumeID))
+		return
+	}
+
+	s.setMigrationPhase(m, MigrationPhaseSwitchingVolume, "")
+	if err := s.persistMigration(ctx, m); err != nil {
+		s.rollBackMigration(ctx, m, target.GetVolumeId(), err.Error())
+		return
+	}
+	if err := s.switchPersistentVolume(ctx, m, target); err != nil {
+		message := fmt.Sprintf("failed to switch PV '%s' to volume '%s': %v", m.pv.Name, target.GetVolumeId(), err)
+		// the target is kept unless the PV still refers to the source, it may refer to the target already
+		if pv, getErr := s.k8sclient.CoreV1().PersistentVolumes().Get(ctx, m.pv.Name, metav1.GetOptions{}); getErr == nil &&
+			pv.Spec.CSI != nil && pv.Spec.CSI.VolumeHandle == m.req.VolumeID {
+			s.rollBackMigration(ctx, m, target.GetVolumeId(), message)
+			return
+		}
+		s.failMigration(ctx, m, message)
+		return
+	}
+
+	s.completeMigration(ctx, m)
+}
+
+// completeMigration deletes the source of a migrated volume unless it is retained, and removes the state of the
+// migration from the PV, which refers to the target volume by now
+func (s *service) completeMigration(ctx context.Context, m *migration) {
+	log := utils.GetRunIDLogger(ctx)
+
+	message := ""
+	if !m.req.RetainSource {
+		s.setMigrationPhase(m, MigrationPhaseCleaningUpSource, "")
+		if _, err := s.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: m.req.VolumeID}); err != nil {
+			log.Warnf("failed to delete source volume '%s' of the migration: '%v'", m.req.VolumeID, err)
+			message = fmt.Sprintf("the source volume could not be deleted: %v", err)
+		}
+	}
+	s.clearPersistedMigration(ctx, m.pv.Name)
+
+	s.migrationsLock.Lock()
+	now := time.Now()
+	m.status.Phase = MigrationPhaseCompleted
+	m.status.Message = message
+	m.status.EndTime = &now
+	s.migrationsLock.Unlock()
+	log.Infof("volume '%s' migrated to '%s'", m.req.VolumeID, m.status.TargetVolumeID)
+}
+
+// rollBackMigration deletes what a migration which has not switched the PV yet created, then fails it. The target
+// volume is looked up on the target cluster if its ID is not known, as CreateVolume may have been interrupted.
+func (s *service) rollBackMigration(ctx context.Context, m *migration, targetVolumeID, message string) {
+	log := utils.GetRunIDLogger(ctx)
+
+	volName, _, _, _, err := utils.ParseNormalizedVolumeID(ctx, m.req.VolumeID)
+	if err != nil {
+		s.failMigration(ctx, m, message)
+		return
+	}
+	if err := m.sourceConfig.isiSvc.DeleteSyncPolicy(ctx, migrationPolicyPrefix+volName); err != nil && !isNotFoundError(err) {
+		log.Warnf("failed to delete SyncIQ policy '%s': '%v'", migrationPolicyPrefix+volName, err)
+	}
+	if m.status.CopyMethod == MigrationCopyRsync {
+		s.deleteRsyncJob(ctx, m)
+		if err := m.sourceConfig.isiSvc.RemoveExportClientByIDWithZone(ctx, m.sourceExportID, m.sourceAccessZone, m.req.NodeID); err != nil {
+			log.Warnf("failed to remove node '%s' from export '%d' of cluster '%s': '%v'", m.req.NodeID, m.sourceExportID, m.sourceConfig.ClusterName, err)
+		}
+	}
+
+	if targetVolumeID == "" {
+		targetPath := utils.GetPathForVolume(m.targetIsiPath, volName)
+		if export, err := m.targetConfig.isiSvc.GetExportWithPathAndZone(ctx, targetPath, m.targetAccessZone); err == nil && export != nil {
+			targetVolumeID = utils.GetNormalizedVolumeID(ctx, volName, export.ID, m.targetAccessZone, m.targetConfig.ClusterName)
+		} else if exists, err := m.targetConfig.isiSvc.IsDirectoryExistent(ctx, targetPath); err == nil && exists {
+			if err := m.targetConfig.isiSvc.DeleteVolume(ctx, m.targetIsiPath, volName); err != nil {
+				log.Warnf("failed to delete target directory '%s' of the failed migration: '%v'", targetPath, err)
+			}
+		}
+	}
+	if targetVolumeID != "" {
+		if _, err := s.DeleteVolume(ctx, &csi.DeleteVolumeRequest{VolumeId: targetVolumeID}); err != nil {
+			log.Warnf("failed to delete target volume '%s' of the failed migration: '%v'", targetVolumeID, err)
+		}
+	}
+	s.clearPersistedMigration(ctx, m.pv.Name)
+	s.failMigration(ctx, m, message)
+}
+
+// createMigrationTarget creates the target volume through CreateVolume, so that it gets the quota, export
+// and localhost client of any other volume
+func (s *service) createMigrationTarget(ctx context.Context, m *migration) (*csi.Volume, error) {
+	volName, _, _, _, err := utils.ParseNormalizedVolumeID(ctx, m.req.VolumeID)
+	if err != nil {
+		return nil, err
+	}
+
+	params := map[string]string{
+		ClusterNameParam:        m.targetConfig.ClusterName,
+		AccessZoneParam:         m.targetAccessZone,
+		IsiPathParam:            m.targetIsiPath,
+		csiPersistentVolumeName: m.pv.Name,
+	}
+	if m.pv.Spec.CSI != nil {
+		if rootClientEnabled, ok := m.pv.Spec.CSI.VolumeAttributes["RootClientEnabled"]; ok {
+			params[RootClientEnabledParam] = rootClientEnabled
+		}
+	}
+	if claim := m.pv.Spec.ClaimRef; claim != nil {
+		params[csiPersistentVolumeClaimName] = claim.Name
+		params[csiPersistentVolumeClaimNamespace] = claim.Namespace
+	}
+
+	resp, err := s.CreateVolume(ctx, &csi.CreateVolumeRequest{
+		Name:               volName,
+		CapacityRange:      &csi.CapacityRange{RequiredBytes: m.status.SizeInBytes},
+		VolumeCapabilities: getVolumeCapabilities(m.pv.Spec.AccessModes),
+		Parameters:         params,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.GetVolume(), nil
+}
+
+// copyWithSyncIQ copies the source directory to the target with a one-off SyncIQ copy policy. Deleting the policy
+// once the copy is over also breaks its association on the target cluster, which makes the target writable.
+// The copy is given up once copyCtx is done, the policy is still deleted with ctx.
+func (s *service) copyWithSyncIQ(ctx, copyCtx context.Context, m *migration) error {
+	log := utils.GetRunIDLogger(ctx)
+	svc := m.sourceConfig.isiSvc
+
+	volName, _, _, _, err := utils.ParseNormalizedVolumeID(ctx, m.req.VolumeID)
+	if err != nil {
+		return err
+	}
+	policy := &syncPolicy{
+		Name:           migrationPolicyPrefix + volName,
+		Description:    fmt.Sprintf("Created by the CSI driver to migrate volume '%s' to cluster '%s'", volName, m.targetConfig.ClusterName),
+		Action:         syncPolicyActionCopy,
+		SourceRootPath: m.status.SourcePath,
+		TargetHost:     m.targetConfig.IsiIP,
+		TargetPath:     m.status.TargetPath,
+		Enabled:        true,
+	}
+	if err := svc.CreateSyncPolicy(ctx, policy); err != nil {
+		return err
+	}
+	defer func() {
+		if err := svc.DeleteSyncPolicy(ctx, policy.Name); err != nil && !isNotFoundError(err) {
+			log.Warnf("failed to delete SyncIQ policy '%s': '%v'", policy.Name, err)
+		}
+	}()
+
+	if err := svc.StartSyncJob(copyCtx, policy.Name); err != nil {
+		return err
+	}
+	for {
+		job, err := svc.GetSyncJob(copyCtx, policy.Name)
+		if err != nil && !isNotFoundError(err) {
+			return err
+		}
+		if job == nil {
+			// the job is over once it is not listed anymore, its report tells how it went
+			report, err := svc.GetLatestSyncReport(copyCtx, policy.Name)
+			if err != nil {
+				return err
+			}
+			if report == nil || report.State != syncJobStateFinished {
+				return fmt.Errorf("SyncIQ job of policy '%s' did not finish: '%+v'", policy.Name, report)
+			}
+			s.setMigrationCopiedBytes(m, report.BytesTransferred)
+			return nil
+		}
+		if utils.IsStringInSlice(job.State, syncJobFailedStates) {
+			return fmt.Errorf("SyncIQ job of policy '%s' is '%s': %s", policy.Name, job.State, strings.Join(job.Errors, ", "))
+		}
+		s.setMigrationCopiedBytes(m, job.BytesTransferred)
+		if err := waitForNextPoll(copyCtx); err != nil {
+			return fmt.Errorf("SyncIQ job of policy '%s' did not finish: %v", policy.Name, err)
+		}
+	}
+}
+
+// copyWithRsync copies the source directory to the target with a job running rsync on the given node, in the
+// namespace of the PVC. The node is a root client of both exports while the job runs. The copy is given up once
+// copyCtx is done, the job and the clients of the exports are still removed with ctx.
+func (s *service) copyWithRsync(ctx, copyCtx context.Context, m *migration, target *csi.Volume) error {
+	log := utils.GetRunIDLogger(ctx)
+
+	nodeName, _, _, err := utils.ParseNodeID(ctx, m.req.NodeID)
+	if err != nil {
+		return err
+	}
+	_, targetExportID, targetAccessZone, _, err := utils.ParseNormalizedVolumeID(ctx, target.GetVolumeId())
+	if err != nil {
+		return err
+	}
+
+	exports := []struct {
+		isiConfig  *IsilonClusterConfig
+		exportID   int
+		accessZone string
+	}{
+		{m.sourceConfig, m.sourceExportID, m.sourceAccessZone},
+		{m.targetConfig, targetExportID, targetAccessZone},
+	}
+	for _, export := range exports {
+		if err := export.isiConfig.isiSvc.AddExportClientNetworkIdentifierByIDWithZone(ctx, export.exportID, export.accessZone,
+			m.req.NodeID, export.isiConfig.isiSvc.AddExportRootClientByIDWithZone); err != nil {
+			return err
+		}
+		defer func(isiConfig *IsilonClusterConfig, exportID int, accessZone string) {
+			if err := isiConfig.isiSvc.RemoveExportClientByIDWithZone(ctx, exportID, accessZone, m.req.NodeID); err != nil {
+				log.Warnf("failed to remove node '%s' from export '%d' of cluster '%s': '%v'", nodeName, exportID, isiConfig.ClusterName, err)
+			}
+		}(export.isiConfig, export.exportID, export.accessZone)
+	}
+
+	namespace := getMigrationJobNamespace(m.pv)
+	sourceServer := m.sourceConfig.IsiIP
+	if m.pv.Spec.CSI != nil && m.pv.Spec.CSI.VolumeAttributes["AzServiceIP"] != "" {
+		sourceServer = m.pv.Spec.CSI.VolumeAttributes["AzServiceIP"]
+	}
+	job := newRsyncJob(m.pv.Name, nodeName, s.opts.MigrationImage,
+		&v1.NFSVolumeSource{Server: sourceServer, Path: m.status.SourcePath, ReadOnly: true},
+		&v1.NFSVolumeSource{Server: target.GetVolumeContext()["AzServiceIP"], Path: m.status.TargetPath})
+
+	jobs := s.k8sclient.BatchV1().Jobs(namespace)
+	if _, err := jobs.Create(ctx, job, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create job '%s/%s': '%v'", namespace, job.Name, err)
+	}
+	defer s.deleteRsyncJob(ctx, m)
+
+	for {
+		current, err := jobs.Get(copyCtx, job.Name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get job '%s/%s': '%v'", namespace, job.Name, err)
+		}
+		if current.Status.Succeeded > 0 {
+			return nil
+		}
+		if current.Status.Failed > 0 {
+			return fmt.Errorf("job '%s/%s' failed, see the logs of its pod", namespace, job.Name)
+		}
+		if err := waitForNextPoll(copyCtx); err != nil {
+			return fmt.Errorf("job '%s/%s' did not finish: %v", namespace, job.Name, err)
+		}
+	}
+}
+
+// getMigrationJobNamespace returns the namespace the rsync job of a migration runs in, i.e. the one of the PVC
+func getMigrationJobNamespace(pv *v1.PersistentVolume) string {
+	if pv.Spec.ClaimRef != nil {
+		return pv.Spec.ClaimRef.Namespace
+	}
+	return metav1.NamespaceDefault
+}
+
+// deleteRsyncJob deletes the rsync job of a migration, along with its pod, if there is one
+func (s *service) deleteRsyncJob(ctx context.Context, m *migration) {
+	log := utils.GetRunIDLogger(ctx)
+	namespace := getMigrationJobNamespace(m.pv)
+	name := migrationJobPrefix + m.pv.Name
+	propagation := metav1.DeletePropagationBackground
+	err := s.k8sclient.BatchV1().Jobs(namespace).Delete(ctx, name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil && !apierrors.IsNotFound(err) {
+		log.Warnf("failed to delete job '%s/%s': '%v'", namespace, name, err)
+	}
+}
+
+// newRsyncJob returns the job copying the content of the source NFS export to the target NFS export
+func newRsyncJob(pvName, nodeName, image string, source, target *v1.NFSVolumeSource) *batchv1.Job {
+	backoffLimit := int32(0)
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   migrationJobPrefix + pvName,
+			Labels: map[string]string{"app.kubernetes.io/managed-by": constants.PluginName},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					NodeName:      nodeName,
+					RestartPolicy: v1.RestartPolicyNever,
+					Containers: []v1.Container{
+						{
+							Name:    "rsync",
+							Image:   image,
+							Command: []string{"rsync", "-aHAX", "--numeric-ids", migrationSourceMount + "/", migrationTargetMount + "/"},
+							VolumeMounts: []v1.VolumeMount{
+								{Name: "source", MountPath: migrationSourceMount, ReadOnly: true},
+								{Name: "target", MountPath: migrationTargetMount},
+							},
+						},
+					},
+					Volumes: []v1.Volume{
+						{Name: "source", VolumeSource: v1.VolumeSource{NFS: source}},
+						{Name: "target", VolumeSource: v1.VolumeSource{NFS: target}},
+					},
+				},
+			},
+		},
+	}
+}
+
+// switchPersistentVolume recreates the PV of a migrated volume with the volume ID of the target, as the volume
+// handle of a PV cannot be changed. The PVC is deleted first, so that the PV is released and the PV protection
+// lets it go, then the PV and the PVC are created again with the same names and bind to each other. The original
+// PV is restored if the migrated one cannot be created, and the PVC and the reclaim policy of the PV are restored
+// whenever the switch fails.
+func (s *service) switchPersistentVolume(ctx context.Context, m *migration, target *csi.Volume) error {
+	log := utils.GetRunIDLogger(ctx)
+	pvs := s.k8sclient.CoreV1().PersistentVolumes()
+
+	pv, err := pvs.Get(ctx, m.pv.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if pv.Spec.CSI == nil || pv.Spec.CSI.VolumeHandle != m.req.VolumeID {
+		return fmt.Errorf("PV '%s' does not refer to volume '%s' anymore", pv.Name, m.req.VolumeID)
+	}
+	var pvc *v1.PersistentVolumeClaim
+	if claimRef := pv.Spec.ClaimRef; claimRef != nil {
+		if pvc, err = s.k8sclient.CoreV1().PersistentVolumeClaims(claimRef.Namespace).Get(ctx, claimRef.Name, metav1.GetOptions{}); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return err
+			}
+			pvc = nil
+		}
+	}
+
+	original := newRecreatedPersistentVolume(pv)
+	migrated := newRecreatedPersistentVolume(pv)
+	migrated.Annotations[migratedFromAnnotation] = m.req.VolumeID
+	migrated.Spec.CSI.VolumeHandle = target.GetVolumeId()
+	migrated.Spec.CSI.VolumeAttributes = target.GetVolumeContext()
+	setNodeAffinityCluster(migrated, m.sourceConfig, m.targetConfig)
+	// the migration resumes by cleaning up the source if the controller restarts once the PV is switched
+	s.migrationsLock.Lock()
+	state, err := json.Marshal(newPersistedMigration(m, MigrationPhaseCleaningUpSource))
+	s.migrationsLock.Unlock()
+	if err != nil {
+		return err
+	}
+	migrated.Annotations[migrationAnnotation] = string(state)
+
+	// retain the source until it is deleted by the migration itself
+	reclaimPolicy := pv.Spec.PersistentVolumeReclaimPolicy
+	if reclaimPolicy != v1.PersistentVolumeReclaimRetain {
+		pv.Spec.PersistentVolumeReclaimPolicy = v1.PersistentVolumeReclaimRetain
+		if _, err = pvs.Update(ctx, pv, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+	}
+
+	// the PVC deleted so far, which is created again if the switch fails
+	var deletedClaim *v1.PersistentVolumeClaim
+	if pvc != nil {
+		claims := s.k8sclient.CoreV1().PersistentVolumeClaims(pvc.Namespace)
+		if err := claims.Delete(ctx, pvc.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			s.rollBackSwitch(ctx, pv.Name, deletedClaim, reclaimPolicy)
+			return err
+		}
+		deletedClaim = pvc
+		if err := waitForDeletion(func() error {
+			_, err := claims.Get(ctx, pvc.Name, metav1.GetOptions{})
+			return err
+		}); err != nil {
+			s.rollBackSwitch(ctx, pv.Name, deletedClaim, reclaimPolicy)
+			return fmt.Errorf("PVC '%s/%s' is not deleted: %v", pvc.Namespace, pvc.Name, err)
+		}
+	}
+	if err := pvs.Delete(ctx, pv.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		s.rollBackSwitch(ctx, pv.Name, deletedClaim, reclaimPolicy)
+		return err
+	}
+	if err := waitForDeletion(func() error {
+		_, err := pvs.Get(ctx, pv.Name, metav1.GetOptions{})
+		return err
+	}); err != nil {
+		s.rollBackSwitch(ctx, pv.Name, deletedClaim, reclaimPolicy)
+		return fmt.Errorf("PV '%s' is not deleted: %v", pv.Name, err)
+	}
+
+	if err := createWithRetries(func() error {
+		_, err := pvs.Create(ctx, migrated, metav1.CreateOptions{})
+		return err
+	}); err != nil {
+		log.Errorf("failed to create PV '%s' of volume '%s', restore the original PV: '%v'", migrated.Name, target.GetVolumeId(), err)
+		if restoreErr := createWithRetries(func() error {
+			_, err := pvs.Create(ctx, original, metav1.CreateOptions{})
+			return err
+		}); restoreErr != nil {
+			log.Errorf("failed to restore PV '%s' of volume '%s': '%v'", original.Name, m.req.VolumeID, restoreErr)
+		}
+		s.rollBackSwitch(ctx, original.Name, deletedClaim, reclaimPolicy)
+		return err
+	}
+	log.Infof("PV '%s' switched to volume '%s'", migrated.Name, target.GetVolumeId())
+
+	if err := s.recreateClaim(ctx, pvc); err != nil {
+		return err
+	}
+	return nil
+}
+
+// newRecreatedPersistentVolume returns a copy of a PV which can be created again once it is deleted, bound to the
+// claim of the same name which is created again as well
+func newRecreatedPersistentVolume(pv *v1.PersistentVolume) *v1.PersistentVolume {
+	recreated := pv.DeepCopy()
+	recreated.ObjectMeta = metav1.ObjectMeta{
+		Name:        pv.Name,
+		Labels:      pv.Labels,
+		Annotations: make(map[string]string),
+	}
+	for key, value := range pv.Annotations {
+		recreated.Annotations[key] = value
+	}
+	if recreated.Spec.ClaimRef != nil {
+		recreated.Spec.ClaimRef = &v1.ObjectReference{
+			Kind:       recreated.Spec.ClaimRef.Kind,
+			APIVersion: recreated.Spec.ClaimRef.APIVersion,
+			Namespace:  recreated.Spec.ClaimRef.Namespace,
+			Name:       recreated.Spec.ClaimRef.Name,
+		}
+	}
+	recreated.Status = v1.PersistentVolumeStatus{}
+	return recreated
+}
+
+// rollBackSwitch creates the PVC deleted by a failed switch again, if any, and gives the PV of the source volume its
+// reclaim policy back. The claim reference of the PV loses the UID of the deleted PVC, so that the PV binds to the
+// PVC created again instead of being released.
+func (s *service) rollBackSwitch(ctx context.Context, pvName string, deletedClaim *v1.PersistentVolumeClaim, reclaimPolicy v1.PersistentVolumeReclaimPolicy) {
+	log := utils.GetRunIDLogger(ctx)
+	pvs := s.k8sclient.CoreV1().PersistentVolumes()
+
+	// recreateClaim logs its own errors, the reclaim policy is restored anyway
+	s.recreateClaim(ctx, deletedClaim)
+
+	pv, err := pvs.Get(ctx, pvName, metav1.GetOptions{})
+	if err != nil {
+		log.Errorf("failed to get PV '%s' to restore its reclaim policy '%s': '%v'", pvName, reclaimPolicy, err)
+		return
+	}
+	updated := false
+	if deletedClaim != nil && pv.Spec.ClaimRef != nil && pv.Spec.ClaimRef.UID != "" {
+		pv.Spec.ClaimRef.UID = ""
+		pv.Spec.ClaimRef.ResourceVersion = ""
+		updated = true
+	}
+	if pv.Spec.PersistentVolumeReclaimPolicy != reclaimPolicy {
+		pv.Spec.PersistentVolumeReclaimPolicy = reclaimPolicy
+		updated = true
+	}
+	if !updated {
+		return
+	}
+	if _, err := pvs.Update(ctx, pv, metav1.UpdateOptions{}); err != nil {
+		log.Errorf("failed to restore the reclaim policy '%s' of PV '%s': '%v'", reclaimPolicy, pvName, err)
+	}
+}
+
+// recreateClaim creates a deleted PVC again, bound to the PV of the same volume name
+func (s *service) recreateClaim(ctx context.Context, pvc *v1.PersistentVolumeClaim) error {
+	if pvc == nil {
+		return nil
+	}
+	log := utils.GetRunIDLogger(ctx)
+	recreated := &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        pvc.Name,
+			Namespace:   pvc.Namespace,
+			Labels:      pvc.Labels,
+			Annotations: make(map[string]string),
+		},
+		Spec: pvc.Spec,
+	}
+	for key, value := range pvc.Annotations {
+		if key != bindCompletedAnnotation && key != boundByControllerAnnotation {
+			recreated.Annotations[key] = value
+		}
+	}
+	if err := createWithRetries(func() error {
+		_, err := s.k8sclient.CoreV1().PersistentVolumeClaims(pvc.Namespace).Create(ctx, recreated, metav1.CreateOptions{})
+		return err
+	}); err != nil {
+		log.Errorf("failed to create PVC '%s/%s' again: '%v'", pvc.Namespace, pvc.Name, err)
+		return fmt.Errorf("failed to create PVC '%s/%s' again: %v", pvc.Namespace, pvc.Name, err)
+	}
+	return nil
+}
+
+// waitForNextPoll waits for the next poll of the copy of a migration, an error is returned once ctx is done
+func waitForNextPoll(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(migrationPollInterval):
+		return nil
+	}
+}
+
+// waitForDeletion polls an object until it is not found, for at most migrationSwitchTimeout
+func waitForDeletion(get func() error) error {
+	for start := time.Now(); ; time.Sleep(migrationPollInterval) {
+		err := get()
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if time.Since(start) > migrationSwitchTimeout {
+			return fmt.Errorf("still present after %v", migrationSwitchTimeout)
+		}
+	}
+}
+
+// createWithRetries retries the creation of an object for at most migrationSwitchTimeout, an object which
+// already exists is considered created
+func createWithRetries(create func() error) error {
+	for start := time.Now(); ; time.Sleep(migrationPollInterval) {
+		err := create()
+		if err == nil || apierrors.IsAlreadyExists(err) {
+			return nil
+		}
+		if time.Since(start) > migrationSwitchTimeout {
+			return err
+		}
+	}
+}
+
+// getPodUsingClaim returns the name of a pod using the given claim, or "" if there is none
+func (s *service) getPodUsingClaim(ctx context.Context, claimRef *v1.ObjectReference) (string, error) {
+	if claimRef == nil {
+		return "", nil
+	}
+	pods, err := s.k8sclient.CoreV1().Pods(claimRef.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to list the pods of namespace '%s': '%v'", claimRef.Namespace, err)
+	}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		for _, volume := range pod.Spec.Volumes {
+			if volume.PersistentVolumeClaim != nil && volume.PersistentVolumeClaim.ClaimName == claimRef.Name {
+				return pod.Name, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// setNodeAffinityCluster replaces the topology key of the source cluster by the one of the target cluster
+func setNodeAffinityCluster(pv *v1.PersistentVolume, sourceConfig, targetConfig *IsilonClusterConfig) {
+	if pv.Spec.NodeAffinity == nil || pv.Spec.NodeAffinity.Required == nil {
+		return
+	}
+	sourceKey := constants.PluginName + "/" + sourceConfig.IsiIP
+	targetKey := constants.PluginName + "/" + targetConfig.IsiIP
+	for i := range pv.Spec.NodeAffinity.Required.NodeSelectorTerms {
+		term := &pv.Spec.NodeAffinity.Required.NodeSelectorTerms[i]
+		for j := range term.MatchExpressions {
+			if term.MatchExpressions[j].Key == sourceKey {
+				term.MatchExpressions[j].Key = targetKey
+			}
+		}
+	}
+}
+
+// getPersistentVolumeByHandle returns the PV of the given volume, nil if there is none
+func (s *service) getPersistentVolumeByHandle(ctx context.Context, volumeID string) (*v1.PersistentVolume, error) {
+	pvs, err := s.k8sclient.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PVs: '%v'", err)
+	}
+	for i := range pvs.Items {
+		if csiSource := pvs.Items[i].Spec.CSI; csiSource != nil && csiSource.Driver == constants.PluginName && csiSource.VolumeHandle == volumeID {
+			return &pvs.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// getVolumeCapabilities returns the CSI capabilities matching the access modes of a PV
+func getVolumeCapabilities(accessModes []v1.PersistentVolumeAccessMode) []*csi.VolumeCapability {
+	capabilities := make([]*csi.VolumeCapability, 0, len(accessModes))
+	for _, accessMode := range accessModes {
+		mode := csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER
+		switch accessMode {
+		case v1.ReadOnlyMany:
+			mode = csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY
+		case v1.ReadWriteMany:
+			mode = csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER
+		case readWriteOncePod:
+			mode = csi.VolumeCapability_AccessMode_SINGLE_NODE_SINGLE_WRITER
+		}
+		capabilities = append(capabilities, &csi.VolumeCapability{
+			AccessType: &csi.VolumeCapability_Mount{Mount: &csi.VolumeCapability_MountVolume{}},
+			AccessMode: &csi.VolumeCapability_AccessMode{Mode: mode},
+		})
+	}
+	return capabilities
+}
+
+func (s *service) setMigrationPhase(m *migration, phase, message string) {
+	s.migrationsLock.Lock()
+	defer s.migrationsLock.Unlock()
+	m.status.Phase = phase
+	m.status.Message = message
+}
+
+func (s *service) setMigrationCopiedBytes(m *migration, copiedBytes int64) {
+	s.migrationsLock.Lock()
+	defer s.migrationsLock.Unlock()
+	m.status.CopiedBytes = copiedBytes
+}
+
+func (s *service) failMigration(ctx context.Context, m *migration, message string) {
+	log := utils.GetRunIDLogger(ctx)
+	log.Errorf("migration of volume '%s' failed: %s", m.req.VolumeID, message)
+
+	s.migrationsLock.Lock()
+	defer s.migrationsLock.Unlock()
+	now := time.Now()
+	m.status.Phase = MigrationPhaseFailed
+	m.status.Message = message
+	m.status.EndTime = &now
+}
+
+// copyMigrationStatus returns a copy of a status which can be read while the migration goes on
+func copyMigrationStatus(migrationStatus *MigrationStatus) *MigrationStatus {
+	statusCopy := *migrationStatus
+	statusCopy.Steps = append([]string(nil), migrationStatus.Steps...)
+	return &statusCopy
+}
+
+func newPersistedMigration(m *migration, phase string) *persistedMigration {
+	return &persistedMigration{
+		Request:          *m.req,
+		Phase:            phase,
+		TargetVolumeID:   m.status.TargetVolumeID,
+		TargetAccessZone: m.targetAccessZone,
+		TargetIsiPath:    m.targetIsiPath,
+		StartTime:        m.status.StartTime,
+	}
+}
+
+// persistMigration stores the current state of a migration in an annotation of the PV of the volume
+func (s *service) persistMigration(ctx context.Context, m *migration) error {
+	s.migrationsLock.Lock()
+	state, err := json.Marshal(newPersistedMigration(m, m.status.Phase))
+	s.migrationsLock.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := s.setPersistentVolumeAnnotation(ctx, m.pv.Name, migrationAnnotation, string(state)); err != nil {
+		return fmt.Errorf("failed to store the state of the migration in PV '%s': %v", m.pv.Name, err)
+	}
+	return nil
+}
+
+// clearPersistedMigration removes the state of a migration which is over from the PV of the volume
+func (s *service) clearPersistedMigration(ctx context.Context, pvName string) {
+	log := utils.GetRunIDLogger(ctx)
+	if err := s.setPersistentVolumeAnnotation(ctx, pvName, migrationAnnotation, ""); err != nil && !apierrors.IsNotFound(err) {
+		log.Warnf("failed to remove the state of the migration from PV '%s': '%v'", pvName, err)
+	}
+}
+
+// setPersistentVolumeAnnotation sets an annotation of a PV, or removes it if the value is empty
+func (s *service) setPersistentVolumeAnnotation(ctx context.Context, pvName, key, value string) error {
+	pvs := s.k8sclient.CoreV1().PersistentVolumes()
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		pv, err := pvs.Get(ctx, pvName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if value == "" {
+			if _, ok := pv.Annotations[key]; !ok {
+				return nil
+			}
+			delete(pv.Annotations, key)
+		} else {
+			if pv.Annotations == nil {
+				pv.Annotations = make(map[string]string)
+			}
+			pv.Annotations[key] = value
+		}
+		_, err = pvs.Update(ctx, pv, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// startMigrationReconciliation cleans up, in the background, the migrations interrupted by a restart of the
+// controller: the ones which had not switched the PV yet are rolled back, the others are completed
+func (s *service) startMigrationReconciliation(ctx context.Context) {
+	if !strings.EqualFold(s.mode, constants.ModeController) || s.k8sclient == nil {
+		return
+	}
+	go s.reconcileMigrations(ctx)
+}
+
+func (s *service) reconcileMigrations(ctx context.Context) {
+	ctx, log := GetLogger(ctx)
+
+	pvs, err := s.k8sclient.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Errorf("failed to list the PVs to clean up the interrupted migrations: '%v'", err)
+		return
+	}
+	for i := range pvs.Items {
+		pv := &pvs.Items[i]
+		value := pv.Annotations[migrationAnnotation]
+		if value == "" {
+			continue
+		}
+		var state persistedMigration
+		if err := json.Unmarshal([]byte(value), &state); err != nil {
+			log.Errorf("invalid state of migration in PV '%s': '%v'", pv.Name, err)
+			continue
+		}
+		s.reconcileMigration(ctx, pv, &state)
+	}
+}
+
+// reconcileMigration rolls back or completes a migration interrupted by a restart of the controller
+func (s *service) reconcileMigration(ctx context.Context, pv *v1.PersistentVolume, state *persistedMigration) {
+	log := utils.GetRunIDLogger(ctx)
+	req := state.Request
+
+	m := &migration{
+		req:              &req,
+		pv:               pv,
+		targetAccessZone: state.TargetAccessZone,
+		targetIsiPath:    state.TargetIsiPath,
+		status: &MigrationStatus{
+			VolumeID:       req.VolumeID,
+			TargetVolumeID: state.TargetVolumeID,
+			PVName:         pv.Name,
+			TargetCluster:  req.TargetCluster,
+			CopyMethod:     strings.ToLower(req.CopyMethod),
+			Phase:          state.Phase,
+			StartTime:      state.StartTime,
+		},
+	}
+	volName, exportID, accessZone, sourceCluster, err := utils.ParseNormalizedVolumeID(ctx, req.VolumeID)
+	if err == nil {
+		m.sourceExportID = exportID
+		m.sourceAccessZone = accessZone
+		m.sourceConfig, err = s.getIsilonConfig(ctx, &sourceCluster)
+	}
+	if err == nil {
+		targetCluster := req.TargetCluster
+		m.targetConfig, err = s.getIsilonConfig(ctx, &targetCluster)
+	}
+	if err != nil {
+		log.Errorf("failed to clean up the interrupted migration of volume '%s' in PV '%s': '%v'", req.VolumeID, pv.Name, err)
+		return
+	}
+	m.status.SourceCluster = m.sourceConfig.ClusterName
+	m.status.TargetPath = utils.GetPathForVolume(m.targetIsiPath, volName)
+
+	// the volume stays fenced from ControllerPublishVolume until the migration is cleaned up
+	s.migrationsLock.Lock()
+	if s.migrations == nil {
+		s.migrations = make(map[string]*MigrationStatus)
+	}
+	if previous, ok := s.migrations[req.VolumeID]; ok && previous.EndTime == nil {
+		s.migrationsLock.Unlock()
+		return
+	}
+	s.migrations[req.VolumeID] = m.status
+	s.migrationsLock.Unlock()
+
+	if state.TargetVolumeID != "" && pv.Spec.CSI != nil && pv.Spec.CSI.VolumeHandle == state.TargetVolumeID {
+		log.Infof("completing the migration of volume '%s' interrupted once PV '%s' was switched", req.VolumeID, pv.Name)
+		s.completeMigration(ctx, m)
+		return
+	}
+	log.Infof("rolling back the migration of volume '%s' interrupted in phase '%s'", req.VolumeID, state.Phase)
+	s.rollBackMigration(ctx, m, state.TargetVolumeID, fmt.Sprintf("the migration was interrupted in phase '%s' by a restart of the controller", state.Phase))
+}
+
+// getInFlightMigration returns the status of the unfinished migration of a volume, nil if there is none
+func (s *service) getInFlightMigration(ctx context.Context, volName string, exportID int, accessZone, clusterName string) *MigrationStatus {
+	s.migrationsLock.Lock()
+	defer s.migrationsLock.Unlock()
+	for _, migrationStatus := range s.migrations {
+		if migrationStatus.EndTime != nil || migrationStatus.DryRun {
+			continue
+		}
+		name, id, zone, _, err := utils.ParseNormalizedVolumeID(ctx, migrationStatus.VolumeID)
+		if err == nil && name == volName && id == exportID && zone == accessZone && migrationStatus.SourceCluster == clusterName {
+			return copyMigrationStatus(migrationStatus)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package service
+
+/*
+ Copyright (c) 2019 Dell Inc, or its subsidiaries.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/dell/csi-isilon/common/utils"
+)
+
+// Parameters which can only be set on existing volumes, through ControllerModifyVolume. The quota limits are percentages of
+// the hard limit of the quota of the volume, 0 removes the limit, and the grace period is a duration such as '168h'.
+const (
+	QuotaSoftLimitParam        = "QuotaSoftLimit"
+	QuotaSoftGracePeriodParam  = "QuotaSoftGracePeriod"
+	QuotaAdvisoryLimitParam    = "QuotaAdvisoryLimit"
+	ExportSecurityFlavorsParam = "ExportSecurityFlavors"
+)
+
+var (
+	// the parameters ControllerModifyVolume applies to an existing volume
+	mutableVolumeParams = []string{
+		RootClientEnabledParam, StoragePoolParam, ProtectionLevelParam, SSDStrategyParam,
+		QuotaSoftLimitParam, QuotaSoftGracePeriodParam, QuotaAdvisoryLimitParam, ExportSecurityFlavorsParam,
+	}
+	// the storage class parameters which are only used when the volume is created
+	immutableVolumeParams = []string{
+		AccessZoneParam, IsiPathParam, AzServiceIPParam, ClusterNameParam,
+		VolumeOwnerParam, VolumeGroupParam, VolumeModeParam, VolumeACLParam,
+		SmartLockEnabledParam, SmartLockTypeParam, SmartLockDefaultRetentionParam, SmartLockMinRetentionParam,
+		SmartLockMaxRetentionParam, SmartLockAutocommitOffsetParam, SnapRevertEnabledParam, SnapshotRestoreModeParam,
+		SnapshotScheduleParam, SnapshotSchedulePatternParam, SnapshotScheduleRetentionParam,
+	}
+	// the security flavors of the NFS exports of OneFS
+	exportSecurityFlavors = []string{"unix", "krb5", "krb5i", "krb5p"}
+)
+
+// ModifyVolumeRequest is the request of the ModifyVolume admin RPC. It mirrors the ControllerModifyVolume RPC,
+// which Kubernetes only calls for the volumes of a VolumeAttributesClass.
+type ModifyVolumeRequest struct {
+	// VolumeID is the normalized ID of the volume to modify
+	VolumeID string `json:"volumeId"`
+	// MutableParameters are the parameters to apply to the volume, see mutableVolumeParams
+	MutableParameters map[string]string `json:"mutableParameters"`
+}
+
+// ModifyVolumeResponse is the response of the ModifyVolume admin RPC
+type ModifyVolumeResponse struct{}
+
+// quotaThresholds are the thresholds of a quota as accepted by 'platform/1/quota/quotas', a nil limit is removed
+type quotaThresholds struct {
+	Hard      int64  `json:"hard"`
+	Soft      *int64 `json:"soft"`
+	SoftGrace *int64 `json:"soft_grace,omitempty"`
+	Advisory  *int64 `json:"advisory"`
+}
+
+// volumeModification holds the parsed mutable parameters of a ControllerModifyVolume request, nil for those not set
+type volumeModification struct {
+	rootClientEnabled    *bool
+	smartPools           *smartPoolsSettings
+	quotaSoftLimit       *int64
+	quotaSoftGracePeriod *int64
+	quotaAdvisoryLimit   *int64
+	securityFlavors      []string
+}
+
+func (m *volumeModification) modifiesQuota() bool {
+	return m.quotaSoftLimit != nil || m.quotaSoftGracePeriod != nil || m.quotaAdvisoryLimit != nil
+}
+
+// parseQuotaLimit parses a quota limit parameter, a percentage of the hard limit
+func parseQuotaLimit(name, value string) (*int64, error) {
+	percent, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || percent < 0 || percent > 100 {
+		return nil, fmt.Errorf("invalid value '%s' for '%s', a percentage between 0 and 100 is expected", value, name)
+	}
+	return &percent, nil
+}
+
+// getVolumeModification parses the mutable parameters of a ControllerModifyVolume request, immutable and unknown parameters
+// are rejected
+func getVolumeModification(params map[string]string) (*volumeModification, error) {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	modification := &volumeModification{}
+	for _, name := range names {
+		value := params[name]
+		switch name {
+		case RootClientEnabledParam:
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value '%s' for '%s', 'true' or 'false' is expected", value, name)
+			}
+			modification.rootClientEnabled = &enabled
+		case StoragePoolParam, ProtectionLevelParam, SSDStrategyParam:
+			// parsed below, all at once
+		case QuotaSoftLimitParam, QuotaAdvisoryLimitParam:
+			limit, err := parseQuotaLimit(name, value)
+			if err != nil {
+				return nil, err
+			}
+			if name == QuotaSoftLimitParam {
+				modification.quotaSoftLimit = limit
+			} else {
+				modification.quotaAdvisoryLimit = limit
+			}
+		case QuotaSoftGracePeriodParam:
+			gracePeriod, err := time.ParseDuration(value)
+			if err != nil || gracePeriod < time.Second {
+				return nil, fmt.Errorf("invalid value '%s' for '%s', a duration of at least one second such as '168h' is expected",
+					value, name)
+			}
+			seconds := int64(gracePeriod / time.Second)
+			modification.quotaSoftGracePeriod = &seconds
+		case ExportSecurityFlavorsParam:
+			for _, flavor := range strings.Split(value, ",") {
+				flavor = strings.ToLower(strings.TrimSpace(flavor))
+				if !utils.IsStringInSlice(flavor, exportSecurityFlavors) {
+					return nil, fmt.Errorf("invalid value '%s' for '%s', a comma-separated list of '%s' is expected",
+						value, name, strings.Join(exportSecurityFlavors, "', '"))
+				}
+				modification.securityFlavors = append(modification.securityFlavors, flavor)
+			}
+		default:
+			if utils.IsStringInSlice(name, immutableVolumeParams) {
+				return nil, fmt.Errorf("parameter '%s' cannot be modified once the volume is created", name)
+			}
+			return nil, fmt.Errorf("unknown parameter '%s', the parameters which can be modified are '%s'",
+				name, strings.Join(mutableVolumeParams, "', '"))
+		}
+	}
+
+	smartPools, err := getSmartPoolsSettings(params)
+	if err != nil {
+		return nil, err
+	}
+	modification.smartPools = smartPools
+	return modification, nil
+}
+
+// getModifiedQuotaThresholds returns the thresholds of a quota with the limits of a modification applied
+func getModifiedQuotaThresholds(hard, soft, advisory int64, modification *volumeModification) (*quotaThresholds, error) {
+	thresholds := &quotaThresholds{Hard: hard, SoftGrace: modification.quotaSoftGracePeriod}
+	if soft > 0 {
+		thresholds.Soft = &soft
+	}
+	if advisory > 0 {
+		thresholds.Advisory = &advisory
+	}
+
+	limit := func(percent *int64) *int64 {
+		if *percent == 0 {
+			return nil
+		}
+		value := hard * *percent / 100
+		return &value
+	}
+	if modification.quotaSoftLimit != nil {
+		thresholds.Soft = limit(modification.quotaSoftLimit)
+		// OneFS requires a grace period with a soft limit
+		if thresholds.Soft != nil && soft <= 0 && thresholds.SoftGrace == nil {
+			return nil, fmt.Errorf("'%s' is required to set '%s' on a quota without soft limit", QuotaSoftGracePeriodParam, QuotaSoftLimitParam)
+		}
+	}
+	if modification.quotaAdvisoryLimit != nil {
+		thresholds.Advisory = limit(modification.quotaAdvisoryLimit)
+	}
+	return thresholds, nil
+}
+
+// ModifyVolume applies mutable parameters to an existing volume through ControllerModifyVolume, for the clusters
+// whose Kubernetes version has no VolumeAttributesClass
+func (s *service) ModifyVolume(ctx context.Context, req *ModifyVolumeRequest) (*ModifyVolumeResponse, error) {
+	if _, err := s.ControllerModifyVolume(ctx, &csi.ControllerModifyVolumeRequest{
+		VolumeId:          req.VolumeID,
+		MutableParameters: req.MutableParameters,
+	}); err != nil {
+		return nil, err
+	}
+	return &ModifyVolumeResponse{}, nil
+}
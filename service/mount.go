@@ -111,9 +111,11 @@ func publishVolume(
 					logrus.WithFields(f).Error("Mount point already in use by device with different options")
 					return status.Error(codes.AlreadyExists, "Mount point already in use by device with different options")
 				}
-				//T1!=T2, P1==P2 || P1 != P2 - return FailedPrecondition for single node
+				//T1!=T2, P1==P2 || P1 != P2 - return FailedPrecondition for single node, except for
+				//SINGLE_NODE_MULTI_WRITER which allows several pods of the node to use the volume
 				if accMode.GetMode() == csi.VolumeCapability_AccessMode_SINGLE_NODE_WRITER ||
-					accMode.GetMode() == csi.VolumeCapability_AccessMode_SINGLE_NODE_READER_ONLY {
+					accMode.GetMode() == csi.VolumeCapability_AccessMode_SINGLE_NODE_READER_ONLY ||
+					accMode.GetMode() == csi.VolumeCapability_AccessMode_SINGLE_NODE_SINGLE_WRITER {
 					logrus.WithFields(f).Error("Mount point already in use for same device")
 					return status.Error(codes.FailedPrecondition, "Mount point already in use for same device")
 				}
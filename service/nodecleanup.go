@@ -0,0 +1,434 @@
+package service
+
+/*
+ Copyright (c) 2019 Dell Inc, or its subsidiaries.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dell/csi-isilon/common/constants"
+	"github.com/dell/csi-isilon/common/utils"
+	isi "github.com/dell/goisilon"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	// nodeIDAnnotation is set by kubelet on a Node with the node IDs reported by the CSI drivers running on it
+	nodeIDAnnotation = "csi.volume.kubernetes.io/nodeid"
+
+	// publishContextNodeID is the key of the node ID in the publish context returned by ControllerPublishVolume,
+	// which the external-attacher stores in the attachment metadata of the VolumeAttachment
+	publishContextNodeID = "NodeID"
+
+	// nodeCleanupResyncPeriod is the interval at which Nodes and VolumeAttachments are re-examined
+	nodeCleanupResyncPeriod = 10 * time.Minute
+
+	// nodeCleanupMaxRetries is the number of times the cleanup of a node is retried before it is dropped until the
+	// next resync of its VolumeAttachments
+	nodeCleanupMaxRetries = 15
+)
+
+// nodeCleanupItem is a node to remove from the exports, the node ID is resolved when the item is processed if it
+// was not known when the node was queued
+type nodeCleanupItem struct {
+	nodeName string
+	nodeID   string
+}
+
+// nodeCleanupController removes the nodes deleted from the kubernetes cluster from the client lists of the CSI exports
+type nodeCleanupController struct {
+	ctx        context.Context
+	svc        *service
+	nodeLister corelisters.NodeLister
+	queue      workqueue.RateLimitingInterface
+}
+
+// startNodeCleanupController watches Nodes, CSINodes and VolumeAttachments, and strips the nodes which are gone
+// from all the CSI exports. When leader election is enabled, only the leader runs the controller service.
+func (s *service) startNodeCleanupController(ctx context.Context) {
+	ctx, log := GetLogger(ctx)
+	if !strings.EqualFold(s.mode, constants.ModeController) || !s.opts.NodeCleanupEnabled {
+		return
+	}
+
+	if s.k8sclient == nil {
+		log.Warn("kubernetes client is not available, node cleanup controller will not be started")
+		return
+	}
+
+	factory := informers.NewSharedInformerFactory(s.k8sclient, nodeCleanupResyncPeriod)
+	nodeInformer := factory.Core().V1().Nodes()
+	c := &nodeCleanupController{
+		ctx:        ctx,
+		svc:        s,
+		nodeLister: nodeInformer.Lister(),
+		queue:      workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "node-cleanup"),
+	}
+
+	nodeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		DeleteFunc: c.onNodeDelete,
+	})
+	factory.Start(ctx.Done())
+
+	go func() {
+		defer c.queue.ShutDown()
+
+		// VolumeAttachments and CSINodes are checked against the node cache, so wait for it to be populated first
+		if !cache.WaitForCacheSync(ctx.Done(), nodeInformer.Informer().HasSynced) {
+			log.Error("failed to sync the node cache, node cleanup controller is not started")
+			return
+		}
+
+		// the VolumeAttachments listed at startup also queue the nodes deleted while the controller was down
+		factory.Storage().V1().VolumeAttachments().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    c.onVolumeAttachmentAdd,
+			UpdateFunc: c.onVolumeAttachmentUpdate,
+		})
+		factory.Storage().V1().CSINodes().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			DeleteFunc: c.onCSINodeDelete,
+		})
+		factory.Start(ctx.Done())
+
+		go wait.Until(c.runWorker, time.Second, ctx.Done())
+		log.Info("node cleanup controller started")
+		<-ctx.Done()
+	}()
+}
+
+func (c *nodeCleanupController) onNodeDelete(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	if node, ok := obj.(*v1.Node); ok {
+		c.queue.Add(nodeCleanupItem{nodeName: node.Name, nodeID: getCSINodeID(node)})
+	}
+}
+
+// onCSINodeDelete queues the node of a CSINode removed along with its node, the CSINode carries the node ID of
+// the driver even when the node annotation was not set
+func (c *nodeCleanupController) onCSINodeDelete(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	if csiNode, ok := obj.(*storagev1.CSINode); ok {
+		if nodeID := getCSINodeDriverID(csiNode); nodeID != "" {
+			c.queue.Add(nodeCleanupItem{nodeName: csiNode.Name, nodeID: nodeID})
+		}
+	}
+}
+
+func (c *nodeCleanupController) onVolumeAttachmentAdd(obj interface{}) {
+	if va, ok := obj.(*storagev1.VolumeAttachment); ok {
+		c.checkVolumeAttachment(va)
+	}
+}
+
+func (c *nodeCleanupController) onVolumeAttachmentUpdate(oldObj, newObj interface{}) {
+	if va, ok := newObj.(*storagev1.VolumeAttachment); ok {
+		c.checkVolumeAttachment(va)
+	}
+}
+
+// checkVolumeAttachment queues the node of a VolumeAttachment created by the driver if the node no longer exists
+func (c *nodeCleanupController) checkVolumeAttachment(va *storagev1.VolumeAttachment) {
+	if va.Spec.Attacher != constants.PluginName {
+		return
+	}
+	if _, err := c.nodeLister.Get(va.Spec.NodeName); apierrors.IsNotFound(err) {
+		c.queue.Add(nodeCleanupItem{nodeName: va.Spec.NodeName})
+	}
+}
+
+func (c *nodeCleanupController) runWorker() {
+	for c.processNextItem() {
+	}
+}
+
+// processNextItem cleans up the next queued node, failed cleanups are retried with an increasing delay
+func (c *nodeCleanupController) processNextItem() bool {
+	obj, shutdown := c.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.queue.Done(obj)
+
+	ctx, log := GetLogger(c.ctx)
+	item := obj.(nodeCleanupItem)
+	if err := c.cleanupNode(ctx, item); err != nil {
+		if c.queue.NumRequeues(obj) < nodeCleanupMaxRetries {
+			log.Errorf("failed to clean up node '%s', will retry later: '%v'", item.nodeName, err)
+			c.queue.AddRateLimited(obj)
+			return true
+		}
+		log.Errorf("failed to clean up node '%s', giving up until the next resync: '%v'", item.nodeName, err)
+	}
+	c.queue.Forget(obj)
+	return true
+}
+
+func (c *nodeCleanupController) cleanupNode(ctx context.Context, item nodeCleanupItem) error {
+	ctx, log := GetLogger(ctx)
+
+	// the node may have been registered again since it was queued
+	if _, err := c.nodeLister.Get(item.nodeName); !apierrors.IsNotFound(err) {
+		return nil
+	}
+
+	nodeID := item.nodeID
+	if nodeID == "" {
+		var err error
+		if nodeID, err = c.svc.resolveNodeID(ctx, item.nodeName); err != nil {
+			return err
+		}
+		if nodeID == "" {
+			log.Warnf("node '%s' no longer exists, but its node ID cannot be resolved from its CSINode, VolumeAttachments or exports", item.nodeName)
+			return nil
+		}
+	}
+
+	log.Infof("node '%s' has been deleted, begin to remove node ID '%s' from the exports", item.nodeName, nodeID)
+	if err := c.svc.removeNodeFromExports(ctx, nodeID); err != nil {
+		return err
+	}
+	log.Infof("node '%s' removed from the exports", item.nodeName)
+	return nil
+}
+
+// getCSINodeID returns the node ID of the driver from the node ID annotation of the node
+func getCSINodeID(node *v1.Node) string {
+	annotation, ok := node.Annotations[nodeIDAnnotation]
+	if !ok {
+		return ""
+	}
+	nodeIDs := make(map[string]string)
+	if err := json.Unmarshal([]byte(annotation), &nodeIDs); err != nil {
+		return ""
+	}
+	return nodeIDs[constants.PluginName]
+}
+
+// getCSINodeDriverID returns the node ID the driver registered in a CSINode
+func getCSINodeDriverID(csiNode *storagev1.CSINode) string {
+	for _, driver := range csiNode.Spec.Drivers {
+		if driver.Name == constants.PluginName {
+			return driver.NodeID
+		}
+	}
+	return ""
+}
+
+// resolveNodeID returns the node ID of a node from its CSINode, which is garbage collected some time after the node,
+// then from the attachment metadata of its VolumeAttachments, and finally from the export clients of the volumes
+// of its VolumeAttachments. An empty node ID is returned if none of them knows the node.
+func (s *service) resolveNodeID(ctx context.Context, nodeName string) (string, error) {
+	ctx, log := GetLogger(ctx)
+
+	csiNode, err := s.k8sclient.StorageV1().CSINodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err == nil {
+		if nodeID := getCSINodeDriverID(csiNode); nodeID != "" {
+			return nodeID, nil
+		}
+	} else if !apierrors.IsNotFound(err) {
+		return "", err
+	}
+
+	vas, err := s.k8sclient.StorageV1().VolumeAttachments().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", err
+	}
+	var pvNames []string
+	for _, va := range vas.Items {
+		if va.Spec.Attacher != constants.PluginName || va.Spec.NodeName != nodeName {
+			continue
+		}
+		if nodeID := va.Status.AttachmentMetadata[publishContextNodeID]; nodeID != "" {
+			return nodeID, nil
+		}
+		if va.Spec.Source.PersistentVolumeName != nil {
+			pvNames = append(pvNames, *va.Spec.Source.PersistentVolumeName)
+		}
+	}
+
+	// the volumes published before the node ID was recorded in the attachment metadata have the node as a client,
+	// which is found by the name of the node
+	for _, pvName := range pvNames {
+		nodeID, err := s.getNodeIDFromVolumeExport(ctx, pvName, nodeName)
+		if err != nil {
+			log.Warnf("failed to look for node '%s' in the export of persistent volume '%s': '%v'", nodeName, pvName, err)
+			continue
+		}
+		if nodeID != "" {
+			return nodeID, nil
+		}
+	}
+	return "", nil
+}
+
+// getNodeIDFromVolumeExport builds the node ID of a node from the client of the export of a persistent volume
+// which is the node name or an FQDN of the node name, an empty node ID is returned if the node is not a client
+func (s *service) getNodeIDFromVolumeExport(ctx context.Context, pvName, nodeName string) (string, error) {
+	pv, err := s.k8sclient.CoreV1().PersistentVolumes().Get(ctx, pvName, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	if pv.Spec.CSI == nil || pv.Spec.CSI.Driver != constants.PluginName {
+		return "", nil
+	}
+	_, exportID, accessZone, clusterName, err := utils.ParseNormalizedVolumeID(ctx, pv.Spec.CSI.VolumeHandle)
+	if err != nil {
+		return "", err
+	}
+	isiConfig, err := s.getIsilonConfig(ctx, &clusterName)
+	if err != nil {
+		return "", err
+	}
+	ctx, _ = setClusterContext(ctx, isiConfig.ClusterName)
+	if err := s.autoProbe(ctx, isiConfig); err != nil {
+		return "", err
+	}
+	export, err := isiConfig.isiSvc.GetExportByIDWithZone(ctx, exportID, accessZone)
+	if err != nil || export == nil {
+		return "", err
+	}
+	for _, clients := range getExportClients(export) {
+		for _, client := range clients {
+			if client == nodeName || strings.HasPrefix(client, nodeName+".") {
+				return nodeName + utils.NodeIDSeparator + client + utils.NodeIDSeparator + client, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// removeNodeFromExports removes the given node from the client lists of all the CSI exports on all the clusters
+func (s *service) removeNodeFromExports(ctx context.Context, nodeID string) error {
+	ctx, log := GetLogger(ctx)
+
+	clientName, clientFQDN, clientIP, err := utils.ParseNodeID(ctx, nodeID)
+	if err != nil {
+		return err
+	}
+
+	accessZones := s.getCSIAccessZones(ctx)
+	var failures []string
+	s.isiClusters.Range(func(key interface{}, value interface{}) bool {
+		isiConfig := value.(*IsilonClusterConfig)
+		ctx, log := setClusterContext(ctx, isiConfig.ClusterName)
+		if err := s.autoProbe(ctx, isiConfig); err != nil {
+			failures = append(failures, fmt.Sprintf("cluster '%s': '%v'", isiConfig.ClusterName, err))
+			return true
+		}
+
+		zones := accessZones[isiConfig.ClusterName]
+		if zones == nil {
+			zones = map[string]bool{s.opts.AccessZone: true}
+		}
+		for accessZone := range zones {
+			exports, err := isiConfig.isiSvc.GetExportsWithZone(ctx, accessZone)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("cluster '%s': '%v'", isiConfig.ClusterName, err))
+				continue
+			}
+			for _, export := range exports {
+				clients := getExportClients(export)
+				// every export created by the driver has the dummy localhost client
+				if !isCSIExport(clients) {
+					continue
+				}
+				if !utils.IsStringInSlices(clientName, clients...) && !utils.IsStringInSlices(clientFQDN, clients...) &&
+					!utils.IsStringInSlices(clientIP, clients...) {
+					continue
+				}
+				log.Infof("removing node '%s' from export '%d' in access zone '%s'", clientName, export.ID, accessZone)
+				if err := isiConfig.isiSvc.RemoveExportClientByIDWithZone(ctx, export.ID, accessZone, nodeID); err != nil {
+					failures = append(failures, fmt.Sprintf("cluster '%s': '%v'", isiConfig.ClusterName, err))
+				}
+			}
+		}
+		return true
+	})
+
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to remove node '%s' from exports: %s", clientName, strings.Join(failures, ", "))
+	}
+	log.Debugf("node '%s' is not a client of any CSI export anymore", clientName)
+	return nil
+}
+
+// getCSIAccessZones returns the access zones of the CSI volumes per cluster, the default access zone is always included
+func (s *service) getCSIAccessZones(ctx context.Context) map[string]map[string]bool {
+	ctx, log := GetLogger(ctx)
+
+	accessZones := make(map[string]map[string]bool)
+	addZone := func(clusterName, accessZone string) {
+		if accessZones[clusterName] == nil {
+			accessZones[clusterName] = make(map[string]bool)
+		}
+		accessZones[clusterName][accessZone] = true
+	}
+	s.isiClusters.Range(func(key interface{}, value interface{}) bool {
+		addZone(value.(*IsilonClusterConfig).ClusterName, s.opts.AccessZone)
+		return true
+	})
+
+	pvs, err := s.k8sclient.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Warnf("failed to list persistent volumes, only access zone '%s' will be checked: '%v'", s.opts.AccessZone, err)
+		return accessZones
+	}
+	for _, pv := range pvs.Items {
+		if pv.Spec.CSI == nil || pv.Spec.CSI.Driver != constants.PluginName {
+			continue
+		}
+		_, _, accessZone, clusterName, err := utils.ParseNormalizedVolumeID(ctx, pv.Spec.CSI.VolumeHandle)
+		if err != nil {
+			continue
+		}
+		if clusterName == "" {
+			clusterName = s.defaultIsiClusterName
+		}
+		addZone(clusterName, accessZone)
+	}
+	return accessZones
+}
+
+func getExportClients(export isi.Export) [][]string {
+	var clients [][]string
+	for _, field := range []*[]string{export.Clients, export.RootClients, export.ReadWriteClients, export.ReadOnlyClients} {
+		if field != nil {
+			clients = append(clients, *field)
+		}
+	}
+	return clients
+}
+
+func isCSIExport(clients [][]string) bool {
+	dummyName, dummyFQDN, dummyIP, _ := utils.ParseNodeID(context.Background(), utils.DummyHostNodeID)
+	return utils.IsStringInSlices(dummyName, clients...) || utils.IsStringInSlices(dummyFQDN, clients...) ||
+		utils.IsStringInSlices(dummyIP, clients...)
+}
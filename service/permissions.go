@@ -0,0 +1,194 @@
+package service
+
+/*
+ Copyright (c) 2019 Dell Inc, or its subsidiaries.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/dell/csi-isilon/common/utils"
+	"github.com/dell/goisilon/api"
+)
+
+const (
+	namespacePath     = "namespace"
+	authPath          = "platform/1/auth/"
+	personaTypeUser   = "user"
+	personaTypeGroup  = "group"
+	authoritativeMode = "mode"
+	authoritativeACL  = "acl"
+	actionReplace     = "replace"
+)
+
+var aclQueryParams = api.OrderedValues{{[]byte("acl")}}
+
+var metadataQueryParams = api.OrderedValues{{[]byte("metadata")}}
+
+// volumeACL is the security descriptor of a directory as returned and accepted by '/namespace/<path>?acl'
+type volumeACL struct {
+	Authoritative string            `json:"authoritative,omitempty"`
+	Action        string            `json:"action,omitempty"`
+	Owner         *volumePersona    `json:"owner,omitempty"`
+	Group         *volumePersona    `json:"group,omitempty"`
+	Mode          string            `json:"mode,omitempty"`
+	ACL           []json.RawMessage `json:"acl,omitempty"`
+}
+
+// volumePersona identifies a user or a group either by ID (UID:<uid>, GID:<gid>) or by name and type
+type volumePersona struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+	Type string `json:"type,omitempty"`
+}
+
+// volumePermissions holds the ownership, mode and ACL of the volumes of a storage class
+type volumePermissions struct {
+	owner string
+	group string
+	mode  string
+	acl   []json.RawMessage
+}
+
+// getVolumePermissions parses the VolumeOwner, VolumeGroup, VolumeMode and VolumeACL storage class parameters,
+// returns nil if none of them is set
+func getVolumePermissions(params map[string]string) (*volumePermissions, error) {
+	permissions := &volumePermissions{
+		owner: params[VolumeOwnerParam],
+		group: params[VolumeGroupParam],
+	}
+
+	if mode, ok := params[VolumeModeParam]; ok && mode != "" {
+		value, err := strconv.ParseUint(mode, 8, 32)
+		if err != nil || value > 07777 {
+			return nil, fmt.Errorf("invalid value '%s' for '%s', an octal mode such as '0750' is expected", mode, VolumeModeParam)
+		}
+		permissions.mode = fmt.Sprintf("%04o", value)
+	}
+
+	if acl, ok := params[VolumeACLParam]; ok && acl != "" {
+		if err := json.Unmarshal([]byte(acl), &permissions.acl); err != nil {
+			return nil, fmt.Errorf("invalid value for '%s', a JSON list of access control entries is expected : '%v'", VolumeACLParam, err)
+		}
+	}
+
+	if permissions.owner == "" && permissions.group == "" && permissions.mode == "" && len(permissions.acl) == 0 {
+		return nil, nil
+	}
+	return permissions, nil
+}
+
+// getPersona returns the persona of an owner or group given either as a numeric ID or as a name of the access zone
+func (svc *isiService) getPersona(ctx context.Context, personaType, value, accessZone string) (*volumePersona, error) {
+	if _, err := strconv.ParseUint(value, 10, 32); err == nil {
+		if personaType == personaTypeUser {
+			return &volumePersona{ID: "UID:" + value}, nil
+		}
+		return &volumePersona{ID: "GID:" + value}, nil
+	}
+
+	id, err := svc.GetPersonaID(ctx, personaType, value, accessZone)
+	if err != nil {
+		return nil, err
+	}
+	return &volumePersona{ID: id}, nil
+}
+
+// setVolumePermissions applies the ownership, mode and ACL of the storage class to the directory of a volume
+func (svc *isiService) setVolumePermissions(ctx context.Context, dirPath, accessZone string, permissions *volumePermissions) error {
+	acl := &volumeACL{
+		Action:        actionReplace,
+		Authoritative: authoritativeMode,
+		Mode:          permissions.mode,
+	}
+	if len(permissions.acl) > 0 {
+		acl.Authoritative = authoritativeACL
+		acl.Mode = ""
+		acl.ACL = permissions.acl
+	} else if acl.Mode == "" {
+		// only the ownership is changed, keep the mode the directory is created with
+		current, err := svc.GetVolumeACL(ctx, dirPath)
+		if err != nil {
+			return err
+		}
+		acl.Mode = current.Mode
+	}
+
+	var err error
+	if permissions.owner != "" {
+		if acl.Owner, err = svc.getPersona(ctx, personaTypeUser, permissions.owner, accessZone); err != nil {
+			return err
+		}
+	}
+	if permissions.group != "" {
+		if acl.Group, err = svc.getPersona(ctx, personaTypeGroup, permissions.group, accessZone); err != nil {
+			return err
+		}
+	}
+
+	return svc.SetVolumeACL(ctx, dirPath, acl)
+}
+
+// copyVolumePermissions gives the directory of a cloned volume the ownership, mode and ACL of its source
+func (svc *isiService) copyVolumePermissions(ctx context.Context, srcDirPath, dstDirPath string) error {
+	acl, err := svc.GetVolumeACL(ctx, srcDirPath)
+	if err != nil {
+		return err
+	}
+
+	acl.Action = actionReplace
+	// the ID is enough to identify the owner and the group
+	if acl.Owner != nil && acl.Owner.ID != "" {
+		acl.Owner = &volumePersona{ID: acl.Owner.ID}
+	}
+	if acl.Group != nil && acl.Group.ID != "" {
+		acl.Group = &volumePersona{ID: acl.Group.ID}
+	}
+	if acl.Authoritative == authoritativeMode {
+		// with mode authority, the ACL is only the representation of the mode
+		acl.ACL = nil
+	}
+
+	return svc.SetVolumeACL(ctx, dstDirPath, acl)
+}
+
+// applyVolumePermissions gives a cloned volume the permissions of its source, then applies the ones of the storage class
+func (s *service) applyVolumePermissions(ctx context.Context, isiConfig *IsilonClusterConfig, isiPath, volName, accessZone string,
+	contentSource *csi.VolumeContentSource, snapshotIsiPath string, permissions *volumePermissions) error {
+	dirPath := utils.GetPathForVolume(isiPath, volName)
+
+	if contentSource != nil {
+		srcDirPath := snapshotIsiPath
+		if contentVolume := contentSource.GetVolume(); contentVolume != nil {
+			srcVolumeName, _, _, _, err := utils.ParseNormalizedVolumeID(ctx, contentVolume.GetVolumeId())
+			if err != nil {
+				return err
+			}
+			srcDirPath = utils.GetPathForVolume(isiPath, srcVolumeName)
+		}
+		if err := isiConfig.isiSvc.copyVolumePermissions(ctx, srcDirPath, dirPath); err != nil {
+			return err
+		}
+	}
+
+	if permissions != nil {
+		return isiConfig.isiSvc.setVolumePermissions(ctx, dirPath, accessZone, permissions)
+	}
+	return nil
+}
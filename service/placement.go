@@ -0,0 +1,291 @@
+package service
+
+/*
+ Copyright (c) 2019 Dell Inc, or its subsidiaries.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/dell/csi-isilon/common/constants"
+	"github.com/dell/csi-isilon/common/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Placement modes of the volumes whose storage class has no ClusterName
+const (
+	// ClusterPlacementDefault places the volumes on the default cluster
+	ClusterPlacementDefault = "default"
+	// ClusterPlacementCapacity places the volumes on the eligible cluster with the most weighted free space
+	ClusterPlacementCapacity = "capacity"
+
+	defaultPlacementWeight = 1
+	availableCapacityKey   = "ifs.bytes.avail"
+)
+
+// clusterCandidate is a cluster considered for the placement of a new volume
+type clusterCandidate struct {
+	clusterName    string
+	availableBytes int64
+	weight         int
+	// preference is the index of the first preferred topology giving access to the cluster
+	preference int
+	// hasVolume is true if the directory of the volume already exists on the cluster
+	hasVolume bool
+	err       error
+}
+
+// score returns the weighted free space of the cluster
+func (c *clusterCandidate) score() float64 {
+	return float64(c.availableBytes) * float64(c.weight)
+}
+
+// selectCluster picks the cluster of a new volume among the clusters which are reachable, allowed for the tenant
+// of the PVC namespace, accessible from the requested topology and have enough free space. The cluster with the
+// most free space, scaled by its placementWeight, is chosen. Clusters with a placementWeight of 0 are never chosen.
+// A cluster which already has the directory of the volume, created by a previous call of a retried request, is
+// chosen whatever its free space so that the retry does not create the volume a second time on another cluster.
+func (s *service) selectCluster(ctx context.Context, req *csi.CreateVolumeRequest) (string, error) {
+	ctx, log, runID := GetRunIDLog(ctx)
+
+	sizeInBytes, err := validateVolSize(req.GetCapacityRange())
+	if err != nil {
+		return "", err
+	}
+
+	var tenant *TenantConfig
+	var allowedClusters []string
+	if namespace := req.GetParameters()[csiPersistentVolumeClaimNamespace]; namespace != "" {
+		if tenant, err = s.getTenant(ctx, namespace); err != nil {
+			return "", status.Error(codes.Internal, utils.GetMessageWithRunID(runID, "failed to get the tenant of namespace '%s': '%v'", namespace, err))
+		}
+		if tenant != nil {
+			allowedClusters = tenant.AllowedClusters
+		}
+	}
+
+	var configs []*IsilonClusterConfig
+	s.isiClusters.Range(func(key interface{}, value interface{}) bool {
+		isiConfig := value.(*IsilonClusterConfig)
+		weight := defaultPlacementWeight
+		if isiConfig.PlacementWeight != nil {
+			weight = *isiConfig.PlacementWeight
+		}
+		switch {
+		case weight == 0:
+			log.Debugf("cluster '%s' is excluded from placement by its weight", isiConfig.ClusterName)
+		case len(allowedClusters) > 0 && !utils.IsStringInSlice(isiConfig.ClusterName, allowedClusters):
+			log.Debugf("cluster '%s' is not allowed for the tenant of the PVC namespace", isiConfig.ClusterName)
+		case !s.opts.CustomTopologyEnabled && !isAccessibleFrom(isiConfig, req.GetAccessibilityRequirements()):
+			log.Debugf("cluster '%s' is not accessible from the requested topology", isiConfig.ClusterName)
+		default:
+			configs = append(configs, isiConfig)
+		}
+		return true
+	})
+
+	// the clusters are considered in the order of their names, so that the choice is deterministic
+	sort.Slice(configs, func(i, j int) bool {
+		return configs[i].ClusterName < configs[j].ClusterName
+	})
+
+	// query the clusters concurrently so that an unreachable cluster does not delay the others
+	candidates := make([]*clusterCandidate, len(configs))
+	var wg sync.WaitGroup
+	for i, isiConfig := range configs {
+		wg.Add(1)
+		go func(i int, isiConfig *IsilonClusterConfig) {
+			defer wg.Done()
+			volPath := utils.GetPathForVolume(getPlacementIsiPath(req.GetParameters(), isiConfig, tenant), req.GetName())
+			candidates[i] = s.getClusterCandidate(ctx, isiConfig, volPath)
+			candidates[i].preference = s.getTopologyPreference(isiConfig, req.GetAccessibilityRequirements())
+		}(i, isiConfig)
+	}
+	wg.Wait()
+
+	for _, candidate := range candidates {
+		if candidate.err == nil && candidate.hasVolume {
+			log.Infof("cluster '%s' selected for volume '%s' which already exists on it", candidate.clusterName, req.GetName())
+			return candidate.clusterName, nil
+		}
+	}
+
+	var eligible []*clusterCandidate
+	var failures []string
+	for _, candidate := range candidates {
+		switch {
+		case candidate.err != nil:
+			failures = append(failures, fmt.Sprintf("cluster '%s': '%v'", candidate.clusterName, candidate.err))
+		case candidate.availableBytes < sizeInBytes:
+			failures = append(failures, fmt.Sprintf("cluster '%s': '%d' bytes available", candidate.clusterName, candidate.availableBytes))
+		default:
+			eligible = append(eligible, candidate)
+		}
+	}
+
+	if len(eligible) == 0 {
+		if len(candidates) == 0 {
+			return "", status.Error(codes.FailedPrecondition, utils.GetMessageWithRunID(runID,
+				"no cluster is eligible for the placement of volume '%s'", req.GetName()))
+		}
+		return "", status.Error(codes.ResourceExhausted, utils.GetMessageWithRunID(runID,
+			"no cluster can host volume '%s' of '%d' bytes: %s", req.GetName(), sizeInBytes, strings.Join(failures, ", ")))
+	}
+
+	// the preferred topologies come first, then break ties by cluster name so that the choice is deterministic
+	sort.Slice(eligible, func(i, j int) bool {
+		if eligible[i].preference != eligible[j].preference {
+			return eligible[i].preference < eligible[j].preference
+		}
+		if eligible[i].score() != eligible[j].score() {
+			return eligible[i].score() > eligible[j].score()
+		}
+		return eligible[i].clusterName < eligible[j].clusterName
+	})
+	log.Infof("cluster '%s' with '%d' bytes available selected for volume '%s'", eligible[0].clusterName, eligible[0].availableBytes, req.GetName())
+	return eligible[0].clusterName, nil
+}
+
+// getClusterCandidate probes a cluster, checks whether the directory of the volume already exists on it and gets
+// its free space
+func (s *service) getClusterCandidate(ctx context.Context, isiConfig *IsilonClusterConfig, volPath string) *clusterCandidate {
+	ctx, _ = setClusterContext(ctx, isiConfig.ClusterName)
+	candidate := &clusterCandidate{
+		clusterName: isiConfig.ClusterName,
+		weight:      defaultPlacementWeight,
+	}
+	if isiConfig.PlacementWeight != nil {
+		candidate.weight = *isiConfig.PlacementWeight
+	}
+
+	if err := s.autoProbe(ctx, isiConfig); err != nil {
+		candidate.err = err
+		return candidate
+	}
+	hasVolume, err := isiConfig.isiSvc.IsDirectoryExistent(ctx, volPath)
+	if err != nil {
+		candidate.err = err
+		return candidate
+	}
+	if hasVolume {
+		candidate.hasVolume = true
+		return candidate
+	}
+	stat, err := isiConfig.isiSvc.GetStatistics(ctx, []string{availableCapacityKey})
+	if err != nil {
+		candidate.err = err
+		return candidate
+	}
+	if len(stat.StatsList) < 1 {
+		candidate.err = fmt.Errorf("no '%s' statistics returned", availableCapacityKey)
+		return candidate
+	}
+	if stat.StatsList[0].Error != "" {
+		candidate.err = fmt.Errorf("'%s' statistics returned error '%s'", availableCapacityKey, stat.StatsList[0].Error)
+		return candidate
+	}
+	candidate.availableBytes = int64(stat.StatsList[0].Value)
+	return candidate
+}
+
+// getPlacementIsiPath returns the isiPath a volume would be created in on the given cluster, as CreateVolume
+// resolves it from the storage class, the cluster config and the tenant of the PVC namespace
+func getPlacementIsiPath(params map[string]string, isiConfig *IsilonClusterConfig, tenant *TenantConfig) string {
+	isiPath := params[IsiPathParam]
+	if isiPath == "" {
+		isiPath = isiConfig.IsiPath
+	}
+	if tenant != nil && tenant.IsiPathPrefix != "" {
+		cleanIsiPath := path.Clean(isiPath)
+		if cleanIsiPath != tenant.IsiPathPrefix && !strings.HasPrefix(cleanIsiPath, tenant.IsiPathPrefix+"/") {
+			isiPath = tenant.IsiPathPrefix
+		}
+	}
+	return isiPath
+}
+
+// isAccessibleFrom returns true if the cluster is in one of the requisite topologies of the request, or if none is given
+func isAccessibleFrom(isiConfig *IsilonClusterConfig, requirements *csi.TopologyRequirement) bool {
+	requisite := requirements.GetRequisite()
+	if len(requisite) == 0 {
+		return true
+	}
+	for _, topology := range requisite {
+		if isInTopology(isiConfig, topology) {
+			return true
+		}
+	}
+	return false
+}
+
+// isInTopology returns true if the topology segment gives access to the cluster.
+// The nodes having access to a cluster are labelled with the '<plugin name>/<cluster IP>' topology key by NodeGetInfo.
+func isInTopology(isiConfig *IsilonClusterConfig, topology *csi.Topology) bool {
+	value, ok := topology.GetSegments()[constants.PluginName+"/"+isiConfig.IsiIP]
+	return ok && value == constants.PluginName
+}
+
+// getTopologyPreference returns the index of the first preferred topology of the request giving access to the
+// cluster, or the number of preferred topologies if none does
+func (s *service) getTopologyPreference(isiConfig *IsilonClusterConfig, requirements *csi.TopologyRequirement) int {
+	preferred := requirements.GetPreferred()
+	if s.opts.CustomTopologyEnabled {
+		return len(preferred)
+	}
+	for i, topology := range preferred {
+		if isInTopology(isiConfig, topology) {
+			return i
+		}
+	}
+	return len(preferred)
+}
+
+// getAccessibleTopology returns the topology the volumes of the cluster are accessible from, i.e. the nodes labelled
+// with the '<plugin name>/<cluster IP>' topology key. With custom topology the nodes aren't labelled by NodeGetInfo,
+// so nil is returned and the topology is left to the allowedTopologies of the storage class.
+func (s *service) getAccessibleTopology(clusterName string) []*csi.Topology {
+	if s.opts.CustomTopologyEnabled {
+		return nil
+	}
+	isiConfig := s.getIsilonClusterConfig(clusterName)
+	if isiConfig == nil || isiConfig.IsiIP == "" {
+		return nil
+	}
+	return []*csi.Topology{
+		{
+			Segments: map[string]string{constants.PluginName + "/" + isiConfig.IsiIP: constants.PluginName},
+		},
+	}
+}
+
+// getPlacementClusters returns the clusters the capacity placement may choose, i.e. the ones with a non-zero weight
+func (s *service) getPlacementClusters() []*IsilonClusterConfig {
+	var isiConfigs []*IsilonClusterConfig
+	s.isiClusters.Range(func(key interface{}, value interface{}) bool {
+		isiConfig := value.(*IsilonClusterConfig)
+		if isiConfig.PlacementWeight == nil || *isiConfig.PlacementWeight > 0 {
+			isiConfigs = append(isiConfigs, isiConfig)
+		}
+		return true
+	})
+	return isiConfigs
+}
@@ -25,8 +25,10 @@ import (
 	"gopkg.in/yaml.v3"
 	"io/ioutil"
 	"net"
+	"net/http"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -43,9 +45,12 @@ import (
 	isi "github.com/dell/goisilon"
 	"github.com/fsnotify/fsnotify"
 	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 )
 
 //To maintain runid for Non debug mode. Note: CSI will not generate runid if CSI_DEBUG=false
@@ -65,7 +70,9 @@ type Service interface {
 	csi.ControllerServer
 	csi.IdentityServer
 	csi.NodeServer
+	AdminServer
 	BeforeServe(context.Context, *gocsi.StoragePlugin, net.Listener) error
+	RegisterAdditionalServers(*grpc.Server)
 }
 
 // Opts defines service configuration options.
@@ -83,6 +90,17 @@ type Opts struct {
 	KubeConfigPath        string
 	allowedNetworks       []string
 	MaxVolumesPerNode     int64
+	NodeCleanupEnabled    bool
+	ClusterPlacement      string
+	MigrationImage        string
+	MigrationCopyTimeout  time.Duration
+	VolumeIDVersion       int
+
+	// background probes of the clusters and circuit breaker of the unavailable ones
+	HealthProbeInterval        time.Duration
+	CircuitBreakerThreshold    int
+	CircuitBreakerResetTimeout time.Duration
+	MetricsAddress             string
 }
 
 type service struct {
@@ -93,12 +111,40 @@ type service struct {
 	statisticsCounter     int
 	isiClusters           *sync.Map
 	defaultIsiClusterName string
+	k8sclient             kubernetes.Interface
+	eventRecorder         record.EventRecorder
+	tenants               []TenantConfig
+	tenantsLock           sync.RWMutex
+	migrations            map[string]*MigrationStatus
+	migrationsLock        sync.Mutex
+	reverts               map[string]*RevertStatus
+	revertsLock           sync.Mutex
+	sessions              map[string]*sessionAuth
+	sessionsLock          sync.Mutex
+	transports            map[string]*clusterTransport
+	transportsLock        sync.Mutex
+
+	// resolver of the credential references of the clusters, and the interval they are refreshed at
+	credentialResolver        *credentialResolver
+	credentialRefreshInterval time.Duration
+	credentialsLock           sync.RWMutex
+
+	// health state of the clusters, by cluster name
+	health     map[string]*ClusterHealth
+	healthLock sync.Mutex
+
+	// guards the clients of the clusters created after the secret was loaded
+	isiSvcLock sync.Mutex
 }
 
 //IsilonClusters To unmarshal secret.json file
 type IsilonClusters struct {
 	IsilonClusters []IsilonClusterConfig `json:"isilonClusters" yaml:"isilonClusters"`
 	LogLevel       string                `json:"logLevel,omitempty" yaml:"logLevel,omitempty"`
+	Tenants        []TenantConfig        `json:"tenants,omitempty" yaml:"tenants,omitempty"`
+
+	SecretProvider            *SecretProviderConfig `json:"secretProvider,omitempty" yaml:"secretProvider,omitempty"`
+	CredentialRefreshInterval string                `json:"credentialRefreshInterval,omitempty" yaml:"credentialRefreshInterval,omitempty"`
 }
 
 //IsilonClusterConfig To hold config details of a isilon cluster
@@ -110,11 +156,18 @@ type IsilonClusterConfig struct {
 	EndpointURL               string
 	User                      string `json:"username" yaml:"username"`
 	Password                  string `json:"password" yaml:"password"`
+	UserRef                   string `json:"usernameRef,omitempty" yaml:"usernameRef,omitempty"`
+	PasswordRef               string `json:"passwordRef,omitempty" yaml:"passwordRef,omitempty"`
 	IsiInsecure               *bool  `json:"isiInsecure,omitempty" yaml:"isiInsecure,omitempty"` // deprecate this attribute in future release
 	SkipCertificateValidation *bool  `json:"skipCertificateValidation,omitempty" yaml:"skipCertificateValidation,omitempty"`
+	CertificateAuthority      string `json:"certificateAuthority,omitempty" yaml:"certificateAuthority,omitempty"`
+	CertificateAuthorityPath  string `json:"certificateAuthorityPath,omitempty" yaml:"certificateAuthorityPath,omitempty"`
+	CertificateFingerprint    string `json:"certificateFingerprint,omitempty" yaml:"certificateFingerprint,omitempty"`
+	AuthType                  string `json:"authType,omitempty" yaml:"authType,omitempty"`
 	IsiPath                   string `json:"isiPath,omitempty" yaml:"isiPath,omitempty"`
 	IsDefaultCluster          *bool  `json:"isDefaultCluster,omitempty" yaml:"isDefaultCluster,omitempty"` // deprecate this attribute in future release
 	IsDefault                 *bool  `json:"isDefault,omitempty" yaml:"isDefault,omitempty"`
+	PlacementWeight           *int   `json:"placementWeight,omitempty" yaml:"placementWeight,omitempty"`
 	isiSvc                    *isiService
 }
 
@@ -200,6 +253,44 @@ func (s *service) initializeServiceOpts(ctx context.Context) error {
 	opts.Verbose = utils.ParseUintFromContext(ctx, constants.EnvVerbose)
 	opts.NfsV3 = utils.ParseBooleanFromContext(ctx, constants.EnvNfsV3)
 	opts.CustomTopologyEnabled = utils.ParseBooleanFromContext(ctx, constants.EnvCustomTopologyEnabled)
+	opts.NodeCleanupEnabled = utils.ParseBooleanFromContext(ctx, constants.EnvNodeCleanupEnabled)
+
+	opts.ClusterPlacement = ClusterPlacementDefault
+	if placement, ok := csictx.LookupEnv(ctx, constants.EnvClusterPlacement); ok && placement != "" {
+		if placement != ClusterPlacementDefault && placement != ClusterPlacementCapacity {
+			log.Warnf("invalid value '%s' for env variable '%s', defaulting to '%s'", placement, constants.EnvClusterPlacement, ClusterPlacementDefault)
+		} else {
+			opts.ClusterPlacement = placement
+		}
+	}
+
+	if image, ok := csictx.LookupEnv(ctx, constants.EnvMigrationImage); ok {
+		opts.MigrationImage = image
+	}
+	opts.MigrationCopyTimeout = parseDurationFromContext(ctx, constants.EnvMigrationCopyTimeout, defaultMigrationCopyTimeout)
+
+	opts.VolumeIDVersion = utils.VolumeIDVersion1
+	if version, ok := csictx.LookupEnv(ctx, constants.EnvVolumeIDVersion); ok && version != "" {
+		if version != strconv.Itoa(utils.VolumeIDVersion1) && version != strconv.Itoa(utils.VolumeIDVersion2) {
+			log.Warnf("invalid value '%s' for env variable '%s', defaulting to '%d'", version, constants.EnvVolumeIDVersion, utils.VolumeIDVersion1)
+		} else {
+			opts.VolumeIDVersion, _ = strconv.Atoi(version)
+		}
+	}
+
+	opts.HealthProbeInterval = parseDurationFromContext(ctx, constants.EnvHealthProbeInterval, defaultHealthProbeInterval)
+	opts.CircuitBreakerResetTimeout = parseDurationFromContext(ctx, constants.EnvCircuitBreakerResetTimeout, defaultCircuitBreakerResetTimeout)
+	opts.CircuitBreakerThreshold = defaultCircuitBreakerThreshold
+	if threshold, ok := csictx.LookupEnv(ctx, constants.EnvCircuitBreakerThreshold); ok && threshold != "" {
+		if value, err := strconv.Atoi(threshold); err != nil || value < 0 {
+			log.Warnf("invalid value '%s' for env variable '%s', defaulting to '%d'", threshold, constants.EnvCircuitBreakerThreshold, defaultCircuitBreakerThreshold)
+		} else {
+			opts.CircuitBreakerThreshold = value
+		}
+	}
+	if address, ok := csictx.LookupEnv(ctx, constants.EnvMetricsAddress); ok {
+		opts.MetricsAddress = address
+	}
 
 	s.opts = opts
 
@@ -261,17 +352,30 @@ func (s *service) probeAllClusters(ctx context.Context) error {
 	ctx, log := GetLogger(ctx)
 
 	probeSuccessCount := 0
+	var failures []string
 	for i := range isilonClusters {
-		err := s.probe(ctx, isilonClusters[i])
+		// the clusters whose circuit breaker is open are not probed until it is time to check them again
+		_, err := s.checkClusterCircuit(ctx, isilonClusters[i].ClusterName)
+		if err == nil {
+			err = s.probe(ctx, isilonClusters[i])
+		}
 		if err == nil {
 			probeSuccessCount++
 		} else {
 			log.Debugf("Probe failed for isilon cluster '%s' error:'%s'", isilonClusters[i].ClusterName, err)
+			failures = append(failures, fmt.Sprintf("cluster '%s' (%s): %v", isilonClusters[i].ClusterName,
+				s.getClusterHealth(isilonClusters[i].ClusterName).State, err))
 		}
 	}
+	summary := s.formatClusterHealth(isilonClusters)
+	log.Infof("health of the isilon clusters: %s", summary)
+	s.setProbeHealthHeader(ctx, isilonClusters)
 
 	if probeSuccessCount == 0 {
-		return fmt.Errorf("probe of all isilon clusters failed")
+		if len(failures) == 0 {
+			return fmt.Errorf("probe of all isilon clusters failed, health of the isilon clusters: %s", summary)
+		}
+		return fmt.Errorf("probe of all isilon clusters failed: %s; health of the isilon clusters: %s", strings.Join(failures, ", "), summary)
 	}
 
 	return nil
@@ -280,24 +384,57 @@ func (s *service) probeAllClusters(ctx context.Context) error {
 func (s *service) probe(ctx context.Context, clusterConfig *IsilonClusterConfig) error {
 
 	ctx, log := GetLogger(ctx)
+	ctx = context.WithValue(ctx, clusterProbeKey{}, true)
 	log.Debugf("calling probe for cluster '%s'", clusterConfig.ClusterName)
+	var err error
 	// Do a controller probe
 	if strings.EqualFold(s.mode, constants.ModeController) {
-		if err := s.controllerProbe(ctx, clusterConfig); err != nil {
-			return err
-		}
+		err = s.controllerProbe(ctx, clusterConfig)
 	} else if strings.EqualFold(s.mode, constants.ModeNode) {
-		if err := s.nodeProbe(ctx, clusterConfig); err != nil {
-			return err
-		}
+		err = s.nodeProbe(ctx, clusterConfig)
 	} else {
 		return status.Error(codes.FailedPrecondition,
 			"Service mode not set")
 	}
+	s.recordClusterProbe(ctx, clusterConfig.ClusterName, err)
+
+	return err
+}
+
+// probeCluster probes a cluster, creating its client first if it could not be created when the secret was loaded
+func (s *service) probeCluster(ctx context.Context, isiConfig *IsilonClusterConfig) error {
+	ctx = context.WithValue(ctx, clusterProbeKey{}, true)
+	if err := s.initIsiService(ctx, isiConfig); err != nil {
+		s.recordClusterProbe(ctx, isiConfig.ClusterName, err)
+		return err
+	}
+	return s.probe(ctx, isiConfig)
+}
 
+// initIsiService creates the client of a cluster if it has none, the client being set under isiSvcLock
+func (s *service) initIsiService(ctx context.Context, isiConfig *IsilonClusterConfig) error {
+	if s.getIsiService(isiConfig) != nil {
+		return nil
+	}
+	isiSvc, err := s.GetIsiService(ctx, isiConfig, utils.GetLogger().GetLevel())
+	if err != nil {
+		return err
+	}
+	s.isiSvcLock.Lock()
+	defer s.isiSvcLock.Unlock()
+	if isiConfig.isiSvc == nil {
+		isiConfig.isiSvc = isiSvc
+	}
 	return nil
 }
 
+// getIsiService returns the client of a cluster, nil if it has none
+func (s *service) getIsiService(isiConfig *IsilonClusterConfig) *isiService {
+	s.isiSvcLock.Lock()
+	defer s.isiSvcLock.Unlock()
+	return isiConfig.isiSvc
+}
+
 func (s *service) probeOnStart(ctx context.Context) error {
 
 	ctx, log := GetLogger(ctx)
@@ -316,7 +453,17 @@ func (s *service) probeOnStart(ctx context.Context) error {
 func (s *service) autoProbe(ctx context.Context, isiConfig *IsilonClusterConfig) error {
 
 	ctx, log := GetLogger(ctx)
-	if isiConfig.isiSvc != nil {
+	// fail fast if the cluster is unavailable, unless it is time to check whether it is reachable again
+	trial, err := s.checkClusterCircuit(ctx, isiConfig.ClusterName)
+	if err != nil {
+		return err
+	}
+	if trial {
+		log.Infof("probing cluster '%s' to check whether it is reachable again", isiConfig.ClusterName)
+		return s.probeCluster(ctx, isiConfig)
+	}
+
+	if s.getIsiService(isiConfig) != nil {
 		log.Debug("isiSvc already initialized, skip probing")
 		return nil
 	}
@@ -327,7 +474,7 @@ func (s *service) autoProbe(ctx context.Context, isiConfig *IsilonClusterConfig)
 	}
 
 	log.Debug("start auto-probing")
-	return s.probe(ctx, isiConfig)
+	return s.probeCluster(ctx, isiConfig)
 }
 
 func (s *service) GetIsiClient(clientCtx context.Context, isiConfig *IsilonClusterConfig, logLevel logrus.Level) (*isi.Client, error) {
@@ -386,10 +533,22 @@ func (s *service) GetIsiClient(clientCtx context.Context, isiConfig *IsilonClust
 		gournal.DefaultLevel = gournalLevel
 	}
 
-	client, err := isi.NewClientWithArgs(
+	clusterTransport, err := s.getClusterTransport(isiConfig)
+	if err != nil {
+		log.Errorf("invalid TLS settings for isilon cluster '%s': '%s'", isiConfig.ClusterName, err.Error())
+		return nil, err
+	}
+	// the requests are authenticated with a session, unless basic authentication is configured
+	var transport http.RoundTripper = clusterTransport
+	if isiConfig.AuthType != authTypeBasic {
+		transport = s.getSessionAuth(isiConfig, transport)
+	}
+	transport = &healthTransport{s: s, clusterName: isiConfig.ClusterName, transport: transport}
+
+	client, err := isi.NewClientWithTransport(
 		clientCtx,
 		isiConfig.EndpointURL,
-		*isiConfig.IsiInsecure,
+		transport,
 		s.opts.Verbose,
 		isiConfig.User,
 		"",
@@ -461,6 +620,14 @@ func (s *service) BeforeServe(
 	//Dynamically load the config
 	go s.loadIsilonConfigs(ctx, isilonConfigFile)
 
+	s.initEventRecorder(ctx)
+	s.startNodeCleanupController(ctx)
+	s.startMigrationReconciliation(ctx)
+	s.startRevertReconciliation(ctx)
+	s.startCredentialRefresh(ctx)
+	s.startClusterHealthProbes(ctx)
+	s.startMetricsServer(ctx)
+
 	return s.probeOnStart(ctx)
 }
 
@@ -538,6 +705,22 @@ func (s *service) syncIsilonConfigs(ctx context.Context) error {
 			return err
 		}
 
+		inputConfigs, jsonErr := unmarshalJSONContent(configBytes)
+		if jsonErr != nil {
+			var yamlErr error
+			if inputConfigs, yamlErr = unmarshalYAMLContent(configBytes); yamlErr != nil {
+				return fmt.Errorf("unable to parse isilon clusters' config details [%v]", yamlErr)
+			}
+		}
+		newTenants, err := getNewTenantConfigs(inputConfigs)
+		if err != nil {
+			return err
+		}
+		refreshInterval, err := getCredentialRefreshInterval(inputConfigs)
+		if err != nil {
+			return err
+		}
+
 		// Update the isiClusters sync.Map
 		s.isiClusters.Range(func(key interface{}, value interface{}) bool {
 			s.isiClusters.Delete(key)
@@ -550,6 +733,18 @@ func (s *service) syncIsilonConfigs(ctx context.Context) error {
 		log.Debugf("New isilon configs:")
 		s.isiClusters.Range(handler)
 
+		s.pruneClusterHealth(newIsilonConfigs)
+
+		s.tenantsLock.Lock()
+		s.tenants = newTenants
+		s.tenantsLock.Unlock()
+		log.Debugf("'%d' tenant(s) configured", len(newTenants))
+
+		s.credentialsLock.Lock()
+		s.credentialResolver = newCredentialResolver(inputConfigs.SecretProvider)
+		s.credentialRefreshInterval = refreshInterval
+		s.credentialsLock.Unlock()
+
 		s.defaultIsiClusterName = defaultClusterName
 		if s.defaultIsiClusterName == "" {
 			log.Warnf("no default cluster name/config available")
@@ -579,6 +774,23 @@ func unmarshalYAMLContent(configBytes []byte) (*IsilonClusters, error) {
 }
 
 func (s *service) getNewIsilonConfigs(ctx context.Context, configBytes []byte) (map[interface{}]interface{}, string, logrus.Level, error) {
+	newIsiClusters, defaultIsiClusterName, logLevel, err := s.parseIsilonConfigs(ctx, configBytes, true, true)
+	if err != nil {
+		return nil, defaultIsiClusterName, logLevel, err
+	}
+
+	clientCtx, _ := GetLogger(ctx)
+	for _, config := range newIsiClusters {
+		isiConfig := config.(*IsilonClusterConfig)
+		isiConfig.isiSvc, _ = s.GetIsiService(clientCtx, isiConfig, logLevel)
+	}
+	return newIsiClusters, defaultIsiClusterName, logLevel, nil
+}
+
+// parseIsilonConfigs parses and validates the clusters' config details of the isilon-creds secret without
+// connecting to the clusters, the credential references are only resolved if resolveCredentials is set, and the
+// CA bundle files only read if readCABundles is set
+func (s *service) parseIsilonConfigs(ctx context.Context, configBytes []byte, resolveCredentials, readCABundles bool) (map[interface{}]interface{}, string, logrus.Level, error) {
 	var noOfDefaultClusters int
 	var defaultIsiClusterName string
 	logLevel := constants.DefaultLogLevel
@@ -605,16 +817,35 @@ func (s *service) getNewIsilonConfigs(ctx context.Context, configBytes []byte) (
 		return nil, defaultIsiClusterName, logLevel, errors.New("custom topology is enabled and it expects single cluster config in secret")
 	}
 
+	if _, err := getCredentialRefreshInterval(inputConfigs); err != nil {
+		return nil, defaultIsiClusterName, logLevel, err
+	}
+	if provider := inputConfigs.SecretProvider; provider != nil && provider.Token != "" && provider.TokenRef != "" {
+		return nil, defaultIsiClusterName, logLevel, errors.New("specify either of token or tokenRef attribute of secretProvider")
+	}
+	resolver := newCredentialResolver(inputConfigs.SecretProvider)
+
 	newIsiClusters := make(map[interface{}]interface{})
 	for i, config := range inputConfigs.IsilonClusters {
 		log.Debugf("parsing config details for cluster %v", config.ClusterName)
 		if config.ClusterName == "" {
 			return nil, defaultIsiClusterName, logLevel, fmt.Errorf("invalid value for clusterName at index [%d]", i)
 		}
-		if config.User == "" {
+		if config.User != "" && config.UserRef != "" {
+			return nil, defaultIsiClusterName, logLevel, fmt.Errorf("specify either of username or usernameRef attribute at index [%d]", i)
+		}
+		if config.Password != "" && config.PasswordRef != "" {
+			return nil, defaultIsiClusterName, logLevel, fmt.Errorf("specify either of password or passwordRef attribute at index [%d]", i)
+		}
+		if resolveCredentials {
+			if err := resolver.resolveClusterCredentials(ctx, &config); err != nil {
+				return nil, defaultIsiClusterName, logLevel, fmt.Errorf("invalid credentials at index [%d]: %v", i, err)
+			}
+		}
+		if config.User == "" && config.UserRef == "" {
 			return nil, defaultIsiClusterName, logLevel, fmt.Errorf("invalid value for username at index [%d]", i)
 		}
-		if config.Password == "" {
+		if config.Password == "" && config.PasswordRef == "" {
 			return nil, defaultIsiClusterName, logLevel, fmt.Errorf("invalid value for password at index [%d]", i)
 		}
 		if config.IsiIP == "" && config.Endpoint == "" {
@@ -638,12 +869,20 @@ func (s *service) getNewIsilonConfigs(ctx context.Context, configBytes []byte) (
 			config.IsiPort = s.opts.Port
 		}
 
-		if config.IsiInsecure == nil && config.SkipCertificateValidation == nil {
-			config.IsiInsecure = &s.opts.Insecure
-		}
 		if config.IsiInsecure != nil && config.SkipCertificateValidation != nil {
 			return nil, defaultIsiClusterName, logLevel, fmt.Errorf("specify either of isiInsecure or skipCertificateValidation attribute at index [%d]", i)
 		}
+		if err := validateTLSConfig(&config, i, readCABundles); err != nil {
+			return nil, defaultIsiClusterName, logLevel, err
+		}
+		if config.hasCustomTrust() {
+			// the certificate of the cluster is verified with its CA bundle or fingerprint
+			insecure := false
+			config.IsiInsecure = &insecure
+		}
+		if config.IsiInsecure == nil && config.SkipCertificateValidation == nil {
+			config.IsiInsecure = &s.opts.Insecure
+		}
 		if config.SkipCertificateValidation != nil {
 			config.IsiInsecure = config.SkipCertificateValidation
 		}
@@ -652,6 +891,17 @@ func (s *service) getNewIsilonConfigs(ctx context.Context, configBytes []byte) (
 			config.IsiPath = s.opts.Path
 		}
 
+		if config.AuthType == "" {
+			config.AuthType = authTypeSession
+		}
+		if config.AuthType != authTypeSession && config.AuthType != authTypeBasic {
+			return nil, defaultIsiClusterName, logLevel, fmt.Errorf("invalid value for authType at index [%d], either '%s' or '%s' is expected", i, authTypeSession, authTypeBasic)
+		}
+
+		if config.PlacementWeight != nil && *config.PlacementWeight < 0 {
+			return nil, defaultIsiClusterName, logLevel, fmt.Errorf("invalid value for placementWeight at index [%d]", i)
+		}
+
 		config.EndpointURL = fmt.Sprintf("https://%s:%s", config.IsiIP, config.IsiPort)
 
 		if inputConfigs.LogLevel != "" {
@@ -662,9 +912,6 @@ func (s *service) getNewIsilonConfigs(ctx context.Context, configBytes []byte) (
 			}
 		}
 
-		clientCtx, _ := GetLogger(ctx)
-		config.isiSvc, _ = s.GetIsiService(clientCtx, &config, logLevel)
-
 		if config.IsDefaultCluster != nil && config.IsDefault != nil {
 			return nil, defaultIsiClusterName, logLevel, fmt.Errorf("specify either of isDefaultCluster or isDefault attribute at index [%d]", i)
 		}
@@ -702,6 +949,8 @@ func (s *service) getNewIsilonConfigs(ctx context.Context, configBytes []byte) (
 			"Username":         config.User,
 			"Password":         "*******",
 			"IsiInsecure":      *config.IsiInsecure,
+			"CustomTrust":      config.hasCustomTrust(),
+			"AuthType":         config.AuthType,
 			"IsiPath":          config.IsiPath,
 			"IsDefaultCluster": *config.IsDefaultCluster,
 		}
@@ -0,0 +1,225 @@
+package service
+
+/*
+ Copyright (c) 2019 Dell Inc, or its subsidiaries.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dell/csi-isilon/common/utils"
+)
+
+const (
+	// authentication types of the clusters
+	authTypeSession = "session"
+	authTypeBasic   = "basic"
+
+	sessionPath       = "/session/1/session"
+	sessionCookieName = "isisessid"
+	csrfCookieName    = "isicsrf"
+	csrfHeaderName    = "X-CSRF-Token"
+
+	// a session is renewed this long before it reaches its absolute timeout
+	sessionRenewalMargin = time.Minute
+)
+
+type sessionRequest struct {
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	Services []string `json:"services"`
+}
+
+type sessionResponse struct {
+	TimeoutAbsolute int64 `json:"timeout_absolute"`
+}
+
+// sessionAuth authenticates the requests to the OneFS API of a cluster with a OneFS session instead of basic
+// authentication. It is shared by all the clients of a cluster, so that the credentials updated by a reload of the
+// secret are used by the requests in flight when their session expires.
+type sessionAuth struct {
+	lock      sync.Mutex
+	endpoint  string
+	username  string
+	password  string
+	transport http.RoundTripper
+
+	// the current session, and the number of sessions created, to only log in once when concurrent requests fail
+	sessionID  string
+	csrfToken  string
+	expiry     time.Time
+	generation int
+}
+
+// getSessionAuth returns the session authentication of a cluster, updated with the endpoint, credentials and
+// transport of its config
+func (s *service) getSessionAuth(isiConfig *IsilonClusterConfig, transport http.RoundTripper) *sessionAuth {
+	s.sessionsLock.Lock()
+	defer s.sessionsLock.Unlock()
+
+	if s.sessions == nil {
+		s.sessions = make(map[string]*sessionAuth)
+	}
+	auth, ok := s.sessions[isiConfig.ClusterName]
+	if !ok {
+		auth = &sessionAuth{}
+		s.sessions[isiConfig.ClusterName] = auth
+	}
+	auth.update(isiConfig.EndpointURL, isiConfig.User, isiConfig.Password, transport)
+	return auth
+}
+
+// update sets the endpoint, credentials and transport used to log in. The current session is dropped if any of
+// them is changed, the requests already sent with it are not affected.
+func (a *sessionAuth) update(endpoint, username, password string, transport http.RoundTripper) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.endpoint != endpoint || a.username != username || a.password != password {
+		a.sessionID = ""
+		a.csrfToken = ""
+		a.generation++
+	}
+	a.endpoint = endpoint
+	a.username = username
+	a.password = password
+	a.transport = transport
+}
+
+// RoundTrip sends a request with the cookie and CSRF token of the session, logging in if there is no valid
+// session, and once again if the request is rejected because the session has expired
+func (a *sessionAuth) RoundTrip(req *http.Request) (*http.Response, error) {
+	sessionID, csrfToken, generation, transport, err := a.getSession(req, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := transport.RoundTrip(a.authenticate(req, sessionID, csrfToken))
+	if err != nil || res.StatusCode != http.StatusUnauthorized {
+		return res, err
+	}
+
+	// the body of the request must be sent again
+	if req.Body != nil && req.GetBody == nil {
+		return res, nil
+	}
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return res, nil
+		}
+	}
+
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+	if sessionID, csrfToken, _, transport, err = a.getSession(req, generation); err != nil {
+		return nil, err
+	}
+	return transport.RoundTrip(a.authenticate(retry, sessionID, csrfToken))
+}
+
+// getSession returns the current session, logging in if there is none, it has expired, or it is the one of the
+// given generation which has been rejected
+func (a *sessionAuth) getSession(req *http.Request, rejected int) (string, string, int, http.RoundTripper, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.sessionID == "" || a.generation == rejected || (!a.expiry.IsZero() && time.Now().After(a.expiry)) {
+		if err := a.login(req); err != nil {
+			return "", "", 0, nil, err
+		}
+	}
+	return a.sessionID, a.csrfToken, a.generation, a.transport, nil
+}
+
+// login creates a session with the credentials of the cluster, the lock must be held
+func (a *sessionAuth) login(req *http.Request) error {
+	log := utils.GetRunIDLogger(req.Context())
+	log.Debugf("begin to create a OneFS session for user '%s' on '%s'", a.username, a.endpoint)
+
+	body, err := json.Marshal(&sessionRequest{
+		Username: a.username,
+		Password: a.password,
+		Services: []string{"platform", "namespace"},
+	})
+	if err != nil {
+		return err
+	}
+	loginReq, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(a.endpoint, "/")+sessionPath, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	loginReq = loginReq.WithContext(req.Context())
+	loginReq.Header.Set("Content-Type", "application/json")
+
+	res, err := a.transport.RoundTrip(loginReq)
+	if err != nil {
+		return fmt.Errorf("failed to create a OneFS session for user '%s': %v", a.username, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusCreated && res.StatusCode != http.StatusOK {
+		message, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("failed to create a OneFS session for user '%s': '%s' '%s'", a.username, res.Status, strings.TrimSpace(string(message)))
+	}
+
+	var sessionID, csrfToken string
+	for _, cookie := range res.Cookies() {
+		switch cookie.Name {
+		case sessionCookieName:
+			sessionID = cookie.Value
+		case csrfCookieName:
+			csrfToken = cookie.Value
+		}
+	}
+	if sessionID == "" {
+		return fmt.Errorf("failed to create a OneFS session for user '%s': no session cookie returned", a.username)
+	}
+
+	session := &sessionResponse{}
+	if err := json.NewDecoder(res.Body).Decode(session); err != nil && err != io.EOF {
+		log.Warnf("failed to decode the OneFS session of user '%s': '%v'", a.username, err)
+	}
+
+	a.sessionID = sessionID
+	a.csrfToken = csrfToken
+	a.generation++
+	a.expiry = time.Time{}
+	if session.TimeoutAbsolute > 0 {
+		a.expiry = time.Now().Add(time.Duration(session.TimeoutAbsolute)*time.Second - sessionRenewalMargin)
+	}
+	log.Infof("OneFS session created for user '%s' on '%s'", a.username, a.endpoint)
+	return nil
+}
+
+// authenticate returns a copy of a request authenticated with a session instead of the basic authentication set
+// by goisilon
+func (a *sessionAuth) authenticate(req *http.Request, sessionID, csrfToken string) *http.Request {
+	authReq := req.Clone(req.Context())
+	authReq.Header.Del("Authorization")
+	authReq.AddCookie(&http.Cookie{Name: sessionCookieName, Value: sessionID})
+	if csrfToken != "" {
+		authReq.Header.Set(csrfHeaderName, csrfToken)
+		authReq.Header.Set("Referer", fmt.Sprintf("%s://%s", req.URL.Scheme, req.URL.Host))
+	}
+	return authReq
+}
@@ -0,0 +1,350 @@
+package service
+
+/*
+ Copyright (c) 2019 Dell Inc, or its subsidiaries.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dell/csi-isilon/common/utils"
+	"github.com/dell/goisilon/api"
+)
+
+const (
+	wormDomainsPath          = "platform/1/worm/domains"
+	wormDomainTypeEnterprise = "enterprise"
+	wormDomainTypeCompliance = "compliance"
+	wormRetentionForever     = "forever"
+	wormRetentionUseMin      = "use_min"
+	wormRetentionUseMax      = "use_max"
+	directoryChildContainer  = "container"
+
+	defaultSmartLockRetentionCheckLimit = 10000
+)
+
+var (
+	// smartLockRetentionCheckLimit is the number of directory entries the retention check of DeleteVolume examines
+	// at most, the deletion of a larger volume in a SmartLock domain is refused
+	smartLockRetentionCheckLimit = defaultSmartLockRetentionCheckLimit
+
+	// errRetentionCheckLimitReached is returned by getRetainedFile when a volume has too many entries to be checked
+	errRetentionCheckLimitReached = errors.New("the volume has too many files and directories for the SmartLock retention of its files to be checked")
+)
+
+var (
+	wormQueryParams = api.OrderedValues{{[]byte("worm")}}
+
+	// a SmartLock duration is a sequence of <integer><unit>, e.g. '1Y6M', as accepted by 'isi worm domains'
+	wormDurationRegexp     = regexp.MustCompile(`^([0-9]+[YMWDHms])+$`)
+	wormDurationPartRegexp = regexp.MustCompile(`([0-9]+)([YMWDHms])`)
+)
+
+// wormRetention is a SmartLock retention period
+type wormRetention struct {
+	Years   int `json:"years"`
+	Months  int `json:"months"`
+	Weeks   int `json:"weeks"`
+	Days    int `json:"days"`
+	Hours   int `json:"hours"`
+	Minutes int `json:"minutes"`
+	Seconds int `json:"seconds"`
+}
+
+// wormDomain is a SmartLock domain as returned and accepted by 'platform/1/worm/domains'.
+// The retention periods are either a wormRetention or one of 'forever', 'use_min' and 'use_max'.
+type wormDomain struct {
+	ID               int64       `json:"id,omitempty"`
+	Path             string      `json:"path"`
+	Type             string      `json:"type,omitempty"`
+	DefaultRetention interface{} `json:"default_retention,omitempty"`
+	MinRetention     interface{} `json:"min_retention,omitempty"`
+	MaxRetention     interface{} `json:"max_retention,omitempty"`
+	AutocommitOffset *int64      `json:"autocommit_offset,omitempty"`
+	OverrideDate     *int64      `json:"override_date,omitempty"`
+}
+
+type wormDomainList struct {
+	Domains []wormDomain `json:"domains"`
+	Resume  string       `json:"resume,omitempty"`
+}
+
+// fileWormStatus is the SmartLock state of a file as returned by '/namespace/<path>?worm'
+type fileWormStatus struct {
+	Committed         bool  `json:"worm_committed"`
+	RetentionDateUnix int64 `json:"worm_retention_date_val"`
+}
+
+type directoryChild struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type directoryChildren struct {
+	Children []directoryChild `json:"children"`
+	Resume   string           `json:"resume,omitempty"`
+}
+
+// smartLockSettings holds the SmartLock domain settings of the volumes of a storage class
+type smartLockSettings struct {
+	domainType       string
+	defaultRetention interface{}
+	minRetention     interface{}
+	maxRetention     interface{}
+	autocommitOffset *int64
+}
+
+// getSmartLockSettings parses the SmartLock storage class parameters, returns nil if SmartLock is not enabled
+func getSmartLockSettings(params map[string]string) (*smartLockSettings, error) {
+	enabled, ok := params[SmartLockEnabledParam]
+	if !ok || enabled == "" {
+		return nil, nil
+	}
+	if isEnabled, err := strconv.ParseBool(enabled); err != nil {
+		return nil, fmt.Errorf("invalid boolean value '%s' for '%s'", enabled, SmartLockEnabledParam)
+	} else if !isEnabled {
+		return nil, nil
+	}
+
+	settings := &smartLockSettings{domainType: wormDomainTypeEnterprise}
+	if domainType := params[SmartLockTypeParam]; domainType != "" {
+		domainType = strings.ToLower(domainType)
+		if domainType != wormDomainTypeEnterprise && domainType != wormDomainTypeCompliance {
+			return nil, fmt.Errorf("invalid value '%s' for '%s', '%s' or '%s' is expected",
+				params[SmartLockTypeParam], SmartLockTypeParam, wormDomainTypeEnterprise, wormDomainTypeCompliance)
+		}
+		settings.domainType = domainType
+	}
+
+	var err error
+	var minSeconds, maxSeconds, defaultSeconds int64 = -1, -1, -1
+	if settings.minRetention, minSeconds, err = parseWormRetention(params, SmartLockMinRetentionParam); err != nil {
+		return nil, err
+	}
+	if settings.maxRetention, maxSeconds, err = parseWormRetention(params, SmartLockMaxRetentionParam); err != nil {
+		return nil, err
+	}
+	if settings.defaultRetention, defaultSeconds, err = parseWormRetention(params, SmartLockDefaultRetentionParam,
+		wormRetentionUseMin, wormRetentionUseMax); err != nil {
+		return nil, err
+	}
+	if minSeconds >= 0 && maxSeconds >= 0 && minSeconds > maxSeconds {
+		return nil, fmt.Errorf("'%s' must not be longer than '%s'", SmartLockMinRetentionParam, SmartLockMaxRetentionParam)
+	}
+	if defaultSeconds >= 0 && ((minSeconds >= 0 && defaultSeconds < minSeconds) || (maxSeconds >= 0 && defaultSeconds > maxSeconds)) {
+		return nil, fmt.Errorf("'%s' must be between '%s' and '%s'", SmartLockDefaultRetentionParam,
+			SmartLockMinRetentionParam, SmartLockMaxRetentionParam)
+	}
+
+	if offset := params[SmartLockAutocommitOffsetParam]; offset != "" {
+		retention, err := parseWormDuration(offset)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value '%s' for '%s': %v", offset, SmartLockAutocommitOffsetParam, err)
+		}
+		seconds := retention.approximateSeconds()
+		settings.autocommitOffset = &seconds
+	}
+	return settings, nil
+}
+
+// parseWormRetention parses a retention period parameter, which is either a SmartLock duration, 'forever'
+// or one of the given keywords. The approximate length of a duration is returned in seconds, -1 otherwise.
+func parseWormRetention(params map[string]string, name string, keywords ...string) (interface{}, int64, error) {
+	value := params[name]
+	if value == "" {
+		return nil, -1, nil
+	}
+	if strings.EqualFold(value, wormRetentionForever) {
+		return wormRetentionForever, -1, nil
+	}
+	for _, keyword := range keywords {
+		if strings.EqualFold(value, keyword) {
+			return keyword, -1, nil
+		}
+	}
+
+	retention, err := parseWormDuration(value)
+	if err != nil {
+		return nil, -1, fmt.Errorf("invalid value '%s' for '%s': %v", value, name, err)
+	}
+	return retention, retention.approximateSeconds(), nil
+}
+
+// parseWormDuration parses a SmartLock duration such as '7Y', '1M2W' or '36H'. The units are Y (years), M (months),
+// W (weeks), D (days), H (hours), m (minutes) and s (seconds).
+func parseWormDuration(value string) (*wormRetention, error) {
+	if !wormDurationRegexp.MatchString(value) {
+		return nil, fmt.Errorf("a duration such as '7Y', '6M' or '30D' is expected")
+	}
+
+	retention := &wormRetention{}
+	for _, part := range wormDurationPartRegexp.FindAllStringSubmatch(value, -1) {
+		n, err := strconv.Atoi(part[1])
+		if err != nil {
+			return nil, err
+		}
+		switch part[2] {
+		case "Y":
+			retention.Years += n
+		case "M":
+			retention.Months += n
+		case "W":
+			retention.Weeks += n
+		case "D":
+			retention.Days += n
+		case "H":
+			retention.Hours += n
+		case "m":
+			retention.Minutes += n
+		case "s":
+			retention.Seconds += n
+		}
+	}
+	return retention, nil
+}
+
+// approximateSeconds returns the length of the retention period in seconds, counting 365 days a year and 30 days a month
+func (r *wormRetention) approximateSeconds() int64 {
+	days := int64(r.Years)*365 + int64(r.Months)*30 + int64(r.Weeks)*7 + int64(r.Days)
+	return days*24*3600 + int64(r.Hours)*3600 + int64(r.Minutes)*60 + int64(r.Seconds)
+}
+
+// isSmartLockUnavailableError returns true if err tells that SmartLock is not licensed on the cluster, or that the
+// user of the driver lacks the privilege to read the SmartLock domains
+func isSmartLockUnavailableError(err error) bool {
+	var jsonError *api.JSONError
+	if errors.As(err, &jsonError) && jsonError.StatusCode == http.StatusForbidden {
+		return true
+	}
+	return strings.Contains(strings.ToLower(err.Error()), "license")
+}
+
+// getWormDomain returns the SmartLock domain the given directory belongs to, nil if there is none
+func (svc *isiService) getWormDomain(ctx context.Context, dirPath string) (*wormDomain, error) {
+	domains, err := svc.GetWormDomains(ctx)
+	if err != nil {
+		if isSmartLockUnavailableError(err) {
+			// there cannot be any domain without the license, nor any the driver is allowed to see
+			log := utils.GetRunIDLogger(ctx)
+			log.Warnf("SmartLock domains are not available, directory '%s' is considered in none: '%v'", dirPath, err)
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	dirPath = path.Clean(dirPath)
+	for i := range domains {
+		domainPath := path.Clean(domains[i].Path)
+		if dirPath == domainPath || strings.HasPrefix(dirPath, domainPath+"/") {
+			return &domains[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// applySmartLock makes sure the directory of a new volume is in a SmartLock domain, and creates one for it if needed.
+// The directory must be empty for a domain to be created on it.
+func (svc *isiService) applySmartLock(ctx context.Context, dirPath string, settings *smartLockSettings) error {
+	log := utils.GetRunIDLogger(ctx)
+
+	domain, err := svc.getWormDomain(ctx, dirPath)
+	if err != nil {
+		return err
+	}
+	if domain != nil {
+		if path.Clean(domain.Path) != path.Clean(dirPath) {
+			log.Infof("directory '%s' is in SmartLock domain '%s', the retention settings of the domain apply", dirPath, domain.Path)
+		}
+		return nil
+	}
+
+	return svc.CreateWormDomain(ctx, &wormDomain{
+		Path:             dirPath,
+		Type:             settings.domainType,
+		DefaultRetention: settings.defaultRetention,
+		MinRetention:     settings.minRetention,
+		MaxRetention:     settings.maxRetention,
+		AutocommitOffset: settings.autocommitOffset,
+	})
+}
+
+// getRetainedFile returns the first file under the given directory which is still under SmartLock retention,
+// along with its retention date. An empty path is returned if there is none, or if the directory does not exist.
+// At most smartLockRetentionCheckLimit entries are examined, errRetentionCheckLimitReached is returned beyond.
+func (svc *isiService) getRetainedFile(ctx context.Context, dirPath string) (string, time.Time, error) {
+	domain, err := svc.getWormDomain(ctx, dirPath)
+	if err != nil {
+		// the deletion would fail part-way on the committed files, so it is refused if the domain is unknown
+		return "", time.Time{}, fmt.Errorf("failed to get the SmartLock domain of directory '%s' : '%v'", dirPath, err)
+	}
+	if domain == nil {
+		return "", time.Time{}, nil
+	}
+
+	now := time.Now().Unix()
+	var overrideDate int64
+	if domain.OverrideDate != nil {
+		overrideDate = *domain.OverrideDate
+	}
+
+	entries := 0
+	dirs := []string{dirPath}
+	for len(dirs) > 0 {
+		dir := dirs[len(dirs)-1]
+		dirs = dirs[:len(dirs)-1]
+
+		children, err := svc.GetDirectoryChildren(ctx, dir)
+		if err != nil {
+			if isNotFoundError(err) && dir == dirPath {
+				return "", time.Time{}, nil
+			}
+			return "", time.Time{}, fmt.Errorf("failed to list directory '%s' : '%v'", dir, err)
+		}
+		if entries += len(children); entries > smartLockRetentionCheckLimit {
+			return "", time.Time{}, errRetentionCheckLimitReached
+		}
+		for _, child := range children {
+			childPath := path.Join(dir, child.Name)
+			if child.Type == directoryChildContainer {
+				dirs = append(dirs, childPath)
+				continue
+			}
+
+			status, err := svc.GetFileWormStatus(ctx, childPath)
+			if err != nil {
+				return "", time.Time{}, err
+			}
+			if !status.Committed {
+				continue
+			}
+			retentionDate := status.RetentionDateUnix
+			if overrideDate > retentionDate {
+				retentionDate = overrideDate
+			}
+			if retentionDate > now {
+				return childPath, time.Unix(retentionDate, 0).UTC(), nil
+			}
+		}
+	}
+	return "", time.Time{}, nil
+}
@@ -0,0 +1,187 @@
+package service
+
+/*
+ Copyright (c) 2019 Dell Inc, or its subsidiaries.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/dell/csi-isilon/common/utils"
+	"github.com/dell/goisilon/api"
+)
+
+const (
+	// SmartPools attributes of a directory, inherited by the files created in it
+	storagePoolAttribute     = "storage_pool"
+	protectionLevelAttribute = "requested_protection"
+	ssdStrategyAttribute     = "ssd_strategy"
+	smartPoolsAttrNamespace  = "system"
+	attributesActionUpdate   = "update"
+	attributeOpUpdate        = "update"
+)
+
+var (
+	// requested protection levels of OneFS, e.g. '+2d:1n', '+3n' or '3x'
+	protectionLevelRegexp = regexp.MustCompile(`^(default|\+[1-4]n|\+[2-4]d:[1-2]n(1d)?|[2-8]x)$`)
+	ssdStrategies         = []string{"metadata", "metadata-write", "data", "avoid"}
+)
+
+// directoryAttributes are the attributes of a directory as returned and accepted by '/namespace/<path>?metadata'
+type directoryAttributes struct {
+	Action string               `json:"action,omitempty"`
+	Attrs  []directoryAttribute `json:"attrs"`
+}
+
+type directoryAttribute struct {
+	Name      string      `json:"name"`
+	Value     interface{} `json:"value"`
+	Namespace string      `json:"namespace,omitempty"`
+	Op        string      `json:"op,omitempty"`
+}
+
+// smartPoolsSettings holds the storage pool, protection level and SSD strategy of the volumes of a storage class
+type smartPoolsSettings struct {
+	storagePool     string
+	protectionLevel string
+	ssdStrategy     string
+}
+
+// getSmartPoolsSettings parses the StoragePool, ProtectionLevel and SSDStrategy storage class parameters,
+// returns nil if none of them is set
+func getSmartPoolsSettings(params map[string]string) (*smartPoolsSettings, error) {
+	settings := &smartPoolsSettings{
+		storagePool:     params[StoragePoolParam],
+		protectionLevel: params[ProtectionLevelParam],
+		ssdStrategy:     strings.ToLower(params[SSDStrategyParam]),
+	}
+
+	if settings.protectionLevel != "" && !protectionLevelRegexp.MatchString(settings.protectionLevel) {
+		return nil, fmt.Errorf("invalid value '%s' for '%s', a protection level such as '+2d:1n', '+3n' or '3x' is expected",
+			settings.protectionLevel, ProtectionLevelParam)
+	}
+	if settings.ssdStrategy != "" && !utils.IsStringInSlice(settings.ssdStrategy, ssdStrategies) {
+		return nil, fmt.Errorf("invalid value '%s' for '%s', one of '%s' is expected",
+			params[SSDStrategyParam], SSDStrategyParam, strings.Join(ssdStrategies, "', '"))
+	}
+
+	if settings.isEmpty() {
+		return nil, nil
+	}
+	return settings, nil
+}
+
+func (settings *smartPoolsSettings) isEmpty() bool {
+	return settings.storagePool == "" && settings.protectionLevel == "" && settings.ssdStrategy == ""
+}
+
+// attributes returns the directory attributes of the settings which are set
+func (settings *smartPoolsSettings) attributes() []directoryAttribute {
+	var attrs []directoryAttribute
+	for _, attr := range []struct{ name, value string }{
+		{storagePoolAttribute, settings.storagePool},
+		{protectionLevelAttribute, settings.protectionLevel},
+		{ssdStrategyAttribute, settings.ssdStrategy},
+	} {
+		if attr.value == "" {
+			continue
+		}
+		attrs = append(attrs, directoryAttribute{
+			Name:      attr.name,
+			Value:     attr.value,
+			Namespace: smartPoolsAttrNamespace,
+			Op:        attributeOpUpdate,
+		})
+	}
+	return attrs
+}
+
+// getDirectorySmartPoolsSettings returns the SmartPools settings among the attributes of a directory
+func getDirectorySmartPoolsSettings(attrs *directoryAttributes) *smartPoolsSettings {
+	settings := &smartPoolsSettings{}
+	for _, attr := range attrs.Attrs {
+		value, ok := attr.Value.(string)
+		if !ok {
+			continue
+		}
+		switch attr.Name {
+		case storagePoolAttribute:
+			settings.storagePool = value
+		case protectionLevelAttribute:
+			settings.protectionLevel = value
+		case ssdStrategyAttribute:
+			settings.ssdStrategy = value
+		}
+	}
+	return settings
+}
+
+// isNotFoundError returns true if err is a 404 error of the OneFS API
+func isNotFoundError(err error) bool {
+	jsonError, ok := err.(*api.JSONError)
+	return ok && jsonError.StatusCode == 404
+}
+
+// applySmartPoolsSettings sets the SmartPools attributes of the directory of a new volume. A volume cloned from
+// another volume inherits the settings of its source, unless the storage class sets its own placement.
+// The attributes belong to the directory, so nothing is left behind on the cluster when it is deleted.
+func (s *service) applySmartPoolsSettings(ctx context.Context, isiConfig *IsilonClusterConfig, isiPath, volName string,
+	contentSource *csi.VolumeContentSource, settings *smartPoolsSettings) error {
+	log := utils.GetRunIDLogger(ctx)
+
+	if settings == nil {
+		contentVolume := contentSource.GetVolume()
+		if contentVolume == nil {
+			return nil
+		}
+		srcVolumeName, srcExportID, srcAccessZone, _, err := utils.ParseNormalizedVolumeID(ctx, contentVolume.GetVolumeId())
+		if err != nil {
+			return err
+		}
+		// the source volume may be in another IsiPath than the new volume, its directory is the path of its export
+		srcExport, err := isiConfig.isiSvc.GetExportByIDWithZone(ctx, srcExportID, srcAccessZone)
+		if err != nil {
+			return fmt.Errorf("failed to get the export of source volume '%s' : '%v'", srcVolumeName, err)
+		}
+		if srcExport == nil || srcExport.Paths == nil || len(*srcExport.Paths) == 0 {
+			return fmt.Errorf("export '%d' of source volume '%s' has no path", srcExportID, srcVolumeName)
+		}
+		attrs, err := isiConfig.isiSvc.GetDirectoryAttributes(ctx, (*srcExport.Paths)[0])
+		if err != nil {
+			return err
+		}
+		if settings = getDirectorySmartPoolsSettings(attrs); settings.isEmpty() {
+			log.Debugf("source volume '%s' has no SmartPools settings", srcVolumeName)
+			return nil
+		}
+		log.Debugf("volume '%s' inherits the SmartPools settings '%+v' of source volume '%s'", volName, *settings, srcVolumeName)
+	}
+
+	// setting the attributes again is harmless, so a retried request simply applies them again
+	return isiConfig.isiSvc.SetDirectoryAttributes(ctx, utils.GetPathForVolume(isiPath, volName), settings.attributes())
+}
+
+// modifySmartPoolsSettings changes the placement of an existing volume, the settings not given are left unchanged
+func (s *service) modifySmartPoolsSettings(ctx context.Context, isiConfig *IsilonClusterConfig, volName, dirPath string,
+	settings *smartPoolsSettings) error {
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("SmartPools settings of volume '%s' modified to '%+v'", volName, *settings)
+	return isiConfig.isiSvc.SetDirectoryAttributes(ctx, dirPath, settings.attributes())
+}
@@ -0,0 +1,492 @@
+package service
+
+/*
+ Copyright (c) 2019 Dell Inc, or its subsidiaries.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dell/csi-isilon/common/constants"
+	"github.com/dell/csi-isilon/common/utils"
+	isi "github.com/dell/goisilon"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// States of a volume revert
+const (
+	RevertStateRunning   = "Running"
+	RevertStateCompleted = "Completed"
+	RevertStateFailed    = "Failed"
+)
+
+const (
+	jobsPath             = "platform/1/job/jobs"
+	jobTypeDomainMark    = "DomainMark"
+	jobTypeSnapRevert    = "SnapRevert"
+	domainTypeSnapRevert = "SnapRevert"
+	jobStateSucceeded    = "succeeded"
+	// the DomainMark job creating the SnapRevert domain of a volume, and the time the domain was created at, are
+	// recorded in user attributes of the volume directory
+	snapRevertJobAttribute        = "csi_snaprevert_job"
+	snapRevertDomainTimeAttribute = "csi_snaprevert_domain_time"
+	userAttrNamespace             = "user"
+	// the status of a running revert is kept in an annotation of the PV of the volume
+	revertAnnotation = constants.PluginName + "/revert"
+)
+
+// states of a OneFS job which is over without having succeeded
+var jobFailedStates = []string{"failed", "failed_not_retried", "cancelled_user", "cancelled_system"}
+
+// RevertVolumeRequest is the request of the RevertVolume admin RPC
+type RevertVolumeRequest struct {
+	// VolumeID is the normalized ID of the volume to revert
+	VolumeID string `json:"volumeId"`
+	// SnapshotID is the normalized ID of the snapshot of the volume to revert it to
+	SnapshotID string `json:"snapshotId"`
+}
+
+// RevertStatus is the state of the revert of a volume to one of its snapshots
+type RevertStatus struct {
+	VolumeID    string     `json:"volumeId"`
+	SnapshotID  string     `json:"snapshotId"`
+	ClusterName string     `json:"clusterName"`
+	Path        string     `json:"path"`
+	JobID       int64      `json:"jobId"`
+	State       string     `json:"state"`
+	JobState    string     `json:"jobState,omitempty"`
+	Progress    string     `json:"progress,omitempty"`
+	Message     string     `json:"message,omitempty"`
+	PVName      string     `json:"pvName,omitempty"`
+	StartTime   time.Time  `json:"startTime"`
+	EndTime     *time.Time `json:"endTime,omitempty"`
+}
+
+// RevertVolumeResponse is the response of the RevertVolume admin RPC
+type RevertVolumeResponse struct {
+	Revert *RevertStatus `json:"revert"`
+}
+
+// GetRevertStatusRequest is the request of the GetRevertStatus admin RPC, all the reverts are returned
+// if no VolumeID is given
+type GetRevertStatusRequest struct {
+	VolumeID string `json:"volumeId,omitempty"`
+}
+
+// GetRevertStatusResponse is the response of the GetRevertStatus admin RPC
+type GetRevertStatusResponse struct {
+	Reverts []*RevertStatus `json:"reverts"`
+}
+
+// jobCreation is the body of the job creation requests sent to 'platform/1/job/jobs'
+type jobCreation struct {
+	Type             string            `json:"type"`
+	DomainMarkParams *domainMarkParams `json:"domainmark_params,omitempty"`
+	SnapRevertParams *snapRevertParams `json:"snaprevert_params,omitempty"`
+}
+
+type domainMarkParams struct {
+	Root   string `json:"root"`
+	DmType string `json:"dm_type"`
+}
+
+type snapRevertParams struct {
+	SnapID int64 `json:"snapid"`
+}
+
+// job is a OneFS job as returned by 'platform/1/job/jobs'
+type job struct {
+	ID       int64  `json:"id"`
+	Type     string `json:"type,omitempty"`
+	State    string `json:"state,omitempty"`
+	Progress string `json:"progress,omitempty"`
+	EndTime  int64  `json:"end_time,omitempty"`
+}
+
+type jobList struct {
+	Jobs []job `json:"jobs"`
+}
+
+// getSnapRevertEnabled parses the SnapRevertEnabled storage class parameter
+func getSnapRevertEnabled(params map[string]string) (bool, error) {
+	enabled, ok := params[SnapRevertEnabledParam]
+	if !ok || enabled == "" {
+		return false, nil
+	}
+	value, err := strconv.ParseBool(enabled)
+	if err != nil {
+		return false, fmt.Errorf("invalid boolean value '%s' for '%s'", enabled, SnapRevertEnabledParam)
+	}
+	return value, nil
+}
+
+// applySnapRevertDomain puts a new volume directory in a SnapRevert domain, which its snapshots must belong to
+// to be reverted. The DomainMark job is quick on the empty directory, it is not waited for, but recorded on the
+// directory so that RevertVolume can check that the domain exists.
+func (svc *isiService) applySnapRevertDomain(ctx context.Context, dirPath string) error {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	jobID, err := svc.StartJob(ctx, &jobCreation{
+		Type:             jobTypeDomainMark,
+		DomainMarkParams: &domainMarkParams{Root: dirPath, DmType: domainTypeSnapRevert},
+	})
+	if err != nil {
+		return err
+	}
+	log.Infof("job '%d' started to create the SnapRevert domain of '%s'", jobID, dirPath)
+	return svc.SetDirectoryAttributes(ctx, dirPath, []directoryAttribute{
+		newUserAttribute(snapRevertJobAttribute, strconv.FormatInt(jobID, 10)),
+	})
+}
+
+func newUserAttribute(name, value string) directoryAttribute {
+	return directoryAttribute{Name: name, Value: value, Namespace: userAttrNamespace, Op: attributeOpUpdate}
+}
+
+// getSnapRevertDomainAttributes returns the ID of the DomainMark job and the creation time of the SnapRevert domain
+// recorded on a volume directory, zero when they are not recorded
+func getSnapRevertDomainAttributes(attrs *directoryAttributes) (int64, int64) {
+	var jobID, domainTime int64
+	for _, attr := range attrs.Attrs {
+		if attr.Namespace != userAttrNamespace {
+			continue
+		}
+		value, ok := attr.Value.(string)
+		if !ok {
+			continue
+		}
+		switch attr.Name {
+		case snapRevertJobAttribute:
+			jobID, _ = strconv.ParseInt(value, 10, 64)
+		case snapRevertDomainTimeAttribute:
+			domainTime, _ = strconv.ParseInt(value, 10, 64)
+		}
+	}
+	return jobID, domainTime
+}
+
+// checkSnapRevertDomain checks that the SnapRevert domain of a volume was created before the snapshot to revert it to
+// was taken, as OneFS only reverts the snapshots taken in the domain. The creation time of the domain is recorded on
+// the directory once its DomainMark job has succeeded, the job may not be kept by OneFS.
+func (s *service) checkSnapRevertDomain(ctx context.Context, isiConfig *IsilonClusterConfig, volPath string, snapshot isi.Snapshot) error {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	attrs, err := isiConfig.isiSvc.GetDirectoryAttributes(ctx, volPath)
+	if err != nil {
+		return err
+	}
+	jobID, domainTime := getSnapRevertDomainAttributes(attrs)
+	if domainTime == 0 {
+		if jobID == 0 {
+			return fmt.Errorf("'%s' is not in a SnapRevert domain, the volume was not created with '%s'", volPath, SnapRevertEnabledParam)
+		}
+		domainJob, err := isiConfig.isiSvc.GetJob(ctx, jobID)
+		if err != nil {
+			return fmt.Errorf("failed to get DomainMark job '%d' creating the SnapRevert domain of '%s': %v", jobID, volPath, err)
+		}
+		switch {
+		case domainJob.State == jobStateSucceeded:
+		case utils.IsStringInSlice(domainJob.State, jobFailedStates):
+			return fmt.Errorf("DomainMark job '%d' creating the SnapRevert domain of '%s' is '%s'", jobID, volPath, domainJob.State)
+		default:
+			return fmt.Errorf("the SnapRevert domain of '%s' is still being created by DomainMark job '%d', retry later", volPath, jobID)
+		}
+		domainTime = domainJob.EndTime
+		if domainTime == 0 {
+			domainTime = time.Now().Unix()
+		}
+		if err := isiConfig.isiSvc.SetDirectoryAttributes(ctx, volPath, []directoryAttribute{
+			newUserAttribute(snapRevertDomainTimeAttribute, strconv.FormatInt(domainTime, 10)),
+		}); err != nil {
+			log.Warnf("failed to record the creation time of the SnapRevert domain of '%s': '%v'", volPath, err)
+		}
+	}
+	if snapshot.Created < domainTime {
+		return fmt.Errorf("snapshot '%s' was taken before the SnapRevert domain of '%s' was created", snapshot.Name, volPath)
+	}
+	return nil
+}
+
+// RevertVolume reverts a volume to one of its snapshots in place with a OneFS SnapRevert job. The volume must have
+// been created with SnapRevertEnabled and must not be published. GetRevertStatus reports the progress of the job.
+func (s *service) RevertVolume(ctx context.Context, req *RevertVolumeRequest) (*RevertVolumeResponse, error) {
+	ctx, log, runID := GetRunIDLog(ctx)
+
+	if req.VolumeID == "" || req.SnapshotID == "" {
+		return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID, "a volume ID and a snapshot ID are required"))
+	}
+	volName, exportID, accessZone, clusterName, err := utils.ParseNormalizedVolumeID(ctx, req.VolumeID)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID, "failed to parse volume ID '%s': '%v'", req.VolumeID, err))
+	}
+	snapshotID, snapshotClusterName, err := utils.ParseNormalizedSnapshotID(ctx, req.SnapshotID)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID, "failed to parse snapshot ID '%s': '%v'", req.SnapshotID, err))
+	}
+	isiConfig, err := s.getIsilonConfig(ctx, &clusterName)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, utils.GetMessageWithRunID(runID, err.Error()))
+	}
+	if snapshotClusterName != "" && snapshotClusterName != clusterName {
+		return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID,
+			"snapshot '%s' is on cluster '%s' while volume '%s' is on cluster '%s'", req.SnapshotID, snapshotClusterName, req.VolumeID, clusterName))
+	}
+	if err := s.autoProbe(ctx, isiConfig); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, utils.GetMessageWithRunID(runID, "failed to probe cluster '%s': '%v'", clusterName, err))
+	}
+
+	export, err := isiConfig.isiSvc.GetExportByIDWithZone(ctx, exportID, accessZone)
+	if err != nil || export == nil {
+		return nil, status.Error(codes.NotFound, utils.GetMessageWithRunID(runID, "failed to get the export of volume '%s': '%v'", req.VolumeID, err))
+	}
+	if export.Paths == nil || len(*export.Paths) == 0 {
+		return nil, status.Error(codes.Internal, utils.GetMessageWithRunID(runID, "export '%d' of volume '%s' has no path", exportID, req.VolumeID))
+	}
+	volPath := (*export.Paths)[0]
+	// the data of the volume changes under the feet of the pods using it
+	if isiConfig.isiSvc.OtherClientsAlreadyAdded(ctx, exportID, accessZone, utils.DummyHostNodeID) {
+		return nil, status.Error(codes.FailedPrecondition, utils.GetMessageWithRunID(runID,
+			"volume '%s' is published to nodes, stop the pods using it first", req.VolumeID))
+	}
+
+	snapshot, err := isiConfig.isiSvc.GetSnapshot(ctx, snapshotID)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, utils.GetMessageWithRunID(runID, "failed to get snapshot '%s': '%v'", req.SnapshotID, err))
+	}
+	if snapshot.Path != volPath {
+		return nil, status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID,
+			"snapshot '%s' of '%s' is not a snapshot of volume '%s'", req.SnapshotID, snapshot.Path, volName))
+	}
+	if isSnapshotExpired(snapshot) {
+		return nil, status.Error(codes.FailedPrecondition, utils.GetMessageWithRunID(runID, "snapshot '%s' has expired", req.SnapshotID))
+	}
+	if err := s.checkSnapRevertDomain(ctx, isiConfig, volPath, snapshot); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, utils.GetMessageWithRunID(runID,
+			"volume '%s' cannot be reverted to snapshot '%s': %v", req.VolumeID, req.SnapshotID, err))
+	}
+
+	s.revertsLock.Lock()
+	defer s.revertsLock.Unlock()
+	if s.reverts == nil {
+		s.reverts = make(map[string]*RevertStatus)
+	}
+	if previous, ok := s.reverts[req.VolumeID]; ok && previous.EndTime == nil {
+		return nil, status.Error(codes.AlreadyExists, utils.GetMessageWithRunID(runID,
+			"volume '%s' is already being reverted to snapshot '%s'", req.VolumeID, previous.SnapshotID))
+	}
+
+	jobID, err := isiConfig.isiSvc.StartJob(ctx, &jobCreation{
+		Type:             jobTypeSnapRevert,
+		SnapRevertParams: &snapRevertParams{SnapID: snapshot.Id},
+	})
+	if err != nil {
+		return nil, status.Error(codes.Internal, utils.GetMessageWithRunID(runID,
+			"failed to revert volume '%s' to snapshot '%s': '%v'", req.VolumeID, req.SnapshotID, err))
+	}
+	log.Infof("job '%d' started to revert volume '%s' to snapshot '%s'", jobID, req.VolumeID, req.SnapshotID)
+
+	revertStatus := &RevertStatus{
+		VolumeID:    req.VolumeID,
+		SnapshotID:  req.SnapshotID,
+		ClusterName: clusterName,
+		Path:        volPath,
+		JobID:       jobID,
+		State:       RevertStateRunning,
+		StartTime:   time.Now(),
+	}
+	s.reverts[req.VolumeID] = revertStatus
+	// the volume must stay fenced from ControllerPublishVolume if the controller restarts during the revert
+	if err := s.persistRevert(ctx, revertStatus); err != nil {
+		log.Errorf("failed to store the state of the revert of volume '%s': '%v'", req.VolumeID, err)
+		revertStatus.Message = err.Error()
+	}
+	statusCopy := *revertStatus
+	return &RevertVolumeResponse{Revert: &statusCopy}, nil
+}
+
+// GetRevertStatus returns the status of the revert of a volume, or of all the reverts since the controller started.
+// The jobs of the reverts which are not over are queried on the clusters.
+func (s *service) GetRevertStatus(ctx context.Context, req *GetRevertStatusRequest) (*GetRevertStatusResponse, error) {
+	ctx, _, runID := GetRunIDLog(ctx)
+
+	s.revertsLock.Lock()
+	defer s.revertsLock.Unlock()
+
+	var reverts []*RevertStatus
+	if req.VolumeID != "" {
+		revertStatus, ok := s.reverts[req.VolumeID]
+		if !ok {
+			return nil, status.Error(codes.NotFound, utils.GetMessageWithRunID(runID, "no revert of volume '%s' found", req.VolumeID))
+		}
+		reverts = append(reverts, revertStatus)
+	} else {
+		for _, revertStatus := range s.reverts {
+			reverts = append(reverts, revertStatus)
+		}
+	}
+
+	response := &GetRevertStatusResponse{Reverts: []*RevertStatus{}}
+	for _, revertStatus := range reverts {
+		if revertStatus.EndTime == nil {
+			s.refreshRevertStatus(ctx, revertStatus)
+		}
+		statusCopy := *revertStatus
+		response.Reverts = append(response.Reverts, &statusCopy)
+	}
+	sort.Slice(response.Reverts, func(i, j int) bool {
+		return response.Reverts[i].StartTime.Before(response.Reverts[j].StartTime)
+	})
+	return response, nil
+}
+
+// refreshRevertStatus updates the status of a revert with the state of its job
+func (s *service) refreshRevertStatus(ctx context.Context, revertStatus *RevertStatus) {
+	clusterName := revertStatus.ClusterName
+	isiConfig, err := s.getIsilonConfig(ctx, &clusterName)
+	if err != nil {
+		revertStatus.Message = err.Error()
+		return
+	}
+	revertJob, err := isiConfig.isiSvc.GetJob(ctx, revertStatus.JobID)
+	if err != nil {
+		revertStatus.Message = fmt.Sprintf("failed to get job '%d': %v", revertStatus.JobID, err)
+		return
+	}
+
+	revertStatus.JobState = revertJob.State
+	revertStatus.Progress = revertJob.Progress
+	revertStatus.Message = ""
+	switch {
+	case revertJob.State == jobStateSucceeded:
+		revertStatus.State = RevertStateCompleted
+	case utils.IsStringInSlice(revertJob.State, jobFailedStates):
+		revertStatus.State = RevertStateFailed
+		revertStatus.Message = fmt.Sprintf("SnapRevert job '%d' is '%s', check that the volume was created with '%s'",
+			revertJob.ID, revertJob.State, SnapRevertEnabledParam)
+	default:
+		return
+	}
+	now := time.Now()
+	revertStatus.EndTime = &now
+	s.clearPersistedRevert(ctx, revertStatus)
+}
+
+// getInFlightRevert returns the status of the unfinished revert of a volume, nil if there is none. The state of the
+// job of the revert is queried on the cluster.
+func (s *service) getInFlightRevert(ctx context.Context, volName string, exportID int, accessZone, clusterName string) *RevertStatus {
+	s.revertsLock.Lock()
+	defer s.revertsLock.Unlock()
+	for _, revertStatus := range s.reverts {
+		if revertStatus.EndTime != nil {
+			continue
+		}
+		name, id, zone, _, err := utils.ParseNormalizedVolumeID(ctx, revertStatus.VolumeID)
+		if err != nil || name != volName || id != exportID || zone != accessZone || revertStatus.ClusterName != clusterName {
+			continue
+		}
+		s.refreshRevertStatus(ctx, revertStatus)
+		if revertStatus.EndTime == nil {
+			statusCopy := *revertStatus
+			return &statusCopy
+		}
+	}
+	return nil
+}
+
+// persistRevert stores the status of a running revert in an annotation of the PV of the volume, if there is one
+func (s *service) persistRevert(ctx context.Context, revertStatus *RevertStatus) error {
+	if s.k8sclient == nil {
+		return nil
+	}
+	pv, err := s.getPersistentVolumeByHandle(ctx, revertStatus.VolumeID)
+	if err != nil || pv == nil {
+		return err
+	}
+	revertStatus.PVName = pv.Name
+	state, err := json.Marshal(revertStatus)
+	if err != nil {
+		return err
+	}
+	if err := s.setPersistentVolumeAnnotation(ctx, pv.Name, revertAnnotation, string(state)); err != nil {
+		return fmt.Errorf("failed to store the state of the revert in PV '%s': %v", pv.Name, err)
+	}
+	return nil
+}
+
+// clearPersistedRevert removes the status of a revert which is over from the PV of the volume
+func (s *service) clearPersistedRevert(ctx context.Context, revertStatus *RevertStatus) {
+	log := utils.GetRunIDLogger(ctx)
+	if s.k8sclient == nil || revertStatus.PVName == "" {
+		return
+	}
+	if err := s.setPersistentVolumeAnnotation(ctx, revertStatus.PVName, revertAnnotation, ""); err != nil && !apierrors.IsNotFound(err) {
+		log.Warnf("failed to remove the state of the revert from PV '%s': '%v'", revertStatus.PVName, err)
+	}
+}
+
+// startRevertReconciliation restores, in the background, the reverts which were running when the controller
+// restarted, so that their volumes stay fenced until their jobs are over
+func (s *service) startRevertReconciliation(ctx context.Context) {
+	if !strings.EqualFold(s.mode, constants.ModeController) || s.k8sclient == nil {
+		return
+	}
+	go s.reconcileReverts(ctx)
+}
+
+func (s *service) reconcileReverts(ctx context.Context) {
+	ctx, log := GetLogger(ctx)
+
+	pvs, err := s.k8sclient.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Errorf("failed to list the PVs to restore the running reverts: '%v'", err)
+		return
+	}
+	s.revertsLock.Lock()
+	defer s.revertsLock.Unlock()
+	if s.reverts == nil {
+		s.reverts = make(map[string]*RevertStatus)
+	}
+	for i := range pvs.Items {
+		pv := &pvs.Items[i]
+		value := pv.Annotations[revertAnnotation]
+		if value == "" {
+			continue
+		}
+		var revertStatus RevertStatus
+		if err := json.Unmarshal([]byte(value), &revertStatus); err != nil {
+			log.Errorf("invalid state of revert in PV '%s': '%v'", pv.Name, err)
+			continue
+		}
+		if previous, ok := s.reverts[revertStatus.VolumeID]; ok && previous.EndTime == nil {
+			continue
+		}
+		revertStatus.PVName = pv.Name
+		revertStatus.EndTime = nil
+		log.Infof("restoring the revert of volume '%s' to snapshot '%s' with job '%d'", revertStatus.VolumeID, revertStatus.SnapshotID, revertStatus.JobID)
+		s.reverts[revertStatus.VolumeID] = &revertStatus
+		s.refreshRevertStatus(ctx, &revertStatus)
+	}
+}
@@ -0,0 +1,615 @@
+package service
+
+/*
+ Copyright (c) 2019 Dell Inc, or its subsidiaries.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/container-storage-interface/spec/lib/go/csi"
+	"github.com/dell/csi-isilon/common/constants"
+	"github.com/dell/csi-isilon/common/utils"
+	isi "github.com/dell/goisilon"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	snapshotsPath                  = "platform/1/snapshot/snapshots"
+	snapshotSchedulesPath          = "platform/1/snapshot/schedules"
+	snapshotAliasesPath            = "platform/1/snapshot/aliases"
+	writableSnapshotsPath          = "platform/14/snapshot/writable"
+	snapshotStateDeleting          = "deleting"
+	snapshotSchedulePrefix         = "csi-"
+	defaultSnapshotSchedulePattern = "%s_%%Y-%%m-%%d_%%H-%%M"
+
+	// snapshotRestoreModeCopy copies the content of the snapshot into the new volume
+	snapshotRestoreModeCopy = "copy"
+	// snapshotRestoreModeWritable creates the new volume as a OneFS writable snapshot of the snapshot
+	snapshotRestoreModeWritable = "writable"
+	// snapshotRestoreModeAuto creates a writable snapshot and copies the snapshot if writable snapshots aren't available
+	snapshotRestoreModeAuto = "auto"
+
+	// writableSnapshotLockComment is the comment of the lock a volume restored as a writable snapshot holds on its
+	// source snapshot
+	writableSnapshotLockComment = "CSI writable snapshot '%s'"
+)
+
+var (
+	// lifetimes of the snapshots, such as '30D', '2W' or '12H'
+	snapshotDurationRegexp     = regexp.MustCompile(`^([0-9]+[WDHms])+$`)
+	snapshotDurationPartRegexp = regexp.MustCompile(`([0-9]+)([WDHms])`)
+	snapshotDurationUnits      = map[string]int64{"W": 7 * 24 * 3600, "D": 24 * 3600, "H": 3600, "m": 60, "s": 1}
+)
+
+// snapshotSettings holds the expiration and the alias of the snapshots of a VolumeSnapshotClass
+type snapshotSettings struct {
+	// expiration is the lifetime of the snapshots in seconds, 0 if they never expire
+	expiration int64
+	// alias is retargeted to the latest snapshot of the class, alias names being unique on a cluster
+	alias string
+}
+
+// snapshotCreation is the body of the snapshot creation requests sent to 'platform/1/snapshot/snapshots'
+type snapshotCreation struct {
+	Path    string `json:"path"`
+	Name    string `json:"name,omitempty"`
+	Expires int64  `json:"expires,omitempty"`
+}
+
+// snapshotAlias is a OneFS snapshot alias as accepted by 'platform/1/snapshot/aliases'
+type snapshotAlias struct {
+	Name   string `json:"name,omitempty"`
+	Target string `json:"target"`
+}
+
+// snapshotSchedule is a OneFS snapshot schedule as returned and accepted by 'platform/1/snapshot/schedules'
+type snapshotSchedule struct {
+	ID       int64  `json:"id,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Pattern  string `json:"pattern,omitempty"`
+	Schedule string `json:"schedule,omitempty"`
+	Duration int64  `json:"duration,omitempty"`
+}
+
+type snapshotScheduleList struct {
+	Schedules []snapshotSchedule `json:"schedules"`
+}
+
+// writableSnapshot is a OneFS writable snapshot as returned and accepted by 'platform/14/snapshot/writable'
+type writableSnapshot struct {
+	ID      int64  `json:"id,omitempty"`
+	SrcSnap string `json:"src_snap,omitempty"`
+	SrcPath string `json:"src_path,omitempty"`
+	DstPath string `json:"dst_path,omitempty"`
+	State   string `json:"state,omitempty"`
+}
+
+type writableSnapshotList struct {
+	Writable []writableSnapshot `json:"writable"`
+}
+
+// snapshotLock is a OneFS snapshot lock as returned and accepted by 'platform/1/snapshot/snapshots/<id>/locks',
+// a locked snapshot is kept by OneFS past its expiration
+type snapshotLock struct {
+	ID      int64  `json:"id,omitempty"`
+	Comment string `json:"comment,omitempty"`
+}
+
+type snapshotLockList struct {
+	Locks []snapshotLock `json:"locks"`
+}
+
+// snapshotScheduleSettings holds the snapshot schedule of the volumes of a storage class
+type snapshotScheduleSettings struct {
+	schedule string
+	pattern  string
+	// retention is the lifetime of the scheduled snapshots in seconds, 0 if they never expire
+	retention int64
+}
+
+// getSnapshotSettings parses the snapshot parameters of a VolumeSnapshotClass, nil is returned if none is set.
+// The expiration is a duration such as '30D' or '12H'.
+func getSnapshotSettings(params map[string]string) (*snapshotSettings, error) {
+	expiration, alias := params[SnapshotExpirationParam], params[SnapshotAliasParam]
+	if expiration == "" && alias == "" {
+		return nil, nil
+	}
+
+	settings := &snapshotSettings{alias: alias}
+	if expiration != "" {
+		duration, err := parseSnapshotDuration(expiration)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value '%s' for '%s': %v", expiration, SnapshotExpirationParam, err)
+		}
+		if settings.expiration = duration; settings.expiration == 0 {
+			return nil, fmt.Errorf("invalid value '%s' for '%s': a positive duration is expected", expiration, SnapshotExpirationParam)
+		}
+	}
+	return settings, nil
+}
+
+// parseSnapshotDuration parses the lifetime of snapshots such as '30D', '2W' or '1D12H' and returns it in seconds.
+// The units are W (weeks), D (days), H (hours), m (minutes) and s (seconds), months and years having no fixed length.
+func parseSnapshotDuration(value string) (int64, error) {
+	if !snapshotDurationRegexp.MatchString(value) {
+		return 0, fmt.Errorf("a duration such as '30D', '2W' or '12H' is expected")
+	}
+	var seconds int64
+	for _, part := range snapshotDurationPartRegexp.FindAllStringSubmatch(value, -1) {
+		n, err := strconv.ParseInt(part[1], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		seconds += n * snapshotDurationUnits[part[2]]
+	}
+	return seconds, nil
+}
+
+// isSnapshotExpired returns true if OneFS is deleting the snapshot or if its expiration date is past, OneFS
+// deleting expired snapshots by itself
+func isSnapshotExpired(snapshot isi.Snapshot) bool {
+	if snapshot == nil {
+		return false
+	}
+	return snapshot.State == snapshotStateDeleting || (snapshot.Expires > 0 && snapshot.Expires <= time.Now().Unix())
+}
+
+// createSnapshotWithSettings creates a snapshot of path which expires and gets an alias according to settings
+func (svc *isiService) createSnapshotWithSettings(ctx context.Context, path, snapshotName string, settings *snapshotSettings) (isi.Snapshot, error) {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	snapshot := &snapshotCreation{Path: path, Name: snapshotName}
+	if settings.expiration > 0 {
+		snapshot.Expires = time.Now().Unix() + settings.expiration
+	}
+	log.Debugf("begin to create snapshot '%s' of '%s' expiring at '%d'", snapshotName, path, snapshot.Expires)
+
+	var resp isi.Snapshot
+	if err := svc.client.API.Post(ctx, snapshotsPath, "", nil, nil, snapshot, &resp); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot '%s' of '%s' : '%v'", snapshotName, path, err)
+	}
+	if settings.alias != "" {
+		if err := svc.SetSnapshotAlias(ctx, settings.alias, snapshotName); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// SetSnapshotAlias points the alias to the given snapshot, the alias being created if it doesn't exist yet and
+// retargeted otherwise
+func (svc *isiService) SetSnapshotAlias(ctx context.Context, alias, snapshotName string) error {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	log.Debugf("point snapshot alias '%s' to snapshot '%s'", alias, snapshotName)
+	err := svc.client.API.Put(ctx, snapshotAliasesPath, alias, nil, nil, &snapshotAlias{Target: snapshotName}, nil)
+	if err != nil && isNotFoundError(err) {
+		err = svc.client.API.Post(ctx, snapshotAliasesPath, "", nil, nil, &snapshotAlias{Name: alias, Target: snapshotName}, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to point snapshot alias '%s' to snapshot '%s' : '%v'", alias, snapshotName, err)
+	}
+	return nil
+}
+
+// getSnapshotRestoreMode parses the SnapshotRestoreMode storage class parameter, 'copy' by default
+func getSnapshotRestoreMode(params map[string]string) (string, error) {
+	switch mode := strings.ToLower(params[SnapshotRestoreModeParam]); mode {
+	case "":
+		return snapshotRestoreModeCopy, nil
+	case snapshotRestoreModeCopy, snapshotRestoreModeWritable, snapshotRestoreModeAuto:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid value '%s' for '%s', '%s', '%s' or '%s' is expected", params[SnapshotRestoreModeParam],
+			SnapshotRestoreModeParam, snapshotRestoreModeCopy, snapshotRestoreModeWritable, snapshotRestoreModeAuto)
+	}
+}
+
+// createVolumeFromWritableSnapshot creates a new volume as a writable snapshot of the given snapshot, which shares
+// the blocks of the snapshot instead of copying them. The volume directory must not exist, OneFS creates it. It
+// returns false if the volume has to be created by copying the snapshot, that is if writable snapshots aren't
+// available and the restore mode is 'auto', or if a previous call already created the volume as a plain directory.
+// The source snapshot is locked until the volume is deleted so that it doesn't expire.
+func (s *service) createVolumeFromWritableSnapshot(ctx context.Context, isiConfig *IsilonClusterConfig, isiPath,
+	snapshotID, dstVolumeName string, sizeInBytes int64, mode string, foundVol bool) (bool, error) {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	dstPath := utils.GetPathForVolume(isiPath, dstVolumeName)
+	if foundVol {
+		if _, err := isiConfig.isiSvc.GetWritableSnapshot(ctx, dstPath); err != nil {
+			if isNotFoundError(err) {
+				return false, nil
+			}
+			return false, fmt.Errorf("failed to get writable snapshot '%s', error '%v'", dstPath, err)
+		}
+		return true, nil
+	}
+
+	snapshotSrc, err := isiConfig.isiSvc.GetSnapshot(ctx, snapshotID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get snapshot id '%s', error '%v'", snapshotID, err)
+	}
+
+	// check source snapshot size
+	size := isiConfig.isiSvc.GetSnapshotSize(ctx, isiPath, snapshotSrc.Name)
+	if size > sizeInBytes {
+		return false, fmt.Errorf("specified size '%d' is smaller than source snapshot size '%d'", sizeInBytes, size)
+	}
+
+	lockComment := fmt.Sprintf(writableSnapshotLockComment, dstPath)
+	if err = isiConfig.isiSvc.LockSnapshot(ctx, snapshotID, lockComment); err != nil {
+		return false, fmt.Errorf("failed to lock snapshot id '%s', error '%v'", snapshotID, err)
+	}
+	if err = isiConfig.isiSvc.CreateWritableSnapshot(ctx, strconv.FormatInt(snapshotSrc.Id, 10), dstPath); err != nil {
+		if err := isiConfig.isiSvc.UnlockSnapshot(ctx, snapshotID, lockComment); err != nil {
+			log.Errorf("failed to unlock snapshot id '%s', error '%v'", snapshotID, err)
+		}
+		if mode == snapshotRestoreModeAuto && isNotFoundError(err) {
+			log.Infof("writable snapshots are not available on cluster '%s', copy snapshot id '%s' instead: '%v'",
+				isiConfig.ClusterName, snapshotID, err)
+			return false, nil
+		}
+		if isNotFoundError(err) {
+			return false, fmt.Errorf("failed to create writable snapshot of snapshot id '%s', writable snapshots are not available on cluster '%s', use the '%s' or '%s' '%s' instead",
+				snapshotID, isiConfig.ClusterName, snapshotRestoreModeCopy, snapshotRestoreModeAuto, SnapshotRestoreModeParam)
+		}
+		return false, fmt.Errorf("failed to create writable snapshot of snapshot id '%s', error '%v'", snapshotID, err)
+	}
+	return true, nil
+}
+
+// getWritableSnapshotDependents returns the paths of the writable snapshots of the given snapshot, except the one
+// at excludedPath. The source snapshot of a writable snapshot is given by name or by ID.
+func (s *service) getWritableSnapshotDependents(ctx context.Context, isiConfig *IsilonClusterConfig, snapshot isi.Snapshot,
+	excludedPath string) ([]string, error) {
+	writableSnapshots, err := isiConfig.isiSvc.GetWritableSnapshots(ctx)
+	if err != nil {
+		if isNotFoundError(err) {
+			// writable snapshots aren't available on the cluster
+			return nil, nil
+		}
+		return nil, err
+	}
+	var dependents []string
+	for _, writable := range writableSnapshots {
+		if writable.DstPath == excludedPath {
+			continue
+		}
+		if writable.SrcSnap == snapshot.Name || writable.SrcSnap == strconv.FormatInt(snapshot.Id, 10) {
+			dependents = append(dependents, writable.DstPath)
+		}
+	}
+	return dependents, nil
+}
+
+// getSnapshotTrackingDir returns the isiPath and the name of the tracking directory of a snapshot, which records
+// the volumes depending on the snapshot and whether its deletion is pending
+func (s *service) getSnapshotTrackingDir(ctx context.Context, isiConfig *IsilonClusterConfig, snapshotID string) (string, string, error) {
+	snapshotIsiPath, err := isiConfig.isiSvc.GetSnapshotIsiPath(ctx, isiConfig.IsiPath, snapshotID)
+	if err != nil {
+		return "", "", err
+	}
+	isiPath, snapshotName, _ := isiConfig.isiSvc.GetSnapshotIsiPathComponents(snapshotIsiPath)
+	return isiPath, isiConfig.isiSvc.GetSnapshotTrackingDirName(snapshotName), nil
+}
+
+// isSnapshotDeletionPending returns true if the snapshot was deleted by Kubernetes while volumes still depended on it
+func (s *service) isSnapshotDeletionPending(ctx context.Context, isiConfig *IsilonClusterConfig, snapshotID string) (bool, error) {
+	isiPath, snapshotTrackingDir, err := s.getSnapshotTrackingDir(ctx, isiConfig, snapshotID)
+	if err != nil {
+		return false, err
+	}
+	return isiConfig.isiSvc.IsVolumeExistent(ctx, isiPath, "", path.Join(snapshotTrackingDir, DeleteSnapshotMarker)), nil
+}
+
+// releaseSnapshotOfWritableVolume unlocks the source snapshot of a deleted volume restored as a writable snapshot,
+// and deletes the snapshot if its deletion was deferred until no volume depends on it anymore
+func (s *service) releaseSnapshotOfWritableVolume(ctx context.Context, isiConfig *IsilonClusterConfig, writable *writableSnapshot) error {
+	// Fetch log handler
+	log := utils.GetRunIDLogger(ctx)
+
+	if err := isiConfig.isiSvc.UnlockSnapshot(ctx, writable.SrcSnap, fmt.Sprintf(writableSnapshotLockComment, writable.DstPath)); err != nil {
+		if isNotFoundError(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to unlock snapshot '%s' : '%v'", writable.SrcSnap, err)
+	}
+
+	snapshot, err := isiConfig.isiSvc.GetSnapshot(ctx, writable.SrcSnap)
+	if err != nil {
+		if isNotFoundError(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get snapshot '%s' : '%v'", writable.SrcSnap, err)
+	}
+	isiPath, snapshotTrackingDir, err := s.getSnapshotTrackingDir(ctx, isiConfig, writable.SrcSnap)
+	if err != nil {
+		return err
+	}
+	if !isiConfig.isiSvc.IsVolumeExistent(ctx, isiPath, "", path.Join(snapshotTrackingDir, DeleteSnapshotMarker)) {
+		return nil
+	}
+	// the tracking directory holds '.', '..', the delete marker and an entry per RO volume of the snapshot
+	if totalSubDirectories, err := isiConfig.isiSvc.GetSubDirectoryCount(ctx, isiPath, snapshotTrackingDir); err != nil || totalSubDirectories > 3 {
+		return err
+	}
+	if dependents, err := s.getWritableSnapshotDependents(ctx, isiConfig, snapshot, writable.DstPath); err != nil || len(dependents) > 0 {
+		return err
+	}
+
+	log.Infof("no volume depends on snapshot '%s' anymore, complete its deletion", snapshot.Name)
+	if err := isiConfig.isiSvc.DeleteVolume(ctx, isiPath, snapshotTrackingDir); err != nil {
+		return err
+	}
+	// an expired snapshot is deleted by OneFS once unlocked
+	if isSnapshotExpired(snapshot) {
+		return nil
+	}
+	return isiConfig.isiSvc.DeleteSnapshot(ctx, snapshot.Id, "")
+}
+
+// getSnapshotScheduleSettings parses the SnapshotSchedule, SnapshotSchedulePattern and SnapshotScheduleRetention
+// storage class parameters, returns nil if no schedule is set. The schedule is a OneFS schedule such as
+// 'every 1 hours' or 'every day at 00:00', the retention a duration such as '7D'.
+func getSnapshotScheduleSettings(params map[string]string) (*snapshotScheduleSettings, error) {
+	schedule := params[SnapshotScheduleParam]
+	if schedule == "" {
+		if params[SnapshotSchedulePatternParam] != "" || params[SnapshotScheduleRetentionParam] != "" {
+			return nil, fmt.Errorf("'%s' is required by '%s' and '%s'", SnapshotScheduleParam, SnapshotSchedulePatternParam, SnapshotScheduleRetentionParam)
+		}
+		return nil, nil
+	}
+
+	settings := &snapshotScheduleSettings{schedule: schedule, pattern: params[SnapshotSchedulePatternParam]}
+	// the names of the snapshots must differ, so the pattern must hold a date
+	if settings.pattern != "" && !strings.Contains(settings.pattern, "%") {
+		return nil, fmt.Errorf("invalid value '%s' for '%s', a pattern with date variables such as '%%Y-%%m-%%d_%%H-%%M' is expected",
+			settings.pattern, SnapshotSchedulePatternParam)
+	}
+	if retention := params[SnapshotScheduleRetentionParam]; retention != "" {
+		duration, err := parseSnapshotDuration(retention)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value '%s' for '%s': %v", retention, SnapshotScheduleRetentionParam, err)
+		}
+		settings.retention = duration
+	}
+	return settings, nil
+}
+
+// getSnapshotScheduleName returns the name of the snapshot schedule of a volume
+func getSnapshotScheduleName(volName string) string {
+	return snapshotSchedulePrefix + volName
+}
+
+// applySnapshotSchedule creates the snapshot schedule of a new volume, or updates the one created by a previous call
+func (s *service) applySnapshotSchedule(ctx context.Context, isiConfig *IsilonClusterConfig, isiPath, volName string,
+	settings *snapshotScheduleSettings) error {
+	if settings == nil {
+		return nil
+	}
+
+	schedule := &snapshotSchedule{
+		Name:     getSnapshotScheduleName(volName),
+		Path:     utils.GetPathForVolume(isiPath, volName),
+		Pattern:  settings.pattern,
+		Schedule: settings.schedule,
+		Duration: settings.retention,
+	}
+	if schedule.Pattern == "" {
+		schedule.Pattern = fmt.Sprintf(defaultSnapshotSchedulePattern, volName)
+	}
+
+	if _, err := isiConfig.isiSvc.GetSnapshotSchedule(ctx, schedule.Name); err == nil {
+		return isiConfig.isiSvc.UpdateSnapshotSchedule(ctx, schedule)
+	} else if !isNotFoundError(err) {
+		return err
+	}
+	return isiConfig.isiSvc.CreateSnapshotSchedule(ctx, schedule)
+}
+
+// deleteSnapshotSchedule deletes the snapshot schedule of a volume if there is one, the snapshots already taken
+// are left to their expiration
+func (s *service) deleteSnapshotSchedule(ctx context.Context, isiConfig *IsilonClusterConfig, volName string) error {
+	if err := isiConfig.isiSvc.DeleteSnapshotSchedule(ctx, getSnapshotScheduleName(volName)); err != nil && !isNotFoundError(err) {
+		return fmt.Errorf("failed to delete the snapshot schedule of volume '%s' : '%v'", volName, err)
+	}
+	return nil
+}
+
+// listSnapshotsByID returns the snapshot with the given normalized ID, if it exists and did not expire
+func (s *service) listSnapshotsByID(ctx context.Context, normalizedSnapshotID string) ([]*csi.ListSnapshotsResponse_Entry, error) {
+	snapshotID, clusterName, err := utils.ParseNormalizedSnapshotID(ctx, normalizedSnapshotID)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if _, err := strconv.ParseInt(snapshotID, 10, 64); err != nil {
+		// not a snapshot of the driver
+		return nil, nil
+	}
+	isiConfig, err := s.getListSnapshotsClusterConfig(ctx, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot, err := isiConfig.isiSvc.GetSnapshot(ctx, snapshotID)
+	if err != nil {
+		if isNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if isSnapshotExpired(snapshot) {
+		return nil, nil
+	}
+	volumeIDs, err := s.getVolumeIDsByPath(ctx, isiConfig)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return []*csi.ListSnapshotsResponse_Entry{
+		s.getListSnapshotsEntry(ctx, snapshot, volumeIDs[snapshot.Path], isiConfig.ClusterName),
+	}, nil
+}
+
+// listSnapshotsOfVolume returns the snapshots of a volume, the scheduled ones included
+func (s *service) listSnapshotsOfVolume(ctx context.Context, volumeID string) ([]*csi.ListSnapshotsResponse_Entry, error) {
+	_, exportID, accessZone, clusterName, err := utils.ParseNormalizedVolumeID(ctx, volumeID)
+	if err != nil {
+		// not a volume of the driver
+		return nil, nil
+	}
+	isiConfig, err := s.getListSnapshotsClusterConfig(ctx, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	export, err := isiConfig.isiSvc.GetExportByIDWithZone(ctx, exportID, accessZone)
+	if err != nil {
+		if isNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if export == nil || export.Paths == nil || len(*export.Paths) == 0 {
+		return nil, nil
+	}
+	dirPath := (*export.Paths)[0]
+
+	snapshots, err := isiConfig.isiSvc.GetSnapshotsUnderPath(ctx, dirPath)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	var entries []*csi.ListSnapshotsResponse_Entry
+	for _, snapshot := range snapshots {
+		if snapshot.Path == dirPath && !isSnapshotExpired(snapshot) {
+			entries = append(entries, s.getListSnapshotsEntry(ctx, snapshot, volumeID, isiConfig.ClusterName))
+		}
+	}
+	return entries, nil
+}
+
+// listAllSnapshots returns the snapshots of the exported directories under the isiPath of every cluster, the
+// clusters which cannot be probed are skipped
+func (s *service) listAllSnapshots(ctx context.Context) ([]*csi.ListSnapshotsResponse_Entry, error) {
+	var entries []*csi.ListSnapshotsResponse_Entry
+	for _, isiConfig := range s.getIsilonClusters() {
+		ctx, log := setClusterContext(ctx, isiConfig.ClusterName)
+		if err := s.autoProbe(ctx, isiConfig); err != nil {
+			log.Warnf("failed to probe cluster '%s', its snapshots are not listed: '%v'", isiConfig.ClusterName, err)
+			continue
+		}
+		volumeIDs, err := s.getVolumeIDsByPath(ctx, isiConfig)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		snapshots, err := isiConfig.isiSvc.GetSnapshotsUnderPath(ctx, isiConfig.IsiPath)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		for _, snapshot := range snapshots {
+			if volumeID, ok := volumeIDs[snapshot.Path]; ok && !isSnapshotExpired(snapshot) {
+				entries = append(entries, s.getListSnapshotsEntry(ctx, snapshot, volumeID, isiConfig.ClusterName))
+			}
+		}
+	}
+	return entries, nil
+}
+
+// getListSnapshotsClusterConfig returns the probed config of a cluster, the default one if clusterName is empty
+func (s *service) getListSnapshotsClusterConfig(ctx context.Context, clusterName string) (*IsilonClusterConfig, error) {
+	isiConfig, err := s.getIsilonConfig(ctx, &clusterName)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.autoProbe(ctx, isiConfig); err != nil {
+		return nil, err
+	}
+	return isiConfig, nil
+}
+
+// getVolumeIDsByPath returns the IDs of the volumes of a cluster by the path of their directory. The directories
+// exported in the access zone of the driver or of a volume are included, the ID of a volume being the handle of its
+// PV, in whichever format it was created, or empty if the directory has no PV.
+func (s *service) getVolumeIDsByPath(ctx context.Context, isiConfig *IsilonClusterConfig) (map[string]string, error) {
+	volumeIDs := make(map[string]string)
+	for accessZone, handles := range s.getVolumeHandlesByExport(ctx, isiConfig.ClusterName) {
+		exports, err := isiConfig.isiSvc.GetExportsWithZone(ctx, accessZone)
+		if err != nil {
+			return nil, err
+		}
+		for _, export := range exports {
+			if export.Paths == nil || len(*export.Paths) != 1 {
+				continue
+			}
+			volumeIDs[(*export.Paths)[0]] = handles[export.ID]
+		}
+	}
+	return volumeIDs, nil
+}
+
+// getVolumeHandlesByExport returns the handles of the CSI PVs of a cluster by access zone and export ID, the access
+// zone of the driver being always included
+func (s *service) getVolumeHandlesByExport(ctx context.Context, clusterName string) map[string]map[int]string {
+	log := utils.GetRunIDLogger(ctx)
+
+	handles := map[string]map[int]string{s.opts.AccessZone: {}}
+	if s.k8sclient == nil {
+		return handles
+	}
+	pvs, err := s.k8sclient.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Warnf("failed to list persistent volumes, the snapshots are listed without their source volume: '%v'", err)
+		return handles
+	}
+	for _, pv := range pvs.Items {
+		if pv.Spec.CSI == nil || pv.Spec.CSI.Driver != constants.PluginName {
+			continue
+		}
+		_, exportID, accessZone, pvClusterName, err := utils.ParseNormalizedVolumeID(ctx, pv.Spec.CSI.VolumeHandle)
+		if err != nil {
+			continue
+		}
+		if pvClusterName == "" {
+			pvClusterName = s.defaultIsiClusterName
+		}
+		if pvClusterName != clusterName {
+			continue
+		}
+		if handles[accessZone] == nil {
+			handles[accessZone] = make(map[int]string)
+		}
+		handles[accessZone][exportID] = pv.Spec.CSI.VolumeHandle
+	}
+	return handles
+}
+
+func (s *service) getListSnapshotsEntry(ctx context.Context, snapshot isi.Snapshot, sourceVolumeID, clusterName string) *csi.ListSnapshotsResponse_Entry {
+	snapID := utils.GetNormalizedSnapshotID(ctx, strconv.FormatInt(snapshot.Id, 10), clusterName)
+	return &csi.ListSnapshotsResponse_Entry{
+		Snapshot: s.getCSISnapshot(snapID, sourceVolumeID, snapshot.Created, snapshot.Size),
+	}
+}
@@ -16,36 +16,68 @@ package service
  limitations under the License.
 */
 import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"github.com/dell/csi-isilon/common/constants"
 	"github.com/dell/csi-isilon/common/k8sutils"
+	"github.com/dell/csi-isilon/common/utils"
+	"io/ioutil"
 	"log"
+	"math/big"
 	"net"
+	"net/http"
 	"net/http/httptest"
 	"os"
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	csi "github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/cucumber/godog"
 	"github.com/dell/gocsi"
 	"github.com/dell/gofsutil"
 	"golang.org/x/net/context"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
 	"os/exec"
 )
 
 type feature struct {
 	nGoRoutines                        int
 	server                             *httptest.Server
+	isilonConfigs                      map[interface{}]interface{}
+	secretValidationReport             *SecretValidationReport
+	validationChecks                   []*ValidationCheck
+	clusterHealth                      []*ClusterHealth
+	secretProvider                     *httptest.Server
+	secretProviderToken                string
+	secretProviderTokenRef             string
 	service                            *service
 	err                                error // return from the preceeding call
 	getPluginInfoResponse              *csi.GetPluginInfoResponse
 	getPluginCapabilitiesResponse      *csi.GetPluginCapabilitiesResponse
 	probeResponse                      *csi.ProbeResponse
+	probeHeader                        metadata.MD
 	createVolumeResponse               *csi.CreateVolumeResponse
 	publishVolumeResponse              *csi.ControllerPublishVolumeResponse
 	unpublishVolumeResponse            *csi.ControllerUnpublishVolumeResponse
@@ -53,6 +85,13 @@ type feature struct {
 	nodeGetCapabilitiesResponse        *csi.NodeGetCapabilitiesResponse
 	deleteVolumeResponse               *csi.DeleteVolumeResponse
 	getCapacityResponse                *csi.GetCapacityResponse
+	migrateVolumeResponse              *MigrateVolumeResponse
+	importVolumeResponse               *ImportVolumeResponse
+	decodedID                          *DecodedID
+	volumeDetails                      *VolumeDetails
+	clusterArtifacts                   []*ClusterArtifacts
+	clusterConnectivity                []*ClusterConnectivity
+	resolvedNodeID                     string
 	controllerGetCapabilitiesResponse  *csi.ControllerGetCapabilitiesResponse
 	validateVolumeCapabilitiesResponse *csi.ValidateVolumeCapabilitiesResponse
 	createSnapshotResponse             *csi.CreateSnapshotResponse
@@ -118,6 +157,31 @@ func (f *feature) aIsilonService() error {
 	f.getPluginInfoResponse = nil
 	f.volumeIDList = f.volumeIDList[:0]
 	f.snapshotIDList = f.snapshotIDList[:0]
+	testDirectoryAttributes = directoryAttributes{}
+	testDirectoryAttributesPath = ""
+	testSyncPolicy = syncPolicy{}
+	testSnapshotCreation = snapshotCreation{}
+	testSnapshotSchedule = snapshotSchedule{}
+	testSnapshotAlias = snapshotAlias{}
+	testDeletedSnapshotSchedule = ""
+	testWritableSnapshot = writableSnapshot{}
+	testDeletedWritableSnapshot = ""
+	testSnapshotCopied = false
+	testSnapshotLocks = nil
+	testDeletedSnapshotLocks = nil
+	testTrackingDirEntries = nil
+	testDeletedSnapshot = ""
+	testJobCreation = jobCreation{}
+	testExportUpdates = nil
+	testQuotaThresholds = ""
+	f.migrateVolumeResponse = nil
+	f.importVolumeResponse = nil
+	f.decodedID = nil
+	f.volumeDetails = nil
+	f.clusterArtifacts = nil
+	f.clusterConnectivity = nil
+	f.resolvedNodeID = ""
+	smartLockRetentionCheckLimit = defaultSmartLockRetentionCheckLimit
 
 	// configure gofsutil; we use a mock interface
 	gofsutil.UseMockFS()
@@ -144,12 +208,42 @@ func (f *feature) aIsilonService() error {
 	// initialize volume and export existence status
 	stepHandlersErrors.ExportNotFoundError = true
 	stepHandlersErrors.VolumeNotExistError = true
+	stepHandlersErrors.IsiPathQuotaExists = false
+	stepHandlersErrors.CSISnapshotsExist = false
+	stepHandlersErrors.ScheduledSnapshotsExist = false
+	stepHandlersErrors.SnapshotAliasExists = false
+	stepHandlersErrors.WritableSnapshotDependents = false
+	stepHandlersErrors.SnapshotDeletionPending = false
+
+	// the revert scenarios check the state of the jobs without clearing the induced errors
+	stepHandlersErrors.JobRunning = false
+	stepHandlersErrors.JobFailed = false
+	stepHandlersErrors.SnapRevertDomainMarked = false
+	stepHandlersErrors.DomainMarkRunning = false
+	stepHandlersErrors.DomainMarkFailed = false
+	stepHandlersErrors.DomainMarkAfterSnapshot = false
+
+	// the secret provider stub is started by the scenarios using it
+	if f.secretProvider != nil {
+		f.secretProvider.Close()
+		f.secretProvider = nil
+	}
+	testProviderSecrets = map[string]string{}
+
+	// the sessions are created again by each scenario
+	stepHandlersErrors.SessionExpired = false
+	testSessionPassword = "blah"
+	testSessionLogins = nil
+	testSessionRequests = 0
+	testBasicAuthRequests = 0
+	testDeletedVolumeDirectories = 0
 
 	// Get the httptest mock handler. Only set
 	// a new server if there isn't one already.
 	handler := getHandler()
 	// Get or reuse the cached service
 	f.getService()
+	f.service.reverts = nil
 	clusterConfig := f.service.getIsilonClusterConfig(clusterName1)
 	if handler != nil && os.Getenv("CSI_ISILON_ENDPOINT") == "" {
 		if f.server == nil {
@@ -300,6 +394,9 @@ func FeatureContext(s *godog.Suite) {
 	s.Step(`^I mark request read only$`, f.iMarkRequestReadOnly)
 	s.Step(`^I call NodeStageVolume with name "([^"]*)" and access type "([^"]*)"$`, f.iCallNodeStageVolume)
 	s.Step(`^I call ControllerPublishVolume with name "([^"]*)" and access type "([^"]*)" to "([^"]*)"$`, f.iCallControllerPublishVolume)
+	s.Step(`^I call ControllerPublishVolume with name "([^"]*)" and access type "([^"]*)" and read only "(true|false)" to "([^"]*)"$`, f.iCallControllerPublishVolumeWithReadOnly)
+	s.Step(`^the node "([^"]*)" is added to the "([^"]*)" of the export$`, f.theNodeIsAddedToTheOfTheExport)
+	s.Step(`^the node "([^"]*)" is not added to the "([^"]*)" of the export$`, f.theNodeIsNotAddedToTheOfTheExport)
 	s.Step(`^a valid NodeStageVolumeResponse is returned$`, f.aValidNodeStageVolumeResponseIsReturned)
 	s.Step(`^I call NodeUnstageVolume with name "([^"]*)"$`, f.iCallNodeUnstageVolume)
 	s.Step(`^I call ControllerUnpublishVolume with name "([^"]*)" and access type "([^"]*)" to "([^"]*)"$`, f.iCallControllerUnPublishVolume)
@@ -311,10 +408,29 @@ func FeatureContext(s *godog.Suite) {
 	s.Step(`^I call EphemeralNodeUnpublishVolume$`, f.iCallEphemeralNodeUnpublishVolume)
 	s.Step(`^a valid NodeUnpublishVolumeResponse is returned$`, f.aValidNodeUnpublishVolumeResponseIsReturned)
 	s.Step(`^I call CreateSnapshot "([^"]*)" "([^"]*)" "([^"]*)"$`, f.iCallCreateSnapshot)
+	s.Step(`^I call CreateVolume "([^"]*)" with snapshot schedule "([^"]*)" pattern "([^"]*)" and retention "([^"]*)"$`, f.iCallCreateVolumeWithSnapshotSchedulePatternAndRetention)
+	s.Step(`^snapshot schedule "([^"]*)" takes snapshots of "([^"]*)" "([^"]*)" named "([^"]*)" kept (\d+) seconds$`, f.snapshotScheduleTakesSnapshotsOfNamedKeptSeconds)
+	s.Step(`^snapshot schedule "([^"]*)" is deleted$`, f.snapshotScheduleIsDeleted)
+	s.Step(`^I call ListSnapshots with snapshot ID "([^"]*)" source volume ID "([^"]*)" max entries (\d+) and starting token "([^"]*)"$`, f.iCallListSnapshotsWithSnapshotIDSourceVolumeIDMaxEntriesAndStartingToken)
+	s.Step(`^the snapshots "([^"]*)" are listed with next token "([^"]*)"$`, f.theSnapshotsAreListedWithNextToken)
+	s.Step(`^I call CreateSnapshot "([^"]*)" "([^"]*)" with expiration "([^"]*)" and alias "([^"]*)"$`, f.iCallCreateSnapshotWithExpirationAndAlias)
+	s.Step(`^the snapshot is created with an expiration in (\d+) seconds and alias "([^"]*)"$`, f.theSnapshotIsCreatedWithAnExpirationInSecondsAndAlias)
+	s.Step(`^snapshot alias "([^"]*)" points to snapshot "([^"]*)"$`, f.snapshotAliasPointsToSnapshot)
 	s.Step(`^a valid CreateSnapshotResponse is returned$`, f.aValidCreateSnapshotResponseIsReturned)
 	s.Step(`^I call DeleteSnapshot "([^"]*)"$`, f.iCallDeleteSnapshot)
 	s.Step(`^I call CreateVolumeFromSnapshot "([^"]*)" "([^"]*)"$`, f.iCallCreateVolumeFromSnapshot)
 	s.Step(`^I call CreateVolumeFromVolume "([^"]*)" "([^"]*)"$`, f.iCallCreateVolumeFromVolume)
+	s.Step(`^I call CreateVolumeFromSnapshot "([^"]*)" "([^"]*)" with restore mode "([^"]*)"$`, f.iCallCreateVolumeFromSnapshotWithRestoreMode)
+	s.Step(`^I call CreateVolumeFromSnapshot "([^"]*)" "([^"]*)" with parameter "([^"]*)" set to '([^']*)'$`, f.iCallCreateVolumeFromSnapshotWithParameterSetTo)
+	s.Step(`^the SmartLock retention check is limited to (\d+) entries$`, f.theSmartLockRetentionCheckIsLimitedToEntries)
+	s.Step(`^the volume is restored from snapshot "([^"]*)" as writable snapshot "([^"]*)"$`, f.theVolumeIsRestoredFromSnapshotAsWritableSnapshot)
+	s.Step(`^the snapshot is copied$`, f.theSnapshotIsCopied)
+	s.Step(`^writable snapshot "([^"]*)" is deleted$`, f.writableSnapshotIsDeleted)
+	s.Step(`^I call CreateVolumeFromSnapshot "([^"]*)" "([^"]*)" with restore mode "([^"]*)" and parameter "([^"]*)" set to '([^']*)'$`, f.iCallCreateVolumeFromSnapshotWithRestoreModeAndParameterSetTo)
+	s.Step(`^snapshot "([^"]*)" is locked by "([^"]*)"$`, f.snapshotIsLockedBy)
+	s.Step(`^snapshot "([^"]*)" is unlocked$`, f.snapshotIsUnlocked)
+	s.Step(`^the deletion of snapshot "([^"]*)" is deferred$`, f.theDeletionOfSnapshotIsDeferred)
+	s.Step(`^snapshot "([^"]*)" is deleted$`, f.snapshotIsDeleted)
 	s.Step(`^I call initialize real isilon service$`, f.iCallInitializeRealIsilonService)
 	s.Step(`^I call logStatistics (\d+) times$`, f.iCallLogStatisticsTimes)
 	s.Step(`^I call BeforeServe$`, f.iCallBeforeServe)
@@ -323,10 +439,118 @@ func FeatureContext(s *godog.Suite) {
 	s.Step(`^I call unimplemented functions$`, f.iCallUnimplementedFunctions)
 	s.Step(`^I call init Service object$`, f.iCallInitServiceObject)
 	s.Step(`^I call ControllerExpandVolume "([^"]*)" "(\d+)"$`, f.iCallControllerExpandVolume)
+	s.Step(`^(\d+) volume directories are deleted$`, f.volumeDirectoriesAreDeleted)
 	s.Step(`^a valid ControllerExpandVolumeResponse is returned$`, f.aValidControllerExpandVolumeResponseIsReturned)
 	s.Step(`^I call set allowed networks "([^"]*)"$`, f.iCallSetAllowedNetworks)
 	s.Step(`^I call set allowed networks with multiple networks "([^"]*)" "([^"]*)"$`, f.iCallSetAllowedNetworkswithmultiplenetworks)
 	s.Step(`^I call NodeGetInfo with invalid networks$`, f.iCallNodeGetInfowithinvalidnetworks)
+	s.Step(`^I enable event recording$`, f.iEnableEventRecording)
+	s.Step(`^an event with reason "([^"]*)" is recorded$`, f.anEventWithReasonIsRecorded)
+	s.Step(`^I call removeNodeFromExports "([^"]*)"$`, f.iCallRemoveNodeFromExports)
+	s.Step(`^a CSINode "([^"]*)" with node ID "([^"]*)"$`, f.aCSINodeWithNodeID)
+	s.Step(`^a VolumeAttachment of PV "([^"]*)" to node "([^"]*)" with node ID "([^"]*)"$`, f.aVolumeAttachmentOfPVToNodeWithNodeID)
+	s.Step(`^I call resolveNodeID "([^"]*)"$`, f.iCallResolveNodeID)
+	s.Step(`^the resolved node ID is "([^"]*)"$`, f.theResolvedNodeIDIs)
+	s.Step(`^I configure tenant "([^"]*)" for namespace "([^"]*)" with params "([^"]*)" "([^"]*)" "([^"]*)" "([^"]*)"$`, f.iConfigureTenantForNamespaceWithParams)
+	s.Step(`^I configure tenant "([^"]*)" for namespace "([^"]*)" with access zone "([^"]*)" and no azServiceIP$`, f.iConfigureTenantForNamespaceWithAccessZoneAndNoAzServiceIP)
+	s.Step(`^I call CreateVolume without persistent metadata "([^"]*)"$`, f.iCallCreateVolume)
+	s.Step(`^I call CreateVolume "([^"]*)" with parameter "([^"]*)" set to '([^']*)'$`, f.iCallCreateVolumeWithParameterSetTo)
+	s.Step(`^I call CreateVolume "([^"]*)" with SmartLock parameters "([^"]*)"$`, f.iCallCreateVolumeWithSmartLockParameters)
+	s.Step(`^directory "([^"]*)" has attribute "([^"]*)" set to '([^']*)'$`, f.directoryHasAttributeSetTo)
+	s.Step(`^attribute "([^"]*)" of directory "([^"]*)" is not set$`, f.attributeOfDirectoryIsNotSet)
+	s.Step(`^I enable capacity placement$`, f.iEnableCapacityPlacement)
+	s.Step(`^I add cluster "([^"]*)" with IP "([^"]*)" and placement weight (\d+)$`, f.iAddClusterWithIPAndPlacementWeight)
+	s.Step(`^I call CreateVolume "([^"]*)" with requisite topology "([^"]*)"$`, f.iCallCreateVolumeWithRequisiteTopology)
+	s.Step(`^the volume is created on cluster "([^"]*)"$`, f.theVolumeIsCreatedOnCluster)
+	s.Step(`^I call CreateVolume "([^"]*)" with preferred topologies "([^"]*)"$`, f.iCallCreateVolumeWithPreferredTopologies)
+	s.Step(`^the volume is accessible from topology "([^"]*)"$`, f.theVolumeIsAccessibleFromTopology)
+	s.Step(`^the plugin capability "([^"]*)" is advertised$`, f.thePluginCapabilityIsAdvertised)
+	s.Step(`^no directory attribute is set$`, f.noDirectoryAttributeIsSet)
+	s.Step(`^I call GetCapacity with parameters "([^"]*)" and topology "([^"]*)"$`, f.iCallGetCapacityWithParametersAndTopology)
+	s.Step(`^the available capacity is (\d+) and the maximum volume size is (\d+)$`, f.theAvailableCapacityIsAndTheMaximumVolumeSizeIs)
+	s.Step(`^a PV "([^"]*)" exists for volume "([^"]*)"$`, f.aPVExistsForVolume)
+	s.Step(`^rsync jobs succeed with image "([^"]*)"$`, f.rsyncJobsSucceedWithImage)
+	s.Step(`^I call MigrateVolume "([^"]*)" to cluster "([^"]*)" with copy method "([^"]*)" and node "([^"]*)"$`, f.iCallMigrateVolumeToClusterWithCopyMethodAndNode)
+	s.Step(`^I call MigrateVolume "([^"]*)" to cluster "([^"]*)" in dry run mode$`, f.iCallMigrateVolumeToClusterInDryRunMode)
+	s.Step(`^a migration plan of (\d+) steps is returned$`, f.aMigrationPlanOfStepsIsReturned)
+	s.Step(`^the migration of volume "([^"]*)" ends in phase "([^"]*)"$`, f.theMigrationOfVolumeEndsInPhase)
+	s.Step(`^PV "([^"]*)" refers to a volume on cluster "([^"]*)"$`, f.pvRefersToAVolumeOnCluster)
+	s.Step(`^a pod "([^"]*)" uses PVC "([^"]*)"$`, f.aPodUsesPVC)
+	s.Step(`^creating PVs of cluster "([^"]*)" fails$`, f.creatingPVsOfClusterFails)
+	s.Step(`^deleting PV "([^"]*)" (fails|hangs)$`, f.deletingPV)
+	s.Step(`^PV "([^"]*)" has reclaim policy "([^"]*)"$`, f.pvHasReclaimPolicy)
+	s.Step(`^rsync jobs never finish with image "([^"]*)"$`, f.rsyncJobsNeverFinishWithImage)
+	s.Step(`^the copy of the migrations times out after (\d+) milliseconds$`, f.theCopyOfTheMigrationsTimesOutAfterMilliseconds)
+	s.Step(`^a migration of volume "([^"]*)" to cluster "([^"]*)" is in progress$`, f.aMigrationOfVolumeToClusterIsInProgress)
+	s.Step(`^PV "([^"]*)" has an interrupted migration of volume "([^"]*)" to volume "([^"]*)" in phase "([^"]*)"$`, f.pvHasAnInterruptedMigrationOfVolumeToVolumeInPhase)
+	s.Step(`^I reconcile the migrations$`, f.iReconcileTheMigrations)
+	s.Step(`^PV "([^"]*)" has no migration state$`, f.pvHasNoMigrationState)
+	s.Step(`^SyncIQ policy copies "([^"]*)" to "([^"]*)" on "([^"]*)"$`, f.syncIQPolicyCopiesToOn)
+	s.Step(`^I call GetMigrationStatus "([^"]*)" through the admin service$`, f.iCallGetMigrationStatusThroughTheAdminService)
+	s.Step(`^I call RevertVolume "([^"]*)" to snapshot "([^"]*)" through the admin service$`, f.iCallRevertVolumeToSnapshotThroughTheAdminService)
+	s.Step(`^the revert of volume "([^"]*)" is in state "([^"]*)" with job state "([^"]*)"$`, f.theRevertOfVolumeIsInStateWithJobState)
+	s.Step(`^a SnapRevert job of snapshot (\d+) is started$`, f.aSnapRevertJobOfSnapshotIsStarted)
+	s.Step(`^a SnapRevert domain is created on "([^"]*)"$`, f.aSnapRevertDomainIsCreatedOn)
+	s.Step(`^the SnapRevert domain of "([^"]*)" is recorded as created at "([^"]*)"$`, f.theSnapRevertDomainOfIsRecordedAsCreatedAt)
+	s.Step(`^a revert of volume "([^"]*)" to snapshot "([^"]*)" is in progress$`, f.aRevertOfVolumeToSnapshotIsInProgress)
+	s.Step(`^PV "([^"]*)" has a running revert of volume "([^"]*)" to snapshot "([^"]*)"$`, f.pvHasARunningRevertOfVolumeToSnapshot)
+	s.Step(`^I reconcile the reverts$`, f.iReconcileTheReverts)
+	s.Step(`^PV "([^"]*)" has the state of the revert$`, f.pvHasTheStateOfTheRevert)
+	s.Step(`^PV "([^"]*)" has no revert state$`, f.pvHasNoRevertState)
+	s.Step(`^I call ModifyVolume "([^"]*)" with parameters "([^"]*)" through the admin service$`, f.iCallModifyVolumeWithParametersThroughTheAdminService)
+	s.Step(`^I call ControllerModifyVolume "([^"]*)" with parameters "([^"]*)"$`, f.iCallControllerModifyVolumeWithParameters)
+	s.Step(`^the thresholds of the quota are set to '([^']*)'$`, f.theThresholdsOfTheQuotaAreSetTo)
+	s.Step(`^the security flavors of the export are set to "([^"]*)"$`, f.theSecurityFlavorsOfTheExportAreSetTo)
+	s.Step(`^the root clients of the export are removed$`, f.theRootClientsOfTheExportAreRemoved)
+	s.Step(`^I call GetIsiService on a TLS server trusted with "([^"]*)"$`, f.iCallGetIsiServiceOnATLSServerTrustedWith)
+	s.Step(`^I call getNewIsilonConfigs with TLS settings '([^']*)'$`, f.iCallGetNewIsilonConfigsWithTLSSettings)
+	s.Step(`^I call getNewIsilonConfigs with credentials '([^']*)'$`, f.iCallGetNewIsilonConfigsWithCredentials)
+	s.Step(`^the certificate of cluster "([^"]*)" is verified$`, f.theCertificateOfClusterIsVerified)
+	s.Step(`^the clients of the cluster share its transport until its TLS settings change$`, f.theClientsOfTheClusterShareItsTransportUntilItsTLSSettingsChange)
+	s.Step(`^the requests are authenticated with a session created with password "([^"]*)"$`, f.theRequestsAreAuthenticatedWithASessionCreatedWithPassword)
+	s.Step(`^(\d+) sessions? (?:is|are) created$`, f.sessionsAreCreated)
+	s.Step(`^the password of the cluster is rotated to "([^"]*)"$`, f.thePasswordOfTheClusterIsRotatedTo)
+	s.Step(`^the cluster rejects the password$`, f.theClusterRejectsThePassword)
+	s.Step(`^the cluster accepts the password "([^"]*)"$`, f.theClusterAcceptsThePassword)
+	s.Step(`^the cluster is configured with basic authentication$`, f.theClusterIsConfiguredWithBasicAuthentication)
+	s.Step(`^the requests are authenticated with basic authentication$`, f.theRequestsAreAuthenticatedWithBasicAuthentication)
+	s.Step(`^a secret provider with secret "([^"]*)" set to "([^"]*)"$`, f.aSecretProviderWithSecretSetTo)
+	s.Step(`^the secret provider token is "([^"]*)"$`, f.theSecretProviderTokenIs)
+	s.Step(`^the secret provider token is a reference to "([^"]*)"$`, f.theSecretProviderTokenIsAReferenceTo)
+	s.Step(`^environment variable "([^"]*)" is set to "([^"]*)"$`, f.environmentVariableIsSetTo)
+	s.Step(`^file "([^"]*)" contains "([^"]*)"$`, f.fileContains)
+	s.Step(`^the credentials of cluster "([^"]*)" are "([^"]*)" and "([^"]*)"$`, f.theCredentialsOfClusterAreAnd)
+	s.Step(`^the password of the cluster is a reference to "([^"]*)"$`, f.thePasswordOfTheClusterIsAReferenceTo)
+	s.Step(`^I refresh the credentials$`, f.iRefreshTheCredentials)
+	s.Step(`^I validate the secret '([^']*)'$`, f.iValidateTheSecret)
+	s.Step(`^I validate the secret '([^']*)' with options "([^"]*)"$`, f.iValidateTheSecretWithOptions)
+	s.Step(`^the secret is valid with clusters "([^"]*)" and default cluster "([^"]*)"$`, f.theSecretIsValidWithClustersAndDefaultCluster)
+	s.Step(`^the secret is not valid with error "([^"]*)"$`, f.theSecretIsNotValidWithError)
+	s.Step(`^I check cluster "([^"]*)" with access zones "([^"]*)"$`, f.iCheckClusterWithAccessZones)
+	s.Step(`^the check "([^"]*)" of "([^"]*)" (passed|failed) with error "([^"]*)"$`, f.theCheckOfPassedWithError)
+	s.Step(`^the circuit breaker opens after (\d+) failed probes and is reset after "([^"]*)"$`, f.theCircuitBreakerOpensAfterFailedProbesAndIsResetAfter)
+	s.Step(`^I render Isilon service reachable$`, f.renderOneFSAPIReachable)
+	s.Step(`^I run the health probes of the clusters$`, f.iRunTheHealthProbesOfTheClusters)
+	s.Step(`^the health of cluster "([^"]*)" is "([^"]*)" with circuit "([^"]*)"$`, f.theHealthOfClusterIsWithCircuit)
+	s.Step(`^I call GetClusterHealth "([^"]*)" through the admin service$`, f.iCallGetClusterHealthThroughTheAdminService)
+	s.Step(`^the health of clusters "([^"]*)" is returned$`, f.theHealthOfClustersIsReturned)
+	s.Step(`^the health of the clusters returned by Probe is "([^"]*)"$`, f.theHealthOfTheClustersReturnedByProbeIs)
+	s.Step(`^the health metrics contain '([^']*)'$`, f.theHealthMetricsContain)
+	s.Step(`^I call ImportVolume "([^"]*)" with quota "([^"]*)" and size (\d+) through the admin service$`, f.iCallImportVolumeWithQuotaAndSizeThroughTheAdminService)
+	s.Step(`^the imported volume ID is "([^"]*)"$`, f.theImportedVolumeIDIs)
+	s.Step(`^the PV manifest contains "([^"]*)"$`, f.thePVManifestContains)
+	s.Step(`^I set the volume ID version to (\d+)$`, f.iSetTheVolumeIDVersionTo)
+	s.Step(`^the created volume ID is "([^"]*)"$`, f.theCreatedVolumeIDIs)
+	s.Step(`^I decode ID "([^"]*)"$`, f.iDecodeID)
+	s.Step(`^the decoded volume ID has version (\d+), path "([^"]*)" and read-only flag "([^"]*)"$`, f.theDecodedVolumeIDHasVersionPathAndReadOnlyFlag)
+	s.Step(`^the decoded ID is a "([^"]*)" of cluster "([^"]*)"$`, f.theDecodedIDIsAOfCluster)
+	s.Step(`^I call ShowVolume "([^"]*)" with the cluster admin$`, f.iCallShowVolumeWithTheClusterAdmin)
+	s.Step(`^the volume has export (\d+) with (\d+) clients and (\d+) snapshots$`, f.theVolumeHasExportWithClientsAndSnapshots)
+	s.Step(`^I call ListArtifacts of cluster "([^"]*)" with the cluster admin$`, f.iCallListArtifactsOfClusterWithTheClusterAdmin)
+	s.Step(`^cluster "([^"]*)" has (\d+) exports, (\d+) quotas and (\d+) snapshots under its isiPath$`, f.clusterHasExportsQuotasAndSnapshotsUnderItsIsiPath)
+	s.Step(`^I call ForceUnpublish "([^"]*)" from node "([^"]*)" with the cluster admin$`, f.iCallForceUnpublishFromNodeWithTheClusterAdmin)
+	s.Step(`^I call CheckConnectivity with the cluster admin$`, f.iCallCheckConnectivityWithTheClusterAdmin)
+	s.Step(`^the connectivity of cluster "([^"]*)" is "([^"]*)"$`, f.theConnectivityOfClusterIs)
 
 }
 
@@ -374,11 +598,44 @@ func (f *feature) aValidGetPluginCapabilitiesResponseIsReturned() error {
 	return nil
 }
 
+// headerStream keeps the header set by a call, in place of the stream of a gRPC server
+type headerStream struct {
+	header metadata.MD
+}
+
+func (s *headerStream) Method() string { return "" }
+
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *headerStream) SendHeader(md metadata.MD) error { return s.SetHeader(md) }
+
+func (s *headerStream) SetTrailer(md metadata.MD) error { return nil }
+
+func (f *feature) volumeDirectoriesAreDeleted(count int) error {
+	if testDeletedVolumeDirectories != count {
+		return fmt.Errorf("expected %d volume directories to be deleted, got %d", count, testDeletedVolumeDirectories)
+	}
+	return nil
+}
+
 func (f *feature) iCallProbe() error {
 	req := new(csi.ProbeRequest)
+	stream := &headerStream{}
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
 	f.checkGoRoutines("before probe")
-	f.probeResponse, f.err = f.service.Probe(context.Background(), req)
+	f.probeResponse, f.err = f.service.Probe(ctx, req)
 	f.checkGoRoutines("after probe")
+	f.probeHeader = stream.header
+	return nil
+}
+
+func (f *feature) theHealthOfTheClustersReturnedByProbeIs(health string) error {
+	if returned := strings.Join(f.probeHeader.Get(ProbeHealthHeader), ";"); returned != health {
+		return fmt.Errorf("expected the health of the clusters returned by Probe to be '%s', got '%s'", health, returned)
+	}
 	return nil
 }
 
@@ -532,6 +789,10 @@ func getAccessMode(accessType string) *csi.VolumeCapability_AccessMode {
 		accessMode.Mode = csi.VolumeCapability_AccessMode_MULTI_NODE_MULTI_WRITER
 	case "single-reader":
 		accessMode.Mode = csi.VolumeCapability_AccessMode_SINGLE_NODE_READER_ONLY
+	case "single-node-single-writer":
+		accessMode.Mode = csi.VolumeCapability_AccessMode_SINGLE_NODE_SINGLE_WRITER
+	case "single-node-multi-writer":
+		accessMode.Mode = csi.VolumeCapability_AccessMode_SINGLE_NODE_MULTI_WRITER
 	case "unknown":
 		accessMode.Mode = csi.VolumeCapability_AccessMode_UNKNOWN
 	}
@@ -636,6 +897,8 @@ func (f *feature) iInduceError(errtype string) error {
 		stepHandlersErrors.VolInstanceError = true
 	case "StatsError":
 		stepHandlersErrors.StatsError = true
+	case "EmptyStatsError":
+		stepHandlersErrors.EmptyStatsError = true
 	case "NoNodeID":
 		inducedErrors.noNodeID = true
 	case "OmitVolumeCapability":
@@ -735,6 +998,76 @@ func (f *feature) iInduceError(errtype string) error {
 		stepHandlersErrors.QuotaNotFoundError = true
 	case "DeleteVolumeError":
 		stepHandlersErrors.DeleteVolumeError = true
+	case "SetACLError":
+		stepHandlersErrors.SetACLError = true
+	case "CreateWormDomainError":
+		stepHandlersErrors.CreateWormDomainError = true
+	case "WormDomainExists":
+		stepHandlersErrors.WormDomainExists = true
+	case "GetWormDomainsError":
+		stepHandlersErrors.GetWormDomainsError = true
+	case "GetWormDomainsForbidden":
+		stepHandlersErrors.GetWormDomainsForbidden = true
+	case "FileUnderRetention":
+		stepHandlersErrors.FileUnderRetention = true
+	case "SetDirectoryAttributesError":
+		stepHandlersErrors.SetDirectoryAttributesError = true
+	case "VolumeDirectoryNotFound":
+		stepHandlersErrors.VolumeDirectoryNotFound = true
+	case "IsiPathQuotaExists":
+		stepHandlersErrors.IsiPathQuotaExists = true
+	case "CSISnapshotsExist":
+		stepHandlersErrors.CSISnapshotsExist = true
+	case "ScheduledSnapshotsExist":
+		stepHandlersErrors.ScheduledSnapshotsExist = true
+	case "SyncPolicyError":
+		stepHandlersErrors.SyncPolicyError = true
+	case "SyncJobFailed":
+		stepHandlersErrors.SyncJobFailed = true
+	case "SnapshotExpired":
+		stepHandlersErrors.SnapshotExpired = true
+	case "SnapshotScheduleExists":
+		stepHandlersErrors.SnapshotScheduleExists = true
+	case "SnapshotAliasExists":
+		stepHandlersErrors.SnapshotAliasExists = true
+	case "SnapshotScheduleError":
+		stepHandlersErrors.SnapshotScheduleError = true
+	case "WritableSnapshotExists":
+		stepHandlersErrors.WritableSnapshotExists = true
+	case "WritableSnapshotUnsupported":
+		stepHandlersErrors.WritableSnapshotUnsupported = true
+	case "WritableSnapshotDependents":
+		stepHandlersErrors.WritableSnapshotDependents = true
+	case "SnapshotDeletionPending":
+		stepHandlersErrors.SnapshotDeletionPending = true
+	case "ExportPublished":
+		stepHandlersErrors.ExportPublished = true
+	case "ExportReadOnlyNode":
+		stepHandlersErrors.ExportReadOnlyNode = true
+	case "ExportReadWriteNode":
+		stepHandlersErrors.ExportReadWriteNode = true
+	case "ExportWithoutClients":
+		stepHandlersErrors.ExportWithoutClients = true
+	case "ExportSmartLock":
+		stepHandlersErrors.ExportSmartLock = true
+	case "JobError":
+		stepHandlersErrors.JobError = true
+	case "JobRunning":
+		stepHandlersErrors.JobRunning = true
+	case "JobFailed":
+		stepHandlersErrors.JobFailed = true
+	case "SnapRevertDomainMarked":
+		stepHandlersErrors.SnapRevertDomainMarked = true
+	case "DomainMarkRunning":
+		stepHandlersErrors.DomainMarkRunning = true
+	case "DomainMarkFailed":
+		stepHandlersErrors.DomainMarkFailed = true
+	case "DomainMarkAfterSnapshot":
+		stepHandlersErrors.DomainMarkAfterSnapshot = true
+	case "SessionExpired":
+		stepHandlersErrors.SessionExpired = true
+	case "IsiPathNotFound":
+		stepHandlersErrors.IsiPathNotFound = true
 	case "none":
 
 	default:
@@ -787,6 +1120,7 @@ func (f *feature) aValidControllerGetCapabilitiesResponseIsReturned() error {
 			return errors.New("no capabilities returned in ControllerGetCapabilitiesResponse")
 		}
 		count := 0
+		listSnapshots := false
 		for _, cap := range rep.Capabilities {
 			rpcType := cap.GetRpc().Type
 			switch rpcType {
@@ -802,17 +1136,25 @@ func (f *feature) aValidControllerGetCapabilitiesResponseIsReturned() error {
 				count = count + 1
 			case csi.ControllerServiceCapability_RPC_LIST_SNAPSHOTS:
 				count = count + 1
+				listSnapshots = true
 			case csi.ControllerServiceCapability_RPC_CLONE_VOLUME:
 				count = count + 1
 			case csi.ControllerServiceCapability_RPC_EXPAND_VOLUME:
 				count = count + 1
+			case csi.ControllerServiceCapability_RPC_SINGLE_NODE_MULTI_WRITER:
+				count = count + 1
+			case csi.ControllerServiceCapability_RPC_MODIFY_VOLUME:
+				count = count + 1
 			default:
 				return fmt.Errorf("received unexpected capability: %v", rpcType)
 			}
 		}
-		if count != 7 /*7*/ {
+		if count != 10 {
 			return errors.New("Did not retrieve all the expected capabilities")
 		}
+		if !listSnapshots {
+			return errors.New("LIST_SNAPSHOTS capability not returned")
+		}
 		return nil
 	}
 	return errors.New("expected ControllerGetCapabilitiesResponse but didn't get one")
@@ -851,6 +1193,10 @@ func (f *feature) iCallValidateVolumeCapabilitiesWithVoltypeAccess(voltype, acce
 		accessMode.Mode = csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY
 	case "multi-node-single-writer":
 		accessMode.Mode = csi.VolumeCapability_AccessMode_MULTI_NODE_SINGLE_WRITER
+	case "single-node-single-writer":
+		accessMode.Mode = csi.VolumeCapability_AccessMode_SINGLE_NODE_SINGLE_WRITER
+	case "single-node-multi-writer":
+		accessMode.Mode = csi.VolumeCapability_AccessMode_SINGLE_NODE_MULTI_WRITER
 	}
 	capability.AccessMode = accessMode
 	capabilities := make([]*csi.VolumeCapability, 0)
@@ -889,6 +1235,7 @@ func clearErrors() {
 	stepHandlersErrors.CreateSnapshotError = false
 	stepHandlersErrors.RemoveVolumeError = false
 	stepHandlersErrors.StatsError = false
+	stepHandlersErrors.EmptyStatsError = false
 	stepHandlersErrors.StartingTokenInvalidError = false
 	stepHandlersErrors.GetSnapshotError = false
 	stepHandlersErrors.DeleteSnapshotError = false
@@ -903,6 +1250,39 @@ func clearErrors() {
 	stepHandlersErrors.DeleteQuotaError = false
 	stepHandlersErrors.QuotaNotFoundError = false
 	stepHandlersErrors.DeleteVolumeError = false
+	stepHandlersErrors.SetACLError = false
+	stepHandlersErrors.CreateWormDomainError = false
+	stepHandlersErrors.WormDomainExists = false
+	stepHandlersErrors.GetWormDomainsError = false
+	stepHandlersErrors.GetWormDomainsForbidden = false
+	stepHandlersErrors.FileUnderRetention = false
+	stepHandlersErrors.SetDirectoryAttributesError = false
+	stepHandlersErrors.VolumeDirectoryNotFound = false
+	stepHandlersErrors.IsiPathQuotaExists = false
+	stepHandlersErrors.CSISnapshotsExist = false
+	stepHandlersErrors.ScheduledSnapshotsExist = false
+	stepHandlersErrors.SyncPolicyError = false
+	stepHandlersErrors.SyncJobFailed = false
+	stepHandlersErrors.SnapshotExpired = false
+	stepHandlersErrors.SnapshotScheduleExists = false
+	stepHandlersErrors.SnapshotAliasExists = false
+	stepHandlersErrors.SnapshotScheduleError = false
+	stepHandlersErrors.WritableSnapshotExists = false
+	stepHandlersErrors.WritableSnapshotUnsupported = false
+	stepHandlersErrors.WritableSnapshotDependents = false
+	stepHandlersErrors.SnapshotDeletionPending = false
+	stepHandlersErrors.ExportPublished = false
+	stepHandlersErrors.ExportReadOnlyNode = false
+	stepHandlersErrors.ExportReadWriteNode = false
+	stepHandlersErrors.ExportWithoutClients = false
+	stepHandlersErrors.ExportSmartLock = false
+	stepHandlersErrors.JobError = false
+	stepHandlersErrors.JobRunning = false
+	stepHandlersErrors.JobFailed = false
+	stepHandlersErrors.SnapRevertDomainMarked = false
+	stepHandlersErrors.DomainMarkRunning = false
+	stepHandlersErrors.DomainMarkFailed = false
+	stepHandlersErrors.DomainMarkAfterSnapshot = false
 	inducedErrors.noIsiService = false
 	inducedErrors.autoProbeNotEnabled = false
 }
@@ -1064,11 +1444,13 @@ func (f *feature) aValidNodeGetCapabilitiesResponseIsReturned() error {
 				count = count + 1
 			case csi.NodeServiceCapability_RPC_EXPAND_VOLUME:
 				count = count + 1
+			case csi.NodeServiceCapability_RPC_SINGLE_NODE_MULTI_WRITER:
+				count = count + 1
 			default:
 				return fmt.Errorf("Received unexpected capability: %v", rpcType)
 			}
 		}
-		if count != 1 /*3*/ {
+		if count != 2 /*4*/ {
 			return errors.New("Did not retrieve all the expected capabilities")
 		}
 		return nil
@@ -1331,6 +1713,10 @@ func (f *feature) aCapabilityWithVoltypeAccess(voltype, access string) error {
 		accessMode.Mode = csi.VolumeCapability_AccessMode_MULTI_NODE_READER_ONLY
 	case "multiple-node-single-writer":
 		accessMode.Mode = csi.VolumeCapability_AccessMode_MULTI_NODE_SINGLE_WRITER
+	case "single-node-single-writer":
+		accessMode.Mode = csi.VolumeCapability_AccessMode_SINGLE_NODE_SINGLE_WRITER
+	case "single-node-multi-writer":
+		accessMode.Mode = csi.VolumeCapability_AccessMode_SINGLE_NODE_MULTI_WRITER
 	}
 	capability.AccessMode = accessMode
 	f.capabilities = make([]*csi.VolumeCapability, 0)
@@ -1478,6 +1864,58 @@ func (f *feature) iCallControllerPublishVolume(volID string, accessMode string,
 	return nil
 }
 
+func (f *feature) iCallControllerPublishVolumeWithReadOnly(volID, accessMode, readOnly, nodeID string) error {
+	f.publishVolumeRequest = f.getControllerPublishVolumeRequest(accessMode, nodeID)
+	f.publishVolumeRequest.Readonly = readOnly == "true"
+	return f.iCallControllerPublishVolume(volID, accessMode, nodeID)
+}
+
+// isNodeInExportUpdates returns whether a client list of the exports updated through the mock contains the node
+func isNodeInExportUpdates(node, clientList string) (bool, error) {
+	for _, update := range testExportUpdates {
+		var clients []string
+		switch clientList {
+		case "clients":
+			clients = update.Clients
+		case "read_only_clients":
+			clients = update.ReadOnlyClients
+		case "root_clients":
+			clients = update.RootClients
+		default:
+			return false, fmt.Errorf("unknown client list '%s'", clientList)
+		}
+		if utils.IsStringInSlice(node, clients) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *feature) theNodeIsAddedToTheOfTheExport(node, clientList string) error {
+	if f.err != nil {
+		return f.err
+	}
+	added, err := isNodeInExportUpdates(node, clientList)
+	if err != nil {
+		return err
+	}
+	if !added {
+		return fmt.Errorf("expected node '%s' to be added to the '%s' of the export, got updates '%+v'", node, clientList, testExportUpdates)
+	}
+	return nil
+}
+
+func (f *feature) theNodeIsNotAddedToTheOfTheExport(node, clientList string) error {
+	added, err := isNodeInExportUpdates(node, clientList)
+	if err != nil {
+		return err
+	}
+	if added {
+		return fmt.Errorf("expected node '%s' not to be added to the '%s' of the export, got updates '%+v'", node, clientList, testExportUpdates)
+	}
+	return nil
+}
+
 func (f *feature) iCallControllerUnPublishVolume(volID string, accessMode string, nodeID string) error {
 	req := f.getControllerUnPublishVolumeRequest(accessMode, nodeID)
 	f.unpublishVolumeRequest = req
@@ -1595,6 +2033,94 @@ func (f *feature) iCallCreateSnapshot(srcVolumeID, name, isiPath string) error {
 	return nil
 }
 
+func (f *feature) iCallCreateVolumeWithSnapshotSchedulePatternAndRetention(name, schedule, pattern, retention string) error {
+	req := getTypicalCreateVolumeRequest()
+	f.createVolumeRequest = req
+	req.Name = name
+	req.Parameters[SnapshotScheduleParam] = schedule
+	req.Parameters[SnapshotSchedulePatternParam] = pattern
+	req.Parameters[SnapshotScheduleRetentionParam] = retention
+	f.createVolumeResponse, f.err = f.service.CreateVolume(context.Background(), req)
+	if f.err != nil {
+		log.Printf("CreateVolume call failed: %s\n", f.err.Error())
+	}
+	return nil
+}
+
+func (f *feature) snapshotScheduleTakesSnapshotsOfNamedKeptSeconds(name, dirPath, schedule, pattern string, duration int64) error {
+	if f.err != nil {
+		return f.err
+	}
+	expected := snapshotSchedule{Name: name, Path: dirPath, Pattern: pattern, Schedule: schedule, Duration: duration}
+	if testSnapshotSchedule != expected {
+		return fmt.Errorf("expected snapshot schedule '%+v', got '%+v'", expected, testSnapshotSchedule)
+	}
+	return nil
+}
+
+func (f *feature) snapshotScheduleIsDeleted(name string) error {
+	if testDeletedSnapshotSchedule != name {
+		return fmt.Errorf("expected snapshot schedule '%s' to be deleted, got '%s'", name, testDeletedSnapshotSchedule)
+	}
+	return nil
+}
+
+func (f *feature) iCallListSnapshotsWithSnapshotIDSourceVolumeIDMaxEntriesAndStartingToken(snapshotID, sourceVolumeID string, maxEntries int32, startingToken string) error {
+	f.listSnapshotsRequest = &csi.ListSnapshotsRequest{
+		SnapshotId:     snapshotID,
+		SourceVolumeId: sourceVolumeID,
+		MaxEntries:     maxEntries,
+		StartingToken:  startingToken,
+	}
+	f.listSnapshotsResponse, f.err = f.service.ListSnapshots(context.Background(), f.listSnapshotsRequest)
+	if f.err != nil {
+		log.Printf("ListSnapshots call failed: %s\n", f.err.Error())
+	}
+	return nil
+}
+
+func (f *feature) theSnapshotsAreListedWithNextToken(snapshotIDs, nextToken string) error {
+	if f.err != nil {
+		return f.err
+	}
+	var listed []string
+	for _, entry := range f.listSnapshotsResponse.Entries {
+		listed = append(listed, entry.Snapshot.SnapshotId+"@"+entry.Snapshot.SourceVolumeId)
+	}
+	if strings.Join(listed, ",") != snapshotIDs || f.listSnapshotsResponse.NextToken != nextToken {
+		return fmt.Errorf("expected snapshots '%s' with next token '%s', got '%s' with next token '%s'",
+			snapshotIDs, nextToken, strings.Join(listed, ","), f.listSnapshotsResponse.NextToken)
+	}
+	return nil
+}
+
+func (f *feature) iCallCreateSnapshotWithExpirationAndAlias(srcVolumeID, name, expiration, alias string) error {
+	req := getCreateSnapshotRequest(srcVolumeID, name, "none")
+	req.Parameters[SnapshotExpirationParam] = expiration
+	req.Parameters[SnapshotAliasParam] = alias
+	f.createSnapshotRequest = req
+	f.createSnapshotResponse, f.err = f.service.CreateSnapshot(context.Background(), req)
+	if f.err != nil {
+		log.Printf("CreateSnapshot call failed: %s\n", f.err.Error())
+	}
+	return nil
+}
+
+func (f *feature) theSnapshotIsCreatedWithAnExpirationInSecondsAndAlias(expiration int64, alias string) error {
+	// allow for the time elapsed since the creation
+	if delta := testSnapshotCreation.Expires - time.Now().Unix() - expiration; delta > 0 || delta < -60 {
+		return fmt.Errorf("expected the snapshot to expire in '%d' seconds, got expiration date '%d'", expiration, testSnapshotCreation.Expires)
+	}
+	return f.snapshotAliasPointsToSnapshot(alias, testSnapshotCreation.Name)
+}
+
+func (f *feature) snapshotAliasPointsToSnapshot(alias, snapshotName string) error {
+	if testSnapshotAlias.Name != alias || testSnapshotAlias.Target != snapshotName {
+		return fmt.Errorf("expected snapshot alias '%s' to point to snapshot '%s', got '%+v'", alias, snapshotName, testSnapshotAlias)
+	}
+	return nil
+}
+
 func (f *feature) aValidCreateSnapshotResponseIsReturned() error {
 	if f.err != nil {
 		return f.err
@@ -1685,6 +2211,138 @@ func (f *feature) iCallCreateVolumeFromSnapshot(srcSnapshotID, name string) erro
 	return nil
 }
 
+func (f *feature) iCallCreateVolumeFromSnapshotWithRestoreMode(srcSnapshotID, name, mode string) error {
+	req := getTypicalCreateVolumeRequest()
+	f.createVolumeRequest = req
+	req.Name = name
+	req = f.setVolumeContent(true, srcSnapshotID)
+	req.Parameters[SnapshotRestoreModeParam] = mode
+	f.createVolumeResponse, f.err = f.service.CreateVolume(context.Background(), req)
+	if f.err != nil {
+		log.Printf("CreateVolume call failed: '%s'\n", f.err.Error())
+	}
+	return nil
+}
+
+func (f *feature) iCallCreateVolumeFromSnapshotWithParameterSetTo(srcSnapshotID, name, param, value string) error {
+	req := getTypicalCreateVolumeRequest()
+	f.createVolumeRequest = req
+	req.Name = name
+	req = f.setVolumeContent(true, srcSnapshotID)
+	req.Parameters[param] = value
+	f.createVolumeResponse, f.err = f.service.CreateVolume(context.Background(), req)
+	if f.err != nil {
+		log.Printf("CreateVolume call failed: '%s'\n", f.err.Error())
+	}
+	return nil
+}
+
+func (f *feature) theSmartLockRetentionCheckIsLimitedToEntries(limit int) error {
+	smartLockRetentionCheckLimit = limit
+	return nil
+}
+
+func (f *feature) theVolumeIsRestoredFromSnapshotAsWritableSnapshot(srcSnapshotID, dstPath string) error {
+	if f.err != nil {
+		return f.err
+	}
+	expected := writableSnapshot{SrcSnap: srcSnapshotID, DstPath: dstPath}
+	if testWritableSnapshot != expected {
+		return fmt.Errorf("expected writable snapshot '%+v', got '%+v'", expected, testWritableSnapshot)
+	}
+	if testSnapshotCopied {
+		return fmt.Errorf("expected the snapshot not to be copied")
+	}
+	return nil
+}
+
+func (f *feature) theSnapshotIsCopied() error {
+	if f.err != nil {
+		return f.err
+	}
+	if !testSnapshotCopied {
+		return fmt.Errorf("expected the snapshot to be copied")
+	}
+	if testWritableSnapshot != (writableSnapshot{}) {
+		return fmt.Errorf("expected no writable snapshot, got '%+v'", testWritableSnapshot)
+	}
+	return nil
+}
+
+func (f *feature) writableSnapshotIsDeleted(dstPath string) error {
+	if f.err != nil {
+		return f.err
+	}
+	if testDeletedWritableSnapshot != dstPath {
+		return fmt.Errorf("expected writable snapshot '%s' to be deleted, got '%s'", dstPath, testDeletedWritableSnapshot)
+	}
+	return nil
+}
+
+func (f *feature) iCallCreateVolumeFromSnapshotWithRestoreModeAndParameterSetTo(srcSnapshotID, name, mode, param, value string) error {
+	req := getTypicalCreateVolumeRequest()
+	f.createVolumeRequest = req
+	req.Name = name
+	req = f.setVolumeContent(true, srcSnapshotID)
+	req.Parameters[SnapshotRestoreModeParam] = mode
+	req.Parameters[param] = value
+	f.createVolumeResponse, f.err = f.service.CreateVolume(context.Background(), req)
+	if f.err != nil {
+		log.Printf("CreateVolume call failed: '%s'\n", f.err.Error())
+	}
+	return nil
+}
+
+func (f *feature) snapshotIsLockedBy(snapshotID, dstPath string) error {
+	if f.err != nil {
+		return f.err
+	}
+	expected := snapshotID + ":" + fmt.Sprintf(writableSnapshotLockComment, dstPath)
+	for _, lock := range testSnapshotLocks {
+		if lock == expected {
+			return nil
+		}
+	}
+	return fmt.Errorf("expected lock '%s', got '%v'", expected, testSnapshotLocks)
+}
+
+func (f *feature) snapshotIsUnlocked(snapshotID string) error {
+	if f.err != nil {
+		return f.err
+	}
+	for _, lock := range testDeletedSnapshotLocks {
+		if strings.HasPrefix(lock, snapshotID+":") {
+			return nil
+		}
+	}
+	return fmt.Errorf("expected a lock of snapshot '%s' to be deleted, got '%v'", snapshotID, testDeletedSnapshotLocks)
+}
+
+func (f *feature) theDeletionOfSnapshotIsDeferred(snapshotID string) error {
+	if f.err != nil {
+		return f.err
+	}
+	if testDeletedSnapshot != "" {
+		return fmt.Errorf("expected snapshot '%s' not to be deleted, got '%s'", snapshotID, testDeletedSnapshot)
+	}
+	for _, entry := range testTrackingDirEntries {
+		if strings.HasSuffix(entry, "/"+DeleteSnapshotMarker) {
+			return nil
+		}
+	}
+	return fmt.Errorf("expected the delete marker of snapshot '%s', got '%v'", snapshotID, testTrackingDirEntries)
+}
+
+func (f *feature) snapshotIsDeleted(snapshotID string) error {
+	if f.err != nil {
+		return f.err
+	}
+	if testDeletedSnapshot != snapshotID {
+		return fmt.Errorf("expected snapshot '%s' to be deleted, got '%s'", snapshotID, testDeletedSnapshot)
+	}
+	return nil
+}
+
 func (f *feature) iCallCreateVolumeFromVolume(srcVolumeName, name string) error {
 	req := getTypicalCreateVolumeRequest()
 	f.createVolumeRequest = req
@@ -2126,3 +2784,1472 @@ func (f *feature) iCallNodeGetInfowithinvalidnetworks() error {
 	}
 	return nil
 }
+
+func (f *feature) iEnableEventRecording() error {
+	f.service.k8sclient = fake.NewSimpleClientset()
+	f.service.eventRecorder = record.NewFakeRecorder(10)
+	return nil
+}
+
+func (f *feature) anEventWithReasonIsRecorded(reason string) error {
+	recorder, ok := f.service.eventRecorder.(*record.FakeRecorder)
+	if !ok {
+		return errors.New("event recording is not enabled")
+	}
+	for {
+		select {
+		case event := <-recorder.Events:
+			log.Printf("recorded event: %s\n", event)
+			if strings.Contains(event, " "+reason+" ") {
+				return nil
+			}
+		default:
+			return fmt.Errorf("expected an event with reason '%s' to be recorded", reason)
+		}
+	}
+}
+
+func (f *feature) iCallRemoveNodeFromExports(nodeID string) error {
+	f.service.k8sclient = fake.NewSimpleClientset()
+	f.err = f.service.removeNodeFromExports(context.Background(), nodeID)
+	if f.err != nil {
+		log.Printf("removeNodeFromExports call failed: %s\n", f.err.Error())
+	}
+	return nil
+}
+
+func (f *feature) aCSINodeWithNodeID(nodeName, nodeID string) error {
+	if f.service.k8sclient == nil {
+		f.service.k8sclient = fake.NewSimpleClientset()
+	}
+	csiNode := &storagev1.CSINode{
+		ObjectMeta: v1.ObjectMeta{Name: nodeName},
+		Spec: storagev1.CSINodeSpec{
+			Drivers: []storagev1.CSINodeDriver{{Name: constants.PluginName, NodeID: nodeID}},
+		},
+	}
+	_, err := f.service.k8sclient.StorageV1().CSINodes().Create(context.Background(), csiNode, v1.CreateOptions{})
+	return err
+}
+
+func (f *feature) aVolumeAttachmentOfPVToNodeWithNodeID(pvName, nodeName, nodeID string) error {
+	if f.service.k8sclient == nil {
+		f.service.k8sclient = fake.NewSimpleClientset()
+	}
+	va := &storagev1.VolumeAttachment{
+		ObjectMeta: v1.ObjectMeta{Name: "csi-" + pvName + "-" + nodeName},
+		Spec: storagev1.VolumeAttachmentSpec{
+			Attacher: constants.PluginName,
+			NodeName: nodeName,
+			Source:   storagev1.VolumeAttachmentSource{PersistentVolumeName: &pvName},
+		},
+		Status: storagev1.VolumeAttachmentStatus{Attached: true},
+	}
+	if nodeID != "" {
+		va.Status.AttachmentMetadata = map[string]string{publishContextNodeID: nodeID}
+	}
+	_, err := f.service.k8sclient.StorageV1().VolumeAttachments().Create(context.Background(), va, v1.CreateOptions{})
+	return err
+}
+
+func (f *feature) iCallResolveNodeID(nodeName string) error {
+	if f.service.k8sclient == nil {
+		f.service.k8sclient = fake.NewSimpleClientset()
+	}
+	f.resolvedNodeID, f.err = f.service.resolveNodeID(context.Background(), nodeName)
+	if f.err != nil {
+		log.Printf("resolveNodeID call failed: %s\n", f.err.Error())
+	}
+	return nil
+}
+
+func (f *feature) theResolvedNodeIDIs(nodeID string) error {
+	if f.resolvedNodeID != nodeID {
+		return fmt.Errorf("expected node ID '%s' but it was '%s'", nodeID, f.resolvedNodeID)
+	}
+	return nil
+}
+
+func (f *feature) iConfigureTenantForNamespaceWithParams(name, namespace, accessZone, isiPathPrefix, allowedClusters, maxCapacity string) error {
+	tenant := TenantConfig{
+		Name:          name,
+		AccessZone:    accessZone,
+		IsiPathPrefix: isiPathPrefix,
+		MaxCapacity:   maxCapacity,
+	}
+	// a tenant overriding the access zone must give an IP serving it
+	if accessZone != "" {
+		tenant.AzServiceIP = "10.0.0.1"
+	}
+	return f.configureTenants(namespace, allowedClusters, tenant)
+}
+
+func (f *feature) iConfigureTenantForNamespaceWithAccessZoneAndNoAzServiceIP(name, namespace, accessZone string) error {
+	return f.configureTenants(namespace, "", TenantConfig{Name: name, AccessZone: accessZone})
+}
+
+func (f *feature) configureTenants(namespace, allowedClusters string, tenant TenantConfig) error {
+	if namespace != "" {
+		tenant.Namespaces = []string{namespace}
+	}
+	if allowedClusters != "" {
+		tenant.AllowedClusters = strings.Split(allowedClusters, ",")
+	}
+	inputConfigs := &IsilonClusters{
+		IsilonClusters: []IsilonClusterConfig{{ClusterName: clusterName1}, {ClusterName: "cluster2"}},
+		Tenants:        []TenantConfig{tenant},
+	}
+	var tenants []TenantConfig
+	if tenants, f.err = getNewTenantConfigs(inputConfigs); f.err == nil {
+		f.service.tenants = tenants
+	}
+	return nil
+}
+
+func (f *feature) iCallCreateVolumeWithSmartLockParameters(name, params string) error {
+	req := getTypicalCreateVolumeRequest()
+	f.createVolumeRequest = req
+	req.Name = name
+	req.Parameters[SmartLockEnabledParam] = "true"
+	for _, param := range strings.Split(params, ",") {
+		if kv := strings.SplitN(param, "=", 2); len(kv) == 2 {
+			req.Parameters[kv[0]] = kv[1]
+		}
+	}
+	f.createVolumeResponse, f.err = f.service.CreateVolume(context.Background(), req)
+	if f.err != nil {
+		log.Printf("CreateVolume call failed: %s\n", f.err.Error())
+	}
+	if f.createVolumeResponse != nil {
+		log.Printf("vol id %s\n", f.createVolumeResponse.GetVolume().VolumeId)
+		stepHandlersErrors.ExportNotFoundError = false
+		stepHandlersErrors.VolumeNotExistError = false
+	}
+	return nil
+}
+
+func (f *feature) iEnableCapacityPlacement() error {
+	f.service.opts.ClusterPlacement = ClusterPlacementCapacity
+	return nil
+}
+
+func (f *feature) iAddClusterWithIPAndPlacementWeight(clusterName, isiIP string, weight int) error {
+	cluster, ok := f.service.isiClusters.Load(clusterName1)
+	if !ok {
+		return fmt.Errorf("cluster '%s' not found", clusterName1)
+	}
+	newConfig := *cluster.(*IsilonClusterConfig)
+	newConfig.ClusterName = clusterName
+	newConfig.IsiIP = isiIP
+	newConfig.PlacementWeight = &weight
+	isDefault := false
+	newConfig.IsDefaultCluster = &isDefault
+	f.service.isiClusters.Store(clusterName, &newConfig)
+	return nil
+}
+
+func (f *feature) iCallCreateVolumeWithRequisiteTopology(name, topology string) error {
+	req := getTypicalCreateVolumeRequest()
+	f.createVolumeRequest = req
+	req.Name = name
+	if topology != "" {
+		req.AccessibilityRequirements = &csi.TopologyRequirement{
+			Requisite: []*csi.Topology{parseTopology(topology)},
+		}
+	}
+	f.createVolumeResponse, f.err = f.service.CreateVolume(context.Background(), req)
+	if f.err != nil {
+		log.Printf("CreateVolume call failed: %s\n", f.err.Error())
+	}
+	return nil
+}
+
+// parseTopology parses a topology of the form "key1=value1,key2=value2"
+func parseTopology(topology string) *csi.Topology {
+	segments := make(map[string]string)
+	for _, segment := range strings.Split(topology, ",") {
+		if kv := strings.SplitN(segment, "=", 2); len(kv) == 2 {
+			segments[kv[0]] = kv[1]
+		}
+	}
+	return &csi.Topology{Segments: segments}
+}
+
+func (f *feature) iCallCreateVolumeWithPreferredTopologies(name, topologies string) error {
+	req := getTypicalCreateVolumeRequest()
+	f.createVolumeRequest = req
+	req.Name = name
+	req.AccessibilityRequirements = &csi.TopologyRequirement{}
+	for _, topology := range strings.Split(topologies, ";") {
+		req.AccessibilityRequirements.Preferred = append(req.AccessibilityRequirements.Preferred, parseTopology(topology))
+	}
+	f.createVolumeResponse, f.err = f.service.CreateVolume(context.Background(), req)
+	if f.err != nil {
+		log.Printf("CreateVolume call failed: %s\n", f.err.Error())
+	}
+	return nil
+}
+
+func (f *feature) theVolumeIsAccessibleFromTopology(topology string) error {
+	if f.createVolumeResponse == nil {
+		return fmt.Errorf("no CreateVolumeResponse returned")
+	}
+	accessible := f.createVolumeResponse.GetVolume().GetAccessibleTopology()
+	if topology == "" {
+		if len(accessible) != 0 {
+			return fmt.Errorf("expected no accessible topology, got '%v'", accessible)
+		}
+		return nil
+	}
+	expected := parseTopology(topology).GetSegments()
+	if len(accessible) != 1 || fmt.Sprint(accessible[0].GetSegments()) != fmt.Sprint(expected) {
+		return fmt.Errorf("expected the volume to be accessible from '%v', got '%v'", expected, accessible)
+	}
+	return nil
+}
+
+func (f *feature) thePluginCapabilityIsAdvertised(capabilityType string) error {
+	if f.err != nil {
+		return f.err
+	}
+	for _, capability := range f.getPluginCapabilitiesResponse.GetCapabilities() {
+		if capability.GetService().GetType().String() == capabilityType {
+			return nil
+		}
+	}
+	return fmt.Errorf("expected plugin capability '%s' to be advertised", capabilityType)
+}
+
+func (f *feature) theVolumeIsCreatedOnCluster(clusterName string) error {
+	if f.createVolumeResponse == nil {
+		return fmt.Errorf("no CreateVolumeResponse returned")
+	}
+	volumeID := f.createVolumeResponse.GetVolume().GetVolumeId()
+	_, _, _, volumeClusterName, err := utils.ParseNormalizedVolumeID(context.Background(), volumeID)
+	if err != nil {
+		return err
+	}
+	if volumeClusterName != clusterName {
+		return fmt.Errorf("expected volume '%s' to be created on cluster '%s', got '%s'", volumeID, clusterName, volumeClusterName)
+	}
+	return nil
+}
+
+func (f *feature) directoryHasAttributeSetTo(dirPath, name, value string) error {
+	if testDirectoryAttributesPath != dirPath {
+		return fmt.Errorf("expected attributes of directory '%s' to be set, got '%s'", dirPath, testDirectoryAttributesPath)
+	}
+	for _, attr := range testDirectoryAttributes.Attrs {
+		if attr.Name == name {
+			if attr.Value != value {
+				return fmt.Errorf("expected attribute '%s' of directory '%s' to be set to '%s', got '%v'", name, dirPath, value, attr.Value)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("attribute '%s' of directory '%s' not set", name, dirPath)
+}
+
+func (f *feature) attributeOfDirectoryIsNotSet(name, dirPath string) error {
+	for _, attr := range testDirectoryAttributes.Attrs {
+		if testDirectoryAttributesPath == dirPath && attr.Name == name {
+			return fmt.Errorf("expected attribute '%s' of directory '%s' not to be set, got '%v'", name, dirPath, attr.Value)
+		}
+	}
+	return nil
+}
+
+func (f *feature) noDirectoryAttributeIsSet() error {
+	if testDirectoryAttributesPath != "" {
+		return fmt.Errorf("expected no directory attribute to be set, got '%+v' on '%s'", testDirectoryAttributes.Attrs, testDirectoryAttributesPath)
+	}
+	return nil
+}
+
+func (f *feature) iCallCreateVolumeWithParameterSetTo(name, key, value string) error {
+	req := getTypicalCreateVolumeRequest()
+	f.createVolumeRequest = req
+	req.Name = name
+	req.Parameters[key] = value
+	f.createVolumeResponse, f.err = f.service.CreateVolume(context.Background(), req)
+	if f.err != nil {
+		log.Printf("CreateVolume call failed: %s\n", f.err.Error())
+	}
+	if f.createVolumeResponse != nil {
+		log.Printf("vol id %s\n", f.createVolumeResponse.GetVolume().VolumeId)
+		stepHandlersErrors.ExportNotFoundError = false
+		stepHandlersErrors.VolumeNotExistError = false
+	}
+	return nil
+}
+
+func (f *feature) iCallGetCapacityWithParametersAndTopology(params, topology string) error {
+	req := getTypicalCapacityRequest(true)
+	req.Parameters = make(map[string]string)
+	for _, param := range strings.Split(params, ",") {
+		if kv := strings.SplitN(param, "=", 2); len(kv) == 2 {
+			req.Parameters[kv[0]] = kv[1]
+		}
+	}
+	if topology != "" {
+		segments := make(map[string]string)
+		for _, segment := range strings.Split(topology, ",") {
+			if kv := strings.SplitN(segment, "=", 2); len(kv) == 2 {
+				segments[kv[0]] = kv[1]
+			}
+		}
+		req.AccessibleTopology = &csi.Topology{Segments: segments}
+	}
+	f.getCapacityResponse, f.err = f.service.GetCapacity(context.Background(), req)
+	if f.err != nil {
+		log.Printf("GetCapacity call failed: %s\n", f.err.Error())
+	}
+	return nil
+}
+
+func (f *feature) theAvailableCapacityIsAndTheMaximumVolumeSizeIs(available, maximum int64) error {
+	if f.getCapacityResponse == nil {
+		return fmt.Errorf("no GetCapacityResponse returned: '%v'", f.err)
+	}
+	if f.getCapacityResponse.GetAvailableCapacity() != available {
+		return fmt.Errorf("expected available capacity '%d', got '%d'", available, f.getCapacityResponse.GetAvailableCapacity())
+	}
+	if f.getCapacityResponse.GetMaximumVolumeSize().GetValue() != maximum {
+		return fmt.Errorf("expected maximum volume size '%d', got '%d'", maximum, f.getCapacityResponse.GetMaximumVolumeSize().GetValue())
+	}
+	return nil
+}
+
+func (f *feature) aPVExistsForVolume(pvName, volumeID string) error {
+	_, _, _, clusterName, err := utils.ParseNormalizedVolumeID(context.Background(), volumeID)
+	if err != nil {
+		return err
+	}
+	if clusterName == "" {
+		clusterName = f.service.defaultIsiClusterName
+	}
+	isiConfig := f.service.getIsilonClusterConfig(clusterName)
+	if isiConfig == nil {
+		return fmt.Errorf("cluster '%s' not found", clusterName)
+	}
+	topologyKey := constants.PluginName + "/" + isiConfig.IsiIP
+	pv := &corev1.PersistentVolume{
+		ObjectMeta: v1.ObjectMeta{
+			Name:       pvName,
+			Finalizers: []string{"kubernetes.io/pv-protection"},
+		},
+		Spec: corev1.PersistentVolumeSpec{
+			Capacity:    corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("8Gi")},
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+			ClaimRef:    &corev1.ObjectReference{Kind: "PersistentVolumeClaim", Namespace: "default", Name: "pvc1", UID: "d1f2e3c4"},
+			PersistentVolumeSource: corev1.PersistentVolumeSource{
+				CSI: &corev1.CSIPersistentVolumeSource{
+					Driver:       constants.PluginName,
+					VolumeHandle: volumeID,
+					VolumeAttributes: map[string]string{
+						"AzServiceIP":       isiConfig.IsiIP,
+						"RootClientEnabled": "false",
+						"ClusterName":       clusterName,
+					},
+				},
+			},
+			PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimDelete,
+			NodeAffinity: &corev1.VolumeNodeAffinity{
+				Required: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+						MatchExpressions: []corev1.NodeSelectorRequirement{{
+							Key:      topologyKey,
+							Operator: corev1.NodeSelectorOpIn,
+							Values:   []string{constants.PluginName},
+						}},
+					}},
+				},
+			},
+		},
+		Status: corev1.PersistentVolumeStatus{Phase: corev1.VolumeBound},
+	}
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: v1.ObjectMeta{
+			Name:        "pvc1",
+			Namespace:   "default",
+			UID:         "d1f2e3c4",
+			Annotations: map[string]string{bindCompletedAnnotation: "yes"},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+			VolumeName:  pvName,
+		},
+		Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound},
+	}
+	f.service.k8sclient = fake.NewSimpleClientset(pv, pvc)
+	migrationPollInterval = 10 * time.Millisecond
+	migrationSwitchTimeout = 100 * time.Millisecond
+	return nil
+}
+
+func (f *feature) aPodUsesPVC(podName, claimName string) error {
+	pod := &corev1.Pod{
+		ObjectMeta: v1.ObjectMeta{Name: podName, Namespace: "default"},
+		Spec: corev1.PodSpec{
+			Volumes: []corev1.Volume{{
+				Name: "data",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claimName},
+				},
+			}},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodPending},
+	}
+	_, err := f.service.k8sclient.CoreV1().Pods("default").Create(context.Background(), pod, v1.CreateOptions{})
+	return err
+}
+
+func (f *feature) creatingPVsOfClusterFails(clusterName string) error {
+	clientset, ok := f.service.k8sclient.(*fake.Clientset)
+	if !ok {
+		return errors.New("no fake kubernetes client")
+	}
+	clientset.PrependReactor("create", "persistentvolumes", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		pv := action.(k8stesting.CreateAction).GetObject().(*corev1.PersistentVolume)
+		if strings.HasSuffix(pv.Spec.CSI.VolumeHandle, utils.VolumeIDSeparator+clusterName) {
+			return true, nil, errors.New("induced PV creation error")
+		}
+		return false, nil, nil
+	})
+	return nil
+}
+
+func (f *feature) deletingPV(pvName, outcome string) error {
+	clientset, ok := f.service.k8sclient.(*fake.Clientset)
+	if !ok {
+		return errors.New("no fake kubernetes client")
+	}
+	clientset.PrependReactor("delete", "persistentvolumes", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		if action.(k8stesting.DeleteAction).GetName() != pvName {
+			return false, nil, nil
+		}
+		if outcome == "fails" {
+			return true, nil, errors.New("induced PV deletion error")
+		}
+		// the PV stays, as if a finalizer held it
+		return true, nil, nil
+	})
+	return nil
+}
+
+func (f *feature) pvHasReclaimPolicy(pvName, policy string) error {
+	pv, err := f.service.k8sclient.CoreV1().PersistentVolumes().Get(context.Background(), pvName, v1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if string(pv.Spec.PersistentVolumeReclaimPolicy) != policy {
+		return fmt.Errorf("expected PV '%s' to have reclaim policy '%s', got '%s'", pvName, policy, pv.Spec.PersistentVolumeReclaimPolicy)
+	}
+	return nil
+}
+
+func (f *feature) rsyncJobsNeverFinishWithImage(image string) error {
+	// the jobs of the fake client have no status, so they never succeed nor fail
+	f.service.opts.MigrationImage = image
+	return nil
+}
+
+func (f *feature) theCopyOfTheMigrationsTimesOutAfterMilliseconds(timeout int) error {
+	f.service.opts.MigrationCopyTimeout = time.Duration(timeout) * time.Millisecond
+	return nil
+}
+
+func (f *feature) aMigrationOfVolumeToClusterIsInProgress(volumeID, clusterName string) error {
+	_, _, _, sourceCluster, err := utils.ParseNormalizedVolumeID(context.Background(), volumeID)
+	if err != nil {
+		return err
+	}
+	f.service.migrationsLock.Lock()
+	defer f.service.migrationsLock.Unlock()
+	if f.service.migrations == nil {
+		f.service.migrations = make(map[string]*MigrationStatus)
+	}
+	f.service.migrations[volumeID] = &MigrationStatus{
+		VolumeID:      volumeID,
+		SourceCluster: sourceCluster,
+		TargetCluster: clusterName,
+		Phase:         MigrationPhaseCopyingData,
+		StartTime:     time.Now(),
+	}
+	return nil
+}
+
+func (f *feature) pvHasAnInterruptedMigrationOfVolumeToVolumeInPhase(pvName, volumeID, targetVolumeID, phase string) error {
+	_, _, _, targetCluster, err := utils.ParseNormalizedVolumeID(context.Background(), targetVolumeID)
+	if err != nil {
+		return err
+	}
+	state, err := json.Marshal(&persistedMigration{
+		Request:          MigrateVolumeRequest{VolumeID: volumeID, TargetCluster: targetCluster, CopyMethod: MigrationCopySyncIQ},
+		Phase:            phase,
+		TargetVolumeID:   targetVolumeID,
+		TargetAccessZone: "System",
+		TargetIsiPath:    "/ifs/data/csi-isilon",
+		StartTime:        time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	return f.service.setPersistentVolumeAnnotation(context.Background(), pvName, migrationAnnotation, string(state))
+}
+
+func (f *feature) iReconcileTheMigrations() error {
+	f.service.reconcileMigrations(context.Background())
+	return nil
+}
+
+func (f *feature) pvHasNoMigrationState(pvName string) error {
+	pv, err := f.service.k8sclient.CoreV1().PersistentVolumes().Get(context.Background(), pvName, v1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if state, ok := pv.Annotations[migrationAnnotation]; ok {
+		return fmt.Errorf("expected PV '%s' to have no migration state, got '%s'", pvName, state)
+	}
+	return nil
+}
+
+func (f *feature) rsyncJobsSucceedWithImage(image string) error {
+	f.service.opts.MigrationImage = image
+	clientset, ok := f.service.k8sclient.(*fake.Clientset)
+	if !ok {
+		return errors.New("no fake kubernetes client")
+	}
+	clientset.PrependReactor("get", "jobs", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		job := &batchv1.Job{ObjectMeta: v1.ObjectMeta{Name: action.(k8stesting.GetAction).GetName()}}
+		job.Status.Succeeded = 1
+		return true, job, nil
+	})
+	return nil
+}
+
+func (f *feature) iCallMigrateVolumeToClusterWithCopyMethodAndNode(volumeID, clusterName, copyMethod, nodeID string) error {
+	req := &MigrateVolumeRequest{
+		VolumeID:      volumeID,
+		TargetCluster: clusterName,
+		CopyMethod:    copyMethod,
+		NodeID:        nodeID,
+	}
+	f.migrateVolumeResponse, f.err = f.service.MigrateVolume(context.Background(), req)
+	if f.err != nil {
+		log.Printf("MigrateVolume call failed: %s\n", f.err.Error())
+	}
+	return nil
+}
+
+func (f *feature) iCallMigrateVolumeToClusterInDryRunMode(volumeID, clusterName string) error {
+	req := &MigrateVolumeRequest{
+		VolumeID:      volumeID,
+		TargetCluster: clusterName,
+		DryRun:        true,
+	}
+	f.migrateVolumeResponse, f.err = f.service.MigrateVolume(context.Background(), req)
+	if f.err != nil {
+		log.Printf("MigrateVolume call failed: %s\n", f.err.Error())
+	}
+	return nil
+}
+
+func (f *feature) aMigrationPlanOfStepsIsReturned(steps int) error {
+	if f.err != nil {
+		return f.err
+	}
+	migration := f.migrateVolumeResponse.Migration
+	if migration.Phase != MigrationPhasePlanned || len(migration.Steps) != steps {
+		return fmt.Errorf("expected a plan of '%d' steps, got '%+v'", steps, migration)
+	}
+	// a dry run leaves the PV untouched
+	return f.pvRefersToAVolumeOnCluster(migration.PVName, migration.SourceCluster)
+}
+
+func (f *feature) theMigrationOfVolumeEndsInPhase(volumeID, phase string) error {
+	if f.err != nil {
+		return f.err
+	}
+	for i := 0; i < 500; i++ {
+		resp, err := f.service.GetMigrationStatus(context.Background(), &GetMigrationStatusRequest{VolumeID: volumeID})
+		if err != nil {
+			return err
+		}
+		migration := resp.Migrations[0]
+		if migration.EndTime != nil {
+			if migration.Phase != phase {
+				return fmt.Errorf("expected the migration to end in phase '%s', got '%s': %s", phase, migration.Phase, migration.Message)
+			}
+			return nil
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return fmt.Errorf("migration of volume '%s' is not over", volumeID)
+}
+
+func (f *feature) pvRefersToAVolumeOnCluster(pvName, clusterName string) error {
+	pv, err := f.service.k8sclient.CoreV1().PersistentVolumes().Get(context.Background(), pvName, v1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	_, _, _, volumeClusterName, err := utils.ParseNormalizedVolumeID(context.Background(), pv.Spec.CSI.VolumeHandle)
+	if err != nil {
+		return err
+	}
+	if volumeClusterName != clusterName {
+		return fmt.Errorf("expected PV '%s' to refer to a volume on cluster '%s', got '%s'", pvName, clusterName, pv.Spec.CSI.VolumeHandle)
+	}
+	isiConfig := f.service.getIsilonClusterConfig(clusterName)
+	topologyKey := pv.Spec.NodeAffinity.Required.NodeSelectorTerms[0].MatchExpressions[0].Key
+	if topologyKey != constants.PluginName+"/"+isiConfig.IsiIP {
+		return fmt.Errorf("expected PV '%s' to be accessible from the nodes of cluster '%s', got '%s'", pvName, clusterName, topologyKey)
+	}
+	if pv.Spec.ClaimRef == nil || pv.Spec.ClaimRef.Name != "pvc1" {
+		return fmt.Errorf("expected PV '%s' to keep its claim, got '%v'", pvName, pv.Spec.ClaimRef)
+	}
+	pvc, err := f.service.k8sclient.CoreV1().PersistentVolumeClaims("default").Get(context.Background(), "pvc1", v1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if pvc.Spec.VolumeName != pvName {
+		return fmt.Errorf("expected PVC 'pvc1' to refer to PV '%s', got '%s'", pvName, pvc.Spec.VolumeName)
+	}
+	return nil
+}
+
+func (f *feature) syncIQPolicyCopiesToOn(sourcePath, targetPath, targetHost string) error {
+	if testSyncPolicy.Action != syncPolicyActionCopy || testSyncPolicy.SourceRootPath != sourcePath ||
+		testSyncPolicy.TargetPath != targetPath || testSyncPolicy.TargetHost != targetHost {
+		return fmt.Errorf("unexpected SyncIQ policy '%+v'", testSyncPolicy)
+	}
+	return nil
+}
+
+// callAdminService serves the admin service of the controller on a local port and calls it with an admin client
+func (f *feature) callAdminService(call func(*AdminClient) error) error {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	server := grpc.NewServer()
+	f.service.RegisterAdditionalServers(server)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return call(NewAdminClient(conn))
+}
+
+func (f *feature) iCallGetMigrationStatusThroughTheAdminService(volumeID string) error {
+	return f.callAdminService(func(client *AdminClient) error {
+		var resp *GetMigrationStatusResponse
+		resp, f.err = client.GetMigrationStatus(context.Background(), &GetMigrationStatusRequest{VolumeID: volumeID})
+		if f.err != nil {
+			log.Printf("GetMigrationStatus call failed: %s\n", f.err.Error())
+			return nil
+		}
+		if len(resp.Migrations) != 1 || resp.Migrations[0].VolumeID != volumeID {
+			return fmt.Errorf("unexpected GetMigrationStatus response '%+v'", resp)
+		}
+		return nil
+	})
+}
+
+func (f *feature) iCallImportVolumeWithQuotaAndSizeThroughTheAdminService(dirPath, quota string, size int64) error {
+	req := &ImportVolumeRequest{
+		Path:        dirPath,
+		Quota:       quota,
+		SizeInBytes: size,
+		PVName:      "pv1",
+		ClaimName:   "pvc1",
+	}
+	return f.callAdminService(func(client *AdminClient) error {
+		f.importVolumeResponse, f.err = client.ImportVolume(context.Background(), req)
+		if f.err != nil {
+			log.Printf("ImportVolume call failed: %s\n", f.err.Error())
+		}
+		return nil
+	})
+}
+
+func (f *feature) theImportedVolumeIDIs(volumeID string) error {
+	if f.err != nil {
+		return f.err
+	}
+	if f.importVolumeResponse.VolumeID != volumeID {
+		return fmt.Errorf("expected imported volume '%s', got '%s'", volumeID, f.importVolumeResponse.VolumeID)
+	}
+	return nil
+}
+
+func (f *feature) thePVManifestContains(text string) error {
+	if f.err != nil {
+		return f.err
+	}
+	if !strings.Contains(f.importVolumeResponse.PersistentVolume, text) {
+		return fmt.Errorf("expected the PV manifest to contain '%s', got '%s'", text, f.importVolumeResponse.PersistentVolume)
+	}
+	return nil
+}
+
+func (f *feature) iSetTheVolumeIDVersionTo(version int) error {
+	f.service.opts.VolumeIDVersion = version
+	return nil
+}
+
+func (f *feature) theCreatedVolumeIDIs(volumeID string) error {
+	if f.err != nil {
+		return f.err
+	}
+	if f.createVolumeResponse.Volume.VolumeId != volumeID {
+		return fmt.Errorf("expected volume ID '%s', got '%s'", volumeID, f.createVolumeResponse.Volume.VolumeId)
+	}
+	return nil
+}
+
+func (f *feature) iDecodeID(id string) error {
+	f.decodedID, f.err = DecodeID(context.Background(), id)
+	return nil
+}
+
+func (f *feature) theDecodedIDIsAOfCluster(idType, clusterName string) error {
+	if f.err != nil {
+		return f.err
+	}
+	if f.decodedID.Type != idType || f.decodedID.ClusterName != clusterName {
+		return fmt.Errorf("expected a '%s' of cluster '%s', got '%+v'", idType, clusterName, f.decodedID)
+	}
+	return nil
+}
+
+func (f *feature) theDecodedVolumeIDHasVersionPathAndReadOnlyFlag(version int, volumePath, readOnly string) error {
+	if f.err != nil {
+		return f.err
+	}
+	if f.decodedID.Version != version || f.decodedID.Path != volumePath || fmt.Sprintf("%t", f.decodedID.ReadOnlyFromSnapshot) != readOnly {
+		return fmt.Errorf("expected version '%d', path '%s' and read-only flag '%s', got '%+v'", version, volumePath, readOnly, f.decodedID)
+	}
+	return nil
+}
+
+func (f *feature) iCallShowVolumeWithTheClusterAdmin(volumeID string) error {
+	admin := &ClusterAdmin{s: f.service}
+	f.volumeDetails, f.err = admin.ShowVolume(context.Background(), volumeID)
+	if f.err != nil {
+		log.Printf("ShowVolume call failed: %s\n", f.err.Error())
+	}
+	return nil
+}
+
+func (f *feature) theVolumeHasExportWithClientsAndSnapshots(exportID, clients, snapshots int) error {
+	if f.err != nil {
+		return f.err
+	}
+	details := f.volumeDetails
+	if details.Export == nil || details.Export.ID != exportID || len(details.Export.Clients) != clients || len(details.Snapshots) != snapshots {
+		return fmt.Errorf("expected export '%d' with '%d' clients and '%d' snapshots, got '%+v'", exportID, clients, snapshots, details)
+	}
+	return nil
+}
+
+func (f *feature) iCallListArtifactsOfClusterWithTheClusterAdmin(clusterName string) error {
+	admin := &ClusterAdmin{s: f.service}
+	f.clusterArtifacts, f.err = admin.ListArtifacts(context.Background(), clusterName, "", "")
+	if f.err != nil {
+		log.Printf("ListArtifacts call failed: %s\n", f.err.Error())
+	}
+	return nil
+}
+
+func (f *feature) clusterHasExportsQuotasAndSnapshotsUnderItsIsiPath(clusterName string, exports, quotas, snapshots int) error {
+	if f.err != nil {
+		return f.err
+	}
+	for _, artifacts := range f.clusterArtifacts {
+		if artifacts.ClusterName != clusterName {
+			continue
+		}
+		if len(artifacts.Exports) != exports || len(artifacts.Quotas) != quotas || len(artifacts.Snapshots) != snapshots {
+			return fmt.Errorf("expected '%d' exports, '%d' quotas and '%d' snapshots, got '%+v'", exports, quotas, snapshots, artifacts)
+		}
+		return nil
+	}
+	return fmt.Errorf("no artifacts listed for cluster '%s'", clusterName)
+}
+
+func (f *feature) iCallForceUnpublishFromNodeWithTheClusterAdmin(volumeID, nodeID string) error {
+	admin := &ClusterAdmin{s: f.service}
+	f.err = admin.ForceUnpublish(context.Background(), volumeID, nodeID)
+	if f.err != nil {
+		log.Printf("ForceUnpublish call failed: %s\n", f.err.Error())
+	}
+	return nil
+}
+
+func (f *feature) iCallCheckConnectivityWithTheClusterAdmin() error {
+	admin := &ClusterAdmin{s: f.service}
+	f.clusterConnectivity = admin.CheckConnectivity(context.Background())
+	return nil
+}
+
+func (f *feature) theConnectivityOfClusterIs(clusterName, connectivity string) error {
+	for _, result := range f.clusterConnectivity {
+		if result.ClusterName != clusterName {
+			continue
+		}
+		if (connectivity == "connected") != result.Connected {
+			return fmt.Errorf("expected cluster '%s' to be '%s', got '%+v'", clusterName, connectivity, result)
+		}
+		return nil
+	}
+	return fmt.Errorf("cluster '%s' was not checked", clusterName)
+}
+
+func (f *feature) iCallRevertVolumeToSnapshotThroughTheAdminService(volumeID, snapshotID string) error {
+	req := &RevertVolumeRequest{VolumeID: volumeID, SnapshotID: snapshotID}
+	return f.callAdminService(func(client *AdminClient) error {
+		_, f.err = client.RevertVolume(context.Background(), req)
+		if f.err != nil {
+			log.Printf("RevertVolume call failed: %s\n", f.err.Error())
+		}
+		return nil
+	})
+}
+
+func (f *feature) theRevertOfVolumeIsInStateWithJobState(volumeID, state, jobState string) error {
+	if f.err != nil {
+		return f.err
+	}
+	return f.callAdminService(func(client *AdminClient) error {
+		resp, err := client.GetRevertStatus(context.Background(), &GetRevertStatusRequest{VolumeID: volumeID})
+		if err != nil {
+			return err
+		}
+		if len(resp.Reverts) != 1 {
+			return fmt.Errorf("expected the revert of volume '%s', got '%d' reverts", volumeID, len(resp.Reverts))
+		}
+		if revert := resp.Reverts[0]; revert.State != state || revert.JobState != jobState {
+			return fmt.Errorf("expected the revert of volume '%s' to be '%s' with job state '%s', got '%+v'", volumeID, state, jobState, *revert)
+		}
+		return nil
+	})
+}
+
+func (f *feature) aSnapRevertJobOfSnapshotIsStarted(snapshotID int64) error {
+	if f.err != nil {
+		return f.err
+	}
+	if testJobCreation.Type != jobTypeSnapRevert || testJobCreation.SnapRevertParams == nil || testJobCreation.SnapRevertParams.SnapID != snapshotID {
+		return fmt.Errorf("expected a SnapRevert job of snapshot '%d', got '%+v'", snapshotID, testJobCreation)
+	}
+	return nil
+}
+
+func (f *feature) aSnapRevertDomainIsCreatedOn(dirPath string) error {
+	if f.err != nil {
+		return f.err
+	}
+	expected := domainMarkParams{Root: dirPath, DmType: domainTypeSnapRevert}
+	if testJobCreation.Type != jobTypeDomainMark || testJobCreation.DomainMarkParams == nil || *testJobCreation.DomainMarkParams != expected {
+		return fmt.Errorf("expected a DomainMark job creating a SnapRevert domain on '%s', got '%+v'", dirPath, testJobCreation)
+	}
+	return checkUserAttribute(dirPath, snapRevertJobAttribute, "42")
+}
+
+func (f *feature) theSnapRevertDomainOfIsRecordedAsCreatedAt(dirPath, domainTime string) error {
+	if f.err != nil {
+		return f.err
+	}
+	return checkUserAttribute(dirPath, snapRevertDomainTimeAttribute, domainTime)
+}
+
+// checkUserAttribute checks that the last attributes set through the mock hold the given user attribute
+func checkUserAttribute(dirPath, name, value string) error {
+	if testDirectoryAttributesPath != dirPath {
+		return fmt.Errorf("expected the attributes of '%s' to be set, got '%s'", dirPath, testDirectoryAttributesPath)
+	}
+	expected := newUserAttribute(name, value)
+	for _, attr := range testDirectoryAttributes.Attrs {
+		if attr == expected {
+			return nil
+		}
+	}
+	return fmt.Errorf("expected attribute '%+v', got '%+v'", expected, testDirectoryAttributes.Attrs)
+}
+
+func (f *feature) aRevertOfVolumeToSnapshotIsInProgress(volumeID, snapshotID string) error {
+	_, _, _, clusterName, err := utils.ParseNormalizedVolumeID(context.Background(), volumeID)
+	if err != nil {
+		return err
+	}
+	f.service.revertsLock.Lock()
+	defer f.service.revertsLock.Unlock()
+	if f.service.reverts == nil {
+		f.service.reverts = make(map[string]*RevertStatus)
+	}
+	f.service.reverts[volumeID] = &RevertStatus{
+		VolumeID:    volumeID,
+		SnapshotID:  snapshotID,
+		ClusterName: clusterName,
+		JobID:       42,
+		State:       RevertStateRunning,
+		StartTime:   time.Now(),
+	}
+	return nil
+}
+
+func (f *feature) pvHasARunningRevertOfVolumeToSnapshot(pvName, volumeID, snapshotID string) error {
+	_, _, _, clusterName, err := utils.ParseNormalizedVolumeID(context.Background(), volumeID)
+	if err != nil {
+		return err
+	}
+	state, err := json.Marshal(&RevertStatus{
+		VolumeID:    volumeID,
+		SnapshotID:  snapshotID,
+		ClusterName: clusterName,
+		JobID:       42,
+		State:       RevertStateRunning,
+		StartTime:   time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	return f.service.setPersistentVolumeAnnotation(context.Background(), pvName, revertAnnotation, string(state))
+}
+
+func (f *feature) iReconcileTheReverts() error {
+	f.service.reconcileReverts(context.Background())
+	return nil
+}
+
+func (f *feature) pvHasTheStateOfTheRevert(pvName string) error {
+	if f.err != nil {
+		return f.err
+	}
+	pv, err := f.service.k8sclient.CoreV1().PersistentVolumes().Get(context.Background(), pvName, v1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	var state RevertStatus
+	if err := json.Unmarshal([]byte(pv.Annotations[revertAnnotation]), &state); err != nil {
+		return fmt.Errorf("expected PV '%s' to have the state of the revert: '%v'", pvName, err)
+	}
+	if state.JobID != 42 || state.PVName != pvName {
+		return fmt.Errorf("unexpected state of the revert in PV '%s': '%+v'", pvName, state)
+	}
+	return nil
+}
+
+func (f *feature) pvHasNoRevertState(pvName string) error {
+	pv, err := f.service.k8sclient.CoreV1().PersistentVolumes().Get(context.Background(), pvName, v1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if state, ok := pv.Annotations[revertAnnotation]; ok {
+		return fmt.Errorf("expected PV '%s' to have no revert state, got '%s'", pvName, state)
+	}
+	return nil
+}
+
+// getMutableParameters parses the parameters of a modification, separated by semicolons as the values may
+// contain commas
+func getMutableParameters(params string) map[string]string {
+	mutableParameters := map[string]string{}
+	for _, param := range strings.Split(params, ";") {
+		if kv := strings.SplitN(param, "=", 2); len(kv) == 2 {
+			mutableParameters[kv[0]] = kv[1]
+		}
+	}
+	return mutableParameters
+}
+
+func (f *feature) iCallControllerModifyVolumeWithParameters(volumeID, params string) error {
+	req := &csi.ControllerModifyVolumeRequest{VolumeId: volumeID, MutableParameters: getMutableParameters(params)}
+	_, f.err = f.service.ControllerModifyVolume(context.Background(), req)
+	if f.err != nil {
+		log.Printf("ControllerModifyVolume call failed: %s\n", f.err.Error())
+	}
+	return nil
+}
+
+func (f *feature) iCallModifyVolumeWithParametersThroughTheAdminService(volumeID, params string) error {
+	req := &ModifyVolumeRequest{VolumeID: volumeID, MutableParameters: getMutableParameters(params)}
+	return f.callAdminService(func(client *AdminClient) error {
+		_, f.err = client.ModifyVolume(context.Background(), req)
+		if f.err != nil {
+			log.Printf("ModifyVolume call failed: %s\n", f.err.Error())
+		}
+		return nil
+	})
+}
+
+func (f *feature) theThresholdsOfTheQuotaAreSetTo(thresholds string) error {
+	if f.err != nil {
+		return f.err
+	}
+	if testQuotaThresholds != thresholds {
+		return fmt.Errorf("expected the thresholds of the quota to be set to '%s', got '%s'", thresholds, testQuotaThresholds)
+	}
+	return nil
+}
+
+func (f *feature) theSecurityFlavorsOfTheExportAreSetTo(flavors string) error {
+	if f.err != nil {
+		return f.err
+	}
+	for _, update := range testExportUpdates {
+		if update.SecurityFlavors != nil && strings.Join(update.SecurityFlavors, ",") == flavors {
+			return nil
+		}
+	}
+	return fmt.Errorf("expected the security flavors of the export to be set to '%s', got updates '%+v'", flavors, testExportUpdates)
+}
+
+func (f *feature) theRootClientsOfTheExportAreRemoved() error {
+	if f.err != nil {
+		return f.err
+	}
+	for _, update := range testExportUpdates {
+		if update.RootClients != nil && len(update.RootClients) == 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("expected the root clients of the export to be removed, got updates '%+v'", testExportUpdates)
+}
+
+// generateTestCertificate returns the PEM of a self-signed certificate, which is not the one of the TLS test server
+func generateTestCertificate() ([]byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "other CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
+
+func (f *feature) iCallGetIsiServiceOnATLSServerTrustedWith(trust string) error {
+	server := httptest.NewTLSServer(getHandler())
+	defer server.Close()
+
+	serverCA := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	serverFingerprint := sha256.Sum256(server.Certificate().Raw)
+	otherCA, err := generateTestCertificate()
+	if err != nil {
+		return err
+	}
+
+	insecure := false
+	isiConfig := &IsilonClusterConfig{
+		ClusterName: "tlscluster",
+		EndpointURL: server.URL,
+		User:        "blah",
+		Password:    "blah",
+		IsiInsecure: &insecure,
+		IsiPath:     "/ifs/data/csi-isilon",
+	}
+	switch trust {
+	case "ca":
+		isiConfig.CertificateAuthority = string(serverCA)
+	case "ca path":
+		caPath := "test/tmp/tls-ca.pem"
+		if err := ioutil.WriteFile(caPath, serverCA, 0600); err != nil {
+			return err
+		}
+		defer os.Remove(caPath)
+		isiConfig.CertificateAuthorityPath = caPath
+	case "fingerprint":
+		isiConfig.CertificateFingerprint = formatCertificateFingerprint(serverFingerprint[:])
+	case "ca and fingerprint":
+		isiConfig.CertificateAuthority = string(serverCA)
+		isiConfig.CertificateFingerprint = hex.EncodeToString(serverFingerprint[:])
+	case "other ca":
+		isiConfig.CertificateAuthority = string(otherCA)
+	case "ca and other fingerprint":
+		otherFingerprint := sha256.Sum256(otherCA)
+		isiConfig.CertificateAuthority = string(serverCA)
+		isiConfig.CertificateFingerprint = hex.EncodeToString(otherFingerprint[:])
+	case "system ca":
+	default:
+		return fmt.Errorf("unknown trust '%s'", trust)
+	}
+
+	isiSvc, err := f.service.GetIsiService(context.Background(), isiConfig, logLevel)
+	f.err = err
+	if err != nil {
+		log.Printf("GetIsiService call failed: %s\n", err.Error())
+		return nil
+	}
+	// the requests made after the creation of the client go through the transport of the cluster
+	resp := &struct {
+		Latest *string `json:"latest"`
+	}{}
+	f.err = isiSvc.client.API.Get(context.Background(), "/platform/latest", "", nil, nil, resp)
+	return nil
+}
+
+func (f *feature) iCallGetNewIsilonConfigsWithTLSSettings(settings string) error {
+	config := `{"isilonClusters":[{"clusterName":"tlscluster","username":"blah","password":"blah","endpoint":"127.0.0.1","isiPort":"1"`
+	if settings != "" {
+		config += "," + settings
+	}
+	config += "}]}"
+	f.isilonConfigs, _, _, f.err = f.service.getNewIsilonConfigs(context.Background(), []byte(config))
+	return nil
+}
+
+// iCallGetNewIsilonConfigsWithCredentials parses the config of a cluster with the given credential settings, the
+// username and password are "blah" unless they or their references are given, and the secret provider stub if it
+// is started
+func (f *feature) iCallGetNewIsilonConfigsWithCredentials(settings string) error {
+	config := `{"isilonClusters":[{"clusterName":"tlscluster","endpoint":"127.0.0.1","isiPort":"1"`
+	if !strings.Contains(settings, `"username`) {
+		config += `,"username":"blah"`
+	}
+	if !strings.Contains(settings, `"password`) {
+		config += `,"password":"blah"`
+	}
+	config += "," + settings + "}]"
+	if f.secretProvider != nil {
+		if f.secretProviderTokenRef != "" {
+			config += fmt.Sprintf(`,"secretProvider":{"endpoint":"%s/secrets","tokenRef":"%s"}`, f.secretProvider.URL, f.secretProviderTokenRef)
+		} else {
+			config += fmt.Sprintf(`,"secretProvider":{"endpoint":"%s/secrets","token":"%s"}`, f.secretProvider.URL, f.secretProviderToken)
+		}
+	}
+	config += "}"
+	f.isilonConfigs, _, _, f.err = f.service.getNewIsilonConfigs(context.Background(), []byte(config))
+	return nil
+}
+
+func (f *feature) theCertificateOfClusterIsVerified(clusterName string) error {
+	if f.err != nil {
+		return f.err
+	}
+	config, ok := f.isilonConfigs[clusterName]
+	if !ok {
+		return fmt.Errorf("cluster '%s' not found in the configs", clusterName)
+	}
+	if insecure := config.(*IsilonClusterConfig).IsiInsecure; insecure == nil || *insecure {
+		return fmt.Errorf("expected the certificate of cluster '%s' to be verified", clusterName)
+	}
+	return nil
+}
+
+func (f *feature) theClientsOfTheClusterShareItsTransportUntilItsTLSSettingsChange() error {
+	getTransport := func() *http.Transport {
+		f.service.transportsLock.Lock()
+		defer f.service.transportsLock.Unlock()
+		return f.service.transports[clusterName1].transport
+	}
+	transport := getTransport()
+
+	newConfig := *f.service.getIsilonClusterConfig(clusterName1)
+	if _, err := f.service.GetIsiService(context.Background(), &newConfig, logLevel); err != nil {
+		return err
+	}
+	if getTransport() != transport {
+		return errors.New("expected the clients of the cluster to share its transport")
+	}
+
+	// the endpoint of the mock is not served over TLS, the fingerprint is not checked
+	newConfig.CertificateFingerprint = strings.Repeat("ab", sha256.Size)
+	if _, err := f.service.GetIsiService(context.Background(), &newConfig, logLevel); err != nil {
+		return err
+	}
+	if getTransport() == transport {
+		return errors.New("expected a new transport to be created when the TLS settings of the cluster change")
+	}
+	return nil
+}
+
+func (f *feature) theRequestsAreAuthenticatedWithASessionCreatedWithPassword(password string) error {
+	if f.err != nil {
+		return f.err
+	}
+	if len(testSessionLogins) == 0 || testSessionLogins[len(testSessionLogins)-1] != password {
+		return fmt.Errorf("expected a session to be created with password '%s', got sessions created with '%v'", password, testSessionLogins)
+	}
+	if testSessionRequests == 0 {
+		return errors.New("expected the requests to be authenticated with a session")
+	}
+	return nil
+}
+
+func (f *feature) sessionsAreCreated(count int) error {
+	if f.err != nil {
+		return f.err
+	}
+	if len(testSessionLogins) != count {
+		return fmt.Errorf("expected %d session(s) to be created, got %d", count, len(testSessionLogins))
+	}
+	return nil
+}
+
+// thePasswordOfTheClusterIsRotatedTo changes the password of the cluster and updates the config of the cluster
+// the way a reload of the secret does, the isiService already created is kept in use
+func (f *feature) thePasswordOfTheClusterIsRotatedTo(password string) error {
+	testSessionPassword = password
+	newConfig := *f.service.getIsilonClusterConfig(clusterName1)
+	newConfig.Password = password
+	_, err := f.service.GetIsiService(context.Background(), &newConfig, logLevel)
+	return err
+}
+
+func (f *feature) theClusterRejectsThePassword() error {
+	// the sessions already created are not valid anymore
+	testSessionPassword = "rejected"
+	testSessionID = ""
+	return nil
+}
+
+func (f *feature) theClusterAcceptsThePassword(password string) error {
+	testSessionPassword = password
+	return nil
+}
+
+func (f *feature) theClusterIsConfiguredWithBasicAuthentication() error {
+	clusterConfig := f.service.getIsilonClusterConfig(clusterName1)
+	clusterConfig.AuthType = authTypeBasic
+	// the client created with the service has already logged in with a session
+	testSessionLogins = nil
+	testSessionRequests = 0
+	isiSvc, err := f.service.GetIsiService(context.Background(), clusterConfig, logLevel)
+	if err != nil {
+		return err
+	}
+	clusterConfig.isiSvc = isiSvc
+	return nil
+}
+
+func (f *feature) theRequestsAreAuthenticatedWithBasicAuthentication() error {
+	if f.err != nil {
+		return f.err
+	}
+	if len(testSessionLogins) != 0 || testSessionRequests != 0 {
+		return fmt.Errorf("expected no session to be used, got %d session(s) created and %d request(s) sent with a session", len(testSessionLogins), testSessionRequests)
+	}
+	if testBasicAuthRequests == 0 {
+		return errors.New("expected the requests to be authenticated with basic authentication")
+	}
+	return nil
+}
+
+func (f *feature) aSecretProviderWithSecretSetTo(key, value string) error {
+	if f.secretProvider == nil {
+		f.secretProvider = httptest.NewServer(getSecretProviderHandler())
+		f.secretProviderToken = testProviderToken
+		f.secretProviderTokenRef = ""
+	}
+	testProviderSecrets[key] = value
+	return nil
+}
+
+func (f *feature) theSecretProviderTokenIs(token string) error {
+	f.secretProviderToken = token
+	return nil
+}
+
+func (f *feature) theSecretProviderTokenIsAReferenceTo(ref string) error {
+	f.secretProviderToken = ""
+	f.secretProviderTokenRef = ref
+	return nil
+}
+
+func (f *feature) environmentVariableIsSetTo(name, value string) error {
+	return os.Setenv(name, value)
+}
+
+func (f *feature) fileContains(path, content string) error {
+	return ioutil.WriteFile(path, []byte(content+"\n"), 0600)
+}
+
+func (f *feature) theCredentialsOfClusterAreAnd(clusterName, username, password string) error {
+	if f.err != nil {
+		return f.err
+	}
+	var isiConfig *IsilonClusterConfig
+	if config, ok := f.isilonConfigs[clusterName]; ok {
+		isiConfig = config.(*IsilonClusterConfig)
+	} else if isiConfig = f.service.getIsilonClusterConfig(clusterName); isiConfig == nil {
+		return fmt.Errorf("cluster '%s' not found in the configs", clusterName)
+	}
+	if isiConfig.User != username || isiConfig.Password != password {
+		return fmt.Errorf("expected the credentials of cluster '%s' to be '%s' and '%s', got '%s' and '%s'",
+			clusterName, username, password, isiConfig.User, isiConfig.Password)
+	}
+	return nil
+}
+
+// thePasswordOfTheClusterIsAReferenceTo sets the password of the cluster the way a secret with a reference does
+func (f *feature) thePasswordOfTheClusterIsAReferenceTo(ref string) error {
+	var provider *SecretProviderConfig
+	if f.secretProvider != nil {
+		provider = &SecretProviderConfig{Endpoint: f.secretProvider.URL + "/secrets", Token: f.secretProviderToken}
+	}
+	f.service.credentialResolver = newCredentialResolver(provider)
+	clusterConfig := f.service.getIsilonClusterConfig(clusterName1)
+	clusterConfig.PasswordRef = ref
+	f.err = f.service.credentialResolver.resolveClusterCredentials(context.Background(), clusterConfig)
+	return nil
+}
+
+func (f *feature) iRefreshTheCredentials() error {
+	f.err = f.service.refreshCredentials(context.Background())
+	return nil
+}
+
+func (f *feature) iValidateTheSecret(config string) error {
+	f.secretValidationReport = f.service.validateSecret(context.Background(), []byte(config), SecretValidationOptions{})
+	return nil
+}
+
+// iValidateTheSecretWithOptions validates a secret with the comma separated options "check-clusters" and
+// "custom-topology"
+func (f *feature) iValidateTheSecretWithOptions(config, options string) error {
+	opts := SecretValidationOptions{}
+	for _, option := range strings.Split(options, ",") {
+		switch option {
+		case "check-clusters":
+			opts.ResolveCredentials = true
+			opts.CheckClusters = true
+		case "custom-topology":
+			customTopology := true
+			opts.CustomTopologyEnabled = &customTopology
+		default:
+			return fmt.Errorf("unknown validation option '%s'", option)
+		}
+	}
+	f.secretValidationReport = f.service.validateSecret(context.Background(), []byte(config), opts)
+	return nil
+}
+
+func (f *feature) theSecretIsValidWithClustersAndDefaultCluster(clusters, defaultCluster string) error {
+	report := f.secretValidationReport
+	if !report.Valid {
+		return fmt.Errorf("expected the secret to be valid, got error '%s'", report.Error)
+	}
+	var clusterNames []string
+	for _, cluster := range report.Clusters {
+		clusterNames = append(clusterNames, cluster.ClusterName)
+	}
+	if strings.Join(clusterNames, ",") != clusters || report.DefaultCluster != defaultCluster {
+		return fmt.Errorf("expected clusters '%s' with default cluster '%s', got '%v' with default cluster '%s'",
+			clusters, defaultCluster, clusterNames, report.DefaultCluster)
+	}
+	return nil
+}
+
+func (f *feature) theSecretIsNotValidWithError(errormsg string) error {
+	report := f.secretValidationReport
+	if report.Valid || !strings.Contains(report.Error, errormsg) {
+		return fmt.Errorf("expected the secret not to be valid with error '%s', got valid '%v' with error '%s'", errormsg, report.Valid, report.Error)
+	}
+	return nil
+}
+
+func (f *feature) iCheckClusterWithAccessZones(clusterName, accessZones string) error {
+	isiConfig := f.service.getIsilonClusterConfig(clusterName)
+	if isiConfig == nil {
+		return fmt.Errorf("cluster '%s' not found", clusterName)
+	}
+	f.validationChecks = f.service.checkCluster(context.Background(), isiConfig, strings.Split(accessZones, ","))
+	return nil
+}
+
+func (f *feature) theCheckOfPassedWithError(name, target, result, errormsg string) error {
+	for _, check := range f.validationChecks {
+		if check.Name != name || (target != "" && check.Target != target) {
+			continue
+		}
+		if check.Passed != (result == "passed") || (errormsg != "" && !strings.Contains(check.Error, errormsg)) {
+			return fmt.Errorf("expected check '%s' of '%s' to have %s with error '%s', got '%+v'", name, target, result, errormsg, *check)
+		}
+		return nil
+	}
+	return fmt.Errorf("check '%s' of '%s' not found", name, target)
+}
+
+func (f *feature) theCircuitBreakerOpensAfterFailedProbesAndIsResetAfter(threshold int, resetTimeout string) error {
+	timeout, err := time.ParseDuration(resetTimeout)
+	if err != nil {
+		return err
+	}
+	f.service.opts.CircuitBreakerThreshold = threshold
+	f.service.opts.CircuitBreakerResetTimeout = timeout
+	return nil
+}
+
+func (f *feature) renderOneFSAPIReachable() error {
+	testControllerHasNoConnection = false
+	testNodeHasNoConnection = false
+	return nil
+}
+
+func (f *feature) iRunTheHealthProbesOfTheClusters() error {
+	f.service.probeClusterHealth(context.Background())
+	return nil
+}
+
+func (f *feature) theHealthOfClusterIsWithCircuit(clusterName, state, circuit string) error {
+	health := f.service.getClusterHealth(clusterName)
+	if health.State != state || health.Circuit != circuit {
+		return fmt.Errorf("expected cluster '%s' to be '%s' with circuit '%s', got '%+v'", clusterName, state, circuit, health)
+	}
+	return nil
+}
+
+func (f *feature) iCallGetClusterHealthThroughTheAdminService(clusterName string) error {
+	return f.callAdminService(func(client *AdminClient) error {
+		var resp *GetClusterHealthResponse
+		resp, f.err = client.GetClusterHealth(context.Background(), &GetClusterHealthRequest{ClusterName: clusterName})
+		f.clusterHealth = nil
+		if f.err == nil {
+			f.clusterHealth = resp.Clusters
+		}
+		return nil
+	})
+}
+
+func (f *feature) theHealthOfClustersIsReturned(clusters string) error {
+	if f.err != nil {
+		return f.err
+	}
+	var clusterNames []string
+	for _, health := range f.clusterHealth {
+		if health.State != ClusterHealthHealthy || health.LastProbeTime == nil {
+			return fmt.Errorf("expected cluster '%s' to be healthy, got '%+v'", health.ClusterName, *health)
+		}
+		clusterNames = append(clusterNames, health.ClusterName)
+	}
+	if strings.Join(clusterNames, ",") != clusters {
+		return fmt.Errorf("expected the health of clusters '%s', got '%v'", clusters, clusterNames)
+	}
+	return nil
+}
+
+func (f *feature) theHealthMetricsContain(line string) error {
+	var metrics bytes.Buffer
+	writeClusterHealthMetrics(&metrics, f.service.listClusterHealth(f.service.getIsilonClusters()))
+	if !strings.Contains(metrics.String(), line+"\n") {
+		return fmt.Errorf("expected the health metrics to contain '%s', got '%s'", line, metrics.String())
+	}
+	return nil
+}
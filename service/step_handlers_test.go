@@ -33,29 +33,65 @@ var (
 	debug bool
 
 	stepHandlersErrors struct {
-		FindVolumeIDError          bool
-		GetVolByIDError            bool
-		GetStoragePoolsError       bool
-		GetStatisticsError         bool
-		CreateSnapshotError        bool
-		RemoveVolumeError          bool
-		InstancesError             bool
-		VolInstanceError           bool
-		StatsError                 bool
-		StartingTokenInvalidError  bool
-		GetSnapshotError           bool
-		DeleteSnapshotError        bool
-		ExportNotFoundError        bool
-		VolumeNotExistError        bool
-		CreateQuotaError           bool
-		UpdateQuotaError           bool
-		CreateExportError          bool
-		GetExportInternalError     bool
-		GetExportByIDNotFoundError bool
-		UnexportError              bool
-		DeleteQuotaError           bool
-		QuotaNotFoundError         bool
-		DeleteVolumeError          bool
+		FindVolumeIDError           bool
+		GetVolByIDError             bool
+		GetStoragePoolsError        bool
+		GetStatisticsError          bool
+		CreateSnapshotError         bool
+		RemoveVolumeError           bool
+		InstancesError              bool
+		VolInstanceError            bool
+		StatsError                  bool
+		StartingTokenInvalidError   bool
+		GetSnapshotError            bool
+		DeleteSnapshotError         bool
+		ExportNotFoundError         bool
+		VolumeNotExistError         bool
+		CreateQuotaError            bool
+		UpdateQuotaError            bool
+		CreateExportError           bool
+		GetExportInternalError      bool
+		GetExportByIDNotFoundError  bool
+		UnexportError               bool
+		DeleteQuotaError            bool
+		QuotaNotFoundError          bool
+		DeleteVolumeError           bool
+		SetACLError                 bool
+		CreateWormDomainError       bool
+		WormDomainExists            bool
+		GetWormDomainsError         bool
+		GetWormDomainsForbidden     bool
+		FileUnderRetention          bool
+		SetDirectoryAttributesError bool
+		VolumeDirectoryNotFound     bool
+		EmptyStatsError             bool
+		IsiPathQuotaExists          bool
+		CSISnapshotsExist           bool
+		ScheduledSnapshotsExist     bool
+		SyncPolicyError             bool
+		SyncJobFailed               bool
+		SnapshotExpired             bool
+		SnapshotScheduleExists      bool
+		SnapshotAliasExists         bool
+		SnapshotScheduleError       bool
+		WritableSnapshotExists      bool
+		WritableSnapshotUnsupported bool
+		WritableSnapshotDependents  bool
+		SnapshotDeletionPending     bool
+		ExportPublished             bool
+		ExportReadOnlyNode          bool
+		ExportReadWriteNode         bool
+		ExportWithoutClients        bool
+		ExportSmartLock             bool
+		JobError                    bool
+		JobRunning                  bool
+		JobFailed                   bool
+		SnapRevertDomainMarked      bool
+		DomainMarkRunning           bool
+		DomainMarkFailed            bool
+		DomainMarkAfterSnapshot     bool
+		SessionExpired              bool
+		IsiPathNotFound             bool
 	}
 )
 
@@ -64,11 +100,68 @@ var isilonRouter http.Handler
 var testControllerHasNoConnection bool
 var testNodeHasNoConnection bool
 
+// the last attributes set on a directory through the mock, and the path of the directory
+var testDirectoryAttributes directoryAttributes
+var testDirectoryAttributesPath string
+
+// the last snapshot created through the mock
+var testSnapshotCreation snapshotCreation
+
+// the last snapshot schedule created or updated, and the last one deleted, through the mock
+var testSnapshotSchedule snapshotSchedule
+var testSnapshotAlias snapshotAlias
+var testDeletedSnapshotSchedule string
+
+// the last writable snapshot created, the last one deleted, and whether a snapshot was copied, through the mock
+var testWritableSnapshot writableSnapshot
+var testDeletedWritableSnapshot string
+var testSnapshotCopied bool
+
+// the locks of the snapshots created and deleted, the entries created in the snapshot tracking directories,
+// and the last snapshot deleted, through the mock
+var testSnapshotLocks []string
+var testDeletedSnapshotLocks []string
+var testTrackingDirEntries []string
+var testDeletedSnapshot string
+
+// the last OneFS job started through the mock
+var testJobCreation jobCreation
+
+// the client lists and security flavors of the exports updated through the mock
+var testExportUpdates []exportUpdate
+
+type exportUpdate struct {
+	Clients         []string `json:"clients"`
+	ReadOnlyClients []string `json:"read_only_clients"`
+	RootClients     []string `json:"root_clients"`
+	SecurityFlavors []string `json:"security_flavors"`
+}
+
+// the thresholds of the last quota updated through the mock
+var testQuotaThresholds string
+
+// the last SyncIQ policy created through the mock
+var testSyncPolicy syncPolicy
+
+// the password accepted by the mock to create sessions, the passwords of the sessions created, the last session
+// created, and the number of requests authenticated with the session or with basic authentication
+var testSessionPassword = "blah"
+var testSessionLogins []string
+var testSessionID string
+var testSessionRequests int
+var testBasicAuthRequests int
+
+// the number of volume directories deleted through the mock
+var testDeletedVolumeDirectories int
+
 // getFileHandler returns an http.Handler that
 func getHandler() http.Handler {
 	handler := http.HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {
 			log.Printf("handler called: %s %s", r.Method, r.URL)
+			if !checkSession(w, r) {
+				return
+			}
 			if isilonRouter == nil {
 				getRouter().ServeHTTP(w, r)
 			}
@@ -79,16 +172,119 @@ func getHandler() http.Handler {
 	return handler
 }
 
+// checkSession checks the session of a request, returns false if the request is rejected
+func checkSession(w http.ResponseWriter, r *http.Request) bool {
+	if r.URL.Path == sessionPath {
+		return true
+	}
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		if _, _, ok := r.BasicAuth(); ok {
+			testBasicAuthRequests++
+		}
+		return true
+	}
+	if _, _, ok := r.BasicAuth(); ok || cookie.Value != testSessionID || stepHandlersErrors.SessionExpired ||
+		r.Header.Get(csrfHeaderName) != "csrf-"+cookie.Value || r.Header.Get("Referer") == "" {
+		stepHandlersErrors.SessionExpired = false
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"errors":[{"code":"AEC_UNAUTHORIZED","message":"Authorization required"}]}`))
+		return false
+	}
+	testSessionRequests++
+	return true
+}
+
+// handleCreateSession implements POST /session/1/session
+func handleCreateSession(w http.ResponseWriter, r *http.Request) {
+	req := &sessionRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil || req.Password != testSessionPassword {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"message":"Username or password is incorrect."}`))
+		return
+	}
+	testSessionLogins = append(testSessionLogins, req.Password)
+	testSessionID = fmt.Sprintf("session-%d", len(testSessionLogins))
+	http.SetCookie(w, &http.Cookie{Name: sessionCookieName, Value: testSessionID})
+	http.SetCookie(w, &http.Cookie{Name: csrfCookieName, Value: "csrf-" + testSessionID})
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(`{"services":["platform","namespace"],"timeout_absolute":14400,"timeout_inactive":900,"username":"` + req.Username + `"}`))
+}
+
+// the secrets of the secret provider stub, and the token it accepts
+var testProviderSecrets map[string]string
+
+const testProviderToken = "provider-token"
+
+// getSecretProviderHandler returns the handler of the secret provider stub, which serves GET /secrets/{key}
+func getSecretProviderHandler() http.Handler {
+	router := mux.NewRouter()
+	router.HandleFunc("/secrets/{key}", handleGetProviderSecret).Methods("GET")
+	return router
+}
+
+func handleGetProviderSecret(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer "+testProviderToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	value, ok := testProviderSecrets[mux.Vars(r)["key"]]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	// the secrets whose key ends with "-raw" are returned as plain text
+	if strings.HasSuffix(mux.Vars(r)["key"], "-raw") {
+		w.Write([]byte(value + "\n"))
+		return
+	}
+	json.NewEncoder(w).Encode(&secretProviderResponse{Value: &value})
+}
+
 func getRouter() http.Handler {
 	isilonRouter := mux.NewRouter()
+	isilonRouter.HandleFunc(sessionPath, handleCreateSession).Methods("POST")
 	isilonRouter.HandleFunc("/platform/latest/", handleNewAPI)
+	isilonRouter.MatcherFunc(isSnapshotTrackingDirRequest).HandlerFunc(handleSnapshotTrackingDir)
+	isilonRouter.PathPrefix("/namespace/").HandlerFunc(handleGetACL).Methods("GET").Queries("acl", "")
+	isilonRouter.PathPrefix("/namespace/").HandlerFunc(handleSetACL).Methods("PUT").Queries("acl", "")
+	isilonRouter.PathPrefix("/namespace/").HandlerFunc(handleGetFileWormStatus).Methods("GET").Queries("worm", "")
+	isilonRouter.PathPrefix("/namespace/").HandlerFunc(handleGetDirectoryChildren).Methods("GET").Queries("detail", "type")
+	isilonRouter.PathPrefix("/namespace/").HandlerFunc(handleSetDirectoryAttributes).Methods("PUT").Queries("metadata", "")
+	isilonRouter.HandleFunc("/namespace/ifs/data/csi-isilon/volume2", handleGetVolume2Attributes).Methods("GET").Queries("metadata", "")
+	isilonRouter.HandleFunc("/platform/1/worm/domains/", handleGetWormDomains).Methods("GET")
+	isilonRouter.HandleFunc("/platform/1/worm/domains/", handleCreateWormDomain).Methods("POST")
+	isilonRouter.HandleFunc("/platform/1/snapshot/schedules/{name}", handleGetSnapshotSchedule).Methods("GET")
+	isilonRouter.HandleFunc("/platform/1/snapshot/schedules/", handleCreateSnapshotSchedule).Methods("POST")
+	isilonRouter.HandleFunc("/platform/1/snapshot/schedules/{name}", handleUpdateSnapshotSchedule).Methods("PUT")
+	isilonRouter.HandleFunc("/platform/1/snapshot/schedules/{name}", handleDeleteSnapshotSchedule).Methods("DELETE")
+	isilonRouter.HandleFunc("/platform/1/snapshot/aliases/", handleCreateSnapshotAlias).Methods("POST")
+	isilonRouter.HandleFunc("/platform/1/snapshot/aliases/{name}", handleUpdateSnapshotAlias).Methods("PUT")
+	isilonRouter.HandleFunc("/platform/14/snapshot/writable/", handleCreateWritableSnapshot).Methods("POST")
+	isilonRouter.HandleFunc("/platform/14/snapshot/writable/", handleGetWritableSnapshots).Methods("GET")
+	isilonRouter.HandleFunc("/platform/1/snapshot/snapshots/{snapshot_id}/locks/", handleGetSnapshotLocks).Methods("GET")
+	isilonRouter.HandleFunc("/platform/1/snapshot/snapshots/{snapshot_id}/locks/", handleCreateSnapshotLock).Methods("POST")
+	isilonRouter.HandleFunc("/platform/1/snapshot/snapshots/{snapshot_id}/locks/{lock_id}", handleDeleteSnapshotLock).Methods("DELETE")
+	isilonRouter.HandleFunc("/platform/1/job/jobs/", handleStartJob).Methods("POST")
+	isilonRouter.HandleFunc("/platform/1/job/jobs/{id}", handleGetJob).Methods("GET")
+	isilonRouter.HandleFunc("/platform/14/snapshot/writable/{path:.*}", handleGetWritableSnapshot).Methods("GET")
+	isilonRouter.HandleFunc("/platform/14/snapshot/writable/{path:.*}", handleDeleteWritableSnapshot).Methods("DELETE")
+	isilonRouter.HandleFunc("/platform/1/sync/policies/", handleCreateSyncPolicy).Methods("POST")
+	isilonRouter.HandleFunc("/platform/1/sync/policies/{name}", handleDeleteSyncPolicy).Methods("DELETE")
+	isilonRouter.HandleFunc("/platform/1/sync/jobs/", handleStartSyncJob).Methods("POST")
+	isilonRouter.HandleFunc("/platform/1/sync/jobs/{name}", handleGetSyncJob).Methods("GET")
+	isilonRouter.HandleFunc("/platform/1/sync/reports/", handleGetSyncReports).Methods("GET")
+	isilonRouter.HandleFunc("/platform/1/auth/users/{name}", handleGetAuthUser).Methods("GET")
+	isilonRouter.HandleFunc("/platform/1/auth/groups/{name}", handleGetAuthGroup).Methods("GET")
 	isilonRouter.HandleFunc("/platform/2/protocols/nfs/exports/", handleExportUpdate).Methods("PUT")
 	isilonRouter.HandleFunc("/platform/2/protocols/nfs/exports/{export_id}", handleModifyExport).Methods("PUT")
 	isilonRouter.HandleFunc("/platform/2/protocols/nfs/exports/{export_id}", handleUnexportPath).Methods("DELETE").Queries("zone", "System")
+	isilonRouter.HandleFunc("/platform/2/protocols/nfs/exports/{export_id}", handleUnexportPath).Methods("DELETE")
 	isilonRouter.HandleFunc("/platform/2/protocols/nfs/exports/{id}", handleGetExportByID).Methods("GET")
 	isilonRouter.HandleFunc("/platform/2/protocols/nfs/exports/", handleCreateExport).Methods("POST")
-	// Do NOT change the sequence of the following four lines, the first three are subsets of the fourth
+	// Do NOT change the sequence of the following five lines, the first four are subsets of the fifth
 	isilonRouter.HandleFunc("/platform/2/protocols/nfs/exports/", handleGetExportWithPathAndZone).Methods("GET").Queries("path", "/ifs/data/csi-isilon/volume1", "zone", "System")
+	isilonRouter.HandleFunc("/platform/2/protocols/nfs/exports/", handleGetExportsWithZone).Methods("GET").Queries("zone", "System")
 	isilonRouter.HandleFunc("/platform/2/protocols/nfs/exports/", handleGetExportsWithLimit).Methods("GET").Queries("limit", "")
 	isilonRouter.HandleFunc("/platform/2/protocols/nfs/exports/", handleGetExportsWithResume).Methods("GET").Queries("resume", "")
 	isilonRouter.HandleFunc("/platform/2/protocols/nfs/exports/", handleGetExports).Methods("GET")
@@ -109,6 +305,7 @@ func getRouter() http.Handler {
 	isilonRouter.HandleFunc("/platform/5/quota/license/", handleGetQuotaLicense).Methods("GET")
 	isilonRouter.HandleFunc("/platform/1/quota/quotas/{quota_id}", handleGetQuotaByID).Methods("GET")
 	isilonRouter.HandleFunc("/platform/1/quota/quotas/", handleCreateQuota).Methods("POST")
+	isilonRouter.HandleFunc("/platform/1/quota/quotas/", handleGetQuotasWithPath).Methods("GET")
 	isilonRouter.HandleFunc("/platform/1/quota/quotas/{quota_id}", handleDeleteQuotaByID).Methods("DELETE")
 	isilonRouter.HandleFunc("/platform/1/quota/quotas/{quota_id}", handleUpdateQuotaByID).Methods("PUT")
 
@@ -118,15 +315,20 @@ func getRouter() http.Handler {
 	isilonRouter.HandleFunc("/namespace/ifs/data/csi-isilon/{id}", handleVolumeCreation).Methods("PUT")
 
 	isilonRouter.HandleFunc("/platform/1/snapshot/snapshots/", handleCreateSnapshot).Methods("POST")
+	isilonRouter.HandleFunc("/platform/1/snapshot/snapshots/", handleGetSnapshots).Methods("GET")
 	isilonRouter.HandleFunc("/platform/1/snapshot/snapshots/create_snapshot_name/", handleGetNonexistentSnapshot).Methods("GET")
 	isilonRouter.HandleFunc("/platform/1/snapshot/snapshots/1/", handleGetNonexistentSnapshot).Methods("GET")
 	isilonRouter.HandleFunc("/platform/1/snapshot/snapshots/existent_snapshot_name/", handleGetExistentSnapshot).Methods("GET")
 	isilonRouter.HandleFunc("/platform/1/snapshot/snapshots/2/", handleGetExistentSnapshot).Methods("GET")
 	isilonRouter.HandleFunc("/platform/1/snapshot/snapshots/existent_comp_snapshot_name/", handleGetExistentCompatibleSnapshot).Methods("GET")
 	isilonRouter.HandleFunc("/platform/1/snapshot/snapshots/3/", handleGetExistentCompatibleSnapshot).Methods("GET")
+	isilonRouter.HandleFunc("/platform/1/snapshot/snapshots/5/", handleGetVolumeSnapshot).Methods("GET")
 	isilonRouter.HandleFunc("/platform/1/snapshot/snapshots/{snapshot_id}/", handleDeleteSnapshot).Methods("DELETE")
 	isilonRouter.HandleFunc("/platform/1/snapshot/snapshots/{snapshot_id}/", handleGetSnapshotByID).Methods("GET")
 	isilonRouter.HandleFunc("/namespace/ifs/.snapshot/{snapshot_name}/data/csi-isilon/{volume_id}", handleGetSnapshotSize).Methods("GET").Queries("detail", "size", "max-depth", "-1")
+	isilonRouter.HandleFunc("/platform/1/zones/{name}", handleGetZone).Methods("GET")
+	// after the routes of the volumes, which also query their metadata
+	isilonRouter.PathPrefix("/namespace/").HandlerFunc(handleGetDirectoryMetadata).Methods("GET").Queries("metadata", "")
 
 	return isilonRouter
 }
@@ -223,6 +425,30 @@ func handleGetExportByID(w http.ResponseWriter, r *http.Request) {
 		w.Write(readFromFile("mock/export/export_not_found_by_id.txt"))
 		return
 	}
+	if stepHandlersErrors.ExportPublished {
+		w.Write(readFromFile("mock/export/get_export_557_published.txt"))
+		return
+	}
+	if stepHandlersErrors.ExportReadOnlyNode {
+		w.Write(readFromFile("mock/export/get_export_557_read_only_node.txt"))
+		return
+	}
+	if stepHandlersErrors.ExportReadWriteNode {
+		w.Write(readFromFile("mock/export/get_export_557_read_write_node.txt"))
+		return
+	}
+	if stepHandlersErrors.ExportWithoutClients {
+		w.Write(readFromFile("mock/export/get_export_557_without_clients.txt"))
+		return
+	}
+	if stepHandlersErrors.ExportSmartLock {
+		w.Write(readFromFile("mock/export/get_export_557_smartlock.txt"))
+		return
+	}
+	if mux.Vars(r)["id"] == "19" {
+		w.Write(readFromFile("mock/export/get_export_19.txt"))
+		return
+	}
 	w.Write(readFromFile("mock/export/get_export_557.txt"))
 }
 
@@ -245,6 +471,16 @@ func handleGetExistentVolumeMetadata(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusRequestTimeout)
 		return
 	}
+	if stepHandlersErrors.VolumeDirectoryNotFound {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write(readFromFile("mock/volume/get_non_existent_volume.txt"))
+		return
+	}
+	// the DomainMark job of a volume in a SnapRevert domain is job 41
+	if stepHandlersErrors.SnapRevertDomainMarked {
+		w.Write([]byte(`{"attrs": [{"name": "` + snapRevertJobAttribute + `", "value": "41", "namespace": "user"}]}`))
+		return
+	}
 	w.Write([]byte("{\"attrs\": [{}]}"))
 }
 
@@ -331,12 +567,32 @@ func handleGetQuotaByID(w http.ResponseWriter, r *http.Request) {
 	w.Write(readFromFile("mock/quota/get_quota_by_id.txt"))
 }
 
+// handleGetQuotasWithPath implements GET /platform/1/quota/quotas?path=/ifs/data/csi-isilon&type=directory
+func handleGetQuotasWithPath(w http.ResponseWriter, r *http.Request) {
+	if testControllerHasNoConnection {
+		w.WriteHeader(http.StatusRequestTimeout)
+		return
+	}
+	if !stepHandlersErrors.IsiPathQuotaExists {
+		w.Write([]byte("{\"quotas\": []}"))
+		return
+	}
+	str := strings.Replace(string(readFromFile("mock/quota/get_quotas_with_path.txt")), "{path}", r.URL.Query().Get("path"), 1)
+	w.Write([]byte(str))
+}
+
 // handleUpdateQuotaByID implements PUT /platform/1/quota/quotas/AABpAQEAAAAAAAAAAAAAQA0AAAAAAAAA
 func handleUpdateQuotaByID(w http.ResponseWriter, r *http.Request) {
 	if stepHandlersErrors.UpdateQuotaError {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	var update struct {
+		Thresholds json.RawMessage `json:"thresholds"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&update); err == nil && update.Thresholds != nil {
+		testQuotaThresholds = string(update.Thresholds)
+	}
 
 	w.WriteHeader(http.StatusNoContent)
 	// response body is empty
@@ -368,17 +624,22 @@ func handleDeleteVolume(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	testDeletedVolumeDirectories++
 	w.WriteHeader(http.StatusNoContent)
 	// response body is empty
 	w.Write([]byte(""))
 }
 
-// handleModifyExport implements GET /platform/2/protocols/nfs/exports/{id}
+// handleModifyExport implements PUT /platform/2/protocols/nfs/exports/{id}
 func handleModifyExport(w http.ResponseWriter, r *http.Request) {
 	if testControllerHasNoConnection {
 		w.WriteHeader(http.StatusRequestTimeout)
 		return
 	}
+	var update exportUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err == nil {
+		testExportUpdates = append(testExportUpdates, update)
+	}
 
 	w.WriteHeader(http.StatusNoContent)
 	// response body is empty
@@ -396,7 +657,9 @@ func handleStatistics(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var str string
-	if stepHandlersErrors.StatsError {
+	if stepHandlersErrors.EmptyStatsError {
+		str = "{ \"stats\": [] }"
+	} else if stepHandlersErrors.StatsError {
 		str = "{ \"stats\": [{ \"devid\": 0, \"error\": \"Error\", \"error_code\": 1234,\"key\": \"ifs.bytes.avail\", " +
 			"\"time\": 1565035610,\"value\": 81224996814848 }] }"
 	} else {
@@ -423,6 +686,23 @@ func handleGetExportWithPathAndZone(w http.ResponseWriter, r *http.Request) {
 	w.Write(readFromFile("mock/export/get_export_557.txt"))
 }
 
+// handleGetExportsWithZone implements GET /platform/2/protocols/nfs/exports?zone=System
+func handleGetExportsWithZone(w http.ResponseWriter, r *http.Request) {
+	if testControllerHasNoConnection {
+		w.WriteHeader(http.StatusRequestTimeout)
+		return
+	}
+	if stepHandlersErrors.GetExportInternalError {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if r.URL.Query().Get("path") != "" {
+		handleGetExports(w, r)
+		return
+	}
+	w.Write(readFromFile("mock/export/get_exports_with_departed_node.txt"))
+}
+
 // handleExportGetId implements GET /platform/2/protocols/nfs/exports?limit=2
 func handleGetExportsWithLimit(w http.ResponseWriter, r *http.Request) {
 	if testControllerHasNoConnection {
@@ -469,6 +749,10 @@ func handleGetSnapshotByID(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
 		w.Write(readFromFile("mock/snapshot/get_non_existent_snapshot.txt"))
 	}
+	if stepHandlersErrors.SnapshotExpired {
+		w.Write(readFromFile("mock/snapshot/get_expired_snapshot.txt"))
+		return
+	}
 	w.Write(readFromFile("mock/snapshot/get_existent_snapshot.txt"))
 }
 
@@ -479,7 +763,7 @@ func handleDeleteSnapshot(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusRequestTimeout)
 		return
 	}
-	if stepHandlersErrors.DeleteSnapshotError == true {
+	if stepHandlersErrors.DeleteSnapshotError == true || stepHandlersErrors.SnapshotExpired {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -487,6 +771,7 @@ func handleDeleteSnapshot(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
 		w.Write(readFromFile("mock/snapshot/get_non_existent_snapshot.txt"))
 	}
+	testDeletedSnapshot = mux.Vars(r)["snapshot_id"]
 	w.WriteHeader(http.StatusNoContent)
 	// response body is empty
 	w.Write([]byte(""))
@@ -515,9 +800,31 @@ func handleCreateSnapshot(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	testSnapshotCreation = snapshotCreation{}
+	if err := json.NewDecoder(r.Body).Decode(&testSnapshotCreation); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
 	w.Write(readFromFile("mock/snapshot/create_snapshot.txt"))
 }
 
+// handleGetSnapshots implements GET /platform/1/snapshot/snapshots/
+func handleGetSnapshots(w http.ResponseWriter, r *http.Request) {
+	if testControllerHasNoConnection {
+		w.WriteHeader(http.StatusRequestTimeout)
+		return
+	}
+	if stepHandlersErrors.CSISnapshotsExist {
+		w.Write(readFromFile("mock/snapshot/get_csi_snapshots.txt"))
+		return
+	}
+	if stepHandlersErrors.ScheduledSnapshotsExist {
+		w.Write(readFromFile("mock/snapshot/get_scheduled_snapshots.txt"))
+		return
+	}
+	w.Write(readFromFile("mock/snapshot/get_snapshots.txt"))
+}
+
 // handleGetNonexistentSnapshot implements GET /platform/1/snapshot/snapshots/create_snapshot_name/
 func handleGetNonexistentSnapshot(w http.ResponseWriter, r *http.Request) {
 	if testControllerHasNoConnection {
@@ -553,6 +860,7 @@ func handleCopySnapshot(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusRequestTimeout)
 		return
 	}
+	testSnapshotCopied = true
 	//w.Write(readFromFile("mock/create_snapshot.txt"))
 }
 
@@ -585,3 +893,482 @@ func handleGetVolumeSize(w http.ResponseWriter, r *http.Request) {
 
 	w.Write(readFromFile("mock/volume/get_volume_size.txt"))
 }
+
+// handleGetWormDomains implements GET /platform/1/worm/domains
+func handleGetWormDomains(w http.ResponseWriter, r *http.Request) {
+	if testControllerHasNoConnection {
+		w.WriteHeader(http.StatusRequestTimeout)
+		return
+	}
+	if stepHandlersErrors.GetWormDomainsError {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if stepHandlersErrors.GetWormDomainsForbidden {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write(readFromFile("mock/worm/get_worm_domains_forbidden.txt"))
+		return
+	}
+	if stepHandlersErrors.WormDomainExists || stepHandlersErrors.FileUnderRetention {
+		w.Write(readFromFile("mock/worm/get_worm_domains.txt"))
+		return
+	}
+	w.Write(readFromFile("mock/worm/get_worm_domains_empty.txt"))
+}
+
+// handleCreateWormDomain implements POST /platform/1/worm/domains
+func handleCreateWormDomain(w http.ResponseWriter, r *http.Request) {
+	if testControllerHasNoConnection {
+		w.WriteHeader(http.StatusRequestTimeout)
+		return
+	}
+	if stepHandlersErrors.CreateWormDomainError {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(readFromFile("mock/worm/create_worm_domain_error.txt"))
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	w.Write(readFromFile("mock/worm/create_worm_domain.txt"))
+}
+
+// handleGetDirectoryChildren implements GET /namespace/{path}?detail=type
+func handleGetDirectoryChildren(w http.ResponseWriter, r *http.Request) {
+	if testControllerHasNoConnection {
+		w.WriteHeader(http.StatusRequestTimeout)
+		return
+	}
+	if strings.HasSuffix(r.URL.Path, "/dir1") {
+		w.Write(readFromFile("mock/worm/get_subdirectory_children.txt"))
+		return
+	}
+	w.Write(readFromFile("mock/worm/get_directory_children.txt"))
+}
+
+// handleGetFileWormStatus implements GET /namespace/{path}?worm
+func handleGetFileWormStatus(w http.ResponseWriter, r *http.Request) {
+	if testControllerHasNoConnection {
+		w.WriteHeader(http.StatusRequestTimeout)
+		return
+	}
+	if stepHandlersErrors.FileUnderRetention && strings.HasSuffix(r.URL.Path, "/file2") {
+		w.Write(readFromFile("mock/worm/get_file_worm_retained.txt"))
+		return
+	}
+	w.Write(readFromFile("mock/worm/get_file_worm_expired.txt"))
+}
+
+// handleGetSnapshotSchedule implements GET /platform/1/snapshot/schedules/{name}
+func handleGetSnapshotSchedule(w http.ResponseWriter, r *http.Request) {
+	if testControllerHasNoConnection {
+		w.WriteHeader(http.StatusRequestTimeout)
+		return
+	}
+	if stepHandlersErrors.SnapshotScheduleExists {
+		w.Write([]byte(`{"schedules": [{"id": 7, "name": "` + mux.Vars(r)["name"] + `", "schedule": "every 1 days"}]}`))
+		return
+	}
+	w.WriteHeader(http.StatusNotFound)
+	w.Write(readFromFile("mock/snapshot/snapshot_schedule_not_found.txt"))
+}
+
+// handleCreateSnapshotSchedule implements POST /platform/1/snapshot/schedules
+func handleCreateSnapshotSchedule(w http.ResponseWriter, r *http.Request) {
+	if testControllerHasNoConnection {
+		w.WriteHeader(http.StatusRequestTimeout)
+		return
+	}
+	if stepHandlersErrors.SnapshotScheduleError {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	testSnapshotSchedule = snapshotSchedule{}
+	if err := json.NewDecoder(r.Body).Decode(&testSnapshotSchedule); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.Write([]byte(`{"id": 7}`))
+}
+
+// handleUpdateSnapshotSchedule implements PUT /platform/1/snapshot/schedules/{name}
+func handleUpdateSnapshotSchedule(w http.ResponseWriter, r *http.Request) {
+	if testControllerHasNoConnection {
+		w.WriteHeader(http.StatusRequestTimeout)
+		return
+	}
+	testSnapshotSchedule = snapshotSchedule{}
+	if err := json.NewDecoder(r.Body).Decode(&testSnapshotSchedule); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	testSnapshotSchedule.Name = mux.Vars(r)["name"]
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleCreateSnapshotAlias implements POST /platform/1/snapshot/aliases
+func handleCreateSnapshotAlias(w http.ResponseWriter, r *http.Request) {
+	if testControllerHasNoConnection {
+		w.WriteHeader(http.StatusRequestTimeout)
+		return
+	}
+	testSnapshotAlias = snapshotAlias{}
+	if err := json.NewDecoder(r.Body).Decode(&testSnapshotAlias); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.Write([]byte(`{"id": 9}`))
+}
+
+// handleUpdateSnapshotAlias implements PUT /platform/1/snapshot/aliases/{name}
+func handleUpdateSnapshotAlias(w http.ResponseWriter, r *http.Request) {
+	if testControllerHasNoConnection {
+		w.WriteHeader(http.StatusRequestTimeout)
+		return
+	}
+	if !stepHandlersErrors.SnapshotAliasExists {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write(readFromFile("mock/snapshot/snapshot_alias_not_found.txt"))
+		return
+	}
+	testSnapshotAlias = snapshotAlias{}
+	if err := json.NewDecoder(r.Body).Decode(&testSnapshotAlias); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	// the name of a retargeted alias isn't sent, record it to tell it from a created one
+	testSnapshotAlias.Name = "retargeted:" + mux.Vars(r)["name"]
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDeleteSnapshotSchedule implements DELETE /platform/1/snapshot/schedules/{name}
+func handleDeleteSnapshotSchedule(w http.ResponseWriter, r *http.Request) {
+	if testControllerHasNoConnection {
+		w.WriteHeader(http.StatusRequestTimeout)
+		return
+	}
+	if stepHandlersErrors.SnapshotScheduleError {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !stepHandlersErrors.SnapshotScheduleExists {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write(readFromFile("mock/snapshot/snapshot_schedule_not_found.txt"))
+		return
+	}
+	testDeletedSnapshotSchedule = mux.Vars(r)["name"]
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGetVolumeSnapshot implements GET /platform/1/snapshot/snapshots/5, a snapshot of volume1
+func handleGetVolumeSnapshot(w http.ResponseWriter, r *http.Request) {
+	if testControllerHasNoConnection {
+		w.WriteHeader(http.StatusRequestTimeout)
+		return
+	}
+	w.Write(readFromFile("mock/snapshot/get_volume_snapshot.txt"))
+}
+
+// handleStartJob implements POST /platform/1/job/jobs
+func handleStartJob(w http.ResponseWriter, r *http.Request) {
+	if testControllerHasNoConnection {
+		w.WriteHeader(http.StatusRequestTimeout)
+		return
+	}
+	if stepHandlersErrors.JobError {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	testJobCreation = jobCreation{}
+	if err := json.NewDecoder(r.Body).Decode(&testJobCreation); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.Write([]byte(`{"id": 42}`))
+}
+
+// handleGetJob implements GET /platform/1/job/jobs/{id}
+func handleGetJob(w http.ResponseWriter, r *http.Request) {
+	if testControllerHasNoConnection {
+		w.WriteHeader(http.StatusRequestTimeout)
+		return
+	}
+	// the SnapRevert domain of volume1 is created by job 41, before its snapshot 5 is taken
+	if mux.Vars(r)["id"] == "41" {
+		state, endTime := "succeeded", "1567000000"
+		if stepHandlersErrors.DomainMarkRunning {
+			state, endTime = "running", "0"
+		} else if stepHandlersErrors.DomainMarkFailed {
+			state = "failed"
+		} else if stepHandlersErrors.DomainMarkAfterSnapshot {
+			endTime = "1568000000"
+		}
+		w.Write([]byte(`{"jobs": [{"id": 41, "type": "DomainMark", "state": "` + state + `", "end_time": ` + endTime + `}]}`))
+		return
+	}
+	state, progress := "succeeded", "Phase 2: completed"
+	if stepHandlersErrors.JobRunning {
+		state, progress = "running", "Phase 1: 1024 files processed"
+	} else if stepHandlersErrors.JobFailed {
+		state, progress = "failed", "Phase 1: error"
+	}
+	w.Write([]byte(`{"jobs": [{"id": ` + mux.Vars(r)["id"] + `, "type": "` + testJobCreation.Type + `", "state": "` + state + `", "progress": "` + progress + `"}]}`))
+}
+
+// handleCreateWritableSnapshot implements POST /platform/14/snapshot/writable
+func handleCreateWritableSnapshot(w http.ResponseWriter, r *http.Request) {
+	if testControllerHasNoConnection {
+		w.WriteHeader(http.StatusRequestTimeout)
+		return
+	}
+	if stepHandlersErrors.WritableSnapshotUnsupported {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write(readFromFile("mock/snapshot/writable_snapshot_not_found.txt"))
+		return
+	}
+	testWritableSnapshot = writableSnapshot{}
+	if err := json.NewDecoder(r.Body).Decode(&testWritableSnapshot); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.Write([]byte(`{"id": 9, "dst_path": "` + testWritableSnapshot.DstPath + `", "state": "active"}`))
+}
+
+// handleGetWritableSnapshot implements GET /platform/14/snapshot/writable/{path}
+func handleGetWritableSnapshot(w http.ResponseWriter, r *http.Request) {
+	if testControllerHasNoConnection {
+		w.WriteHeader(http.StatusRequestTimeout)
+		return
+	}
+	// the tracking directories of the snapshots are never writable snapshots
+	if stepHandlersErrors.WritableSnapshotExists && !strings.Contains(mux.Vars(r)["path"], "-tracking-dir") {
+		w.Write([]byte(`{"writable": [{"id": 9, "dst_path": "/` + mux.Vars(r)["path"] + `", "src_snap": "2", "state": "active"}]}`))
+		return
+	}
+	w.WriteHeader(http.StatusNotFound)
+	w.Write(readFromFile("mock/snapshot/writable_snapshot_not_found.txt"))
+}
+
+// handleGetWritableSnapshots implements GET /platform/14/snapshot/writable/
+func handleGetWritableSnapshots(w http.ResponseWriter, r *http.Request) {
+	if testControllerHasNoConnection {
+		w.WriteHeader(http.StatusRequestTimeout)
+		return
+	}
+	if stepHandlersErrors.WritableSnapshotDependents {
+		w.Write([]byte(`{"writable": [{"id": 9, "dst_path": "/ifs/data/csi-isilon/volume9", "src_snap": "existent_snapshot_name", "state": "active"}]}`))
+		return
+	}
+	w.Write([]byte(`{"writable": []}`))
+}
+
+// handleGetSnapshotLocks implements GET /platform/1/snapshot/snapshots/{snapshot_id}/locks/
+func handleGetSnapshotLocks(w http.ResponseWriter, r *http.Request) {
+	if testControllerHasNoConnection {
+		w.WriteHeader(http.StatusRequestTimeout)
+		return
+	}
+	if stepHandlersErrors.WritableSnapshotExists {
+		w.Write([]byte(`{"locks": [{"id": 4, "comment": "CSI writable snapshot '/ifs/data/csi-isilon/volume1'"}]}`))
+		return
+	}
+	w.Write([]byte(`{"locks": []}`))
+}
+
+// handleCreateSnapshotLock implements POST /platform/1/snapshot/snapshots/{snapshot_id}/locks/
+func handleCreateSnapshotLock(w http.ResponseWriter, r *http.Request) {
+	if testControllerHasNoConnection {
+		w.WriteHeader(http.StatusRequestTimeout)
+		return
+	}
+	var lock snapshotLock
+	if err := json.NewDecoder(r.Body).Decode(&lock); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	testSnapshotLocks = append(testSnapshotLocks, mux.Vars(r)["snapshot_id"]+":"+lock.Comment)
+	w.Write([]byte(`{"id": 4}`))
+}
+
+// handleDeleteSnapshotLock implements DELETE /platform/1/snapshot/snapshots/{snapshot_id}/locks/{lock_id}
+func handleDeleteSnapshotLock(w http.ResponseWriter, r *http.Request) {
+	if testControllerHasNoConnection {
+		w.WriteHeader(http.StatusRequestTimeout)
+		return
+	}
+	testDeletedSnapshotLocks = append(testDeletedSnapshotLocks, mux.Vars(r)["snapshot_id"]+":"+mux.Vars(r)["lock_id"])
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// isSnapshotTrackingDirRequest matches the requests on the tracking directories of the snapshots
+func isSnapshotTrackingDirRequest(r *http.Request, rm *mux.RouteMatch) bool {
+	return strings.HasPrefix(r.URL.Path, "/namespace/") && strings.Contains(r.URL.Path, "-tracking-dir")
+}
+
+// handleSnapshotTrackingDir implements GET, PUT and DELETE /namespace/{isiPath}/.csi-{snapshot}-tracking-dir/{entry},
+// a tracking directory holds the delete marker and no volume entry once the deletion of the snapshot is pending
+func handleSnapshotTrackingDir(w http.ResponseWriter, r *http.Request) {
+	if testControllerHasNoConnection {
+		w.WriteHeader(http.StatusRequestTimeout)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		if !stepHandlersErrors.SnapshotDeletionPending {
+			writeError(w, "Path not found", http.StatusNotFound, codes.NotFound)
+			return
+		}
+		w.Write([]byte(`{"attrs": [{"name": "nlink", "value": 3}]}`))
+	case http.MethodPut:
+		testTrackingDirEntries = append(testTrackingDirEntries, strings.TrimPrefix(r.URL.Path, "/namespace"))
+	case http.MethodDelete:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleDeleteWritableSnapshot implements DELETE /platform/14/snapshot/writable/{path}
+func handleDeleteWritableSnapshot(w http.ResponseWriter, r *http.Request) {
+	if testControllerHasNoConnection {
+		w.WriteHeader(http.StatusRequestTimeout)
+		return
+	}
+	testDeletedWritableSnapshot = "/" + mux.Vars(r)["path"]
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleSetDirectoryAttributes implements PUT /namespace/{path}?metadata
+func handleSetDirectoryAttributes(w http.ResponseWriter, r *http.Request) {
+	if testControllerHasNoConnection {
+		w.WriteHeader(http.StatusRequestTimeout)
+		return
+	}
+	if stepHandlersErrors.SetDirectoryAttributesError {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	testDirectoryAttributes = directoryAttributes{}
+	if err := json.NewDecoder(r.Body).Decode(&testDirectoryAttributes); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	testDirectoryAttributesPath = strings.TrimPrefix(r.URL.Path, "/namespace")
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleGetVolume2Attributes implements GET /namespace/ifs/data/csi-isilon/volume2?metadata
+func handleGetVolume2Attributes(w http.ResponseWriter, r *http.Request) {
+	if testControllerHasNoConnection {
+		w.WriteHeader(http.StatusRequestTimeout)
+		return
+	}
+	w.Write(readFromFile("mock/volume/get_volume2_metadata.txt"))
+}
+
+// handleGetACL implements GET /namespace/{path}?acl
+func handleGetACL(w http.ResponseWriter, r *http.Request) {
+	if testControllerHasNoConnection {
+		w.WriteHeader(http.StatusRequestTimeout)
+		return
+	}
+	w.Write(readFromFile("mock/acl/get_acl.txt"))
+}
+
+// handleSetACL implements PUT /namespace/{path}?acl
+func handleSetACL(w http.ResponseWriter, r *http.Request) {
+	if testControllerHasNoConnection {
+		w.WriteHeader(http.StatusRequestTimeout)
+		return
+	}
+	if stepHandlersErrors.SetACLError {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleGetAuthUser implements GET /platform/1/auth/users/{name}
+func handleGetAuthUser(w http.ResponseWriter, r *http.Request) {
+	if testControllerHasNoConnection {
+		w.WriteHeader(http.StatusRequestTimeout)
+		return
+	}
+	if mux.Vars(r)["name"] != "user1" {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write(readFromFile("mock/auth/user_not_found.txt"))
+		return
+	}
+	w.Write(readFromFile("mock/auth/get_user.txt"))
+}
+
+// handleGetAuthGroup implements GET /platform/1/auth/groups/{name}
+func handleGetAuthGroup(w http.ResponseWriter, r *http.Request) {
+	if testControllerHasNoConnection {
+		w.WriteHeader(http.StatusRequestTimeout)
+		return
+	}
+	w.Write(readFromFile("mock/auth/get_group.txt"))
+}
+
+// handleCreateSyncPolicy implements POST /platform/1/sync/policies
+func handleCreateSyncPolicy(w http.ResponseWriter, r *http.Request) {
+	if stepHandlersErrors.SyncPolicyError {
+		writeError(w, "SyncIQ is not licensed", http.StatusInternalServerError, codes.Internal)
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&testSyncPolicy); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte("{\"id\": \"a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6\"}"))
+}
+
+// handleDeleteSyncPolicy implements DELETE /platform/1/sync/policies/csi-migrate-volume1
+func handleDeleteSyncPolicy(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleStartSyncJob implements POST /platform/1/sync/jobs
+func handleStartSyncJob(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte("{\"id\": \"csi-migrate-volume1\"}"))
+}
+
+// handleGetSyncJob implements GET /platform/1/sync/jobs/csi-migrate-volume1, the job is always over
+func handleGetSyncJob(w http.ResponseWriter, r *http.Request) {
+	writeError(w, "Job not found", http.StatusNotFound, codes.NotFound)
+}
+
+// handleGetSyncReports implements GET /platform/1/sync/reports?policy_name=csi-migrate-volume1&reports_per_policy=1
+func handleGetSyncReports(w http.ResponseWriter, r *http.Request) {
+	if stepHandlersErrors.SyncJobFailed {
+		w.Write(readFromFile("mock/sync/get_sync_report_failed.txt"))
+		return
+	}
+	w.Write(readFromFile("mock/sync/get_sync_report_finished.txt"))
+}
+
+// handleGetZone implements GET /platform/1/zones/{name}, the zones named "unknown..." do not exist
+func handleGetZone(w http.ResponseWriter, r *http.Request) {
+	if testControllerHasNoConnection {
+		w.WriteHeader(http.StatusRequestTimeout)
+		return
+	}
+	name := mux.Vars(r)["name"]
+	if strings.HasPrefix(name, "unknown") {
+		writeError(w, "Zone not found", http.StatusNotFound, codes.NotFound)
+		return
+	}
+	w.Write([]byte(`{"zones":[{"name":"` + name + `","path":"/ifs","zone_id":1}]}`))
+}
+
+// handleGetDirectoryMetadata implements GET /namespace/{path}?metadata for the directories other than the volumes
+func handleGetDirectoryMetadata(w http.ResponseWriter, r *http.Request) {
+	if testControllerHasNoConnection {
+		w.WriteHeader(http.StatusRequestTimeout)
+		return
+	}
+	if stepHandlersErrors.IsiPathNotFound {
+		writeError(w, "Path not found", http.StatusNotFound, codes.NotFound)
+		return
+	}
+	w.Write([]byte(`{"attrs":[{"name":"is_hidden","value":false}]}`))
+}
@@ -0,0 +1,221 @@
+package service
+
+/*
+ Copyright (c) 2019 Dell Inc, or its subsidiaries.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/dell/csi-isilon/common/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// TenantConfig To hold the access zone, isiPath and clusters the volumes of a tenant are restricted to
+type TenantConfig struct {
+	Name               string            `json:"name" yaml:"name"`
+	Namespaces         []string          `json:"namespaces,omitempty" yaml:"namespaces,omitempty"`
+	NamespaceLabels    map[string]string `json:"namespaceLabels,omitempty" yaml:"namespaceLabels,omitempty"`
+	AccessZone         string            `json:"accessZone,omitempty" yaml:"accessZone,omitempty"`
+	AzServiceIP        string            `json:"azServiceIP,omitempty" yaml:"azServiceIP,omitempty"`
+	IsiPathPrefix      string            `json:"isiPathPrefix,omitempty" yaml:"isiPathPrefix,omitempty"`
+	AllowedClusters    []string          `json:"allowedClusters,omitempty" yaml:"allowedClusters,omitempty"`
+	MaxCapacity        string            `json:"maxCapacity,omitempty" yaml:"maxCapacity,omitempty"`
+	maxCapacityInBytes int64
+}
+
+// getNewTenantConfigs parses and validates the tenancy mapping of the isilon-creds secret
+func getNewTenantConfigs(inputConfigs *IsilonClusters) ([]TenantConfig, error) {
+	clusterNames := make(map[string]bool)
+	for _, config := range inputConfigs.IsilonClusters {
+		clusterNames[config.ClusterName] = true
+	}
+
+	tenantNames := make(map[string]bool)
+	tenants := make([]TenantConfig, 0, len(inputConfigs.Tenants))
+	for i, tenant := range inputConfigs.Tenants {
+		if tenant.Name == "" {
+			return nil, fmt.Errorf("invalid value for tenant name at index [%d]", i)
+		}
+		if tenantNames[tenant.Name] {
+			return nil, fmt.Errorf("duplicate tenant name '%s' at index [%d]", tenant.Name, i)
+		}
+		tenantNames[tenant.Name] = true
+
+		if len(tenant.Namespaces) == 0 && len(tenant.NamespaceLabels) == 0 {
+			return nil, fmt.Errorf("specify namespaces or namespaceLabels for tenant '%s'", tenant.Name)
+		}
+		// the nodes must mount the volumes of the tenant through an IP serving its access zone
+		if tenant.AccessZone != "" && tenant.AzServiceIP == "" {
+			return nil, fmt.Errorf("azServiceIP must be set for tenant '%s' as it sets accessZone '%s'", tenant.Name, tenant.AccessZone)
+		}
+		if tenant.IsiPathPrefix != "" {
+			if !path.IsAbs(tenant.IsiPathPrefix) {
+				return nil, fmt.Errorf("isiPathPrefix '%s' of tenant '%s' is not an absolute path", tenant.IsiPathPrefix, tenant.Name)
+			}
+			tenant.IsiPathPrefix = path.Clean(tenant.IsiPathPrefix)
+		}
+		for _, clusterName := range tenant.AllowedClusters {
+			if !clusterNames[clusterName] {
+				return nil, fmt.Errorf("allowed cluster '%s' of tenant '%s' is not defined in isilonClusters", clusterName, tenant.Name)
+			}
+		}
+		if tenant.MaxCapacity != "" {
+			maxCapacity, err := resource.ParseQuantity(tenant.MaxCapacity)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for maxCapacity '%s' of tenant '%s': %v", tenant.MaxCapacity, tenant.Name, err)
+			}
+			tenant.maxCapacityInBytes = maxCapacity.Value()
+		}
+		// the IP serving the access zone of the tenant belongs to one cluster, the volumes cannot be placed on another
+		if tenant.AzServiceIP != "" && (len(tenant.AllowedClusters) > 1 || (len(tenant.AllowedClusters) == 0 && len(clusterNames) > 1)) {
+			return nil, fmt.Errorf("tenant '%s' sets azServiceIP, allowedClusters must be set to a single cluster", tenant.Name)
+		}
+		tenants = append(tenants, tenant)
+	}
+	return tenants, nil
+}
+
+// getTenant returns the first tenant the namespace belongs to, either by name or by labels, nil if there is none
+func (s *service) getTenant(ctx context.Context, namespace string) (*TenantConfig, error) {
+	s.tenantsLock.RLock()
+	tenants := s.tenants
+	s.tenantsLock.RUnlock()
+
+	var namespaceLabels labels.Set
+	for i := range tenants {
+		tenant := &tenants[i]
+		if utils.IsStringInSlice(namespace, tenant.Namespaces) {
+			return tenant, nil
+		}
+		if len(tenant.NamespaceLabels) == 0 {
+			continue
+		}
+
+		if namespaceLabels == nil {
+			if s.k8sclient == nil {
+				return nil, fmt.Errorf("kubernetes client is not available to get the labels of namespace '%s'", namespace)
+			}
+			ns, err := s.k8sclient.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("failed to get namespace '%s': %v", namespace, err)
+			}
+			namespaceLabels = labels.Set(ns.Labels)
+		}
+		if labels.SelectorFromSet(tenant.NamespaceLabels).Matches(namespaceLabels) {
+			return tenant, nil
+		}
+	}
+	return nil, nil
+}
+
+// checkTenantMaxCapacity rejects the expansion of a volume beyond the max capacity of the tenant of the namespace of
+// its PVC
+func (s *service) checkTenantMaxCapacity(ctx context.Context, volumeID string, requiredBytes int64) error {
+	ctx, log, runID := GetRunIDLog(ctx)
+
+	s.tenantsLock.RLock()
+	noTenants := len(s.tenants) == 0
+	s.tenantsLock.RUnlock()
+	if noTenants {
+		return nil
+	}
+
+	if s.k8sclient == nil {
+		return status.Error(codes.FailedPrecondition, utils.GetMessageWithRunID(runID,
+			"tenancy mapping is configured but the kubernetes client is not available to get the PVC of volume '%s'", volumeID))
+	}
+	pv, err := s.getPersistentVolumeByHandle(ctx, volumeID)
+	if err != nil {
+		return status.Error(codes.Internal, utils.GetMessageWithRunID(runID, "failed to get the PV of volume '%s': '%v'", volumeID, err))
+	}
+	if pv == nil || pv.Spec.ClaimRef == nil {
+		log.Debugf("no PVC found for volume '%s', no tenant to check", volumeID)
+		return nil
+	}
+
+	namespace := pv.Spec.ClaimRef.Namespace
+	tenant, err := s.getTenant(ctx, namespace)
+	if err != nil {
+		return status.Error(codes.Internal, utils.GetMessageWithRunID(runID, "failed to get the tenant of namespace '%s': '%v'", namespace, err))
+	}
+	if tenant != nil && tenant.maxCapacityInBytes > 0 && requiredBytes > tenant.maxCapacityInBytes {
+		return status.Error(codes.OutOfRange, utils.GetMessageWithRunID(runID,
+			"requested size '%d' bytes exceeds the max capacity '%s' of tenant '%s'", requiredBytes, tenant.MaxCapacity, tenant.Name))
+	}
+	return nil
+}
+
+// applyTenancy restricts the access zone, isiPath and cluster of a new volume to the ones of the tenant of the PVC namespace.
+// The tenancy mapping takes precedence over the storage class parameters.
+func (s *service) applyTenancy(ctx context.Context, params map[string]string, clusterName string, sizeInBytes int64,
+	accessZone, isiPath, azServiceIP *string) error {
+	ctx, log, runID := GetRunIDLog(ctx)
+
+	s.tenantsLock.RLock()
+	noTenants := len(s.tenants) == 0
+	s.tenantsLock.RUnlock()
+	if noTenants {
+		return nil
+	}
+
+	namespace := params[csiPersistentVolumeClaimNamespace]
+	if namespace == "" {
+		return status.Error(codes.InvalidArgument, utils.GetMessageWithRunID(runID,
+			"tenancy mapping is configured but the PVC namespace is not available, make sure the provisioner runs with '--extra-create-metadata'"))
+	}
+
+	tenant, err := s.getTenant(ctx, namespace)
+	if err != nil {
+		return status.Error(codes.Internal, utils.GetMessageWithRunID(runID, "failed to get the tenant of namespace '%s': '%v'", namespace, err))
+	}
+	if tenant == nil {
+		log.Debugf("namespace '%s' does not belong to any tenant", namespace)
+		return nil
+	}
+	log.Debugf("namespace '%s' belongs to tenant '%s'", namespace, tenant.Name)
+
+	if len(tenant.AllowedClusters) > 0 && !utils.IsStringInSlice(clusterName, tenant.AllowedClusters) {
+		return status.Error(codes.PermissionDenied, utils.GetMessageWithRunID(runID,
+			"cluster '%s' is not allowed for tenant '%s' of namespace '%s'", clusterName, tenant.Name, namespace))
+	}
+	if tenant.maxCapacityInBytes > 0 && sizeInBytes > tenant.maxCapacityInBytes {
+		return status.Error(codes.OutOfRange, utils.GetMessageWithRunID(runID,
+			"requested size '%d' bytes exceeds the max capacity '%s' of tenant '%s'", sizeInBytes, tenant.MaxCapacity, tenant.Name))
+	}
+
+	if tenant.AccessZone != "" && *accessZone != tenant.AccessZone {
+		log.Infof("access zone '%s' replaced by '%s' of tenant '%s'", *accessZone, tenant.AccessZone, tenant.Name)
+		*accessZone = tenant.AccessZone
+	}
+	if tenant.AzServiceIP != "" && !s.opts.CustomTopologyEnabled {
+		*azServiceIP = tenant.AzServiceIP
+	}
+	if tenant.IsiPathPrefix != "" {
+		cleanIsiPath := path.Clean(*isiPath)
+		if cleanIsiPath != tenant.IsiPathPrefix && !strings.HasPrefix(cleanIsiPath, tenant.IsiPathPrefix+"/") {
+			log.Infof("isiPath '%s' replaced by '%s' of tenant '%s'", *isiPath, tenant.IsiPathPrefix, tenant.Name)
+			*isiPath = tenant.IsiPathPrefix
+		}
+	}
+	return nil
+}
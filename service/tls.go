@@ -0,0 +1,199 @@
+package service
+
+/*
+ Copyright (c) 2019 Dell Inc, or its subsidiaries.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// hasCustomTrust returns true if a CA bundle or a certificate fingerprint is configured for the cluster
+func (s IsilonClusterConfig) hasCustomTrust() bool {
+	return s.CertificateAuthority != "" || s.CertificateAuthorityPath != "" || s.CertificateFingerprint != ""
+}
+
+// validateTLSConfig checks the TLS trust settings of a cluster config at the given index of the secret, the file of
+// its CA bundle is only read if readCABundle is set
+func validateTLSConfig(config *IsilonClusterConfig, index int, readCABundle bool) error {
+	if !config.hasCustomTrust() {
+		return nil
+	}
+	if config.CertificateAuthority != "" && config.CertificateAuthorityPath != "" {
+		return fmt.Errorf("specify either of certificateAuthority or certificateAuthorityPath attribute at index [%d]", index)
+	}
+	if (config.IsiInsecure != nil && *config.IsiInsecure) || (config.SkipCertificateValidation != nil && *config.SkipCertificateValidation) {
+		return fmt.Errorf("certificateAuthority, certificateAuthorityPath and certificateFingerprint cannot be used with skipCertificateValidation at index [%d]", index)
+	}
+	caBundle := []byte(config.CertificateAuthority)
+	if readCABundle {
+		var err error
+		if caBundle, err = getCABundle(config); err != nil {
+			return fmt.Errorf("invalid TLS settings at index [%d]: %v", index, err)
+		}
+	}
+	if _, err := newClusterTLSConfig(config, caBundle); err != nil {
+		return fmt.Errorf("invalid TLS settings at index [%d]: %v", index, err)
+	}
+	return nil
+}
+
+// getClusterTLSConfig builds the TLS config used to connect to a cluster from its CA bundle and certificate
+// fingerprint, nil is returned if none of them is configured.
+// The CA bundle is added to the system CAs. When only a fingerprint is configured, the certificate of the
+// cluster is trusted if its fingerprint matches, which allows self-signed certificates to be pinned.
+func getClusterTLSConfig(isiConfig *IsilonClusterConfig) (*tls.Config, error) {
+	if !isiConfig.hasCustomTrust() {
+		return nil, nil
+	}
+	caBundle, err := getCABundle(isiConfig)
+	if err != nil {
+		return nil, err
+	}
+	return newClusterTLSConfig(isiConfig, caBundle)
+}
+
+// getCABundle returns the CA bundle of a cluster, given inline or read from its path
+func getCABundle(isiConfig *IsilonClusterConfig) ([]byte, error) {
+	if isiConfig.CertificateAuthorityPath == "" {
+		return []byte(isiConfig.CertificateAuthority), nil
+	}
+	caBundle, err := ioutil.ReadFile(isiConfig.CertificateAuthorityPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the CA bundle '%s': %v", isiConfig.CertificateAuthorityPath, err)
+	}
+	return caBundle, nil
+}
+
+// newClusterTLSConfig builds the TLS config of a cluster from its CA bundle and certificate fingerprint
+func newClusterTLSConfig(isiConfig *IsilonClusterConfig, caBundle []byte) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+
+	if len(caBundle) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(caBundle) {
+			return nil, errors.New("no PEM certificate found in the CA bundle")
+		}
+		tlsConfig.RootCAs = pool
+	} else {
+		// the certificate of the cluster is verified against the fingerprint only
+		tlsConfig.InsecureSkipVerify = true
+	}
+
+	if isiConfig.CertificateFingerprint != "" {
+		fingerprint, err := parseCertificateFingerprint(isiConfig.CertificateFingerprint)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return errors.New("no certificate presented by the cluster")
+			}
+			sum := sha256.Sum256(state.PeerCertificates[0].Raw)
+			if !bytes.Equal(sum[:], fingerprint) {
+				return fmt.Errorf("the SHA-256 fingerprint '%s' of the certificate of the cluster does not match the configured fingerprint",
+					formatCertificateFingerprint(sum[:]))
+			}
+			return nil
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+// parseCertificateFingerprint parses a SHA-256 fingerprint given as hex digits, optionally separated by colons
+func parseCertificateFingerprint(value string) ([]byte, error) {
+	hexValue := strings.ReplaceAll(strings.TrimSpace(value), ":", "")
+	fingerprint, err := hex.DecodeString(hexValue)
+	if err != nil || len(fingerprint) != sha256.Size {
+		return nil, fmt.Errorf("invalid certificate fingerprint '%s', a SHA-256 fingerprint is expected", value)
+	}
+	return fingerprint, nil
+}
+
+// formatCertificateFingerprint formats a fingerprint the way openssl prints it
+func formatCertificateFingerprint(fingerprint []byte) string {
+	parts := make([]string, len(fingerprint))
+	for i, b := range fingerprint {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+// newClusterTransport returns the transport of the requests to a cluster, which verifies the certificate of the
+// cluster with the given TLS config, or with the system CAs unless insecure is set when the config is nil
+func newClusterTransport(insecure bool, tlsConfig *tls.Config) *http.Transport {
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{InsecureSkipVerify: insecure}
+	}
+	// the proxy, dial and idle connection settings are the ones of the default transport
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return transport
+}
+
+// clusterTransport is the transport of the requests to a cluster, shared by all the clients of the cluster so that
+// they reuse its connections, with the TLS settings it has been created with
+type clusterTransport struct {
+	transport *http.Transport
+	settings  string
+}
+
+// getClusterTransport returns the transport of the requests to a cluster. It is only created again when the TLS
+// settings of the cluster or the content of its CA bundle change, the idle connections of the previous one are
+// then closed.
+func (s *service) getClusterTransport(isiConfig *IsilonClusterConfig) (*http.Transport, error) {
+	var tlsConfig *tls.Config
+	settings := fmt.Sprintf("insecure=%t", *isiConfig.IsiInsecure)
+	if isiConfig.hasCustomTrust() {
+		caBundle, err := getCABundle(isiConfig)
+		if err != nil {
+			return nil, err
+		}
+		if tlsConfig, err = newClusterTLSConfig(isiConfig, caBundle); err != nil {
+			return nil, err
+		}
+		settings = fmt.Sprintf("ca=%x,fingerprint=%s", sha256.Sum256(caBundle), isiConfig.CertificateFingerprint)
+	}
+
+	s.transportsLock.Lock()
+	defer s.transportsLock.Unlock()
+
+	if s.transports == nil {
+		s.transports = make(map[string]*clusterTransport)
+	}
+	current, ok := s.transports[isiConfig.ClusterName]
+	if ok && current.settings == settings {
+		return current.transport, nil
+	}
+	if ok {
+		current.transport.CloseIdleConnections()
+	}
+	transport := newClusterTransport(*isiConfig.IsiInsecure, tlsConfig)
+	s.transports[isiConfig.ClusterName] = &clusterTransport{transport: transport, settings: settings}
+	return transport, nil
+}
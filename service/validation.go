@@ -0,0 +1,223 @@
+package service
+
+/*
+ Copyright (c) 2019 Dell Inc, or its subsidiaries.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/dell/csi-isilon/common/constants"
+	"github.com/dell/csi-isilon/common/utils"
+)
+
+// Names of the checks of the clusters run by ValidateSecret
+const (
+	CheckConnectivity = "connectivity"
+	CheckAccessZone   = "accessZone"
+	CheckIsiPath      = "isiPath"
+)
+
+// SecretValidationOptions selects what ValidateSecret checks in addition to the rules applied by the driver
+type SecretValidationOptions struct {
+	// ResolveCredentials resolves the file, env and provider references of the credentials
+	ResolveCredentials bool
+	// CheckClusters reads the CA bundle files, connects to each cluster and checks its access zones and isiPath
+	CheckClusters bool
+
+	// the options of the driver the rules depend on, which override the ones read from the X_CSI_* environment
+	// variables when they are set
+	CustomTopologyEnabled *bool
+	Insecure              *bool
+	Port                  string
+	Path                  string
+	AccessZone            string
+}
+
+// applyTo overrides the options of the driver with the ones set in the validation options
+func (o SecretValidationOptions) applyTo(opts *Opts) {
+	if o.CustomTopologyEnabled != nil {
+		opts.CustomTopologyEnabled = *o.CustomTopologyEnabled
+	}
+	if o.Insecure != nil {
+		opts.Insecure = *o.Insecure
+	}
+	if o.Port != "" {
+		opts.Port = o.Port
+	}
+	if o.Path != "" {
+		opts.Path = o.Path
+	}
+	if o.AccessZone != "" {
+		opts.AccessZone = o.AccessZone
+	}
+}
+
+// SecretValidationReport is the result of the validation of a cluster secret
+type SecretValidationReport struct {
+	Valid          bool                 `json:"valid"`
+	Error          string               `json:"error,omitempty"`
+	LogLevel       string               `json:"logLevel,omitempty"`
+	DefaultCluster string               `json:"defaultCluster,omitempty"`
+	Tenants        []string             `json:"tenants,omitempty"`
+	Clusters       []*ClusterValidation `json:"clusters,omitempty"`
+}
+
+// ClusterValidation is the result of the validation of a cluster of the secret
+type ClusterValidation struct {
+	ClusterName string             `json:"clusterName"`
+	Endpoint    string             `json:"endpoint"`
+	IsiPath     string             `json:"isiPath"`
+	Checks      []*ValidationCheck `json:"checks,omitempty"`
+}
+
+// ValidationCheck is the result of a check of a cluster
+type ValidationCheck struct {
+	Name   string `json:"name"`
+	Target string `json:"target,omitempty"`
+	Passed bool   `json:"passed"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ValidateSecret checks a cluster secret with the rules the driver applies when it loads or reloads it, with the
+// options the driver reads from its environment unless they are set in the validation options. An error is only
+// returned if the file cannot be read.
+func ValidateSecret(ctx context.Context, configFile string, opts SecretValidationOptions) (*SecretValidationReport, error) {
+	s := &service{}
+	if err := s.initializeServiceOpts(ctx); err != nil {
+		return nil, err
+	}
+	// the clusters are checked the way the controller uses them
+	s.mode = constants.ModeController
+
+	configBytes, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("file ('%s') error: %v", configFile, err)
+	}
+	return s.validateSecret(ctx, configBytes, opts), nil
+}
+
+func (s *service) validateSecret(ctx context.Context, configBytes []byte, opts SecretValidationOptions) *SecretValidationReport {
+	report := &SecretValidationReport{}
+	opts.applyTo(&s.opts)
+
+	newIsilonConfigs, defaultClusterName, logLevel, err := s.parseIsilonConfigs(ctx, configBytes, opts.ResolveCredentials, opts.CheckClusters)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	report.LogLevel = logLevel.String()
+	report.DefaultCluster = defaultClusterName
+
+	inputConfigs, jsonErr := unmarshalJSONContent(configBytes)
+	if jsonErr != nil {
+		inputConfigs, _ = unmarshalYAMLContent(configBytes)
+	}
+	tenants, err := getNewTenantConfigs(inputConfigs)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	for _, tenant := range tenants {
+		report.Tenants = append(report.Tenants, tenant.Name)
+	}
+
+	report.Valid = true
+	for _, config := range newIsilonConfigs {
+		isiConfig := config.(*IsilonClusterConfig)
+		result := &ClusterValidation{
+			ClusterName: isiConfig.ClusterName,
+			Endpoint:    isiConfig.EndpointURL,
+			IsiPath:     isiConfig.IsiPath,
+		}
+		if opts.CheckClusters {
+			result.Checks = s.checkCluster(ctx, isiConfig, getClusterAccessZones(isiConfig.ClusterName, s.opts.AccessZone, tenants))
+			for _, check := range result.Checks {
+				if !check.Passed {
+					report.Valid = false
+				}
+			}
+		}
+		report.Clusters = append(report.Clusters, result)
+	}
+	sort.Slice(report.Clusters, func(i, j int) bool {
+		return report.Clusters[i].ClusterName < report.Clusters[j].ClusterName
+	})
+	return report
+}
+
+// getClusterAccessZones returns the access zones the volumes of a cluster can be created in, the default access
+// zone and the access zones of the tenants allowed on the cluster
+func getClusterAccessZones(clusterName, defaultAccessZone string, tenants []TenantConfig) []string {
+	accessZones := []string{defaultAccessZone}
+	for _, tenant := range tenants {
+		if tenant.AccessZone == "" || tenant.AccessZone == defaultAccessZone {
+			continue
+		}
+		allowed := len(tenant.AllowedClusters) == 0
+		for _, allowedCluster := range tenant.AllowedClusters {
+			allowed = allowed || allowedCluster == clusterName
+		}
+		if allowed && !utils.IsStringInSlice(tenant.AccessZone, accessZones) {
+			accessZones = append(accessZones, tenant.AccessZone)
+		}
+	}
+	return accessZones
+}
+
+// checkCluster connects to a cluster and checks that its access zones and isiPath exist
+func (s *service) checkCluster(ctx context.Context, isiConfig *IsilonClusterConfig, accessZones []string) []*ValidationCheck {
+	connectivity := &ValidationCheck{Name: CheckConnectivity, Target: isiConfig.EndpointURL}
+	checks := []*ValidationCheck{connectivity}
+
+	var err error
+	if isiConfig.isiSvc, err = s.GetIsiService(ctx, isiConfig, utils.GetLogger().GetLevel()); err == nil {
+		err = s.controllerProbe(ctx, isiConfig)
+	}
+	if err != nil {
+		connectivity.Error = err.Error()
+		return checks
+	}
+	connectivity.Passed = true
+
+	for _, accessZone := range accessZones {
+		check := &ValidationCheck{Name: CheckAccessZone, Target: accessZone}
+		zone, err := isiConfig.isiSvc.GetZoneByName(ctx, accessZone)
+		switch {
+		case err != nil:
+			check.Error = err.Error()
+		case zone == nil:
+			check.Error = fmt.Sprintf("access zone '%s' not found", accessZone)
+		default:
+			check.Passed = true
+		}
+		checks = append(checks, check)
+	}
+
+	check := &ValidationCheck{Name: CheckIsiPath, Target: isiConfig.IsiPath}
+	exists, err := isiConfig.isiSvc.IsDirectoryExistent(ctx, isiConfig.IsiPath)
+	switch {
+	case err != nil:
+		check.Error = err.Error()
+	case !exists:
+		check.Error = fmt.Sprintf("directory '%s' not found", isiConfig.IsiPath)
+	default:
+		check.Passed = true
+	}
+	return append(checks, check)
+}
@@ -0,0 +1,6 @@
+module github.com/dell/goisilon
+
+require (
+	github.com/akutz/gournal v0.5.0
+	github.com/sirupsen/logrus v1.4.2
+)
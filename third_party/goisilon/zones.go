@@ -0,0 +1,27 @@
+/* 
+ Copyright (c) 2019 Dell Inc, or its subsidiaries.
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+      http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+ */
+package goisilon
+
+import (
+	"context"
+
+	apiv1 "github.com/dell/goisilon/api/v1"
+)
+
+// GetZoneByName returns a specific access zone which matched the name
+func (c *Client) GetZoneByName(ctx context.Context, name string) (*apiv1.IsiZone, error) {
+	return apiv1.GetZoneByName(ctx, c.API, name)
+}